@@ -0,0 +1,112 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/andy2kuo/TourHelper/internal/auth"
+	"github.com/andy2kuo/TourHelper/internal/config"
+	"github.com/andy2kuo/TourHelper/internal/dao"
+	"github.com/andy2kuo/TourHelper/internal/database"
+	"github.com/andy2kuo/TourHelper/internal/logger"
+	"github.com/andy2kuo/TourHelper/internal/services"
+)
+
+var SERVICE_VERSION = "0.0.1-dev" // 預設值，會在編譯時透過 -ldflags 覆寫
+
+func usage() {
+	fmt.Fprintf(flag.CommandLine.Output(), "用法: admin [選項] <create|set-role>\n\n")
+	fmt.Fprintf(flag.CommandLine.Output(), "  create    建立後台帳號（預設角色 super_admin），密碼由標準輸入讀取\n")
+	fmt.Fprintf(flag.CommandLine.Output(), "  set-role  變更既有帳號的角色\n\n")
+	fmt.Fprintf(flag.CommandLine.Output(), "範例:\n")
+	fmt.Fprintf(flag.CommandLine.Output(), "  admin -username root create < password.txt\n")
+	fmt.Fprintf(flag.CommandLine.Output(), "  admin -username alice -role operator set-role\n\n")
+	flag.PrintDefaults()
+}
+
+func main() {
+	serviceName := flag.String("service", "backend_server", "讀取設定檔使用的服務名稱")
+	serviceEnv := flag.String("env", "dev", "服務環境")
+	username := flag.String("username", "", "帳號")
+	role := flag.String("role", auth.RoleSuperAdmin, "角色：member, operator, admin, super_admin")
+	flag.Usage = usage
+	flag.Parse()
+
+	if flag.NArg() != 1 || *username == "" {
+		usage()
+		os.Exit(2)
+	}
+
+	env := strings.ToLower(*serviceEnv)
+	cfg, err := config.Load(*serviceName, env, SERVICE_VERSION)
+	if err != nil {
+		panic(fmt.Errorf("無法載入設定: %v", err))
+	}
+	if err := logger.Init(*serviceName, env, cfg.Log); err != nil {
+		panic(fmt.Errorf("無法初始化 Logger: %v", err))
+	}
+
+	err = run(flag.Arg(0), cfg, *username, *role)
+	if err != nil {
+		logger.Errorf("後台帳號操作失敗: %v", err)
+		fmt.Fprintln(os.Stderr, err)
+	}
+	logger.GetLogger().Close()
+
+	if err != nil {
+		os.Exit(1)
+	}
+}
+
+// run 執行指定的帳號指令
+func run(command string, cfg *config.Config, username, role string) error {
+	if err := database.InitMySQL(cfg.Database); err != nil {
+		return fmt.Errorf("資料庫初始化失敗: %w", err)
+	}
+	defer database.GetMySQL().Close()
+
+	ctx := context.Background()
+	authService := services.NewAuthService(dao.New(dao.NewDBConn(database.GetMySQL().GetMaster())))
+
+	switch command {
+	case "create":
+		password, err := readPassword()
+		if err != nil {
+			return err
+		}
+		user, err := authService.Register(ctx, services.RegisterInput{Username: username, Password: password, Role: role})
+		if err != nil {
+			return err
+		}
+		logger.Infof("已建立後台帳號 %s（會員 ID %d，角色 %s）", user.Username, user.ID, role)
+	case "set-role":
+		cred, err := authService.SetRole(ctx, username, role)
+		if err != nil {
+			return err
+		}
+		logger.Infof("已將帳號 %s 的角色變更為 %s", cred.Username, cred.Role)
+	default:
+		return fmt.Errorf("不支援的指令: %s", command)
+	}
+	return nil
+}
+
+// readPassword 從標準輸入讀取第一行作為密碼，避免密碼出現在指令列參數與 shell 歷史記錄中
+func readPassword() (string, error) {
+	fmt.Fprint(os.Stderr, "請輸入密碼: ")
+	line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	fmt.Fprintln(os.Stderr)
+	password := strings.TrimRight(line, "\r\n")
+	if password == "" {
+		if err != nil {
+			return "", fmt.Errorf("讀取密碼失敗: %w", err)
+		}
+		return "", errors.New("密碼不可為空")
+	}
+	return password, nil
+}
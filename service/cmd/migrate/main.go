@@ -0,0 +1,110 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/andy2kuo/TourHelper/internal/config"
+	"github.com/andy2kuo/TourHelper/internal/database"
+	"github.com/andy2kuo/TourHelper/internal/logger"
+	"github.com/andy2kuo/TourHelper/internal/migration"
+)
+
+var SERVICE_VERSION = "0.0.1-dev" // 預設值，會在編譯時透過 -ldflags 覆寫
+
+func usage() {
+	fmt.Fprintf(flag.CommandLine.Output(), "用法: migrate [選項] <up|down|status>\n\n")
+	fmt.Fprintf(flag.CommandLine.Output(), "  up      套用所有尚未執行的遷移\n")
+	fmt.Fprintf(flag.CommandLine.Output(), "  down    回滾最近的遷移（數量由 -steps 指定）\n")
+	fmt.Fprintf(flag.CommandLine.Output(), "  status  顯示所有遷移的套用狀態\n\n")
+	flag.PrintDefaults()
+}
+
+func main() {
+	serviceName := flag.String("service", "tour_server", "讀取設定檔使用的服務名稱")
+	serviceEnv := flag.String("env", "dev", "服務環境")
+	steps := flag.Int("steps", 1, "down 時回滾的遷移數量")
+	flag.Usage = usage
+	flag.Parse()
+
+	if flag.NArg() != 1 {
+		usage()
+		os.Exit(2)
+	}
+
+	env := strings.ToLower(*serviceEnv)
+	cfg, err := config.Load(*serviceName, env, SERVICE_VERSION)
+	if err != nil {
+		panic(fmt.Errorf("無法載入設定: %v", err))
+	}
+	if err := logger.Init(*serviceName, env, cfg.Log); err != nil {
+		panic(fmt.Errorf("無法初始化 Logger: %v", err))
+	}
+
+	err = run(flag.Arg(0), cfg, *steps)
+	if err != nil {
+		logger.Errorf("資料表遷移失敗: %v", err)
+	}
+	logger.GetLogger().Close()
+
+	if err != nil {
+		os.Exit(1)
+	}
+}
+
+// run 執行指定的遷移指令
+func run(command string, cfg *config.Config, steps int) error {
+	if err := database.InitMySQL(cfg.Database); err != nil {
+		return fmt.Errorf("資料庫初始化失敗: %w", err)
+	}
+	defer database.GetMySQL().Close()
+
+	ctx := context.Background()
+	migrator := migration.New(database.GetMySQL().GetMaster(), migration.All(), cfg.Database.MigrationLockTimeout)
+
+	switch command {
+	case "up":
+		applied, err := migrator.Up(ctx)
+		if err != nil {
+			return err
+		}
+		logger.Infof("已套用 %d 個資料表遷移", len(applied))
+	case "down":
+		rolledBack, err := migrator.Down(ctx, steps)
+		if err != nil {
+			return err
+		}
+		logger.Infof("已回滾 %d 個資料表遷移", len(rolledBack))
+	case "status":
+		statuses, err := migrator.Status(ctx)
+		if err != nil {
+			return err
+		}
+		printStatus(statuses)
+	default:
+		return fmt.Errorf("不支援的指令: %s", command)
+	}
+	return nil
+}
+
+// printStatus 以表格輸出遷移狀態
+func printStatus(statuses []migration.Status) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "VERSION\tSTATUS\tAPPLIED AT\tDESCRIPTION")
+	for _, status := range statuses {
+		state, appliedAt := "pending", "-"
+		if status.AppliedAt != nil {
+			state = "applied"
+			appliedAt = status.AppliedAt.Format("2006-01-02 15:04:05")
+		}
+		if status.Unknown {
+			state = "unknown"
+		}
+		fmt.Fprintf(w, "%d\t%s\t%s\t%s\n", status.Version, state, appliedAt, status.Description)
+	}
+	w.Flush()
+}
@@ -0,0 +1,169 @@
+package auth
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"golang.org/x/sync/singleflight"
+)
+
+// minJWKSRefetchInterval 遇到未知 kid 時重新取得 JWKS 的最短間隔，避免偽造的 kid 造成大量請求
+const minJWKSRefetchInterval = 30 * time.Second
+
+// JWKS JSON Web Key Set（RFC 7517）
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
+
+// JWK EC 公鑰
+type JWK struct {
+	KeyType   string `json:"kty"`
+	Curve     string `json:"crv"`
+	KeyID     string `json:"kid"`
+	Use       string `json:"use"`
+	Algorithm string `json:"alg"`
+	X         string `json:"x"`
+	Y         string `json:"y"`
+}
+
+// newJWK 將 P-256 公鑰轉為 JWK
+func newJWK(kid string, pub *ecdsa.PublicKey) JWK {
+	// 未壓縮格式為 0x04 || X || Y，X、Y 各 32 位元組
+	point, _ := pub.Bytes()
+	return JWK{
+		KeyType:   "EC",
+		Curve:     "P-256",
+		KeyID:     kid,
+		Use:       "sig",
+		Algorithm: signingAlgorithm,
+		X:         base64.RawURLEncoding.EncodeToString(point[1:33]),
+		Y:         base64.RawURLEncoding.EncodeToString(point[33:]),
+	}
+}
+
+// PublicKey 將 JWK 轉為 P-256 公鑰
+func (k JWK) PublicKey() (*ecdsa.PublicKey, error) {
+	if k.KeyType != "EC" || k.Curve != "P-256" {
+		return nil, fmt.Errorf("不支援的金鑰類型 %s/%s", k.KeyType, k.Curve)
+	}
+	x, err := base64.RawURLEncoding.DecodeString(k.X)
+	if err != nil {
+		return nil, err
+	}
+	y, err := base64.RawURLEncoding.DecodeString(k.Y)
+	if err != nil {
+		return nil, err
+	}
+	if len(x) != 32 || len(y) != 32 {
+		return nil, errors.New("金鑰座標長度錯誤")
+	}
+
+	point := append([]byte{0x04}, x...)
+	return ecdsa.ParseUncompressedPublicKey(elliptic.P256(), append(point, y...))
+}
+
+// RemoteKeySet 從 JWKS 端點取得的公鑰組，讓沒有私鑰的伺服器離線驗證 Token
+// 公鑰每隔 refreshInterval 重新取得；遇到未知 kid（金鑰剛輪替）時也會立即重新取得
+// 取得失敗時繼續使用先前的公鑰
+type RemoteKeySet struct {
+	url             string
+	client          *http.Client
+	refreshInterval time.Duration
+
+	mu        sync.RWMutex
+	keys      map[string]*ecdsa.PublicKey
+	fetchedAt time.Time
+	group     singleflight.Group
+}
+
+// NewRemoteKeySet 建立從 url 取得公鑰的金鑰組
+func NewRemoteKeySet(url string, refreshInterval time.Duration) *RemoteKeySet {
+	return &RemoteKeySet{
+		url:             url,
+		client:          &http.Client{Timeout: 5 * time.Second},
+		refreshInterval: refreshInterval,
+	}
+}
+
+// PublicKey 依 kid 取得公鑰
+func (r *RemoteKeySet) PublicKey(ctx context.Context, kid string) (*ecdsa.PublicKey, error) {
+	r.mu.RLock()
+	key, ok := r.keys[kid]
+	age := time.Since(r.fetchedAt)
+	r.mu.RUnlock()
+
+	if (ok && age < r.refreshInterval) || (!ok && age < minJWKSRefetchInterval) {
+		if ok {
+			return key, nil
+		}
+		return nil, fmt.Errorf("%w: %s", ErrUnknownKey, kid)
+	}
+
+	// 多個請求同時需要重新取得時只發出一次 HTTP 請求
+	_, err, _ := r.group.Do("fetch", func() (any, error) {
+		return nil, r.fetch(context.WithoutCancel(ctx))
+	})
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	if key, ok := r.keys[kid]; ok {
+		return key, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s（取得 JWKS 失敗: %v）", ErrUnknownKey, kid, err)
+	}
+	return nil, fmt.Errorf("%w: %s", ErrUnknownKey, kid)
+}
+
+// fetch 取得 JWKS 並取代目前的公鑰組
+func (r *RemoteKeySet) fetch(ctx context.Context) error {
+	// 無論成功與否都記錄時間，避免 JWKS 端點異常時每個請求都重試
+	defer func() {
+		r.mu.Lock()
+		r.fetchedAt = time.Now()
+		r.mu.Unlock()
+	}()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, r.url, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("JWKS 端點回應 %d", resp.StatusCode)
+	}
+
+	var set JWKS
+	if err := json.NewDecoder(resp.Body).Decode(&set); err != nil {
+		return fmt.Errorf("解析 JWKS 失敗: %w", err)
+	}
+
+	keys := make(map[string]*ecdsa.PublicKey, len(set.Keys))
+	for _, jwk := range set.Keys {
+		if jwk.Algorithm != "" && jwk.Algorithm != signingAlgorithm {
+			continue
+		}
+		pub, err := jwk.PublicKey()
+		if err != nil {
+			return fmt.Errorf("解析金鑰 %s 失敗: %w", jwk.KeyID, err)
+		}
+		keys[jwk.KeyID] = pub
+	}
+
+	r.mu.Lock()
+	r.keys = keys
+	r.mu.Unlock()
+	return nil
+}
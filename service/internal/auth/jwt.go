@@ -0,0 +1,253 @@
+package auth
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Token 受眾（aud），會員與管理員的 Token 互不通用
+const (
+	AudienceMember = "member"
+	AudienceAdmin  = "admin"
+)
+
+// 角色
+const (
+	RoleMember     = "member"      // 一般會員
+	RoleOperator   = "operator"    // 後台營運人員
+	RoleAdmin      = "admin"       // 後台管理員
+	RoleSuperAdmin = "super_admin" // 最高權限管理員
+)
+
+// signingAlgorithm 簽章演算法（ECDSA P-256 + SHA-256）
+const signingAlgorithm = "ES256"
+
+// clockSkew 驗證 exp、nbf 時容許的伺服器時間誤差
+const clockSkew = 30 * time.Second
+
+var (
+	// ErrInvalidToken Token 格式、簽章、簽發者或受眾錯誤
+	ErrInvalidToken = errors.New("無效的 Token")
+	// ErrTokenExpired Token 已過期
+	ErrTokenExpired = errors.New("Token 已過期")
+	// ErrUnknownKey 找不到 Token 標頭 kid 對應的金鑰（可能已輪替移除）
+	ErrUnknownKey = errors.New("找不到 Token 簽章金鑰")
+)
+
+// Claims JWT 內容
+type Claims struct {
+	Issuer    string   `json:"iss"`
+	Subject   string   `json:"sub"` // 會員或管理員 ID
+	Audience  string   `json:"aud"`
+	Roles     []string `json:"roles,omitempty"`
+	IssuedAt  int64    `json:"iat"`
+	NotBefore int64    `json:"nbf,omitempty"`
+	ExpiresAt int64    `json:"exp"`
+	ID        string   `json:"jti"`
+	SessionID string   `json:"sid,omitempty"` // 所屬工作階段，登出後即使 Token 尚未過期也會被拒絕
+}
+
+// HasRole 是否具有任一指定角色
+func (c *Claims) HasRole(roles ...string) bool {
+	for _, role := range roles {
+		if slices.Contains(c.Roles, role) {
+			return true
+		}
+	}
+	return false
+}
+
+// ExpiresTime 過期時間
+func (c *Claims) ExpiresTime() time.Time {
+	return time.Unix(c.ExpiresAt, 0)
+}
+
+// header JWT 標頭
+type header struct {
+	Algorithm string `json:"alg"`
+	Type      string `json:"typ"`
+	KeyID     string `json:"kid"`
+}
+
+// KeyResolver 依 kid 取得驗證簽章用的公鑰，找不到時回傳 ErrUnknownKey
+type KeyResolver interface {
+	PublicKey(ctx context.Context, kid string) (*ecdsa.PublicKey, error)
+}
+
+// Issuer 簽發 JWT
+type Issuer struct {
+	keys   *KeySet
+	issuer string
+	now    func() time.Time
+}
+
+// NewIssuer 建立 JWT 簽發者，以 keys 目前的簽章金鑰簽發
+func NewIssuer(keys *KeySet, issuer string) *Issuer {
+	return &Issuer{keys: keys, issuer: issuer, now: time.Now}
+}
+
+// Issue 簽發 Token，回傳 Token 字串與其內容
+func (i *Issuer) Issue(subject, audience string, roles []string, ttl time.Duration) (string, *Claims, error) {
+	return i.issue(subject, audience, roles, "", ttl)
+}
+
+// IssueSession 簽發屬於工作階段的 Token，內容取自工作階段
+func (i *Issuer) IssueSession(session *Session, ttl time.Duration) (string, *Claims, error) {
+	return i.issue(session.Subject, session.Audience, session.Roles, session.ID, ttl)
+}
+
+// issue 簽發 Token
+func (i *Issuer) issue(subject, audience string, roles []string, sessionID string, ttl time.Duration) (string, *Claims, error) {
+	kid, key := i.keys.signingKey()
+	if key == nil {
+		return "", nil, errors.New("未設定簽章金鑰")
+	}
+
+	jti, err := randomID()
+	if err != nil {
+		return "", nil, err
+	}
+
+	now := i.now()
+	claims := &Claims{
+		Issuer:    i.issuer,
+		Subject:   subject,
+		Audience:  audience,
+		Roles:     roles,
+		IssuedAt:  now.Unix(),
+		NotBefore: now.Unix(),
+		ExpiresAt: now.Add(ttl).Unix(),
+		ID:        jti,
+		SessionID: sessionID,
+	}
+
+	token, err := sign(header{Algorithm: signingAlgorithm, Type: "JWT", KeyID: kid}, claims, key)
+	if err != nil {
+		return "", nil, err
+	}
+	return token, claims, nil
+}
+
+// Verifier 驗證 JWT
+type Verifier struct {
+	keys   KeyResolver
+	issuer string
+	now    func() time.Time
+}
+
+// NewVerifier 建立 JWT 驗證者，keys 可為本機金鑰（KeySet）或遠端 JWKS（RemoteKeySet）
+func NewVerifier(keys KeyResolver, issuer string) *Verifier {
+	return &Verifier{keys: keys, issuer: issuer, now: time.Now}
+}
+
+// Verify 驗證簽章、簽發者、受眾與有效時間，成功時回傳 Token 內容
+// 已過期時回傳 ErrTokenExpired，其他錯誤皆包裝 ErrInvalidToken 或 ErrUnknownKey
+func (v *Verifier) Verify(ctx context.Context, token, audience string) (*Claims, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, fmt.Errorf("%w: 格式錯誤", ErrInvalidToken)
+	}
+
+	var h header
+	if err := decodeSegment(parts[0], &h); err != nil {
+		return nil, fmt.Errorf("%w: 標頭錯誤", ErrInvalidToken)
+	}
+	// 只接受 ES256，避免 alg=none 或以公鑰當 HMAC 密鑰的演算法混淆攻擊
+	if h.Algorithm != signingAlgorithm {
+		return nil, fmt.Errorf("%w: 不支援的演算法 %q", ErrInvalidToken, h.Algorithm)
+	}
+
+	pub, err := v.keys.PublicKey(ctx, h.KeyID)
+	if err != nil {
+		return nil, err
+	}
+	sig, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil || !verifySignature(pub, parts[0]+"."+parts[1], sig) {
+		return nil, fmt.Errorf("%w: 簽章錯誤", ErrInvalidToken)
+	}
+
+	var claims Claims
+	if err := decodeSegment(parts[1], &claims); err != nil {
+		return nil, fmt.Errorf("%w: 內容錯誤", ErrInvalidToken)
+	}
+	if claims.Issuer != v.issuer {
+		return nil, fmt.Errorf("%w: 簽發者 %q", ErrInvalidToken, claims.Issuer)
+	}
+	if claims.Audience != audience {
+		return nil, fmt.Errorf("%w: 受眾 %q", ErrInvalidToken, claims.Audience)
+	}
+
+	now := v.now()
+	if claims.NotBefore != 0 && now.Add(clockSkew).Unix() < claims.NotBefore {
+		return nil, fmt.Errorf("%w: 尚未生效", ErrInvalidToken)
+	}
+	if now.Add(-clockSkew).Unix() >= claims.ExpiresAt {
+		return nil, ErrTokenExpired
+	}
+	return &claims, nil
+}
+
+// sign 以 ES256 簽署標頭與內容
+func sign(h header, claims *Claims, key *ecdsa.PrivateKey) (string, error) {
+	headerJSON, err := json.Marshal(h)
+	if err != nil {
+		return "", err
+	}
+	claimsJSON, err := json.Marshal(claims)
+	if err != nil {
+		return "", err
+	}
+
+	signingInput := base64.RawURLEncoding.EncodeToString(headerJSON) + "." + base64.RawURLEncoding.EncodeToString(claimsJSON)
+	digest := sha256.Sum256([]byte(signingInput))
+	r, s, err := ecdsa.Sign(rand.Reader, key, digest[:])
+	if err != nil {
+		return "", fmt.Errorf("簽署 Token 失敗: %w", err)
+	}
+
+	// JWS 的 ECDSA 簽章為固定長度的 r || s（各 32 位元組），而非 ASN.1 編碼
+	sig := make([]byte, 64)
+	r.FillBytes(sig[:32])
+	s.FillBytes(sig[32:])
+	return signingInput + "." + base64.RawURLEncoding.EncodeToString(sig), nil
+}
+
+// verifySignature 驗證 ES256 簽章
+func verifySignature(pub *ecdsa.PublicKey, signingInput string, sig []byte) bool {
+	if len(sig) != 64 {
+		return false
+	}
+	digest := sha256.Sum256([]byte(signingInput))
+	r := new(big.Int).SetBytes(sig[:32])
+	s := new(big.Int).SetBytes(sig[32:])
+	return ecdsa.Verify(pub, digest[:], r, s)
+}
+
+// decodeSegment 解碼 base64url 編碼的 JSON 區段
+func decodeSegment(segment string, v any) error {
+	data, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
+// randomID 產生隨機識別碼（jti）
+func randomID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("產生 Token ID 失敗: %w", err)
+	}
+	return hex.EncodeToString(b), nil
+}
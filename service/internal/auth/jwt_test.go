@@ -0,0 +1,152 @@
+package auth
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/andy2kuo/TourHelper/internal/config"
+)
+
+// newKeyConfig 產生 PEM 格式的測試金鑰設定
+func newKeyConfig(t *testing.T, kid string) config.SigningKeyConfig {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("產生金鑰失敗: %v", err)
+	}
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatalf("編碼金鑰失敗: %v", err)
+	}
+	return config.SigningKeyConfig{
+		KeyID:      kid,
+		PrivateKey: string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})),
+	}
+}
+
+func TestVerify(t *testing.T) {
+	ctx := context.Background()
+	keys, err := NewKeySet(config.AuthConfig{Keys: []config.SigningKeyConfig{newKeyConfig(t, "k1")}})
+	if err != nil {
+		t.Fatalf("NewKeySet() 失敗: %v", err)
+	}
+	issuer := NewIssuer(keys, "tourhelper")
+	verifier := NewVerifier(keys, "tourhelper")
+
+	token, _, err := issuer.Issue("42", AudienceMember, []string{RoleMember}, time.Hour)
+	if err != nil {
+		t.Fatalf("Issue() 失敗: %v", err)
+	}
+	expired, _, _ := issuer.Issue("42", AudienceMember, nil, -time.Hour)
+
+	otherKeys, _ := GenerateKeySet("k1")
+	forged, _, _ := NewIssuer(otherKeys, "tourhelper").Issue("42", AudienceMember, nil, time.Hour)
+
+	// 竄改內容（改為管理員角色）但沿用原簽章
+	parts := strings.Split(token, ".")
+	payload, _ := json.Marshal(Claims{Issuer: "tourhelper", Subject: "1", Audience: AudienceMember, Roles: []string{RoleSuperAdmin}, ExpiresAt: time.Now().Add(time.Hour).Unix()})
+	tampered := parts[0] + "." + base64.RawURLEncoding.EncodeToString(payload) + "." + parts[2]
+
+	// alg=none 不應被接受
+	none := base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"none","typ":"JWT","kid":"k1"}`)) + "." + parts[1] + "."
+
+	tests := []struct {
+		name     string
+		token    string
+		audience string
+		wantErr  error
+	}{
+		{"有效的 Token", token, AudienceMember, nil},
+		{"受眾不符", token, AudienceAdmin, ErrInvalidToken},
+		{"已過期", expired, AudienceMember, ErrTokenExpired},
+		{"其他金鑰簽署", forged, AudienceMember, ErrInvalidToken},
+		{"內容遭竄改", tampered, AudienceMember, ErrInvalidToken},
+		{"alg=none", none, AudienceMember, ErrInvalidToken},
+		{"格式錯誤", "not-a-token", AudienceMember, ErrInvalidToken},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			claims, err := verifier.Verify(ctx, tt.token, tt.audience)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Verify() 錯誤 = %v, 期望 %v", err, tt.wantErr)
+			}
+			if err == nil && (claims.Subject != "42" || !claims.HasRole(RoleMember)) {
+				t.Errorf("Verify() = %+v, 期望會員 42", claims)
+			}
+		})
+	}
+}
+
+func TestKeyRotation(t *testing.T) {
+	ctx := context.Background()
+	oldKey, newKey := newKeyConfig(t, "2026-01"), newKeyConfig(t, "2026-07")
+
+	before, err := NewKeySet(config.AuthConfig{Keys: []config.SigningKeyConfig{oldKey}})
+	if err != nil {
+		t.Fatalf("NewKeySet() 失敗: %v", err)
+	}
+	oldToken, _, _ := NewIssuer(before, "tourhelper").Issue("1", AudienceAdmin, []string{RoleAdmin}, time.Hour)
+
+	// 輪替：加入新金鑰並改用新金鑰簽發，舊金鑰保留驗證
+	after, err := NewKeySet(config.AuthConfig{SigningKeyID: "2026-07", Keys: []config.SigningKeyConfig{oldKey, newKey}})
+	if err != nil {
+		t.Fatalf("NewKeySet() 失敗: %v", err)
+	}
+	newToken, _, _ := NewIssuer(after, "tourhelper").Issue("1", AudienceAdmin, []string{RoleAdmin}, time.Hour)
+
+	// 其他伺服器透過 JWKS 端點取得公鑰驗證
+	var fetches atomic.Int32
+	var published atomic.Pointer[KeySet]
+	published.Store(before)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fetches.Add(1)
+		json.NewEncoder(w).Encode(published.Load().JWKS())
+	}))
+	defer srv.Close()
+	remote := NewRemoteKeySet(srv.URL, time.Hour)
+	remoteVerifier := NewVerifier(remote, "tourhelper")
+
+	if _, err := remoteVerifier.Verify(ctx, oldToken, AudienceAdmin); err != nil {
+		t.Fatalf("輪替前 Verify() 舊 Token 失敗: %v", err)
+	}
+
+	// 新 kid 觸發重新取得，但需間隔 minJWKSRefetchInterval；模擬時間經過後重新取得
+	published.Store(after)
+	remote.mu.Lock()
+	remote.fetchedAt = time.Now().Add(-minJWKSRefetchInterval)
+	remote.mu.Unlock()
+
+	for _, verifier := range []*Verifier{NewVerifier(after, "tourhelper"), remoteVerifier} {
+		for name, token := range map[string]string{"舊金鑰": oldToken, "新金鑰": newToken} {
+			if _, err := verifier.Verify(ctx, token, AudienceAdmin); err != nil {
+				t.Errorf("輪替後 Verify() %s簽發的 Token 失敗: %v", name, err)
+			}
+		}
+	}
+	if n := fetches.Load(); n != 2 {
+		t.Errorf("JWKS 取得次數 = %d, 期望 2", n)
+	}
+
+	// 未知 kid 在間隔內不會重新取得
+	if _, err := remote.PublicKey(ctx, "unknown"); !errors.Is(err, ErrUnknownKey) {
+		t.Errorf("PublicKey() 錯誤 = %v, 期望 %v", err, ErrUnknownKey)
+	}
+	if n := fetches.Load(); n != 2 {
+		t.Errorf("未知 kid 後 JWKS 取得次數 = %d, 期望仍為 2", n)
+	}
+}
@@ -0,0 +1,135 @@
+package auth
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/andy2kuo/TourHelper/internal/config"
+)
+
+// KeySet 本機的簽章金鑰組，可同時保有多把金鑰（以 kid 區分）
+// 只有一把用於簽發新 Token，其餘只用於驗證，讓金鑰輪替時已簽發的 Token 仍然有效
+type KeySet struct {
+	keys       map[string]*ecdsa.PrivateKey
+	order      []string // 設定檔中的順序，JWKS 依此順序輸出
+	signingKID string
+}
+
+// NewKeySet 依設定載入簽章金鑰，SigningKeyID 未設定時使用第一把金鑰簽發
+func NewKeySet(cfg config.AuthConfig) (*KeySet, error) {
+	if len(cfg.Keys) == 0 {
+		return nil, errors.New("未設定任何簽章金鑰")
+	}
+
+	ks := &KeySet{keys: make(map[string]*ecdsa.PrivateKey, len(cfg.Keys))}
+	for _, keyCfg := range cfg.Keys {
+		if keyCfg.KeyID == "" {
+			return nil, errors.New("簽章金鑰缺少 kid")
+		}
+		if _, ok := ks.keys[keyCfg.KeyID]; ok {
+			return nil, fmt.Errorf("簽章金鑰 kid 重複: %s", keyCfg.KeyID)
+		}
+
+		key, err := loadPrivateKey(keyCfg)
+		if err != nil {
+			return nil, fmt.Errorf("載入簽章金鑰 %s 失敗: %w", keyCfg.KeyID, err)
+		}
+		ks.keys[keyCfg.KeyID] = key
+		ks.order = append(ks.order, keyCfg.KeyID)
+	}
+
+	ks.signingKID = cfg.SigningKeyID
+	if ks.signingKID == "" {
+		ks.signingKID = ks.order[0]
+	}
+	if _, ok := ks.keys[ks.signingKID]; !ok {
+		return nil, fmt.Errorf("找不到 signingKeyID 指定的金鑰: %s", ks.signingKID)
+	}
+	return ks, nil
+}
+
+// GenerateKeySet 產生只有一把隨機金鑰的金鑰組（開發與測試用，重新啟動後先前簽發的 Token 全部失效）
+func GenerateKeySet(kid string) (*KeySet, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, fmt.Errorf("產生簽章金鑰失敗: %w", err)
+	}
+	return &KeySet{
+		keys:       map[string]*ecdsa.PrivateKey{kid: key},
+		order:      []string{kid},
+		signingKID: kid,
+	}, nil
+}
+
+// PublicKey 依 kid 取得公鑰
+func (ks *KeySet) PublicKey(_ context.Context, kid string) (*ecdsa.PublicKey, error) {
+	key, ok := ks.keys[kid]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownKey, kid)
+	}
+	return &key.PublicKey, nil
+}
+
+// JWKS 所有金鑰的公鑰（供 /.well-known/jwks.json 輸出）
+func (ks *KeySet) JWKS() JWKS {
+	set := JWKS{Keys: make([]JWK, 0, len(ks.order))}
+	for _, kid := range ks.order {
+		set.Keys = append(set.Keys, newJWK(kid, &ks.keys[kid].PublicKey))
+	}
+	return set
+}
+
+// signingKey 目前用於簽發的金鑰
+func (ks *KeySet) signingKey() (string, *ecdsa.PrivateKey) {
+	return ks.signingKID, ks.keys[ks.signingKID]
+}
+
+// loadPrivateKey 讀取 PEM 格式的 ECDSA P-256 私鑰（PKCS#8 或 SEC 1）
+func loadPrivateKey(cfg config.SigningKeyConfig) (*ecdsa.PrivateKey, error) {
+	data := []byte(cfg.PrivateKey)
+	if cfg.PrivateKeyFile != "" {
+		var err error
+		if data, err = os.ReadFile(cfg.PrivateKeyFile); err != nil {
+			return nil, err
+		}
+	}
+
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("找不到 PEM 資料")
+	}
+
+	var key *ecdsa.PrivateKey
+	switch block.Type {
+	case "EC PRIVATE KEY":
+		k, err := x509.ParseECPrivateKey(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		key = k
+	case "PRIVATE KEY":
+		k, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		ecKey, ok := k.(*ecdsa.PrivateKey)
+		if !ok {
+			return nil, errors.New("私鑰不是 ECDSA 金鑰")
+		}
+		key = ecKey
+	default:
+		return nil, fmt.Errorf("不支援的 PEM 類型 %q", block.Type)
+	}
+
+	if key.Curve != elliptic.P256() {
+		return nil, errors.New("私鑰需使用 P-256 曲線")
+	}
+	return key, nil
+}
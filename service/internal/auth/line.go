@@ -0,0 +1,226 @@
+package auth
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/andy2kuo/TourHelper/internal/config"
+)
+
+// LINE Login v2.1 官方位址
+const (
+	defaultLineAuthorizeURL = "https://access.line.me/oauth2/v2.1/authorize"
+	defaultLineTokenURL     = "https://api.line.me/oauth2/v2.1/token"
+	defaultLineProfileURL   = "https://api.line.me/v2/profile"
+	defaultLineTimeout      = 5 * time.Second
+)
+
+// lineIDTokenIssuer LINE ID Token 的 iss
+const lineIDTokenIssuer = "https://access.line.me"
+
+// ErrLineLogin LINE 拒絕授權碼或 ID Token 驗證失敗（授權碼已使用或過期、nonce 不符、簽章錯誤等）
+var ErrLineLogin = errors.New("LINE 登入驗證失敗")
+
+// LineTokens LINE Token 端點的回應
+type LineTokens struct {
+	AccessToken string `json:"access_token"`
+	IDToken     string `json:"id_token"`
+	ExpiresIn   int    `json:"expires_in"`
+	Scope       string `json:"scope"`
+	TokenType   string `json:"token_type"`
+}
+
+// LineIDToken LINE ID Token 的內容
+type LineIDToken struct {
+	Issuer    string `json:"iss"`
+	Subject   string `json:"sub"` // LINE 使用者 ID
+	Audience  string `json:"aud"` // Channel ID
+	ExpiresAt int64  `json:"exp"`
+	IssuedAt  int64  `json:"iat"`
+	Nonce     string `json:"nonce"`
+	Name      string `json:"name"`
+	Picture   string `json:"picture"`
+}
+
+// LineProfile LINE 使用者資料
+type LineProfile struct {
+	UserID      string `json:"userId"`
+	DisplayName string `json:"displayName"`
+	PictureURL  string `json:"pictureUrl"`
+}
+
+// lineError LINE API 錯誤回應
+type lineError struct {
+	Error            string `json:"error"`
+	ErrorDescription string `json:"error_description"`
+	Message          string `json:"message"`
+}
+
+// LineClient LINE Login v2.1 客戶端
+type LineClient struct {
+	cfg    config.LineLoginConfig
+	client *http.Client
+	now    func() time.Time
+}
+
+// NewLineClient 建立 LINE Login 客戶端，未設定的 API 位址使用官方位址
+func NewLineClient(cfg config.LineLoginConfig) (*LineClient, error) {
+	if cfg.ChannelID == "" || cfg.ChannelSecret == "" || cfg.RedirectURI == "" {
+		return nil, errors.New("LINE Login 需要設定 auth.line.channelID、channelSecret 與 redirectURI")
+	}
+	if cfg.AuthorizeURL == "" {
+		cfg.AuthorizeURL = defaultLineAuthorizeURL
+	}
+	if cfg.TokenURL == "" {
+		cfg.TokenURL = defaultLineTokenURL
+	}
+	if cfg.ProfileURL == "" {
+		cfg.ProfileURL = defaultLineProfileURL
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = defaultLineTimeout
+	}
+
+	return &LineClient{
+		cfg:    cfg,
+		client: &http.Client{Timeout: cfg.Timeout},
+		now:    time.Now,
+	}, nil
+}
+
+// AuthorizeURL LINE 授權頁網址，使用者同意後 LINE 會帶著 code 與 state 導回 RedirectURI
+func (c *LineClient) AuthorizeURL(state, nonce string) string {
+	query := url.Values{}
+	query.Set("response_type", "code")
+	query.Set("client_id", c.cfg.ChannelID)
+	query.Set("redirect_uri", c.cfg.RedirectURI)
+	query.Set("state", state)
+	query.Set("scope", "openid profile")
+	query.Set("nonce", nonce)
+	return c.cfg.AuthorizeURL + "?" + query.Encode()
+}
+
+// Exchange 以授權碼換取 Access Token 與 ID Token
+func (c *LineClient) Exchange(ctx context.Context, code string) (*LineTokens, error) {
+	form := url.Values{}
+	form.Set("grant_type", "authorization_code")
+	form.Set("code", code)
+	form.Set("redirect_uri", c.cfg.RedirectURI)
+	form.Set("client_id", c.cfg.ChannelID)
+	form.Set("client_secret", c.cfg.ChannelSecret)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.cfg.TokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, fmt.Errorf("建立 LINE Token 請求失敗: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	var tokens LineTokens
+	if err := c.do(req, &tokens); err != nil {
+		return nil, err
+	}
+	if tokens.AccessToken == "" || tokens.IDToken == "" {
+		return nil, fmt.Errorf("%w: LINE 未回傳 ID Token（scope 需包含 openid）", ErrLineLogin)
+	}
+	return &tokens, nil
+}
+
+// VerifyIDToken 驗證 ID Token 的簽章（HS256，以 Channel Secret 簽署）、簽發者、受眾、有效時間與 nonce
+func (c *LineClient) VerifyIDToken(idToken, nonce string) (*LineIDToken, error) {
+	parts := strings.Split(idToken, ".")
+	if len(parts) != 3 {
+		return nil, fmt.Errorf("%w: ID Token 格式錯誤", ErrLineLogin)
+	}
+
+	var h header
+	if err := decodeSegment(parts[0], &h); err != nil || h.Algorithm != "HS256" {
+		return nil, fmt.Errorf("%w: 不支援的 ID Token 簽章演算法", ErrLineLogin)
+	}
+
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, fmt.Errorf("%w: ID Token 簽章格式錯誤", ErrLineLogin)
+	}
+	mac := hmac.New(sha256.New, []byte(c.cfg.ChannelSecret))
+	mac.Write([]byte(parts[0] + "." + parts[1]))
+	if !hmac.Equal(signature, mac.Sum(nil)) {
+		return nil, fmt.Errorf("%w: ID Token 簽章錯誤", ErrLineLogin)
+	}
+
+	var claims LineIDToken
+	if err := decodeSegment(parts[1], &claims); err != nil {
+		return nil, fmt.Errorf("%w: ID Token 內容格式錯誤", ErrLineLogin)
+	}
+
+	now := c.now()
+	switch {
+	case claims.Issuer != lineIDTokenIssuer:
+		return nil, fmt.Errorf("%w: ID Token 簽發者不符", ErrLineLogin)
+	case claims.Audience != c.cfg.ChannelID:
+		return nil, fmt.Errorf("%w: ID Token 受眾不符", ErrLineLogin)
+	case now.After(time.Unix(claims.ExpiresAt, 0).Add(clockSkew)):
+		return nil, fmt.Errorf("%w: ID Token 已過期", ErrLineLogin)
+	case !hmac.Equal([]byte(claims.Nonce), []byte(nonce)):
+		return nil, fmt.Errorf("%w: nonce 不符", ErrLineLogin)
+	case claims.Subject == "":
+		return nil, fmt.Errorf("%w: ID Token 缺少使用者 ID", ErrLineLogin)
+	}
+	return &claims, nil
+}
+
+// Profile 以 Access Token 取得使用者資料
+func (c *LineClient) Profile(ctx context.Context, accessToken string) (*LineProfile, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.cfg.ProfileURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("建立 LINE Profile 請求失敗: %w", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+accessToken)
+
+	var profile LineProfile
+	if err := c.do(req, &profile); err != nil {
+		return nil, err
+	}
+	return &profile, nil
+}
+
+// do 呼叫 LINE API 並解析 JSON 回應，LINE 回應 4xx 時以 ErrLineLogin 包裝
+func (c *LineClient) do(req *http.Request, out any) error {
+	res, err := c.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("呼叫 LINE API 失敗: %w", err)
+	}
+	defer res.Body.Close()
+
+	body, err := io.ReadAll(res.Body)
+	if err != nil {
+		return fmt.Errorf("讀取 LINE API 回應失敗: %w", err)
+	}
+
+	if res.StatusCode != http.StatusOK {
+		var apiErr lineError
+		_ = json.Unmarshal(body, &apiErr)
+		message := apiErr.ErrorDescription
+		if message == "" {
+			message = apiErr.Message
+		}
+		if res.StatusCode >= 400 && res.StatusCode < 500 {
+			return fmt.Errorf("%w: LINE 回應錯誤 (HTTP %d): %s %s", ErrLineLogin, res.StatusCode, apiErr.Error, message)
+		}
+		return fmt.Errorf("LINE API 回應錯誤 (HTTP %d): %s %s", res.StatusCode, apiErr.Error, message)
+	}
+
+	if err := json.Unmarshal(body, out); err != nil {
+		return fmt.Errorf("解析 LINE API 回應失敗: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,112 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// ErrUnsupportedHash 無法辨識的密碼雜湊格式
+var ErrUnsupportedHash = errors.New("不支援的密碼雜湊格式")
+
+// argon2id 參數（OWASP 建議值：19 MiB 記憶體、2 次迭代、1 條平行線）
+// 調整參數後，舊參數產生的雜湊仍可驗證，並會在登入成功時透過 NeedsRehash 更新
+const (
+	argon2Memory  uint32 = 19 * 1024 // KiB
+	argon2Time    uint32 = 2
+	argon2Threads uint8  = 1
+	argon2SaltLen        = 16
+	argon2KeyLen  uint32 = 32
+)
+
+// argon2Hash 解析後的 argon2id 雜湊
+type argon2Hash struct {
+	memory  uint32
+	time    uint32
+	threads uint8
+	salt    []byte
+	key     []byte
+}
+
+// HashPassword 以 argon2id 雜湊密碼，回傳 PHC 格式字串：$argon2id$v=19$m=...,t=...,p=...$salt$hash
+func HashPassword(password string) (string, error) {
+	salt := make([]byte, argon2SaltLen)
+	if _, err := rand.Read(salt); err != nil {
+		return "", fmt.Errorf("產生鹽值失敗: %w", err)
+	}
+
+	key := argon2.IDKey([]byte(password), salt, argon2Time, argon2Memory, argon2Threads, argon2KeyLen)
+	return fmt.Sprintf("$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s",
+		argon2.Version, argon2Memory, argon2Time, argon2Threads,
+		base64.RawStdEncoding.EncodeToString(salt),
+		base64.RawStdEncoding.EncodeToString(key),
+	), nil
+}
+
+// VerifyPassword 驗證密碼是否與雜湊相符，支援 argon2id 與 bcrypt（$2a$、$2b$、$2y$）
+// 雜湊格式無法辨識時回傳 ErrUnsupportedHash
+func VerifyPassword(password, encoded string) (bool, error) {
+	if isBcrypt(encoded) {
+		err := bcrypt.CompareHashAndPassword([]byte(encoded), []byte(password))
+		if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
+			return false, nil
+		}
+		return err == nil, err
+	}
+
+	h, err := parseArgon2(encoded)
+	if err != nil {
+		return false, err
+	}
+	key := argon2.IDKey([]byte(password), h.salt, h.time, h.memory, h.threads, uint32(len(h.key)))
+	return subtle.ConstantTimeCompare(key, h.key) == 1, nil
+}
+
+// NeedsRehash 雜湊是否應以目前的演算法與參數重新產生（例如 bcrypt 或舊的 argon2id 參數）
+func NeedsRehash(encoded string) bool {
+	h, err := parseArgon2(encoded)
+	if err != nil {
+		return true
+	}
+	return h.memory != argon2Memory || h.time != argon2Time || h.threads != argon2Threads ||
+		len(h.salt) != argon2SaltLen || uint32(len(h.key)) != argon2KeyLen
+}
+
+// isBcrypt 是否為 bcrypt 雜湊
+func isBcrypt(encoded string) bool {
+	return strings.HasPrefix(encoded, "$2a$") || strings.HasPrefix(encoded, "$2b$") || strings.HasPrefix(encoded, "$2y$")
+}
+
+// parseArgon2 解析 PHC 格式的 argon2id 雜湊
+func parseArgon2(encoded string) (*argon2Hash, error) {
+	// 以 $ 分隔後為 ["", "argon2id", "v=19", "m=...,t=...,p=...", salt, hash]
+	parts := strings.Split(encoded, "$")
+	if len(parts) != 6 || parts[0] != "" || parts[1] != "argon2id" {
+		return nil, ErrUnsupportedHash
+	}
+
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil || version != argon2.Version {
+		return nil, fmt.Errorf("%w: argon2 版本 %q", ErrUnsupportedHash, parts[2])
+	}
+
+	h := &argon2Hash{}
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &h.memory, &h.time, &h.threads); err != nil || h.time == 0 || h.threads == 0 {
+		return nil, fmt.Errorf("%w: argon2 參數 %q", ErrUnsupportedHash, parts[3])
+	}
+
+	var err error
+	if h.salt, err = base64.RawStdEncoding.DecodeString(parts[4]); err != nil {
+		return nil, fmt.Errorf("%w: 鹽值編碼錯誤", ErrUnsupportedHash)
+	}
+	if h.key, err = base64.RawStdEncoding.DecodeString(parts[5]); err != nil || len(h.key) == 0 {
+		return nil, fmt.Errorf("%w: 雜湊值編碼錯誤", ErrUnsupportedHash)
+	}
+	return h, nil
+}
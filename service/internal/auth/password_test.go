@@ -0,0 +1,57 @@
+package auth
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+func TestVerifyPassword(t *testing.T) {
+	argonHash, err := HashPassword("secret123")
+	if err != nil {
+		t.Fatalf("HashPassword() 失敗: %v", err)
+	}
+	if !strings.HasPrefix(argonHash, "$argon2id$v=19$") {
+		t.Errorf("HashPassword() = %s, 期望 PHC 格式的 argon2id 雜湊", argonHash)
+	}
+	bcryptHash, err := bcrypt.GenerateFromPassword([]byte("secret123"), bcrypt.MinCost)
+	if err != nil {
+		t.Fatalf("產生 bcrypt 雜湊失敗: %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		password string
+		hash     string
+		want     bool
+		wantErr  error
+	}{
+		{"argon2id 密碼正確", "secret123", argonHash, true, nil},
+		{"argon2id 密碼錯誤", "secret124", argonHash, false, nil},
+		{"bcrypt 密碼正確", "secret123", string(bcryptHash), true, nil},
+		{"bcrypt 密碼錯誤", "secret124", string(bcryptHash), false, nil},
+		{"未知格式", "secret123", "plain-text", false, ErrUnsupportedHash},
+		{"argon2 參數錯誤", "secret123", "$argon2id$v=19$m=1,t=0,p=1$c2FsdA$a2V5", false, ErrUnsupportedHash},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := VerifyPassword(tt.password, tt.hash)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("VerifyPassword() 錯誤 = %v, 期望 %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("VerifyPassword() = %v, 期望 %v", got, tt.want)
+			}
+		})
+	}
+
+	if NeedsRehash(argonHash) {
+		t.Error("NeedsRehash() 目前參數的 argon2id 雜湊不需重新產生")
+	}
+	if !NeedsRehash(string(bcryptHash)) {
+		t.Error("NeedsRehash() bcrypt 雜湊應重新產生")
+	}
+}
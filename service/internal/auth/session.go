@@ -0,0 +1,310 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+var (
+	// ErrInvalidRefreshToken Refresh Token 格式錯誤、不存在、已過期或受眾不符
+	ErrInvalidRefreshToken = errors.New("無效的 Refresh Token")
+	// ErrRefreshTokenReused 已輪替過的 Refresh Token 再次被使用（可能已外洩），整個工作階段已撤銷
+	ErrRefreshTokenReused = errors.New("Refresh Token 已使用過，工作階段已撤銷")
+	// ErrSessionRevoked Token 所屬的工作階段已登出、撤銷或過期
+	ErrSessionRevoked = errors.New("工作階段已失效")
+)
+
+// rotateRefreshScript 輪替 Refresh Token
+// 與目前的 Refresh Token 相符時換成新的、更新角色並回傳工作階段資料；
+// 與已輪替過的 Refresh Token 相符時視為重複使用，刪除整個工作階段並回傳 -1；其餘情況回傳 0
+// 工作階段與已使用 Token 的 key 使用相同的 hash tag，Cluster 模式下位於同一節點
+var rotateRefreshScript = redis.NewScript(`
+local session = redis.call('HMGET', KEYS[1], 'refresh', 'aud')
+if not session[1] or session[2] ~= ARGV[4] then
+	return 0
+end
+if session[1] == ARGV[1] then
+	redis.call('HSET', KEYS[1], 'refresh', ARGV[2], 'roles', ARGV[5])
+	redis.call('SADD', KEYS[2], ARGV[1])
+	redis.call('PEXPIRE', KEYS[1], ARGV[3])
+	redis.call('PEXPIRE', KEYS[2], ARGV[3])
+	return redis.call('HGETALL', KEYS[1])
+end
+if redis.call('SISMEMBER', KEYS[2], ARGV[1]) == 1 then
+	redis.call('DEL', KEYS[1], KEYS[2])
+	return -1
+end
+return 0
+`)
+
+// revokeRefreshScript 以目前或已輪替過的 Refresh Token 刪除工作階段，不相符時回傳 0
+var revokeRefreshScript = redis.NewScript(`
+local current = redis.call('HGET', KEYS[1], 'refresh')
+if not current then
+	return 0
+end
+if current == ARGV[1] or redis.call('SISMEMBER', KEYS[2], ARGV[1]) == 1 then
+	return redis.call('DEL', KEYS[1], KEYS[2])
+end
+return 0
+`)
+
+// indexSessionScript 將工作階段 ID（ARGV[1]，空字串表示不新增）加入主體的工作階段索引，
+// 並將索引的有效時間延長為 ARGV[2] 毫秒；已有較長的有效時間時不縮短（會員與管理員工作階段的閒置到期時間不同）
+var indexSessionScript = redis.NewScript(`
+if ARGV[1] ~= '' then
+	redis.call('SADD', KEYS[1], ARGV[1])
+end
+if redis.call('PTTL', KEYS[1]) < tonumber(ARGV[2]) then
+	redis.call('PEXPIRE', KEYS[1], ARGV[2])
+end
+return 1
+`)
+
+// Session 登入工作階段
+// 每次登入建立一個工作階段，Access Token 以 sid 指向工作階段；Refresh Token 每次使用後輪替，
+// 同一工作階段輪替出的所有 Refresh Token 屬於同一個 token family，任一舊 Token 被重複使用時整個工作階段一併撤銷
+type Session struct {
+	ID        string
+	Subject   string
+	Audience  string
+	Roles     []string
+	CreatedAt time.Time
+}
+
+// SessionStore 建立在 Redis 上的工作階段儲存，多台伺服器共用，登出後所有伺服器立即拒絕該工作階段的 Token
+type SessionStore struct {
+	client redis.UniversalClient
+}
+
+// NewSessionStore 建立工作階段儲存
+func NewSessionStore(client redis.UniversalClient) *SessionStore {
+	return &SessionStore{client: client}
+}
+
+// Create 建立工作階段並回傳第一個 Refresh Token，工作階段閒置超過 ttl 後過期
+func (s *SessionStore) Create(ctx context.Context, subject, audience string, roles []string, ttl time.Duration) (*Session, string, error) {
+	session := &Session{
+		ID:        rand.Text(),
+		Subject:   subject,
+		Audience:  audience,
+		Roles:     roles,
+		CreatedAt: time.Now(),
+	}
+	refreshToken := newRefreshToken(session.ID)
+
+	key := sessionKey(session.ID)
+	_, err := s.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.HSet(ctx, key,
+			"sub", session.Subject,
+			"aud", session.Audience,
+			"roles", strings.Join(session.Roles, ","),
+			"created", session.CreatedAt.Unix(),
+			"refresh", hashRefreshToken(refreshToken),
+		)
+		pipe.PExpire(ctx, key, ttl)
+		return nil
+	})
+	if err != nil {
+		return nil, "", fmt.Errorf("建立工作階段失敗: %w", err)
+	}
+
+	// 主體的工作階段索引與工作階段可能位於不同節點，無法放在同一個交易中
+	if err := s.index(ctx, session, ttl, true); err != nil {
+		return nil, "", err
+	}
+	return session, refreshToken, nil
+}
+
+// Lookup 取得 Refresh Token 所屬的工作階段，工作階段不存在或受眾不符時回傳 ErrInvalidRefreshToken
+// 只以 Token 中的工作階段 ID 查詢、不驗證 Token 本身，換發前用來查詢工作階段的主體；Token 是否有效由 Rotate 判斷
+func (s *SessionStore) Lookup(ctx context.Context, refreshToken, audience string) (*Session, error) {
+	id, ok := parseRefreshToken(refreshToken)
+	if !ok {
+		return nil, ErrInvalidRefreshToken
+	}
+
+	fields, err := s.client.HGetAll(ctx, sessionKey(id)).Result()
+	if err != nil {
+		return nil, fmt.Errorf("查詢工作階段失敗: %w", err)
+	}
+	if len(fields) == 0 || fields["aud"] != audience {
+		return nil, ErrInvalidRefreshToken
+	}
+
+	values := make([]interface{}, 0, len(fields)*2)
+	for name, value := range fields {
+		values = append(values, name, value)
+	}
+	return parseSession(id, values), nil
+}
+
+// Rotate 以 Refresh Token 換取新的 Refresh Token、將工作階段的角色更新為 roles 並延長 ttl
+// Token 無效或受眾不符時回傳 ErrInvalidRefreshToken；Token 已輪替過時撤銷整個工作階段並回傳 ErrRefreshTokenReused
+func (s *SessionStore) Rotate(ctx context.Context, refreshToken, audience string, roles []string, ttl time.Duration) (*Session, string, error) {
+	id, ok := parseRefreshToken(refreshToken)
+	if !ok {
+		return nil, "", ErrInvalidRefreshToken
+	}
+
+	next := newRefreshToken(id)
+	key := sessionKey(id)
+	result, err := rotateRefreshScript.Run(ctx, s.client, []string{key, key + ":used"},
+		hashRefreshToken(refreshToken), hashRefreshToken(next), ttl.Milliseconds(), audience, strings.Join(roles, ",")).Result()
+	if err != nil {
+		return nil, "", fmt.Errorf("輪替 Refresh Token 失敗: %w", err)
+	}
+
+	switch v := result.(type) {
+	case []interface{}:
+		session := parseSession(id, v)
+		if err := s.index(ctx, session, ttl, false); err != nil {
+			return nil, "", err
+		}
+		return session, next, nil
+	case int64:
+		if v < 0 {
+			return nil, "", ErrRefreshTokenReused
+		}
+	}
+	return nil, "", ErrInvalidRefreshToken
+}
+
+// Active 工作階段是否仍有效
+func (s *SessionStore) Active(ctx context.Context, id string) (bool, error) {
+	n, err := s.client.Exists(ctx, sessionKey(id)).Result()
+	if err != nil {
+		return false, fmt.Errorf("查詢工作階段失敗: %w", err)
+	}
+	return n > 0, nil
+}
+
+// Revoke 撤銷工作階段，工作階段不存在時不回傳錯誤
+func (s *SessionStore) Revoke(ctx context.Context, id string) error {
+	key := sessionKey(id)
+	if err := s.client.Del(ctx, key, key+":used").Err(); err != nil {
+		return fmt.Errorf("撤銷工作階段失敗: %w", err)
+	}
+	return nil
+}
+
+// RevokeByRefreshToken 以 Refresh Token（目前或已輪替過的皆可）撤銷所屬工作階段，Token 不相符時回傳 ErrInvalidRefreshToken
+func (s *SessionStore) RevokeByRefreshToken(ctx context.Context, refreshToken string) error {
+	id, ok := parseRefreshToken(refreshToken)
+	if !ok {
+		return ErrInvalidRefreshToken
+	}
+
+	key := sessionKey(id)
+	n, err := revokeRefreshScript.Run(ctx, s.client, []string{key, key + ":used"}, hashRefreshToken(refreshToken)).Int64()
+	if err != nil {
+		return fmt.Errorf("撤銷工作階段失敗: %w", err)
+	}
+	if n == 0 {
+		return ErrInvalidRefreshToken
+	}
+	return nil
+}
+
+// RevokeSubject 撤銷主體（會員或管理員 ID）所有受眾的全部工作階段，例如會員被合併後
+func (s *SessionStore) RevokeSubject(ctx context.Context, subject string) error {
+	key := subjectSessionsKey(subject)
+	ids, err := s.client.SMembers(ctx, key).Result()
+	if err != nil {
+		return fmt.Errorf("查詢工作階段失敗: %w", err)
+	}
+	if len(ids) == 0 {
+		return nil
+	}
+
+	for _, id := range ids {
+		if err := s.Revoke(ctx, id); err != nil {
+			return err
+		}
+	}
+	// 只移除已撤銷的工作階段，不影響撤銷期間新建立的工作階段
+	members := make([]interface{}, len(ids))
+	for i, id := range ids {
+		members[i] = id
+	}
+	if err := s.client.SRem(ctx, key, members...).Err(); err != nil {
+		return fmt.Errorf("撤銷工作階段失敗: %w", err)
+	}
+	return nil
+}
+
+// index 將工作階段加入主體的工作階段索引（add 為 false 時只延長有效時間），索引的有效時間至少延長為 ttl
+func (s *SessionStore) index(ctx context.Context, session *Session, ttl time.Duration, add bool) error {
+	id := ""
+	if add {
+		id = session.ID
+	}
+	err := indexSessionScript.Run(ctx, s.client, []string{subjectSessionsKey(session.Subject)}, id, ttl.Milliseconds()).Err()
+	if err != nil {
+		return fmt.Errorf("更新工作階段索引失敗: %w", err)
+	}
+	return nil
+}
+
+// sessionKey 工作階段的 Redis key
+func sessionKey(id string) string {
+	return "tourhelper:session:{" + id + "}"
+}
+
+// subjectSessionsKey 主體所有工作階段 ID 的 Redis key（Set），成員可能包含已過期或已登出的工作階段
+func subjectSessionsKey(subject string) string {
+	return "tourhelper:subject-sessions:{" + subject + "}"
+}
+
+// newRefreshToken 產生 Refresh Token，格式為「工作階段 ID.隨機值」
+func newRefreshToken(sessionID string) string {
+	return sessionID + "." + rand.Text()
+}
+
+// parseRefreshToken 取得 Refresh Token 所屬的工作階段 ID
+func parseRefreshToken(token string) (string, bool) {
+	id, secret, ok := strings.Cut(token, ".")
+	if !ok || id == "" || secret == "" {
+		return "", false
+	}
+	return id, true
+}
+
+// hashRefreshToken Redis 只保存 Refresh Token 的雜湊值，Redis 資料外洩時無法直接使用
+func hashRefreshToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// parseSession 將 HGETALL 的結果轉為工作階段
+func parseSession(id string, fields []interface{}) *Session {
+	session := &Session{ID: id}
+	for i := 0; i+1 < len(fields); i += 2 {
+		name, _ := fields[i].(string)
+		value, _ := fields[i+1].(string)
+		switch name {
+		case "sub":
+			session.Subject = value
+		case "aud":
+			session.Audience = value
+		case "roles":
+			if value != "" {
+				session.Roles = strings.Split(value, ",")
+			}
+		case "created":
+			if sec, err := strconv.ParseInt(value, 10, 64); err == nil {
+				session.CreatedAt = time.Unix(sec, 0)
+			}
+		}
+	}
+	return session
+}
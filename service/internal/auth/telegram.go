@@ -0,0 +1,87 @@
+package auth
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrTelegramLogin Telegram Login Widget 資料驗證失敗（簽章錯誤、資料過期或格式錯誤）
+var ErrTelegramLogin = errors.New("Telegram 登入驗證失敗")
+
+// TelegramLogin 驗證通過的 Telegram Login Widget 使用者資料
+type TelegramLogin struct {
+	ID        int64
+	FirstName string
+	LastName  string
+	Username  string
+	PhotoURL  string
+	AuthDate  time.Time
+}
+
+// DisplayName 顯示名稱（與 Telegram Bot 建立會員時相同）
+func (l *TelegramLogin) DisplayName() string {
+	return strings.TrimSpace(l.FirstName + " " + l.LastName)
+}
+
+// VerifyTelegramLogin 驗證 Telegram Login Widget 回傳的資料
+// 簽章為以 SHA256(Bot Token) 為金鑰、對 data-check-string（除 hash 外的欄位依名稱排序，以 key=value 換行連接）計算的 HMAC-SHA256；
+// auth_date 需在 maxAge 之內，避免舊資料被重複使用
+// https://core.telegram.org/widgets/login#checking-authorization
+func VerifyTelegramLogin(data map[string]string, botToken string, maxAge time.Duration, now time.Time) (*TelegramLogin, error) {
+	hash, err := hex.DecodeString(data["hash"])
+	if err != nil || len(hash) != sha256.Size {
+		return nil, fmt.Errorf("%w: hash 格式錯誤", ErrTelegramLogin)
+	}
+
+	keys := make([]string, 0, len(data))
+	for key := range data {
+		if key != "hash" {
+			keys = append(keys, key)
+		}
+	}
+	slices.Sort(keys)
+	lines := make([]string, len(keys))
+	for i, key := range keys {
+		lines[i] = key + "=" + data[key]
+	}
+
+	secret := sha256.Sum256([]byte(botToken))
+	mac := hmac.New(sha256.New, secret[:])
+	mac.Write([]byte(strings.Join(lines, "\n")))
+	if !hmac.Equal(hash, mac.Sum(nil)) {
+		return nil, fmt.Errorf("%w: 簽章錯誤", ErrTelegramLogin)
+	}
+
+	authDate, err := strconv.ParseInt(data["auth_date"], 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("%w: auth_date 格式錯誤", ErrTelegramLogin)
+	}
+	authTime := time.Unix(authDate, 0)
+	switch {
+	case now.Sub(authTime) > maxAge:
+		return nil, fmt.Errorf("%w: 登入資料已過期", ErrTelegramLogin)
+	case authTime.After(now.Add(clockSkew)):
+		return nil, fmt.Errorf("%w: auth_date 晚於目前時間", ErrTelegramLogin)
+	}
+
+	id, err := strconv.ParseInt(data["id"], 10, 64)
+	if err != nil || id <= 0 {
+		return nil, fmt.Errorf("%w: id 格式錯誤", ErrTelegramLogin)
+	}
+
+	return &TelegramLogin{
+		ID:        id,
+		FirstName: data["first_name"],
+		LastName:  data["last_name"],
+		Username:  data["username"],
+		PhotoURL:  data["photo_url"],
+		AuthDate:  authTime,
+	}, nil
+}
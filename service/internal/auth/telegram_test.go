@@ -0,0 +1,72 @@
+package auth
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"maps"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+// signTelegramLogin 以 Bot Token 計算 Login Widget 資料的 hash
+func signTelegramLogin(data map[string]string, botToken string) map[string]string {
+	var lines []string
+	for _, key := range slices.Sorted(maps.Keys(data)) {
+		lines = append(lines, key+"="+data[key])
+	}
+	secret := sha256.Sum256([]byte(botToken))
+	mac := hmac.New(sha256.New, secret[:])
+	mac.Write([]byte(strings.Join(lines, "\n")))
+
+	signed := maps.Clone(data)
+	signed["hash"] = hex.EncodeToString(mac.Sum(nil))
+	return signed
+}
+
+func TestVerifyTelegramLogin(t *testing.T) {
+	const botToken = "123456:ABC-DEF"
+	now := time.Unix(1792137600, 0)
+	payload := map[string]string{
+		"id":         "987654321",
+		"first_name": "小明",
+		"last_name":  "王",
+		"username":   "xiaoming",
+		"auth_date":  "1792137000",
+	}
+
+	valid := signTelegramLogin(payload, botToken)
+	tampered := maps.Clone(valid)
+	tampered["id"] = "1"
+	old := signTelegramLogin(map[string]string{"id": "987654321", "auth_date": "1792000000"}, botToken)
+	future := signTelegramLogin(map[string]string{"id": "987654321", "auth_date": "1792141200"}, botToken)
+	noHash := maps.Clone(payload)
+
+	tests := []struct {
+		name    string
+		data    map[string]string
+		wantErr error
+	}{
+		{"有效的資料", valid, nil},
+		{"內容遭竄改", tampered, ErrTelegramLogin},
+		{"其他 Bot 簽署", signTelegramLogin(payload, "999:other"), ErrTelegramLogin},
+		{"登入資料已過期", old, ErrTelegramLogin},
+		{"auth_date 晚於目前時間", future, ErrTelegramLogin},
+		{"缺少 hash", noHash, ErrTelegramLogin},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			login, err := VerifyTelegramLogin(tt.data, botToken, time.Hour, now)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("VerifyTelegramLogin() 錯誤 = %v, 期望 %v", err, tt.wantErr)
+			}
+			if err == nil && (login.ID != 987654321 || login.Username != "xiaoming" || login.DisplayName() != "小明 王") {
+				t.Errorf("VerifyTelegramLogin() = %+v, 期望使用者 987654321", login)
+			}
+		})
+	}
+}
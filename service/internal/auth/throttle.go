@@ -0,0 +1,312 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// 登入失敗計數的對象
+const (
+	ThrottleUsername = "username" // 依帳號計數，防止針對單一帳號猜密碼
+	ThrottleIP       = "ip"       // 依來源 IP 計數，防止同一來源對大量帳號嘗試
+)
+
+// recordFailureScript 記錄一次登入失敗並回傳滑動視窗內的失敗次數
+// KEYS[1] 為失敗時間的 sorted set，KEYS[2] 為鎖定 key；ARGV 依序為現在時間（毫秒）、視窗長度（毫秒）、
+// 鎖定門檻（0 表示不鎖定）、鎖定時間（毫秒）、本次失敗的唯一值（與預先記錄的嘗試相同時只更新時間）
+// 達到鎖定門檻時建立鎖定 key 並清除失敗記錄，回傳負的失敗次數表示本次觸發鎖定
+// 兩個 key 使用相同的 hash tag，Cluster 模式下位於同一節點
+var recordFailureScript = redis.NewScript(`
+local now = tonumber(ARGV[1])
+local window = tonumber(ARGV[2])
+redis.call('ZREMRANGEBYSCORE', KEYS[1], '-inf', now - window)
+redis.call('ZADD', KEYS[1], now, ARGV[5])
+redis.call('PEXPIRE', KEYS[1], window)
+local count = redis.call('ZCARD', KEYS[1])
+local maxFailures = tonumber(ARGV[3])
+if maxFailures > 0 and count >= maxFailures then
+	redis.call('SET', KEYS[2], now, 'PX', ARGV[4])
+	redis.call('DEL', KEYS[1])
+	return -count
+end
+return count
+`)
+
+// reserveAttemptScript 檢查限制並預先記錄一次嘗試（先視為失敗），讓並行的嘗試無法同時通過檢查
+// KEYS 與 recordFailureScript 相同；ARGV 依序為現在時間（毫秒）、視窗長度（毫秒）、鎖定門檻、
+// 不需等待的失敗次數、第一次等待時間（毫秒）、等待時間上限（毫秒）、本次嘗試的唯一值
+// 回傳 {狀態, 值}：狀態 0 表示已預先記錄，值為先前的失敗次數；1 表示鎖定中、2 表示需等待，值為需等待的毫秒數
+// 等待時間的計算與 ThrottleConfig.Delay 相同
+var reserveAttemptScript = redis.NewScript(`
+local lockTTL = redis.call('PTTL', KEYS[2])
+if lockTTL > 0 then
+	return {1, lockTTL}
+end
+
+local now = tonumber(ARGV[1])
+local window = tonumber(ARGV[2])
+redis.call('ZREMRANGEBYSCORE', KEYS[1], '-inf', now - window)
+local count = redis.call('ZCARD', KEYS[1])
+
+local maxFailures = tonumber(ARGV[3])
+if maxFailures > 0 and count >= maxFailures then
+	local oldest = redis.call('ZRANGE', KEYS[1], 0, 0, 'WITHSCORES')
+	return {2, math.max(tonumber(oldest[2]) + window - now, 1)}
+end
+
+local delay = 0
+local extra = count - tonumber(ARGV[4])
+local base, maxDelay = tonumber(ARGV[5]), tonumber(ARGV[6])
+if extra > 0 and base > 0 then
+	delay = base
+	local i = 1
+	while i < extra and delay < maxDelay do
+		delay = delay * 2
+		i = i + 1
+	end
+	if maxDelay > 0 and delay > maxDelay then
+		delay = maxDelay
+	end
+end
+if delay > 0 then
+	local last = redis.call('ZREVRANGE', KEYS[1], 0, 0, 'WITHSCORES')
+	local wait = tonumber(last[2]) + delay - now
+	if wait > 0 then
+		return {2, wait}
+	end
+end
+
+redis.call('ZADD', KEYS[1], now, ARGV[7])
+redis.call('PEXPIRE', KEYS[1], window)
+return {0, count}
+`)
+
+// ThrottleConfig 登入失敗限制規則
+type ThrottleConfig struct {
+	Window          time.Duration // 滑動視窗長度，只計算此時間內的失敗次數
+	MaxFailures     int           // 視窗內失敗達到此次數即鎖定，0 表示不鎖定（只依 FreeAttempts 與 BaseDelay 延遲）
+	FreeAttempts    int           // 視窗內前幾次失敗不需等待
+	BaseDelay       time.Duration // 超過 FreeAttempts 後第一次需等待的時間，之後每多失敗一次加倍
+	MaxDelay        time.Duration // 等待時間上限
+	LockoutDuration time.Duration // 鎖定時間
+}
+
+// Delay 視窗內已失敗 failures 次時，下一次嘗試前需等待的時間
+func (c ThrottleConfig) Delay(failures int) time.Duration {
+	extra := failures - c.FreeAttempts
+	if extra <= 0 || c.BaseDelay <= 0 {
+		return 0
+	}
+
+	delay := c.BaseDelay
+	for i := 1; i < extra && delay < c.MaxDelay; i++ {
+		delay *= 2
+	}
+	if c.MaxDelay > 0 && delay > c.MaxDelay {
+		delay = c.MaxDelay
+	}
+	return delay
+}
+
+// ThrottleStatus 單一對象目前的限制狀態
+type ThrottleStatus struct {
+	Failures   int           // 視窗內的失敗次數
+	Locked     bool          // 是否已鎖定
+	RetryAfter time.Duration // 需等待多久才能再次嘗試，0 表示可立即嘗試
+}
+
+// LoginThrottle 建立在 Redis 上的登入失敗限制，多台伺服器共用計數
+// 依 scope（例如會員或後台）與對象（帳號或 IP）分別以滑動視窗計算失敗次數：
+// 失敗次數超過 FreeAttempts 後每次嘗試前需等待遞增的時間，達到 MaxFailures 時鎖定 LockoutDuration
+type LoginThrottle struct {
+	client redis.UniversalClient
+}
+
+// NewLoginThrottle 建立登入失敗限制
+func NewLoginThrottle(client redis.UniversalClient) *LoginThrottle {
+	return &LoginThrottle{client: client}
+}
+
+// Status 取得對象目前的限制狀態
+func (t *LoginThrottle) Status(ctx context.Context, scope, kind, subject string, cfg ThrottleConfig, now time.Time) (ThrottleStatus, error) {
+	failKey, lockKey := throttleKeys(scope, kind, subject)
+
+	var lockTTL *redis.DurationCmd
+	var last *redis.ZSliceCmd
+	var count *redis.IntCmd
+	_, err := t.client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		lockTTL = pipe.PTTL(ctx, lockKey)
+		min := strconv.FormatInt(now.Add(-cfg.Window).UnixMilli()+1, 10)
+		count = pipe.ZCount(ctx, failKey, min, "+inf")
+		last = pipe.ZRevRangeWithScores(ctx, failKey, 0, 0)
+		return nil
+	})
+	if err != nil {
+		return ThrottleStatus{}, fmt.Errorf("查詢登入失敗記錄失敗: %w", err)
+	}
+
+	if ttl := lockTTL.Val(); ttl > 0 {
+		return ThrottleStatus{Locked: true, RetryAfter: ttl}, nil
+	}
+
+	status := ThrottleStatus{Failures: int(count.Val())}
+	if entries := last.Val(); len(entries) > 0 {
+		lastFailure := time.UnixMilli(int64(entries[0].Score))
+		if wait := lastFailure.Add(cfg.Delay(status.Failures)).Sub(now); wait > 0 {
+			status.RetryAfter = wait
+		}
+	}
+	return status, nil
+}
+
+// ThrottleReservation 以 Reserve 預先記錄的一次嘗試
+type ThrottleReservation struct {
+	scope   string
+	kind    string
+	subject string
+	id      string
+}
+
+// Scope 預先記錄的嘗試所屬的 scope
+func (r *ThrottleReservation) Scope() string { return r.scope }
+
+// Kind 預先記錄的嘗試的計數對象（ThrottleUsername 或 ThrottleIP）
+func (r *ThrottleReservation) Kind() string { return r.kind }
+
+// Subject 預先記錄的嘗試的帳號或 IP
+func (r *ThrottleReservation) Subject() string { return r.subject }
+
+// Reserve 檢查對象的限制狀態，未受限制時預先記錄一次嘗試並回傳預先記錄的嘗試
+// 預先記錄的嘗試在結果確定前即計入失敗次數，並行的嘗試不會全部通過檢查；
+// 呼叫端需在結果確定後以 Fail 確認失敗或以 Release 取消記錄。受限制時不記錄並回傳 nil 與限制狀態
+func (t *LoginThrottle) Reserve(ctx context.Context, scope, kind, subject string, cfg ThrottleConfig, now time.Time) (*ThrottleReservation, ThrottleStatus, error) {
+	failKey, lockKey := throttleKeys(scope, kind, subject)
+	r := &ThrottleReservation{scope: scope, kind: kind, subject: subject, id: rand.Text()}
+	result, err := reserveAttemptScript.Run(ctx, t.client, []string{failKey, lockKey},
+		now.UnixMilli(), cfg.Window.Milliseconds(), cfg.MaxFailures,
+		cfg.FreeAttempts, cfg.BaseDelay.Milliseconds(), cfg.MaxDelay.Milliseconds(), r.id).Int64Slice()
+	if err != nil {
+		return nil, ThrottleStatus{}, fmt.Errorf("檢查登入限制失敗: %w", err)
+	}
+
+	switch result[0] {
+	case 0:
+		return r, ThrottleStatus{Failures: int(result[1])}, nil
+	case 1:
+		return nil, ThrottleStatus{Locked: true, RetryAfter: time.Duration(result[1]) * time.Millisecond}, nil
+	default:
+		return nil, ThrottleStatus{RetryAfter: time.Duration(result[1]) * time.Millisecond}, nil
+	}
+}
+
+// Fail 確認預先記錄的嘗試失敗，回傳視窗內的失敗次數與本次是否觸發鎖定
+func (t *LoginThrottle) Fail(ctx context.Context, r *ThrottleReservation, cfg ThrottleConfig, now time.Time) (int, bool, error) {
+	return t.record(ctx, r.scope, r.kind, r.subject, r.id, cfg, now)
+}
+
+// Release 取消預先記錄的嘗試（例如登入成功，或因其他錯誤未完成驗證）
+func (t *LoginThrottle) Release(ctx context.Context, r *ThrottleReservation) error {
+	failKey, _ := throttleKeys(r.scope, r.kind, r.subject)
+	if err := t.client.ZRem(ctx, failKey, r.id).Err(); err != nil {
+		return fmt.Errorf("取消登入嘗試記錄失敗: %w", err)
+	}
+	return nil
+}
+
+// RecordFailure 記錄一次登入失敗，回傳視窗內的失敗次數與本次是否觸發鎖定
+func (t *LoginThrottle) RecordFailure(ctx context.Context, scope, kind, subject string, cfg ThrottleConfig, now time.Time) (int, bool, error) {
+	return t.record(ctx, scope, kind, subject, rand.Text(), cfg, now)
+}
+
+// record 以 id 記錄一次失敗（id 已預先記錄時只更新時間，不重複計數）
+func (t *LoginThrottle) record(ctx context.Context, scope, kind, subject, id string, cfg ThrottleConfig, now time.Time) (int, bool, error) {
+	failKey, lockKey := throttleKeys(scope, kind, subject)
+	count, err := recordFailureScript.Run(ctx, t.client, []string{failKey, lockKey},
+		now.UnixMilli(), cfg.Window.Milliseconds(), cfg.MaxFailures, cfg.LockoutDuration.Milliseconds(), id).Int64()
+	if err != nil {
+		return 0, false, fmt.Errorf("記錄登入失敗失敗: %w", err)
+	}
+	if count < 0 {
+		return int(-count), true, nil
+	}
+	return int(count), false, nil
+}
+
+// Reset 清除對象的失敗記錄（登入成功時），不解除鎖定
+func (t *LoginThrottle) Reset(ctx context.Context, scope, kind, subject string) error {
+	failKey, _ := throttleKeys(scope, kind, subject)
+	if err := t.client.Del(ctx, failKey).Err(); err != nil {
+		return fmt.Errorf("清除登入失敗記錄失敗: %w", err)
+	}
+	return nil
+}
+
+// Unlock 解除對象的鎖定並清除失敗記錄，回傳是否原本處於鎖定
+func (t *LoginThrottle) Unlock(ctx context.Context, scope, kind, subject string) (bool, error) {
+	failKey, lockKey := throttleKeys(scope, kind, subject)
+	var locked *redis.IntCmd
+	_, err := t.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		locked = pipe.Del(ctx, lockKey)
+		pipe.Del(ctx, failKey)
+		return nil
+	})
+	if err != nil {
+		return false, fmt.Errorf("解除登入鎖定失敗: %w", err)
+	}
+	return locked.Val() > 0, nil
+}
+
+// RecordGlobalFailure 將一次失敗計入 scope 內所有對象合計的失敗次數
+// 以 window 為固定視窗只保存次數，大量分散的失敗也不會占用大量記憶體
+func (t *LoginThrottle) RecordGlobalFailure(ctx context.Context, scope string, window time.Duration, now time.Time) error {
+	if window <= 0 {
+		return nil
+	}
+	key := globalFailureKey(scope, now.UnixMilli()/window.Milliseconds())
+	_, err := t.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.Incr(ctx, key)
+		pipe.PExpire(ctx, key, 2*window)
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("記錄登入失敗次數失敗: %w", err)
+	}
+	return nil
+}
+
+// GlobalFailures 取得 scope 內所有對象在目前與上一個固定視窗合計的失敗次數
+func (t *LoginThrottle) GlobalFailures(ctx context.Context, scope string, window time.Duration, now time.Time) (int, error) {
+	if window <= 0 {
+		return 0, nil
+	}
+	bucket := now.UnixMilli() / window.Milliseconds()
+	values, err := t.client.MGet(ctx, globalFailureKey(scope, bucket), globalFailureKey(scope, bucket-1)).Result()
+	if err != nil {
+		return 0, fmt.Errorf("查詢登入失敗次數失敗: %w", err)
+	}
+
+	total := 0
+	for _, v := range values {
+		if s, ok := v.(string); ok {
+			n, _ := strconv.Atoi(s)
+			total += n
+		}
+	}
+	return total, nil
+}
+
+// globalFailureKey scope 內所有對象合計失敗次數的 Redis key，同一 scope 的 key 使用相同的 hash tag 以便一次讀取
+func globalFailureKey(scope string, bucket int64) string {
+	return "tourhelper:login-fail-global:{" + scope + "}:" + strconv.FormatInt(bucket, 10)
+}
+
+// throttleKeys 失敗記錄與鎖定的 Redis key
+func throttleKeys(scope, kind, subject string) (failKey, lockKey string) {
+	tag := "{" + scope + ":" + kind + ":" + subject + "}"
+	return "tourhelper:login-fail:" + tag, "tourhelper:login-lock:" + tag
+}
@@ -0,0 +1,130 @@
+package auth
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+func TestThrottleConfigDelay(t *testing.T) {
+	cfg := ThrottleConfig{FreeAttempts: 3, BaseDelay: time.Second, MaxDelay: 5 * time.Second}
+
+	tests := []struct {
+		failures int
+		want     time.Duration
+	}{
+		{0, 0},
+		{3, 0},
+		{4, time.Second},
+		{5, 2 * time.Second},
+		{6, 4 * time.Second},
+		{7, 5 * time.Second},
+		{50, 5 * time.Second},
+	}
+	for _, tt := range tests {
+		if got := cfg.Delay(tt.failures); got != tt.want {
+			t.Errorf("Delay(%d) = %v, 期望 %v", tt.failures, got, tt.want)
+		}
+	}
+}
+
+func TestLoginThrottle(t *testing.T) {
+	mr := miniredis.RunT(t)
+	throttle := NewLoginThrottle(redis.NewClient(&redis.Options{Addr: mr.Addr()}))
+	ctx := context.Background()
+	cfg := ThrottleConfig{Window: time.Minute, MaxFailures: 4, FreeAttempts: 2, BaseDelay: time.Second, MaxDelay: 10 * time.Second, LockoutDuration: 5 * time.Minute}
+	now := time.UnixMilli(time.Now().UnixMilli()) // Redis 以毫秒記錄失敗時間
+
+	// 前兩次失敗不需等待，第三次失敗後需等待 BaseDelay
+	for i := 1; i <= 3; i++ {
+		if n, locked, err := throttle.RecordFailure(ctx, "member", ThrottleUsername, "alice", cfg, now); err != nil || n != i || locked {
+			t.Fatalf("RecordFailure() 第 %d 次 = %d, %v, %v", i, n, locked, err)
+		}
+	}
+	status, err := throttle.Status(ctx, "member", ThrottleUsername, "alice", cfg, now.Add(500*time.Millisecond))
+	if err != nil || status.Failures != 3 || status.Locked || status.RetryAfter != 500*time.Millisecond {
+		t.Errorf("Status() = %+v, %v, 期望 3 次失敗且需再等待 500ms", status, err)
+	}
+
+	// 超過滑動視窗的失敗不計入
+	if status, _ := throttle.Status(ctx, "member", ThrottleUsername, "alice", cfg, now.Add(time.Minute)); status.Failures != 0 || status.RetryAfter != 0 {
+		t.Errorf("Status(視窗外) = %+v, 期望不受限制", status)
+	}
+
+	// 不同 scope 分別計數
+	if status, _ := throttle.Status(ctx, "admin", ThrottleUsername, "alice", cfg, now); status.Failures != 0 {
+		t.Errorf("Status(其他 scope) = %+v, 期望 0 次失敗", status)
+	}
+
+	// 達到門檻時鎖定
+	if n, locked, err := throttle.RecordFailure(ctx, "member", ThrottleUsername, "alice", cfg, now); err != nil || n != 4 || !locked {
+		t.Fatalf("RecordFailure() 第 4 次 = %d, %v, %v, 期望觸發鎖定", n, locked, err)
+	}
+	status, _ = throttle.Status(ctx, "member", ThrottleUsername, "alice", cfg, now)
+	if !status.Locked || status.RetryAfter != cfg.LockoutDuration {
+		t.Errorf("Status(鎖定中) = %+v, 期望鎖定 %v", status, cfg.LockoutDuration)
+	}
+
+	mr.FastForward(cfg.LockoutDuration)
+	if status, _ := throttle.Status(ctx, "member", ThrottleUsername, "alice", cfg, now.Add(cfg.LockoutDuration)); status.Locked {
+		t.Errorf("Status(鎖定到期) = %+v, 期望已解除", status)
+	}
+
+	// 管理員提前解除鎖定
+	throttle.RecordFailure(ctx, "member", ThrottleIP, "192.0.2.1", ThrottleConfig{Window: time.Minute, MaxFailures: 1, LockoutDuration: time.Hour}, now)
+	if unlocked, err := throttle.Unlock(ctx, "member", ThrottleIP, "192.0.2.1"); err != nil || !unlocked {
+		t.Errorf("Unlock() = %v, %v, 期望解除鎖定", unlocked, err)
+	}
+	if unlocked, _ := throttle.Unlock(ctx, "member", ThrottleIP, "192.0.2.1"); unlocked {
+		t.Error("Unlock(未鎖定) = true, 期望 false")
+	}
+}
+
+func TestLoginThrottleReserve(t *testing.T) {
+	mr := miniredis.RunT(t)
+	throttle := NewLoginThrottle(redis.NewClient(&redis.Options{Addr: mr.Addr()}))
+	ctx := context.Background()
+	cfg := ThrottleConfig{Window: time.Minute, MaxFailures: 3, FreeAttempts: 1, BaseDelay: time.Second, MaxDelay: 10 * time.Second, LockoutDuration: 5 * time.Minute}
+	now := time.UnixMilli(time.Now().UnixMilli())
+
+	// 結果未確定的嘗試已計入，第三個並行的嘗試需等待
+	first, _, err := throttle.Reserve(ctx, "member", ThrottleUsername, "alice", cfg, now)
+	if err != nil || first == nil {
+		t.Fatalf("Reserve() 第 1 次 = %v, %v, 期望預先記錄", first, err)
+	}
+	second, status, _ := throttle.Reserve(ctx, "member", ThrottleUsername, "alice", cfg, now)
+	if second == nil || status.Failures != 1 {
+		t.Fatalf("Reserve() 第 2 次 = %v, %+v, 期望預先記錄且先前 1 次", second, status)
+	}
+	if r, status, _ := throttle.Reserve(ctx, "member", ThrottleUsername, "alice", cfg, now); r != nil || status.RetryAfter != time.Second {
+		t.Errorf("Reserve() 第 3 次 = %v, %+v, 期望需等待 1s", r, status)
+	}
+
+	// 取消的嘗試不計入，確認失敗的嘗試不重複計數
+	if err := throttle.Release(ctx, second); err != nil {
+		t.Fatalf("Release() 失敗: %v", err)
+	}
+	if n, locked, err := throttle.Fail(ctx, first, cfg, now); err != nil || n != 1 || locked {
+		t.Errorf("Fail() = %d, %v, %v, 期望 1 次失敗", n, locked, err)
+	}
+
+	// 預先記錄的嘗試達到門檻時等待最早的記錄離開視窗
+	cfg = ThrottleConfig{Window: time.Minute, MaxFailures: 2, LockoutDuration: time.Hour}
+	for i := 0; i < 2; i++ {
+		if r, _, _ := throttle.Reserve(ctx, "member", ThrottleIP, "192.0.2.1", cfg, now); r == nil {
+			t.Fatalf("Reserve(IP) 第 %d 次 = nil, 期望預先記錄", i+1)
+		}
+	}
+	if r, status, _ := throttle.Reserve(ctx, "member", ThrottleIP, "192.0.2.1", cfg, now.Add(10*time.Second)); r != nil || status.RetryAfter != 50*time.Second {
+		t.Errorf("Reserve(IP 達到門檻) = %v, %+v, 期望需等待 50s", r, status)
+	}
+
+	// 鎖定中不記錄
+	throttle.RecordFailure(ctx, "member", ThrottleUsername, "bob", ThrottleConfig{Window: time.Minute, MaxFailures: 1, LockoutDuration: time.Hour}, now)
+	if r, status, _ := throttle.Reserve(ctx, "member", ThrottleUsername, "bob", cfg, now); r != nil || !status.Locked {
+		t.Errorf("Reserve(鎖定中) = %v, %+v, 期望鎖定", r, status)
+	}
+}
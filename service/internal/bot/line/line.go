@@ -1,10 +1,17 @@
 package line
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
+	"strings"
+	"time"
 
+	"github.com/andy2kuo/TourHelper/internal/dao"
+	"github.com/andy2kuo/TourHelper/internal/models"
+	"github.com/andy2kuo/TourHelper/internal/services"
 	"github.com/gin-gonic/gin"
 	"github.com/line/line-bot-sdk-go/v8/linebot/messaging_api"
 	"github.com/line/line-bot-sdk-go/v8/linebot/webhook"
@@ -15,10 +22,11 @@ type Bot struct {
 	channelSecret      string
 	channelAccessToken string
 	client             *messaging_api.MessagingApiAPI
+	users              services.UserService
 }
 
 // NewBot 建立新的 Line Bot
-func NewBot(channelSecret, channelAccessToken string) *Bot {
+func NewBot(channelSecret, channelAccessToken string, users services.UserService) *Bot {
 	client, err := messaging_api.NewMessagingApiAPI(channelAccessToken)
 	if err != nil {
 		log.Printf("建立 Line Bot 客戶端錯誤: %v", err)
@@ -28,6 +36,7 @@ func NewBot(channelSecret, channelAccessToken string) *Bot {
 		channelSecret:      channelSecret,
 		channelAccessToken: channelAccessToken,
 		client:             client,
+		users:              users,
 	}
 }
 
@@ -48,12 +57,12 @@ func (b *Bot) HandleWebhook(c *gin.Context) {
 		case webhook.MessageEvent:
 			switch message := e.Message.(type) {
 			case webhook.TextMessageContent:
-				b.handleTextMessage(e.ReplyToken, message.Text, e.Source)
+				b.handleTextMessage(c.Request.Context(), e.ReplyToken, message.Text, e.Source)
 			case webhook.LocationMessageContent:
 				b.handleLocationMessage(e.ReplyToken, message, e.Source)
 			}
 		case webhook.FollowEvent:
-			b.handleFollowEvent(e.ReplyToken, e.Source)
+			b.handleFollowEvent(c.Request.Context(), e.ReplyToken, e.Source)
 		}
 	}
 
@@ -61,17 +70,20 @@ func (b *Bot) HandleWebhook(c *gin.Context) {
 }
 
 // handleTextMessage 處理文字訊息
-func (b *Bot) handleTextMessage(replyToken, text string, source webhook.SourceInterface) {
+func (b *Bot) handleTextMessage(ctx context.Context, replyToken, text string, source webhook.SourceInterface) {
 	log.Printf("收到文字訊息: %s", text)
 
 	var replyText string
 
 	// TODO: 實作智慧回覆邏輯
-	switch text {
+	command, arg, _ := strings.Cut(strings.TrimSpace(text), " ")
+	switch command {
 	case "推薦":
 		replyText = "請分享您的位置資訊，我會為您推薦適合的旅遊景點！"
 	case "設定":
 		replyText = "請告訴我您的偏好：\n1. 距離範圍（例如：50公里內）\n2. 景點類型（自然、文化、美食等）"
+	case "綁定":
+		replyText = b.linkAccount(ctx, source, strings.TrimSpace(arg))
 	default:
 		replyText = fmt.Sprintf("您說：%s\n\n請輸入「推薦」來獲取旅遊建議，或輸入「設定」來調整偏好設定。", text)
 	}
@@ -111,10 +123,58 @@ func (b *Bot) handleLocationMessage(replyToken string, location webhook.Location
 	}
 }
 
-// handleFollowEvent 處理加入好友事件
-func (b *Bot) handleFollowEvent(replyToken string, source webhook.SourceInterface) {
+// linkAccount 處理「綁定」指令：未帶驗證碼時產生驗證碼，帶驗證碼時將此 LINE 帳號連結到產生驗證碼的會員
+func (b *Bot) linkAccount(ctx context.Context, source webhook.SourceInterface, code string) string {
+	userSource, ok := source.(webhook.UserSource)
+	if !ok || userSource.UserId == "" {
+		return "請在與 TourHelper 的一對一聊天中連結帳號。"
+	}
+	user, err := b.users.FindOrCreateByExternal(ctx, models.PlatformLine, userSource.UserId, dao.ExternalProfile{})
+	if err != nil {
+		log.Printf("建立 Line 使用者錯誤: %v", err)
+		return "目前無法連結帳號，請稍後再試。"
+	}
+
+	if code == "" {
+		linkCode, err := b.users.IssueLinkCode(ctx, user.ID)
+		if err != nil {
+			log.Printf("產生帳號連結驗證碼錯誤: %v", err)
+			return "目前無法連結帳號，請稍後再試。"
+		}
+		return fmt.Sprintf("您的帳號連結驗證碼：%s\n\n請在 %d 分鐘內於 Telegram 輸入「/link %s」或在網頁輸入驗證碼，即可連結帳號。請勿將驗證碼提供給他人。",
+			linkCode.Code, int(time.Until(linkCode.ExpiresAt).Round(time.Minute).Minutes()), linkCode.Code)
+	}
+
+	if _, err := b.users.Link(ctx, user.ID, code, ""); err != nil {
+		switch {
+		case errors.Is(err, services.ErrInvalidLinkCode), errors.Is(err, services.ErrTooManyLinkAttempts),
+			errors.Is(err, services.ErrAlreadyLinked), errors.Is(err, services.ErrMergeConflict):
+			return err.Error()
+		default:
+			log.Printf("連結帳號錯誤: %v", err)
+			return "目前無法連結帳號，請稍後再試。"
+		}
+	}
+	return "帳號連結成功！您的偏好設定與搜尋記錄已與其他平台共用。"
+}
+
+// handleFollowEvent 處理加入好友事件，建立或更新使用者資料
+func (b *Bot) handleFollowEvent(ctx context.Context, replyToken string, source webhook.SourceInterface) {
 	log.Println("新使用者加入")
 
+	if userSource, ok := source.(webhook.UserSource); ok && userSource.UserId != "" {
+		var profile dao.ExternalProfile
+		if resp, err := b.client.GetProfile(userSource.UserId); err != nil {
+			log.Printf("取得 Line 使用者資料錯誤: %v", err)
+		} else {
+			profile.DisplayName = resp.DisplayName
+		}
+
+		if _, err := b.users.FindOrCreateByExternal(ctx, models.PlatformLine, userSource.UserId, profile); err != nil {
+			log.Printf("建立 Line 使用者錯誤: %v", err)
+		}
+	}
+
 	welcomeText := "歡迎使用 TourHelper！\n\n我可以根據您的位置、天氣和偏好，為您推薦適合的旅遊景點。\n\n請分享您的位置，或輸入「推薦」開始使用。"
 
 	if _, err := b.client.ReplyMessage(
@@ -1,10 +1,18 @@
 package telegram
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
+	"strconv"
+	"strings"
+	"time"
 
+	"github.com/andy2kuo/TourHelper/internal/dao"
+	"github.com/andy2kuo/TourHelper/internal/models"
+	"github.com/andy2kuo/TourHelper/internal/services"
 	"github.com/gin-gonic/gin"
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
 )
@@ -13,14 +21,15 @@ import (
 type Bot struct {
 	token string
 	api   *tgbotapi.BotAPI
+	users services.UserService
 }
 
 // NewBot 建立新的 Telegram Bot
-func NewBot(token string) *Bot {
+func NewBot(token string, users services.UserService) *Bot {
 	api, err := tgbotapi.NewBotAPI(token)
 	if err != nil {
 		log.Printf("建立 Telegram Bot 客戶端錯誤: %v", err)
-		return &Bot{token: token}
+		return &Bot{token: token, users: users}
 	}
 
 	api.Debug = false
@@ -29,6 +38,7 @@ func NewBot(token string) *Bot {
 	return &Bot{
 		token: token,
 		api:   api,
+		users: users,
 	}
 }
 
@@ -48,7 +58,7 @@ func (b *Bot) HandleWebhook(c *gin.Context) {
 
 	// 處理不同類型的訊息
 	if update.Message.Text != "" {
-		b.handleTextMessage(update.Message)
+		b.handleTextMessage(c.Request.Context(), update.Message)
 	} else if update.Message.Location != nil {
 		b.handleLocationMessage(update.Message)
 	}
@@ -57,14 +67,16 @@ func (b *Bot) HandleWebhook(c *gin.Context) {
 }
 
 // handleTextMessage 處理文字訊息
-func (b *Bot) handleTextMessage(message *tgbotapi.Message) {
+func (b *Bot) handleTextMessage(ctx context.Context, message *tgbotapi.Message) {
 	log.Printf("[%s] %s", message.From.UserName, message.Text)
 
 	var replyText string
 
-	// 處理指令
-	switch message.Text {
+	// 處理指令（指令後可帶參數，例如 /link 12345678）
+	command, arg, _ := strings.Cut(message.Text, " ")
+	switch command {
 	case "/start":
+		b.registerUser(ctx, message.From)
 		replyText = "歡迎使用 TourHelper！\n\n我可以根據您的位置、天氣和偏好，為您推薦適合的旅遊景點。\n\n請使用以下指令：\n/recommend - 取得推薦\n/settings - 設定偏好\n/help - 查看說明"
 	case "/recommend":
 		replyText = "請分享您的位置資訊，我會為您推薦適合的旅遊景點！"
@@ -82,8 +94,10 @@ func (b *Bot) handleTextMessage(message *tgbotapi.Message) {
 		return
 	case "/settings":
 		replyText = "請告訴我您的偏好：\n\n1. 距離範圍（例如：50公里內）\n2. 景點類型（自然、文化、美食等）\n3. 預算（低、中、高）"
+	case "/link":
+		replyText = b.linkAccount(ctx, message.From, strings.TrimSpace(arg))
 	case "/help":
-		replyText = "TourHelper 使用說明：\n\n/recommend - 取得旅遊推薦\n/settings - 設定偏好\n/history - 查看歷史記錄\n\n您也可以直接分享位置，我會立即為您推薦景點！"
+		replyText = "TourHelper 使用說明：\n\n/recommend - 取得旅遊推薦\n/settings - 設定偏好\n/history - 查看歷史記錄\n/link - 連結 LINE 或網頁帳號\n\n您也可以直接分享位置，我會立即為您推薦景點！"
 	default:
 		replyText = fmt.Sprintf("您說：%s\n\n請使用 /recommend 來獲取旅遊建議。", message.Text)
 	}
@@ -115,6 +129,54 @@ func (b *Bot) handleLocationMessage(message *tgbotapi.Message) {
 	// TODO: 實際呼叫推薦服務並回傳結果
 }
 
+// linkAccount 處理 /link 指令：未帶驗證碼時產生驗證碼，帶驗證碼時將此 Telegram 帳號連結到產生驗證碼的會員
+func (b *Bot) linkAccount(ctx context.Context, from *tgbotapi.User, code string) string {
+	user := b.registerUser(ctx, from)
+	if user == nil {
+		return "目前無法連結帳號，請稍後再試。"
+	}
+
+	if code == "" {
+		linkCode, err := b.users.IssueLinkCode(ctx, user.ID)
+		if err != nil {
+			log.Printf("產生帳號連結驗證碼錯誤: %v", err)
+			return "目前無法連結帳號，請稍後再試。"
+		}
+		return fmt.Sprintf("您的帳號連結驗證碼：%s\n\n請在 %d 分鐘內於 LINE 輸入「綁定 %s」或在網頁輸入驗證碼，即可連結帳號。請勿將驗證碼提供給他人。",
+			linkCode.Code, int(time.Until(linkCode.ExpiresAt).Round(time.Minute).Minutes()), linkCode.Code)
+	}
+
+	if _, err := b.users.Link(ctx, user.ID, code, ""); err != nil {
+		switch {
+		case errors.Is(err, services.ErrInvalidLinkCode), errors.Is(err, services.ErrTooManyLinkAttempts),
+			errors.Is(err, services.ErrAlreadyLinked), errors.Is(err, services.ErrMergeConflict):
+			return err.Error()
+		default:
+			log.Printf("連結帳號錯誤: %v", err)
+			return "目前無法連結帳號，請稍後再試。"
+		}
+	}
+	return "帳號連結成功！您的偏好設定與搜尋記錄已與其他平台共用。"
+}
+
+// registerUser 建立或更新傳送訊息的 Telegram 使用者，失敗時回傳 nil
+func (b *Bot) registerUser(ctx context.Context, from *tgbotapi.User) *models.User {
+	if from == nil {
+		return nil
+	}
+
+	profile := dao.ExternalProfile{
+		Username:    from.UserName,
+		DisplayName: strings.TrimSpace(from.FirstName + " " + from.LastName),
+	}
+	user, err := b.users.FindOrCreateByExternal(ctx, models.PlatformTelegram, strconv.FormatInt(from.ID, 10), profile)
+	if err != nil {
+		log.Printf("建立 Telegram 使用者錯誤: %v", err)
+		return nil
+	}
+	return user
+}
+
 // SetWebhook 設定 webhook
 func (b *Bot) SetWebhook(webhookURL string) error {
 	webhook, err := tgbotapi.NewWebhook(webhookURL)
@@ -0,0 +1,186 @@
+// Package cache 提供建立在 Redis 上的型別化快取（cache-aside）
+// 支援讀取或載入（GetOrLoad）、JSON/MessagePack 序列化、TTL 隨機延長與同鍵值並行載入合併，
+// 並可為快取加上標籤，在資料異動時依標籤一次清除所有相關快取
+package cache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"time"
+
+	"github.com/andy2kuo/TourHelper/internal/logger"
+	"github.com/redis/go-redis/v9"
+	"golang.org/x/sync/singleflight"
+)
+
+// defaultTTL 未設定快取時間時使用的預設值
+const defaultTTL = 5 * time.Minute
+
+// Options 快取設定
+type Options struct {
+	Prefix string        // Redis key 前綴（例如：recommend），實際 key 為 "{Prefix}:{key}"
+	TTL    time.Duration // 快取時間，未設定時為 5 分鐘
+	Jitter float64       // 快取時間隨機延長的比例上限（0.1 表示延長 0-10%），避免大量快取同時過期
+	Codec  Codec         // 序列化方式，未設定時使用 JSON
+}
+
+// Cache 型別化的 Redis 快取，T 為快取值的型別
+// client 為 nil 時不快取，GetOrLoad 會直接載入（仍會合併並行載入）
+type Cache[T any] struct {
+	client redis.UniversalClient
+	prefix string
+	ttl    time.Duration
+	jitter float64
+	codec  Codec
+
+	group singleflight.Group // 合併同一 key 的並行載入
+}
+
+// New 建立快取
+func New[T any](client redis.UniversalClient, opts Options) *Cache[T] {
+	ttl := opts.TTL
+	if ttl <= 0 {
+		ttl = defaultTTL
+	}
+	codec := opts.Codec
+	if codec == nil {
+		codec = JSON
+	}
+
+	return &Cache[T]{
+		client: client,
+		prefix: opts.Prefix,
+		ttl:    ttl,
+		jitter: max(opts.Jitter, 0),
+		codec:  codec,
+	}
+}
+
+// Get 讀取快取，未命中時 found 為 false
+func (c *Cache[T]) Get(ctx context.Context, key string) (value T, found bool, err error) {
+	if c.client == nil {
+		return value, false, nil
+	}
+
+	raw, err := c.client.Get(ctx, c.key(key)).Bytes()
+	if err != nil {
+		if errors.Is(err, redis.Nil) {
+			return value, false, nil
+		}
+		return value, false, fmt.Errorf("讀取快取失敗: %w", err)
+	}
+
+	if err := c.codec.Unmarshal(raw, &value); err != nil {
+		return value, false, fmt.Errorf("解析快取失敗: %w", err)
+	}
+	return value, true, nil
+}
+
+// Set 寫入快取並加上標籤，快取時間會依 Jitter 隨機延長
+func (c *Cache[T]) Set(ctx context.Context, key string, value T, tags ...string) error {
+	if c.client == nil {
+		return nil
+	}
+
+	raw, err := c.codec.Marshal(value)
+	if err != nil {
+		return fmt.Errorf("序列化快取失敗: %w", err)
+	}
+
+	fullKey := c.key(key)
+	ttl := c.expiration()
+
+	pipe := c.client.Pipeline()
+	pipe.Set(ctx, fullKey, raw, ttl)
+	for _, tag := range tags {
+		addTagMember(ctx, pipe, tag, fullKey, ttl)
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		return fmt.Errorf("寫入快取失敗: %w", err)
+	}
+	return nil
+}
+
+// Delete 刪除快取（標籤中殘留的 key 會在清除標籤或標籤過期時一併移除）
+func (c *Cache[T]) Delete(ctx context.Context, keys ...string) error {
+	if c.client == nil || len(keys) == 0 {
+		return nil
+	}
+
+	// Cluster 模式下多個 key 可能位於不同節點，逐一刪除
+	pipe := c.client.Pipeline()
+	for _, key := range keys {
+		pipe.Del(ctx, c.key(key))
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		return fmt.Errorf("刪除快取失敗: %w", err)
+	}
+	return nil
+}
+
+// GetOrLoad 讀取快取，未命中時以 load 載入並寫入快取（加上 tags 標籤）
+// 同一 key 的並行呼叫只會執行一次 load，結果由所有呼叫者共用，呼叫者不應修改回傳值內的參照資料
+// Redis 異常時視為未命中並直接載入，load 失敗時不寫入快取
+func (c *Cache[T]) GetOrLoad(ctx context.Context, key string, load func(ctx context.Context) (T, error), tags ...string) (T, error) {
+	if value, found := c.lookup(ctx, key); found {
+		return value, nil
+	}
+
+	v, err, _ := c.group.Do(key, func() (any, error) {
+		// 共用載入不應因第一個呼叫者取消而讓其他呼叫者一併失敗
+		ctx := context.WithoutCancel(ctx)
+
+		// 等待期間其他呼叫者可能已完成載入並寫入快取
+		if value, found := c.lookup(ctx, key); found {
+			return value, nil
+		}
+
+		value, err := load(ctx)
+		if err != nil {
+			return value, err
+		}
+		if err := c.Set(ctx, key, value, tags...); err != nil {
+			logger.Warnf("寫入快取 [%s] 失敗: %v", c.key(key), err)
+		}
+		return value, nil
+	})
+	if err != nil {
+		var zero T
+		return zero, err
+	}
+	value, _ := v.(T)
+	return value, nil
+}
+
+// InvalidateTags 清除具有任一標籤的快取
+func (c *Cache[T]) InvalidateTags(ctx context.Context, tags ...string) error {
+	return InvalidateTags(ctx, c.client, tags...)
+}
+
+// lookup 讀取快取，發生錯誤時記錄警告並視為未命中
+func (c *Cache[T]) lookup(ctx context.Context, key string) (T, bool) {
+	value, found, err := c.Get(ctx, key)
+	if err != nil {
+		logger.Warnf("讀取快取 [%s] 失敗: %v", c.key(key), err)
+		return value, false
+	}
+	return value, found
+}
+
+// key 組合 Redis key
+func (c *Cache[T]) key(key string) string {
+	if c.prefix == "" {
+		return key
+	}
+	return c.prefix + ":" + key
+}
+
+// expiration 計算本次寫入的快取時間（依 Jitter 隨機延長）
+func (c *Cache[T]) expiration() time.Duration {
+	if c.jitter <= 0 {
+		return c.ttl
+	}
+	return c.ttl + time.Duration(rand.Float64()*c.jitter*float64(c.ttl))
+}
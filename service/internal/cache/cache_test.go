@@ -0,0 +1,288 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"os"
+	"reflect"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/andy2kuo/TourHelper/internal/config"
+	"github.com/andy2kuo/TourHelper/internal/logger"
+	"github.com/redis/go-redis/v9"
+)
+
+func TestMain(m *testing.M) {
+	// 快取讀寫失敗時會寫入 Logger，於暫存目錄初始化 Logger 避免在專案目錄產生日誌檔
+	dir, err := os.MkdirTemp("", "cache-test")
+	if err != nil {
+		panic(err)
+	}
+	if err := os.Chdir(dir); err != nil {
+		panic(err)
+	}
+	if err := logger.Init("cache_test", "dev", config.LogConfig{Level: "error"}); err != nil {
+		panic(err)
+	}
+
+	code := m.Run()
+	os.RemoveAll(dir)
+	os.Exit(code)
+}
+
+type item struct {
+	Name      string    `json:"name"`
+	Tags      []string  `json:"tags"`
+	Score     float64   `json:"score"`
+	UpdatedAt time.Time `json:"updated_at"`
+	Parent    *item     `json:"parent,omitempty"`
+}
+
+func newTestClient(t *testing.T) (*miniredis.Miniredis, redis.UniversalClient) {
+	t.Helper()
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { client.Close() })
+	return mr, client
+}
+
+func TestCodecs(t *testing.T) {
+	want := item{
+		Name:      "台北 101",
+		Tags:      []string{"culture", "shopping"},
+		Score:     4.5,
+		UpdatedAt: time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC),
+		Parent:    &item{Name: "信義區"},
+	}
+
+	tests := []struct {
+		name  string
+		codec string
+	}{
+		{"預設為 JSON", ""},
+		{"JSON", "json"},
+		{"MessagePack", "msgpack"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			codec, err := CodecByName(tt.codec)
+			if err != nil {
+				t.Fatalf("CodecByName() 失敗: %v", err)
+			}
+
+			raw, err := codec.Marshal(want)
+			if err != nil {
+				t.Fatalf("Marshal() 失敗: %v", err)
+			}
+			var got item
+			if err := codec.Unmarshal(raw, &got); err != nil {
+				t.Fatalf("Unmarshal() 失敗: %v", err)
+			}
+			if !got.UpdatedAt.Equal(want.UpdatedAt) {
+				t.Errorf("UpdatedAt = %v, 期望 %v", got.UpdatedAt, want.UpdatedAt)
+			}
+			got.UpdatedAt = want.UpdatedAt
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("Unmarshal() = %+v, 期望 %+v", got, want)
+			}
+		})
+	}
+
+	if _, err := CodecByName("xml"); err == nil {
+		t.Error("CodecByName(xml) 應回傳錯誤")
+	}
+}
+
+func TestCacheGetOrLoad(t *testing.T) {
+	mr, client := newTestClient(t)
+	ctx := context.Background()
+
+	c := New[item](client, Options{Prefix: "test", TTL: time.Minute, Jitter: 0.5, Codec: Msgpack})
+
+	var loads atomic.Int32
+	load := func(context.Context) (item, error) {
+		loads.Add(1)
+		return item{Name: "loaded"}, nil
+	}
+
+	for range 3 {
+		got, err := c.GetOrLoad(ctx, "a", load)
+		if err != nil {
+			t.Fatalf("GetOrLoad() 失敗: %v", err)
+		}
+		if got.Name != "loaded" {
+			t.Errorf("GetOrLoad() = %+v, 期望 Name 為 loaded", got)
+		}
+	}
+	if loads.Load() != 1 {
+		t.Errorf("載入次數 = %d, 期望 1", loads.Load())
+	}
+
+	// 快取時間依 Jitter 延長 0-50%
+	if ttl := mr.TTL("test:a"); ttl < time.Minute || ttl > 90*time.Second {
+		t.Errorf("快取時間 = %v, 期望介於 1m 與 1m30s", ttl)
+	}
+
+	// 載入失敗時不寫入快取
+	loadErr := errors.New("載入失敗")
+	if _, err := c.GetOrLoad(ctx, "b", func(context.Context) (item, error) { return item{}, loadErr }); !errors.Is(err, loadErr) {
+		t.Errorf("GetOrLoad() 錯誤 = %v, 期望 %v", err, loadErr)
+	}
+	if mr.Exists("test:b") {
+		t.Error("載入失敗時不應寫入快取")
+	}
+
+	// 過期後重新載入
+	mr.FastForward(2 * time.Minute)
+	if _, err := c.GetOrLoad(ctx, "a", load); err != nil {
+		t.Fatalf("GetOrLoad() 失敗: %v", err)
+	}
+	if loads.Load() != 2 {
+		t.Errorf("過期後載入次數 = %d, 期望 2", loads.Load())
+	}
+
+	// 未使用 Redis 時直接載入
+	direct := New[item](nil, Options{Prefix: "test"})
+	if got, err := direct.GetOrLoad(ctx, "a", load); err != nil || got.Name != "loaded" {
+		t.Errorf("GetOrLoad() = %+v, %v, 期望直接載入", got, err)
+	}
+}
+
+func TestCacheGetOrLoadConcurrent(t *testing.T) {
+	_, client := newTestClient(t)
+	c := New[int](client, Options{Prefix: "test", TTL: time.Minute})
+
+	var loads atomic.Int32
+	release := make(chan struct{})
+	load := func(context.Context) (int, error) {
+		loads.Add(1)
+		<-release
+		return 42, nil
+	}
+
+	const callers = 20
+	var wg sync.WaitGroup
+	results := make([]int, callers)
+	for i := range callers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i], _ = c.GetOrLoad(context.Background(), "hot", load)
+		}()
+	}
+
+	// 等待所有呼叫者進入等待後再完成載入
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	if loads.Load() != 1 {
+		t.Errorf("載入次數 = %d, 期望 1", loads.Load())
+	}
+	for i, v := range results {
+		if v != 42 {
+			t.Errorf("呼叫者 %d 取得 %d, 期望 42", i, v)
+		}
+	}
+}
+
+func TestInvalidateTags(t *testing.T) {
+	mr, client := newTestClient(t)
+	ctx := context.Background()
+
+	items := New[item](client, Options{Prefix: "item", TTL: time.Minute})
+	counts := New[int](client, Options{Prefix: "count", TTL: 2 * time.Minute})
+
+	if err := items.Set(ctx, "a", item{Name: "a"}, "destinations"); err != nil {
+		t.Fatalf("Set() 失敗: %v", err)
+	}
+	if err := items.Set(ctx, "b", item{Name: "b"}, "destinations", "user:1"); err != nil {
+		t.Fatalf("Set() 失敗: %v", err)
+	}
+	if err := counts.Set(ctx, "a", 1, "destinations"); err != nil {
+		t.Fatalf("Set() 失敗: %v", err)
+	}
+	if err := items.Set(ctx, "c", item{Name: "c"}, "user:1"); err != nil {
+		t.Fatalf("Set() 失敗: %v", err)
+	}
+
+	// 標籤索引的存活時間不短於其中最晚過期的快取
+	if ttl := mr.TTL(tagKey("destinations")); ttl != 2*time.Minute {
+		t.Errorf("標籤索引存活時間 = %v, 期望 2m", ttl)
+	}
+
+	if err := InvalidateTags(ctx, client, "destinations"); err != nil {
+		t.Fatalf("InvalidateTags() 失敗: %v", err)
+	}
+
+	tests := []struct {
+		key    string
+		exists bool
+	}{
+		{"item:a", false},
+		{"item:b", false},
+		{"count:a", false},
+		{"item:c", true},
+		{tagKey("destinations"), false},
+	}
+	for _, tt := range tests {
+		if got := mr.Exists(tt.key); got != tt.exists {
+			t.Errorf("%s 存在 = %v, 期望 %v", tt.key, got, tt.exists)
+		}
+	}
+
+	if _, found, err := items.Get(ctx, "c"); err != nil || !found {
+		t.Errorf("Get(c) = %v, %v, 期望命中", found, err)
+	}
+}
+
+// failDelHook 讓 pipeline 中的 DEL 指令失敗，模擬清除快取時 Redis 發生錯誤
+type failDelHook struct{}
+
+func (failDelHook) DialHook(next redis.DialHook) redis.DialHook { return next }
+
+func (failDelHook) ProcessHook(next redis.ProcessHook) redis.ProcessHook { return next }
+
+func (failDelHook) ProcessPipelineHook(next redis.ProcessPipelineHook) redis.ProcessPipelineHook {
+	return func(ctx context.Context, cmds []redis.Cmder) error {
+		for _, cmd := range cmds {
+			if cmd.Name() == "del" {
+				return errors.New("del failed")
+			}
+		}
+		return next(ctx, cmds)
+	}
+}
+
+func TestInvalidateTagsKeepsIndexOnFailure(t *testing.T) {
+	mr, client := newTestClient(t)
+	ctx := context.Background()
+
+	items := New[item](client, Options{Prefix: "item", TTL: time.Minute})
+	if err := items.Set(ctx, "a", item{Name: "a"}, "destinations"); err != nil {
+		t.Fatalf("Set() 失敗: %v", err)
+	}
+
+	// 刪除失敗時索引保持不變，之後仍可清除
+	failing := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { failing.Close() })
+	failing.AddHook(failDelHook{})
+	if err := InvalidateTags(ctx, failing, "destinations"); err == nil {
+		t.Fatal("InvalidateTags() 期望回傳錯誤")
+	}
+	if members, _ := mr.Members(tagKey("destinations")); len(members) != 1 {
+		t.Fatalf("清除失敗後標籤索引 = %v, 期望保留 item:a", members)
+	}
+
+	if err := InvalidateTags(ctx, client, "destinations"); err != nil {
+		t.Fatalf("InvalidateTags() 失敗: %v", err)
+	}
+	if mr.Exists("item:a") || mr.Exists(tagKey("destinations")) {
+		t.Error("重新清除後快取與標籤索引應已刪除")
+	}
+}
@@ -0,0 +1,71 @@
+package cache
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/ugorji/go/codec"
+)
+
+// Codec 快取值的序列化方式
+type Codec interface {
+	// Name 序列化方式名稱（json、msgpack）
+	Name() string
+	Marshal(v any) ([]byte, error)
+	Unmarshal(data []byte, v any) error
+}
+
+var (
+	// JSON 以 JSON 序列化，方便在 redis-cli 中直接查看內容
+	JSON Codec = jsonCodec{}
+	// Msgpack 以 MessagePack 序列化，體積較小且編解碼較快；結構欄位名稱沿用 json 標籤
+	Msgpack Codec = newMsgpackCodec()
+)
+
+// CodecByName 依名稱取得序列化方式，空字串表示 JSON
+func CodecByName(name string) (Codec, error) {
+	switch strings.ToLower(strings.TrimSpace(name)) {
+	case "", "json":
+		return JSON, nil
+	case "msgpack":
+		return Msgpack, nil
+	default:
+		return nil, fmt.Errorf("不支援的快取序列化方式: %s", name)
+	}
+}
+
+// jsonCodec JSON 序列化
+type jsonCodec struct{}
+
+func (jsonCodec) Name() string { return "json" }
+
+func (jsonCodec) Marshal(v any) ([]byte, error) { return json.Marshal(v) }
+
+func (jsonCodec) Unmarshal(data []byte, v any) error { return json.Unmarshal(data, v) }
+
+// msgpackCodec MessagePack 序列化
+type msgpackCodec struct {
+	handle *codec.MsgpackHandle
+}
+
+// newMsgpackCodec 建立 MessagePack 序列化，字串以 str 型別寫入並使用標準時間擴充格式
+func newMsgpackCodec() msgpackCodec {
+	h := &codec.MsgpackHandle{WriteExt: true}
+	h.RawToString = true
+	return msgpackCodec{handle: h}
+}
+
+func (msgpackCodec) Name() string { return "msgpack" }
+
+func (c msgpackCodec) Marshal(v any) ([]byte, error) {
+	var out []byte
+	if err := codec.NewEncoderBytes(&out, c.handle).Encode(v); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c msgpackCodec) Unmarshal(data []byte, v any) error {
+	return codec.NewDecoderBytes(data, c.handle).Decode(v)
+}
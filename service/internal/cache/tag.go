@@ -0,0 +1,79 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// tagKeyPrefix 標籤索引的 Redis key 前綴，每個標籤以 Set 記錄具有此標籤的快取 key
+const tagKeyPrefix = "cache:tag:"
+
+// invalidateBatch 清除標籤時每批取出的 key 數量
+const invalidateBatch = 500
+
+// addTagMemberScript 將快取 key 加入標籤索引，並確保索引的存活時間不短於該快取
+// 索引只會延長不會縮短，避免較早寫入且較晚過期的快取從索引中消失
+var addTagMemberScript = redis.NewScript(`
+redis.call('SADD', KEYS[1], ARGV[1])
+local ttl = redis.call('PTTL', KEYS[1])
+if ttl < tonumber(ARGV[2]) then
+	redis.call('PEXPIRE', KEYS[1], ARGV[2])
+end
+return 1
+`)
+
+// tagKey 標籤索引的 Redis key
+func tagKey(tag string) string {
+	return tagKeyPrefix + tag
+}
+
+// addTagMember 在 pipeline 中將快取 key 加入標籤索引
+func addTagMember(ctx context.Context, pipe redis.Pipeliner, tag, key string, ttl time.Duration) {
+	addTagMemberScript.Eval(ctx, pipe, []string{tagKey(tag)}, key, ttl.Milliseconds())
+}
+
+// InvalidateTags 清除具有任一標籤的快取（可跨不同型別的 Cache），client 為 nil 時不做任何事
+// 分批讀取索引中的 key，先刪除快取再從索引移除，刪除失敗時索引保持不變，重新呼叫即可再次清除
+// 注意：清除前已開始載入的資料仍可能在清除後寫入，最長存活到快取時間結束
+func InvalidateTags(ctx context.Context, client redis.UniversalClient, tags ...string) error {
+	if client == nil {
+		return nil
+	}
+
+	for _, tag := range tags {
+		for {
+			keys, err := client.SRandMemberN(ctx, tagKey(tag), invalidateBatch).Result()
+			if err != nil && !errors.Is(err, redis.Nil) {
+				return fmt.Errorf("讀取快取標籤 [%s] 失敗: %w", tag, err)
+			}
+			if len(keys) == 0 {
+				break
+			}
+
+			// Cluster 模式下快取 key 與索引可能位於不同節點，無法在同一個交易中處理，逐一刪除後再移出索引
+			pipe := client.Pipeline()
+			for _, key := range keys {
+				pipe.Del(ctx, key)
+			}
+			if _, err := pipe.Exec(ctx); err != nil {
+				return fmt.Errorf("清除快取標籤 [%s] 失敗: %w", tag, err)
+			}
+			members := make([]any, len(keys))
+			for i, key := range keys {
+				members[i] = key
+			}
+			if err := client.SRem(ctx, tagKey(tag), members...).Err(); err != nil {
+				return fmt.Errorf("更新快取標籤 [%s] 失敗: %w", tag, err)
+			}
+
+			if len(keys) < invalidateBatch {
+				break
+			}
+		}
+	}
+	return nil
+}
@@ -17,6 +17,9 @@ type Config struct {
 	Redis    RedisConfig       `mapstructure:"redis" json:"redis" yaml:"redis"`
 	Line     LineBotConfig     `mapstructure:"line" json:"line" yaml:"line"`
 	Telegram TelegramBotConfig `mapstructure:"telegram" json:"telegram" yaml:"telegram"`
+	Weather  WeatherConfig     `mapstructure:"weather" json:"weather" yaml:"weather"`
+	Cache    CacheConfig       `mapstructure:"cache" json:"cache" yaml:"cache"`
+	Auth     AuthConfig        `mapstructure:"auth" json:"auth" yaml:"auth"`
 	Log      LogConfig         `mapstructure:"log" json:"log" yaml:"log"`
 }
 
@@ -29,6 +32,16 @@ type ServerConfig struct {
 	ReadTimeout  time.Duration `mapstructure:"readTimeout" json:"readTimeout" yaml:"readTimeout"`    // HTTP 讀取超時時間
 	WriteTimeout time.Duration `mapstructure:"writeTimeout" json:"writeTimeout" yaml:"writeTimeout"` // HTTP 寫入超時時間
 	CORS         CORSConfig    `mapstructure:"cors" json:"cors" yaml:"cors"`
+
+	// 可信任的反向代理（IP 或 CIDR），只有來自這些位址的請求才採用 X-Forwarded-For 判斷來源 IP
+	// 未設定時一律以連線位址為來源 IP，避免用戶端偽造標頭繞過依 IP 的登入限制
+	TrustedProxies []string `mapstructure:"trustedProxies" json:"trustedProxies" yaml:"trustedProxies"`
+
+	// 背景工作的領導權租約時間：多台伺服器時每個背景工作只由當選的一台執行，當選者異常終止時最長經過此時間後由其他伺服器接手
+	LeaderLeaseTTL time.Duration `mapstructure:"leaderLeaseTTL" json:"leaderLeaseTTL" yaml:"leaderLeaseTTL"`
+
+	// 關閉時 /readyz 先回應 503，等待此時間讓負載平衡器停止導入新流量後才停止伺服器，0 表示立即關閉
+	DrainDelay time.Duration `mapstructure:"drainDelay" json:"drainDelay" yaml:"drainDelay"`
 }
 
 type CORSConfig struct {
@@ -38,7 +51,7 @@ type CORSConfig struct {
 	AllowHeaders []string `mapstructure:"allowHeaders" json:"allowHeaders" yaml:"allowHeaders"`
 }
 
-// DatabaseConfig 資料庫設定（MySQL Master-Slave 架構）
+// DatabaseConfig 資料庫設定（Master-Slave 架構，支援 MySQL、PostgreSQL、SQLite）
 type DatabaseConfig struct {
 	Masters []MasterDBConfig `mapstructure:"masters" json:"masters" yaml:"masters"` // Master 資料庫列表（可根據 Database 區分）
 	Slaves  []SlaveDBConfig  `mapstructure:"slaves" json:"slaves" yaml:"slaves"`    // Slave 資料庫列表（數量不定，可為空）
@@ -48,15 +61,30 @@ type DatabaseConfig struct {
 	MaxOpenConns    int           `mapstructure:"maxOpenConns" json:"maxOpenConns" yaml:"maxOpenConns"`
 	ConnMaxLifetime time.Duration `mapstructure:"connMaxLifetime" json:"connMaxLifetime" yaml:"connMaxLifetime"` // 連線最大存活時間
 
+	// Slave 健康檢查設定（連續失敗達門檻時移出讀取輪替，恢復後重新加入；所有 Slave 異常時改讀 Master）
+	ReplicaHealthCheckInterval time.Duration `mapstructure:"replicaHealthCheckInterval" json:"replicaHealthCheckInterval" yaml:"replicaHealthCheckInterval"` // 檢查間隔，0 表示停用
+	ReplicaHealthCheckTimeout  time.Duration `mapstructure:"replicaHealthCheckTimeout" json:"replicaHealthCheckTimeout" yaml:"replicaHealthCheckTimeout"`    // 單次檢查逾時
+	ReplicaFailureThreshold    int           `mapstructure:"replicaFailureThreshold" json:"replicaFailureThreshold" yaml:"replicaFailureThreshold"`          // 連續失敗幾次後移出輪替
+	ReplicaMaxLag              time.Duration `mapstructure:"replicaMaxLag" json:"replicaMaxLag" yaml:"replicaMaxLag"`                                        // 複寫延遲超過此值時暫停讀取該 Slave，0 表示不量測
+
+	// 讀寫一致性（read-your-writes）設定：同一使用者寫入後的時間窗內，讀取改走 Master
+	StickyMasterWindow  time.Duration `mapstructure:"stickyMasterWindow" json:"stickyMasterWindow" yaml:"stickyMasterWindow"`    // 寫入後讀取 Master 的時間，0 表示停用
+	StickyRedisInstance string        `mapstructure:"stickyRedisInstance" json:"stickyRedisInstance" yaml:"stickyRedisInstance"` // 跨伺服器記錄時間窗使用的 Redis Database（對應 Redis 實例的 database 設定），未設定時使用預設實例
+
 	// SQL 日誌設定
 	LogSlowQuery       bool          `mapstructure:"logSlowQuery" json:"logSlowQuery" yaml:"logSlowQuery"`                   // 是否記錄慢查詢
 	SlowQueryThreshold time.Duration `mapstructure:"slowQueryThreshold" json:"slowQueryThreshold" yaml:"slowQueryThreshold"` // 慢查詢門檻
 	LogAllQueries      bool          `mapstructure:"logAllQueries" json:"logAllQueries" yaml:"logAllQueries"`                // 是否記錄所有查詢（開發用）
+
+	// 資料表遷移設定
+	AutoMigrate          bool          `mapstructure:"autoMigrate" json:"autoMigrate" yaml:"autoMigrate"`                            // 啟動時是否自動套用尚未執行的遷移
+	MigrationLockTimeout time.Duration `mapstructure:"migrationLockTimeout" json:"migrationLockTimeout" yaml:"migrationLockTimeout"` // 等待其他伺服器完成遷移的最長時間
 }
 
 // MasterDBConfig Master 資料庫設定
 type MasterDBConfig struct {
-	Name      string `mapstructure:"name" json:"name" yaml:"name"` // Master 識別名稱（例如：main, analytics）
+	Name      string `mapstructure:"name" json:"name" yaml:"name"`       // Master 識別名稱（例如：main, analytics）
+	Driver    string `mapstructure:"driver" json:"driver" yaml:"driver"` // 資料庫驅動：mysql（預設）、postgres、sqlite
 	Host      string `mapstructure:"host" json:"host" yaml:"host"`
 	Port      string `mapstructure:"port" json:"port" yaml:"port"`
 	User      string `mapstructure:"user" json:"user" yaml:"user"`
@@ -65,6 +93,7 @@ type MasterDBConfig struct {
 	Charset   string `mapstructure:"charset" json:"charset" yaml:"charset"`
 	ParseTime bool   `mapstructure:"parsetime" json:"parsetime" yaml:"parsetime"`
 	Loc       string `mapstructure:"loc" json:"loc" yaml:"loc"`
+	SSLMode   string `mapstructure:"sslmode" json:"sslmode" yaml:"sslmode"` // PostgreSQL 專用，預設 disable
 
 	// 可選：針對特定 Database 的設定
 	Database string `mapstructure:"database" json:"database" yaml:"database"` // 如果需要根據 Database 區分 Master
@@ -77,7 +106,8 @@ type MasterDBConfig struct {
 
 // SlaveDBConfig Slave 資料庫設定
 type SlaveDBConfig struct {
-	Name      string `mapstructure:"name" json:"name" yaml:"name"` // Slave 識別名稱（例如：slave1, slave2）
+	Name      string `mapstructure:"name" json:"name" yaml:"name"`       // Slave 識別名稱（例如：slave1, slave2）
+	Driver    string `mapstructure:"driver" json:"driver" yaml:"driver"` // 資料庫驅動，未設定時與對應的 Master 相同
 	Host      string `mapstructure:"host" json:"host" yaml:"host"`
 	Port      string `mapstructure:"port" json:"port" yaml:"port"`
 	User      string `mapstructure:"user" json:"user" yaml:"user"`
@@ -86,6 +116,7 @@ type SlaveDBConfig struct {
 	Charset   string `mapstructure:"charset" json:"charset" yaml:"charset"`
 	ParseTime bool   `mapstructure:"parsetime" json:"parsetime" yaml:"parsetime"`
 	Loc       string `mapstructure:"loc" json:"loc" yaml:"loc"`
+	SSLMode   string `mapstructure:"sslmode" json:"sslmode" yaml:"sslmode"` // PostgreSQL 專用，預設 disable
 
 	// 負載平衡權重（數值越大，被選中機率越高）
 	Weight int `mapstructure:"weight" json:"weight" yaml:"weight"`
@@ -122,6 +153,12 @@ type RedisInstanceConfig struct {
 	Password string `mapstructure:"password" json:"password" yaml:"password"` // Redis 密碼（可選）
 	DB       int    `mapstructure:"db" json:"db" yaml:"db"`               // Redis 資料庫編號（0-15）
 
+	// 部署模式：single（預設，使用 Host/Port）、sentinel、cluster（使用 Addrs）
+	Mode             string   `mapstructure:"mode" json:"mode" yaml:"mode"`
+	Addrs            []string `mapstructure:"addrs" json:"addrs" yaml:"addrs"`                                  // Sentinel 或 Cluster 節點位址（host:port）
+	MasterName       string   `mapstructure:"masterName" json:"masterName" yaml:"masterName"`                   // Sentinel 監控的 Master 名稱
+	SentinelPassword string   `mapstructure:"sentinelPassword" json:"sentinelPassword" yaml:"sentinelPassword"` // Sentinel 本身的密碼（可選）
+
 	// 可選：針對特定資料庫的設定（用於按 Database 名稱區分）
 	Database string `mapstructure:"database" json:"database" yaml:"database"` // 如果需要根據 Database 區分 Redis 實例
 
@@ -149,6 +186,96 @@ type TelegramBotConfig struct {
 	Token   string `mapstructure:"token" json:"token" yaml:"token"`
 }
 
+// WeatherConfig 天氣服務設定
+type WeatherConfig struct {
+	Provider string        `mapstructure:"provider" json:"provider" yaml:"provider"` // 天氣資料來源: openweathermap, cwa, fake
+	APIKey   string        `mapstructure:"apiKey" json:"apiKey" yaml:"apiKey"`       // 天氣 API 金鑰
+	BaseURL  string        `mapstructure:"baseURL" json:"baseURL" yaml:"baseURL"`    // 天氣 API 位址（可指向相容的代理或測試伺服器），空白時使用資料來源的官方位址
+	Language string        `mapstructure:"language" json:"language" yaml:"language"` // 天氣描述語系
+	Timeout  time.Duration `mapstructure:"timeout" json:"timeout" yaml:"timeout"`    // 呼叫天氣 API 的超時時間
+
+	// 快取設定：座標會對齊到 GridSize 度的格子，同一格子共用天氣資料
+	GridSize float64       `mapstructure:"gridSize" json:"gridSize" yaml:"gridSize"` // 格子大小（度），0.05 度約 5 公里
+	CacheTTL time.Duration `mapstructure:"cacheTTL" json:"cacheTTL" yaml:"cacheTTL"` // 天氣資料快取時間
+}
+
+// CacheConfig Redis 快取設定（使用 database 為 cache 的 Redis 實例，未設定 Redis 時不快取）
+type CacheConfig struct {
+	Codec             string        `mapstructure:"codec" json:"codec" yaml:"codec"`                                     // 序列化方式: json（預設）, msgpack
+	Jitter            float64       `mapstructure:"jitter" json:"jitter" yaml:"jitter"`                                  // 快取時間隨機延長的比例上限，避免大量快取同時過期
+	RecommendationTTL time.Duration `mapstructure:"recommendationTTL" json:"recommendationTTL" yaml:"recommendationTTL"` // 推薦結果快取時間，0 表示不快取
+}
+
+// AuthConfig 會員與管理員 Token（JWT）設定
+type AuthConfig struct {
+	Issuer         string        `mapstructure:"issuer" json:"issuer" yaml:"issuer"`                         // JWT 的 iss
+	MemberTokenTTL time.Duration `mapstructure:"memberTokenTTL" json:"memberTokenTTL" yaml:"memberTokenTTL"` // 會員 Access Token 有效時間
+	AdminTokenTTL  time.Duration `mapstructure:"adminTokenTTL" json:"adminTokenTTL" yaml:"adminTokenTTL"`    // 管理員 Access Token 有效時間
+
+	// 工作階段（存放於 Redis）閒置超過此時間未以 Refresh Token 換發即過期，每次換發重新計算
+	MemberSessionTTL time.Duration `mapstructure:"memberSessionTTL" json:"memberSessionTTL" yaml:"memberSessionTTL"`
+	AdminSessionTTL  time.Duration `mapstructure:"adminSessionTTL" json:"adminSessionTTL" yaml:"adminSessionTTL"`
+
+	// 簽章金鑰：SigningKeyID 指定簽發新 Token 的金鑰，其餘金鑰只用於驗證
+	// 輪替時先加入新金鑰並改用新金鑰簽發，舊金鑰保留到以其簽發的 Token 全部過期後再移除
+	SigningKeyID string             `mapstructure:"signingKeyID" json:"signingKeyID" yaml:"signingKeyID"`
+	Keys         []SigningKeyConfig `mapstructure:"keys" json:"keys" yaml:"keys"`
+
+	// 沒有私鑰的伺服器（例如 Tour Server）從 JWKS 端點取得公鑰離線驗證 Token
+	JWKSURL             string        `mapstructure:"jwksURL" json:"jwksURL" yaml:"jwksURL"`
+	JWKSRefreshInterval time.Duration `mapstructure:"jwksRefreshInterval" json:"jwksRefreshInterval" yaml:"jwksRefreshInterval"` // 定期重新取得公鑰的間隔（遇到未知 kid 時也會重新取得）
+
+	// LINE Login 第三方登入
+	Line LineLoginConfig `mapstructure:"line" json:"line" yaml:"line"`
+
+	// Telegram Login Widget 登入資料（auth_date）的有效時間，簽章以 telegram.token 驗證
+	TelegramLoginMaxAge time.Duration `mapstructure:"telegramLoginMaxAge" json:"telegramLoginMaxAge" yaml:"telegramLoginMaxAge"`
+
+	// 跨平台帳號連結驗證碼（存放於 Redis）的有效時間
+	LinkCodeTTL time.Duration `mapstructure:"linkCodeTTL" json:"linkCodeTTL" yaml:"linkCodeTTL"`
+
+	// 帳號密碼登入（Lobby 與 Backend）的暴力破解防護
+	LoginThrottle LoginThrottleConfig `mapstructure:"loginThrottle" json:"loginThrottle" yaml:"loginThrottle"`
+}
+
+// LoginThrottleConfig 登入失敗限制設定，失敗次數存放於 Redis，依帳號與來源 IP 分別以滑動視窗計算
+// 未啟用 Redis 時不限制
+type LoginThrottleConfig struct {
+	Enabled           bool          `mapstructure:"enabled" json:"enabled" yaml:"enabled"`
+	Window            time.Duration `mapstructure:"window" json:"window" yaml:"window"`                                  // 滑動視窗長度
+	MaxUserFailures   int           `mapstructure:"maxUserFailures" json:"maxUserFailures" yaml:"maxUserFailures"`       // 大規模攻擊期間同一帳號在視窗內失敗達到此次數即鎖定帳號
+	MaxGlobalFailures int           `mapstructure:"maxGlobalFailures" json:"maxGlobalFailures" yaml:"maxGlobalFailures"` // 所有帳號合計在視窗內失敗達到此次數視為大規模攻擊，0 表示不鎖定帳號
+	MaxIPFailures     int           `mapstructure:"maxIPFailures" json:"maxIPFailures" yaml:"maxIPFailures"`             // 同一 IP 在視窗內失敗達到此次數即鎖定 IP
+	FreeAttempts      int           `mapstructure:"freeAttempts" json:"freeAttempts" yaml:"freeAttempts"`                // 同一帳號前幾次失敗不需等待
+	BaseDelay         time.Duration `mapstructure:"baseDelay" json:"baseDelay" yaml:"baseDelay"`                         // 超過 freeAttempts 後需等待的時間，每多失敗一次加倍
+	MaxDelay          time.Duration `mapstructure:"maxDelay" json:"maxDelay" yaml:"maxDelay"`                            // 等待時間上限
+	LockoutDuration   time.Duration `mapstructure:"lockoutDuration" json:"lockoutDuration" yaml:"lockoutDuration"`       // 帳號鎖定時間
+	IPLockoutDuration time.Duration `mapstructure:"ipLockoutDuration" json:"ipLockoutDuration" yaml:"ipLockoutDuration"` // IP 鎖定時間
+}
+
+// LineLoginConfig LINE Login v2.1 設定（LINE Login Channel，與 Line Bot 的 Messaging API Channel 不同）
+// LINE 的使用者 ID 在同一個 Provider 下相同，Login Channel 與 Bot Channel 屬於同一 Provider 時可對應到同一位會員
+type LineLoginConfig struct {
+	Enabled       bool          `mapstructure:"enabled" json:"enabled" yaml:"enabled"`
+	ChannelID     string        `mapstructure:"channelID" json:"channelID" yaml:"channelID"`
+	ChannelSecret string        `mapstructure:"channelSecret" json:"channelSecret" yaml:"channelSecret"`
+	RedirectURI   string        `mapstructure:"redirectURI" json:"redirectURI" yaml:"redirectURI"` // 需與 LINE Developers Console 設定的 Callback URL 一致
+	StateTTL      time.Duration `mapstructure:"stateTTL" json:"stateTTL" yaml:"stateTTL"`          // state 與 nonce 的有效時間（存放於 Redis）
+	Timeout       time.Duration `mapstructure:"timeout" json:"timeout" yaml:"timeout"`             // 呼叫 LINE API 的超時時間
+
+	// LINE API 位址，空白時使用官方位址（可指向本機的假 LINE 伺服器以便測試）
+	AuthorizeURL string `mapstructure:"authorizeURL" json:"authorizeURL" yaml:"authorizeURL"`
+	TokenURL     string `mapstructure:"tokenURL" json:"tokenURL" yaml:"tokenURL"`
+	ProfileURL   string `mapstructure:"profileURL" json:"profileURL" yaml:"profileURL"`
+}
+
+// SigningKeyConfig JWT 簽章金鑰（ECDSA P-256 私鑰，PEM 格式，ES256），PrivateKey 與 PrivateKeyFile 擇一
+type SigningKeyConfig struct {
+	KeyID          string `mapstructure:"kid" json:"kid" yaml:"kid"`
+	PrivateKey     string `mapstructure:"privateKey" json:"privateKey" yaml:"privateKey"`
+	PrivateKeyFile string `mapstructure:"privateKeyFile" json:"privateKeyFile" yaml:"privateKeyFile"`
+}
+
 // LogConfig 日誌設定
 type LogConfig struct {
 	Level      string `mapstructure:"level" json:"level" yaml:"level"`                // 日誌等級: debug, info, warn, error, fatal
@@ -221,6 +348,8 @@ func setDefaults() {
 	// Server 預設值
 	viper.SetDefault("server.host", "0.0.0.0")
 	viper.SetDefault("server.port", "8080")
+	viper.SetDefault("server.leaderLeaseTTL", 15*time.Second)
+	viper.SetDefault("server.drainDelay", 5*time.Second)
 
 	// Database 預設值（MySQL Master-Slave）
 	// 全域連線池設定
@@ -228,15 +357,29 @@ func setDefaults() {
 	viper.SetDefault("database.maxopenconns", 100)
 	viper.SetDefault("database.connmaxlifetime", 3600) // 1 小時
 
+	// Slave 健康檢查設定
+	viper.SetDefault("database.replicaHealthCheckInterval", 10*time.Second)
+	viper.SetDefault("database.replicaHealthCheckTimeout", 2*time.Second)
+	viper.SetDefault("database.replicaFailureThreshold", 2)
+	viper.SetDefault("database.replicaMaxLag", 0)
+
+	// 讀寫一致性設定
+	viper.SetDefault("database.stickyMasterWindow", 5*time.Second)
+
 	// SQL 日誌設定
 	viper.SetDefault("database.logslowquery", true)                       // 預設啟用慢查詢記錄
 	viper.SetDefault("database.slowquerythreshold", 200*time.Millisecond) // 預設 200ms 為慢查詢
 	viper.SetDefault("database.logallqueries", false)                     // 預設不記錄所有查詢
 
+	// 資料表遷移設定
+	viper.SetDefault("database.autoMigrate", true)
+	viper.SetDefault("database.migrationLockTimeout", time.Minute)
+
 	// 預設 Master 設定（向後相容）
 	viper.SetDefault("database.masters", []map[string]any{
 		{
 			"name":      "main",
+			"driver":    "mysql",
 			"host":      "localhost",
 			"port":      "3306",
 			"user":      "root",
@@ -273,6 +416,38 @@ func setDefaults() {
 
 	// Weather 預設值
 	viper.SetDefault("weather.provider", "openweathermap")
+	viper.SetDefault("weather.language", "zh_tw")
+	viper.SetDefault("weather.timeout", 5*time.Second)
+	viper.SetDefault("weather.gridSize", 0.05)
+	viper.SetDefault("weather.cacheTTL", 30*time.Minute)
+
+	// Cache 預設值
+	viper.SetDefault("cache.codec", "json")
+	viper.SetDefault("cache.jitter", 0.1)
+	viper.SetDefault("cache.recommendationTTL", 5*time.Minute)
+
+	// Auth 預設值
+	viper.SetDefault("auth.issuer", "tourhelper")
+	viper.SetDefault("auth.memberTokenTTL", 15*time.Minute)
+	viper.SetDefault("auth.adminTokenTTL", 15*time.Minute)
+	viper.SetDefault("auth.memberSessionTTL", 30*24*time.Hour)
+	viper.SetDefault("auth.adminSessionTTL", 12*time.Hour)
+	viper.SetDefault("auth.jwksRefreshInterval", 10*time.Minute)
+	viper.SetDefault("auth.line.enabled", false)
+	viper.SetDefault("auth.line.stateTTL", 10*time.Minute)
+	viper.SetDefault("auth.line.timeout", 5*time.Second)
+	viper.SetDefault("auth.telegramLoginMaxAge", time.Hour)
+	viper.SetDefault("auth.linkCodeTTL", 10*time.Minute)
+	viper.SetDefault("auth.loginThrottle.enabled", true)
+	viper.SetDefault("auth.loginThrottle.window", 15*time.Minute)
+	viper.SetDefault("auth.loginThrottle.maxUserFailures", 10)
+	viper.SetDefault("auth.loginThrottle.maxIPFailures", 50)
+	viper.SetDefault("auth.loginThrottle.maxGlobalFailures", 500)
+	viper.SetDefault("auth.loginThrottle.freeAttempts", 3)
+	viper.SetDefault("auth.loginThrottle.baseDelay", time.Second)
+	viper.SetDefault("auth.loginThrottle.maxDelay", 30*time.Second)
+	viper.SetDefault("auth.loginThrottle.lockoutDuration", 15*time.Minute)
+	viper.SetDefault("auth.loginThrottle.ipLockoutDuration", time.Hour)
 
 	// Maps 預設值
 	viper.SetDefault("maps.provider", "google")
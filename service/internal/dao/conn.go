@@ -0,0 +1,59 @@
+package dao
+
+import (
+	"context"
+
+	"github.com/andy2kuo/TourHelper/internal/database"
+	"gorm.io/gorm"
+)
+
+// Conn DAO 使用的資料庫連線來源
+// 讀取一律透過 Reader，寫入（含需要讀取最新資料的交易）一律透過 Writer
+type Conn interface {
+	// Reader 取得讀取用連線（Slave，沒有 Slave 時為 Master）
+	Reader(ctx context.Context) *gorm.DB
+
+	// Writer 取得寫入用連線（Master）
+	Writer(ctx context.Context) *gorm.DB
+}
+
+// mysqlConn 透過 MySQLManager 進行讀寫分離的連線來源
+type mysqlConn struct {
+	manager *database.MySQLManager
+	name    []string
+}
+
+// NewMySQLConn 建立使用 MySQLManager 的連線來源，name 為 Master 名稱或 Database 名稱（未指定時使用預設資料庫）
+func NewMySQLConn(manager *database.MySQLManager, name ...string) Conn {
+	return &mysqlConn{manager: manager, name: name}
+}
+
+// Reader 取得 Slave 連線
+func (c *mysqlConn) Reader(ctx context.Context) *gorm.DB {
+	return c.manager.GetSlave(c.name...).WithContext(ctx)
+}
+
+// Writer 取得 Master 連線
+func (c *mysqlConn) Writer(ctx context.Context) *gorm.DB {
+	return c.manager.GetMaster(c.name...).WithContext(ctx)
+}
+
+// dbConn 讀寫都使用同一個 *gorm.DB 的連線來源（單機資料庫或測試用）
+type dbConn struct {
+	db *gorm.DB
+}
+
+// NewDBConn 建立讀寫都使用 db 的連線來源
+func NewDBConn(db *gorm.DB) Conn {
+	return &dbConn{db: db}
+}
+
+// Reader 取得讀取用連線
+func (c *dbConn) Reader(ctx context.Context) *gorm.DB {
+	return c.db.WithContext(ctx)
+}
+
+// Writer 取得寫入用連線
+func (c *dbConn) Writer(ctx context.Context) *gorm.DB {
+	return c.db.WithContext(ctx)
+}
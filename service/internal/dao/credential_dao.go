@@ -0,0 +1,91 @@
+package dao
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/andy2kuo/TourHelper/internal/models"
+	"gorm.io/gorm/clause"
+)
+
+// ErrCredentialExists 帳號已被使用，或使用者已設定過帳號密碼
+var ErrCredentialExists = errors.New("帳號已存在")
+
+// CredentialDAO 帳號密碼資料庫操作介面
+type CredentialDAO interface {
+	// Create 建立帳號密碼，帳號或使用者已有帳號密碼時回傳 ErrCredentialExists
+	Create(ctx context.Context, cred *models.Credential) error
+
+	// FindByUsername 依帳號取得帳號密碼，查無資料時回傳 gorm.ErrRecordNotFound
+	FindByUsername(ctx context.Context, username string) (*models.Credential, error)
+
+	// FindByUserID 依使用者 ID 取得帳號密碼，查無資料時回傳 gorm.ErrRecordNotFound
+	FindByUserID(ctx context.Context, userID uint) (*models.Credential, error)
+
+	// UpdatePasswordHash 更新密碼雜湊
+	UpdatePasswordHash(ctx context.Context, id uint, passwordHash string) error
+
+	// UpdateRole 更新帳號角色
+	UpdateRole(ctx context.Context, id uint, role string) error
+}
+
+// credentialDAO 帳號密碼資料庫操作實作
+type credentialDAO struct {
+	conn Conn
+}
+
+// NewCredentialDAO 建立帳號密碼 DAO
+func NewCredentialDAO(conn Conn) CredentialDAO {
+	return &credentialDAO{conn: conn}
+}
+
+// Create 建立帳號密碼，以唯一索引搭配 ON CONFLICT DO NOTHING 處理並行註冊同一帳號
+func (d *credentialDAO) Create(ctx context.Context, cred *models.Credential) error {
+	result := d.conn.Writer(ctx).Clauses(clause.OnConflict{DoNothing: true}).Create(cred)
+	if result.Error != nil {
+		return fmt.Errorf("建立帳號密碼失敗: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return ErrCredentialExists
+	}
+	return nil
+}
+
+// FindByUsername 依帳號取得帳號密碼
+// 讀取 Master：註冊或變更密碼後需立即生效，不可受 Slave 複寫延遲影響
+func (d *credentialDAO) FindByUsername(ctx context.Context, username string) (*models.Credential, error) {
+	var cred models.Credential
+	if err := d.conn.Writer(ctx).Where("username = ?", username).First(&cred).Error; err != nil {
+		return nil, err
+	}
+	return &cred, nil
+}
+
+// FindByUserID 依使用者 ID 取得帳號密碼
+// 讀取 Master：換發管理員 Token 時依此確認目前的角色，角色變更後需立即生效
+func (d *credentialDAO) FindByUserID(ctx context.Context, userID uint) (*models.Credential, error) {
+	var cred models.Credential
+	if err := d.conn.Writer(ctx).Where("user_id = ?", userID).First(&cred).Error; err != nil {
+		return nil, err
+	}
+	return &cred, nil
+}
+
+// UpdatePasswordHash 更新密碼雜湊
+func (d *credentialDAO) UpdatePasswordHash(ctx context.Context, id uint, passwordHash string) error {
+	err := d.conn.Writer(ctx).Model(&models.Credential{}).Where("id = ?", id).Update("password_hash", passwordHash).Error
+	if err != nil {
+		return fmt.Errorf("更新密碼失敗: %w", err)
+	}
+	return nil
+}
+
+// UpdateRole 更新帳號角色
+func (d *credentialDAO) UpdateRole(ctx context.Context, id uint, role string) error {
+	err := d.conn.Writer(ctx).Model(&models.Credential{}).Where("id = ?", id).Update("role", role).Error
+	if err != nil {
+		return fmt.Errorf("更新帳號角色失敗: %w", err)
+	}
+	return nil
+}
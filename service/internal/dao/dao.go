@@ -3,6 +3,7 @@ package dao
 import (
 	"sync"
 
+	"github.com/andy2kuo/TourHelper/internal/database"
 	"gorm.io/gorm"
 )
 
@@ -10,9 +11,14 @@ import (
 type DAO struct {
 	User        UserDAO
 	Destination DestinationDAO
+	Weather     WeatherDAO
+	Credential  CredentialDAO
+	Lockout     LoginLockoutDAO
 	// 未來可以新增其他 DAO，例如：
 	// Tag         TagDAO
 	// Preference  PreferenceDAO
+
+	conn Conn // 所有 DAO 共用的連線來源，WithTx 以此開啟交易
 }
 
 var (
@@ -21,7 +27,7 @@ var (
 	db       *gorm.DB
 )
 
-// SetDB 設定資料庫連線
+// SetDB 設定資料庫連線（需在 Get 之前呼叫），未設定時使用 MySQLManager 的預設資料庫進行讀寫分離
 func SetDB(database *gorm.DB) {
 	db = database
 }
@@ -29,11 +35,25 @@ func SetDB(database *gorm.DB) {
 // Get 取得 DAO 實例（單例模式）
 func Get() *DAO {
 	once.Do(func() {
-		instance = &DAO{
-			User:        NewUserDAO(db),
-			Destination: NewDestinationDAO(db),
-			// 初始化其他 DAO
+		if db != nil {
+			instance = New(NewDBConn(db))
+		} else {
+			instance = New(NewMySQLConn(database.GetMySQL()))
 		}
 	})
 	return instance
 }
+
+// New 建立使用指定連線來源的 DAO
+func New(conn Conn) *DAO {
+	return &DAO{
+		User:        NewUserDAO(conn),
+		Destination: NewDestinationDAO(conn),
+		Weather:     NewWeatherDAO(conn),
+		Credential:  NewCredentialDAO(conn),
+		Lockout:     NewLoginLockoutDAO(conn),
+		// 初始化其他 DAO
+
+		conn: conn,
+	}
+}
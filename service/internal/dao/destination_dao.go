@@ -1,20 +1,418 @@
 package dao
 
 import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/andy2kuo/TourHelper/internal/models"
+	"github.com/andy2kuo/TourHelper/pkg/geo"
 	"gorm.io/gorm"
 )
 
+const (
+	defaultDestinationListLimit = 20  // 未指定筆數時每頁的景點數
+	maxDestinationListLimit     = 100 // 每頁最多的景點數
+
+	// findByIDsBatchSize FindByIDs 每批查詢的 ID 數量，需低於 SQLite 舊版預設的 999 個參數上限
+	findByIDsBatchSize = 500
+)
+
+// ErrInvalidQuery 查詢條件錯誤（例如：不支援的排序欄位、分頁游標格式錯誤）
+var ErrInvalidQuery = errors.New("無效的查詢條件")
+
+// errInvalidCursor 分頁游標格式錯誤或與排序方式不符
+var errInvalidCursor = fmt.Errorf("%w: 分頁游標格式錯誤或與排序方式不符", ErrInvalidQuery)
+
+// destinationSortColumns 可排序的欄位
+var destinationSortColumns = map[string]string{
+	"id":         "destinations.id",
+	"name":       "destinations.name",
+	"rating":     "destinations.rating",
+	"created_at": "destinations.created_at",
+	"updated_at": "destinations.updated_at",
+}
+
+// DestinationFilter 景點篩選條件，零值的條件不套用
+type DestinationFilter struct {
+	Category    string
+	City        string
+	Region      string
+	Tags        []string         // 需同時具有所有標籤
+	MinRating   *float64         // 評分下限（含）
+	MaxRating   *float64         // 評分上限（含）
+	BoundingBox *geo.BoundingBox // 座標範圍
+}
+
+// DestinationQuery 景點列表查詢條件
+type DestinationQuery struct {
+	DestinationFilter
+
+	SortBy string // 排序欄位: id（預設）, name, rating, created_at, updated_at
+	Desc   bool   // 是否遞減排序
+	Limit  int    // 每頁筆數，預設 20，最多 100
+
+	// 分頁方式擇一使用：有 Cursor 時使用游標分頁並忽略 Offset
+	Offset int
+	Cursor string
+}
+
+// DestinationPage 景點列表查詢結果
+type DestinationPage struct {
+	Items      []models.Destination
+	Total      int64  // 符合條件的總筆數，僅偏移分頁時計算（游標分頁為 -1）
+	NextCursor string // 下一頁的游標，沒有下一頁時為空字串
+}
+
+// destinationCursor 游標分頁的位置：上一頁最後一筆的排序欄位值與 ID
+type destinationCursor struct {
+	SortBy string          `json:"s"`
+	Value  json.RawMessage `json:"v,omitempty"`
+	ID     uint            `json:"id"`
+}
+
 // DestinationDAO 景點資料庫操作介面
 type DestinationDAO interface {
-	// TODO: 實作 CRUD 方法
+	// Create 建立景點，Tags 依名稱對應既有標籤，不存在的標籤會一併建立
+	Create(ctx context.Context, dest *models.Destination) error
+
+	// Get 取得景點（含標籤），不存在或已刪除時回傳 gorm.ErrRecordNotFound
+	Get(ctx context.Context, id uint) (*models.Destination, error)
+
+	// Update 以 dest 覆寫景點的所有欄位，Tags 為 nil 時保留原有標籤
+	// 景點不存在或已刪除時回傳 gorm.ErrRecordNotFound
+	Update(ctx context.Context, dest *models.Destination) error
+
+	// SoftDelete 軟刪除景點，景點不存在或已刪除時回傳 gorm.ErrRecordNotFound
+	SoftDelete(ctx context.Context, id uint) error
+
+	// List 依條件篩選、排序並分頁查詢景點（含標籤），查詢條件錯誤時回傳 ErrInvalidQuery
+	List(ctx context.Context, query DestinationQuery) (*DestinationPage, error)
+
+	// FindByIDs 依 ID 取得景點（含標籤），不存在或已刪除的 ID 會被略過
+	FindByIDs(ctx context.Context, ids []uint) ([]models.Destination, error)
+
+	// FindChangedSince 取得指定時間（含）之後新增、更新或刪除的景點，包含已軟刪除的資料
+	// 僅載入 ID、座標與異動時間欄位，供空間索引增量更新使用；一律讀取 Master，避免 Slave 延遲的異動被同步進度略過
+	FindChangedSince(ctx context.Context, since time.Time) ([]models.Destination, error)
 }
 
 // destinationDAO 景點資料庫操作實作
 type destinationDAO struct {
-	db *gorm.DB
+	conn Conn
 }
 
 // NewDestinationDAO 建立景點 DAO
-func NewDestinationDAO(db *gorm.DB) DestinationDAO {
-	return &destinationDAO{db: db}
+func NewDestinationDAO(conn Conn) DestinationDAO {
+	return &destinationDAO{conn: conn}
+}
+
+// Create 建立景點
+func (d *destinationDAO) Create(ctx context.Context, dest *models.Destination) error {
+	return d.conn.Writer(ctx).Transaction(func(tx *gorm.DB) error {
+		tags, err := resolveTags(tx, dest.Tags)
+		if err != nil {
+			return err
+		}
+		dest.Tags = tags
+
+		// 標籤已在 resolveTags 建立，只需建立關聯
+		return tx.Omit("Tags.*").Create(dest).Error
+	})
+}
+
+// Get 取得景點
+func (d *destinationDAO) Get(ctx context.Context, id uint) (*models.Destination, error) {
+	var dest models.Destination
+	if err := d.conn.Reader(ctx).Preload("Tags").First(&dest, id).Error; err != nil {
+		return nil, err
+	}
+	return &dest, nil
+}
+
+// Update 更新景點
+func (d *destinationDAO) Update(ctx context.Context, dest *models.Destination) error {
+	return d.conn.Writer(ctx).Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(dest).Select("*").Omit("ID", "CreatedAt", "DeletedAt", "Tags").Updates(dest)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+
+		if dest.Tags == nil {
+			return nil
+		}
+		tags, err := resolveTags(tx, dest.Tags)
+		if err != nil {
+			return err
+		}
+		dest.Tags = tags
+		return tx.Model(dest).Omit("Tags.*").Association("Tags").Replace(dest.Tags)
+	})
+}
+
+// SoftDelete 軟刪除景點
+func (d *destinationDAO) SoftDelete(ctx context.Context, id uint) error {
+	result := d.conn.Writer(ctx).Delete(&models.Destination{}, id)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// List 依條件查詢景點列表
+func (d *destinationDAO) List(ctx context.Context, query DestinationQuery) (*DestinationPage, error) {
+	sortBy := query.SortBy
+	if sortBy == "" {
+		sortBy = "id"
+	}
+	column, ok := destinationSortColumns[sortBy]
+	if !ok {
+		return nil, fmt.Errorf("%w: 不支援的排序欄位 %s", ErrInvalidQuery, query.SortBy)
+	}
+	if query.MinRating != nil && query.MaxRating != nil && *query.MinRating > *query.MaxRating {
+		return nil, fmt.Errorf("%w: 評分下限大於上限", ErrInvalidQuery)
+	}
+
+	limit := query.Limit
+	if limit <= 0 {
+		limit = defaultDestinationListLimit
+	}
+	limit = min(limit, maxDestinationListLimit)
+
+	db := d.conn.Reader(ctx)
+	q := applyDestinationFilter(db, db.Model(&models.Destination{}), query.DestinationFilter).Session(&gorm.Session{})
+
+	page := &DestinationPage{Total: -1}
+	if query.Cursor == "" {
+		if err := q.Count(&page.Total).Error; err != nil {
+			return nil, err
+		}
+		q = q.Offset(max(query.Offset, 0))
+	} else {
+		cursor, value, err := decodeDestinationCursor(query.Cursor, sortBy)
+		if err != nil {
+			return nil, err
+		}
+		op := ">"
+		if query.Desc {
+			op = "<"
+		}
+		if sortBy == "id" {
+			q = q.Where("destinations.id "+op+" ?", cursor.ID)
+		} else {
+			q = q.Where(fmt.Sprintf("(%s %s ? OR (%s = ? AND destinations.id %s ?))", column, op, column, op), value, value, cursor.ID)
+		}
+	}
+
+	direction := "ASC"
+	if query.Desc {
+		direction = "DESC"
+	}
+	if sortBy != "id" {
+		q = q.Order(column + " " + direction)
+	}
+	q = q.Order("destinations.id " + direction)
+
+	// 多取一筆判斷是否還有下一頁
+	var items []models.Destination
+	if err := q.Preload("Tags").Limit(limit + 1).Find(&items).Error; err != nil {
+		return nil, err
+	}
+
+	if len(items) > limit {
+		items = items[:limit]
+		next, err := encodeDestinationCursor(&items[limit-1], sortBy)
+		if err != nil {
+			return nil, err
+		}
+		page.NextCursor = next
+	}
+	page.Items = items
+
+	return page, nil
+}
+
+// FindByIDs 依 ID 取得景點（含標籤），ID 數量較多時分批查詢，避免超過資料庫的參數數量上限
+func (d *destinationDAO) FindByIDs(ctx context.Context, ids []uint) ([]models.Destination, error) {
+	destinations := make([]models.Destination, 0, len(ids))
+	for chunk := range slices.Chunk(ids, findByIDsBatchSize) {
+		var batch []models.Destination
+		if err := d.conn.Reader(ctx).Preload("Tags").Where("id IN ?", chunk).Find(&batch).Error; err != nil {
+			return nil, err
+		}
+		destinations = append(destinations, batch...)
+	}
+	return destinations, nil
+}
+
+// FindChangedSince 取得指定時間之後有異動的景點（包含已軟刪除的資料）
+// 呼叫端以回傳資料的最新異動時間推進同步進度，若讀取 Slave，尚未複製到 Slave 的較早異動會被永久略過
+func (d *destinationDAO) FindChangedSince(ctx context.Context, since time.Time) ([]models.Destination, error) {
+	var destinations []models.Destination
+	err := d.conn.Writer(ctx).Unscoped().
+		Select("id", "latitude", "longitude", "updated_at", "deleted_at").
+		Where("updated_at >= ? OR deleted_at >= ?", since, since).
+		Find(&destinations).Error
+	if err != nil {
+		return nil, err
+	}
+	return destinations, nil
+}
+
+// applyDestinationFilter 將篩選條件套用到查詢，db 用於建立標籤子查詢
+func applyDestinationFilter(db, q *gorm.DB, f DestinationFilter) *gorm.DB {
+	if f.Category != "" {
+		q = q.Where("destinations.category = ?", f.Category)
+	}
+	if f.City != "" {
+		q = q.Where("destinations.city = ?", f.City)
+	}
+	if f.Region != "" {
+		q = q.Where("destinations.region = ?", f.Region)
+	}
+	if f.MinRating != nil {
+		q = q.Where("destinations.rating >= ?", *f.MinRating)
+	}
+	if f.MaxRating != nil {
+		q = q.Where("destinations.rating <= ?", *f.MaxRating)
+	}
+	if box := f.BoundingBox; box != nil {
+		q = q.Where("destinations.latitude BETWEEN ? AND ?", box.MinLat, box.MaxLat)
+		if box.CrossesAntimeridian() {
+			q = q.Where("(destinations.longitude >= ? OR destinations.longitude <= ?)", box.MinLon, box.MaxLon)
+		} else {
+			q = q.Where("destinations.longitude BETWEEN ? AND ?", box.MinLon, box.MaxLon)
+		}
+	}
+
+	if tags := uniqueTagNames(f.Tags); len(tags) > 0 {
+		sub := db.Table("destination_tags").
+			Select("destination_tags.destination_id").
+			Joins("JOIN tags ON tags.id = destination_tags.tag_id AND tags.deleted_at IS NULL").
+			Where("tags.name IN ?", tags).
+			Group("destination_tags.destination_id").
+			Having("COUNT(DISTINCT tags.id) = ?", len(tags))
+		q = q.Where("destinations.id IN (?)", sub)
+	}
+
+	return q
+}
+
+// resolveTags 依名稱取得標籤，不存在的標籤會建立，已刪除的標籤會還原
+func resolveTags(tx *gorm.DB, tags []models.Tag) ([]models.Tag, error) {
+	names := make([]string, 0, len(tags))
+	for _, tag := range tags {
+		names = append(names, tag.Name)
+	}
+	names = uniqueTagNames(names)
+
+	resolved := make([]models.Tag, 0, len(names))
+	for _, name := range names {
+		var tag models.Tag
+		if err := tx.Unscoped().Where(models.Tag{Name: name}).FirstOrCreate(&tag).Error; err != nil {
+			return nil, fmt.Errorf("建立標籤 [%s] 失敗: %w", name, err)
+		}
+		if tag.DeletedAt.Valid {
+			if err := tx.Unscoped().Model(&tag).Update("deleted_at", nil).Error; err != nil {
+				return nil, fmt.Errorf("還原標籤 [%s] 失敗: %w", name, err)
+			}
+		}
+		resolved = append(resolved, tag)
+	}
+	return resolved, nil
+}
+
+// uniqueTagNames 去除標籤名稱的空白與重複
+func uniqueTagNames(names []string) []string {
+	seen := make(map[string]struct{}, len(names))
+	unique := make([]string, 0, len(names))
+	for _, name := range names {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		if _, ok := seen[name]; ok {
+			continue
+		}
+		seen[name] = struct{}{}
+		unique = append(unique, name)
+	}
+	return unique
+}
+
+// encodeDestinationCursor 以景點的排序欄位值與 ID 建立游標
+func encodeDestinationCursor(dest *models.Destination, sortBy string) (string, error) {
+	var value any
+	switch sortBy {
+	case "name":
+		value = dest.Name
+	case "rating":
+		value = dest.Rating
+	case "created_at":
+		value = dest.CreatedAt
+	case "updated_at":
+		value = dest.UpdatedAt
+	}
+
+	cursor := destinationCursor{SortBy: sortBy, ID: dest.ID}
+	if value != nil {
+		raw, err := json.Marshal(value)
+		if err != nil {
+			return "", fmt.Errorf("建立分頁游標失敗: %w", err)
+		}
+		cursor.Value = raw
+	}
+
+	raw, err := json.Marshal(cursor)
+	if err != nil {
+		return "", fmt.Errorf("建立分頁游標失敗: %w", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(raw), nil
+}
+
+// decodeDestinationCursor 解析游標，回傳游標與轉換為欄位型別的排序值
+func decodeDestinationCursor(text, sortBy string) (*destinationCursor, any, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(text)
+	if err != nil {
+		return nil, nil, errInvalidCursor
+	}
+
+	var cursor destinationCursor
+	if err := json.Unmarshal(raw, &cursor); err != nil || cursor.SortBy != sortBy {
+		return nil, nil, errInvalidCursor
+	}
+
+	var value any
+	switch sortBy {
+	case "id":
+		return &cursor, nil, nil
+	case "name":
+		var v string
+		err = json.Unmarshal(cursor.Value, &v)
+		value = v
+	case "rating":
+		var v float64
+		err = json.Unmarshal(cursor.Value, &v)
+		value = v
+	case "created_at", "updated_at":
+		var v time.Time
+		err = json.Unmarshal(cursor.Value, &v)
+		value = v
+	}
+	if err != nil {
+		return nil, nil, errInvalidCursor
+	}
+
+	return &cursor, value, nil
 }
@@ -0,0 +1,233 @@
+package dao
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/andy2kuo/TourHelper/internal/models"
+	"github.com/andy2kuo/TourHelper/pkg/geo"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	gormLogger "gorm.io/gorm/logger"
+)
+
+// newTestDAO 建立使用暫存 SQLite 資料庫的 DAO
+func newTestDAO(t *testing.T) (*DAO, *gorm.DB) {
+	t.Helper()
+
+	dsn := filepath.Join(t.TempDir(), "dao.db") + "?_busy_timeout=5000"
+	db, err := gorm.Open(sqlite.Open(dsn), &gorm.Config{Logger: gormLogger.Discard})
+	if err != nil {
+		t.Fatalf("開啟 SQLite 失敗: %v", err)
+	}
+	if err := db.AutoMigrate(&models.Destination{}, &models.Tag{}, &models.User{}, &models.UserIdentity{}, &models.UserPreferences{}, &models.SearchHistory{}, &models.Credential{}); err != nil {
+		t.Fatalf("建立資料表失敗: %v", err)
+	}
+	return New(NewDBConn(db)), db
+}
+
+// seedDestinations 建立測試景點
+func seedDestinations(t *testing.T, d *DAO) []*models.Destination {
+	t.Helper()
+
+	tag := func(names ...string) []models.Tag {
+		tags := make([]models.Tag, len(names))
+		for i, name := range names {
+			tags[i] = models.Tag{Name: name}
+		}
+		return tags
+	}
+
+	dests := []*models.Destination{
+		{Name: "陽明山", Category: "nature", City: "台北市", Region: "北部", Latitude: 25.1554, Longitude: 121.5495, Rating: 4.5, Tags: tag("山", "賞花")},
+		{Name: "淡水老街", Category: "culture", City: "新北市", Region: "北部", Latitude: 25.1697, Longitude: 121.4396, Rating: 4.2, Tags: tag("老街", "夕陽")},
+		{Name: "九份老街", Category: "culture", City: "新北市", Region: "北部", Latitude: 25.1095, Longitude: 121.8447, Rating: 4.6, Tags: tag("老街", "山")},
+		{Name: "台北101", Category: "shopping", City: "台北市", Region: "北部", Latitude: 25.0340, Longitude: 121.5645, Rating: 4.7},
+		{Name: "日月潭", Category: "nature", City: "南投縣", Region: "中部", Latitude: 23.8570, Longitude: 120.9150, Rating: 4.8, Tags: tag("湖", "山")},
+	}
+	for _, dest := range dests {
+		if err := d.Destination.Create(context.Background(), dest); err != nil {
+			t.Fatalf("Create(%s) 錯誤: %v", dest.Name, err)
+		}
+	}
+	return dests
+}
+
+func TestDestinationDAOCRUD(t *testing.T) {
+	d, db := newTestDAO(t)
+	ctx := context.Background()
+	dests := seedDestinations(t, d)
+
+	var tagCount int64
+	db.Model(&models.Tag{}).Count(&tagCount)
+	if tagCount != 5 {
+		t.Errorf("標籤數 = %d, 期望 5（相同名稱共用）", tagCount)
+	}
+
+	// 更新欄位並替換標籤
+	update := *dests[0]
+	update.Rating = 3.9
+	update.Tags = []models.Tag{{Name: "溫泉"}}
+	if err := d.Destination.Update(ctx, &update); err != nil {
+		t.Fatalf("Update() 錯誤: %v", err)
+	}
+	got, err := d.Destination.Get(ctx, dests[0].ID)
+	if err != nil {
+		t.Fatalf("Get() 錯誤: %v", err)
+	}
+	if got.Rating != 3.9 || got.Name != "陽明山" || len(got.Tags) != 1 || got.Tags[0].Name != "溫泉" {
+		t.Errorf("Get() = %+v, 更新未生效", got)
+	}
+
+	// Tags 為 nil 時保留原有標籤
+	update.Tags = nil
+	update.Description = "國家公園"
+	if err := d.Destination.Update(ctx, &update); err != nil {
+		t.Fatalf("Update() 錯誤: %v", err)
+	}
+	if got, _ := d.Destination.Get(ctx, dests[0].ID); len(got.Tags) != 1 || got.Description != "國家公園" {
+		t.Errorf("Get() = %+v, 應保留原有標籤", got)
+	}
+
+	if err := d.Destination.SoftDelete(ctx, dests[0].ID); err != nil {
+		t.Fatalf("SoftDelete() 錯誤: %v", err)
+	}
+	if _, err := d.Destination.Get(ctx, dests[0].ID); !errors.Is(err, gorm.ErrRecordNotFound) {
+		t.Errorf("Get(已刪除) 錯誤 = %v, 期望 gorm.ErrRecordNotFound", err)
+	}
+	if err := d.Destination.SoftDelete(ctx, dests[0].ID); !errors.Is(err, gorm.ErrRecordNotFound) {
+		t.Errorf("SoftDelete(已刪除) 錯誤 = %v, 期望 gorm.ErrRecordNotFound", err)
+	}
+	if err := d.Destination.Update(ctx, &update); !errors.Is(err, gorm.ErrRecordNotFound) {
+		t.Errorf("Update(已刪除) 錯誤 = %v, 期望 gorm.ErrRecordNotFound", err)
+	}
+}
+
+func TestDestinationDAOListFilters(t *testing.T) {
+	d, _ := newTestDAO(t)
+	seedDestinations(t, d)
+
+	rating := func(v float64) *float64 { return &v }
+
+	tests := []struct {
+		name     string
+		filter   DestinationFilter
+		expected []string
+	}{
+		{name: "無條件", expected: []string{"陽明山", "淡水老街", "九份老街", "台北101", "日月潭"}},
+		{name: "分類", filter: DestinationFilter{Category: "culture"}, expected: []string{"淡水老街", "九份老街"}},
+		{name: "縣市", filter: DestinationFilter{City: "台北市"}, expected: []string{"陽明山", "台北101"}},
+		{name: "地區", filter: DestinationFilter{Region: "中部"}, expected: []string{"日月潭"}},
+		{name: "單一標籤", filter: DestinationFilter{Tags: []string{"山"}}, expected: []string{"陽明山", "九份老街", "日月潭"}},
+		{name: "需同時具有所有標籤", filter: DestinationFilter{Tags: []string{"山", "老街"}}, expected: []string{"九份老街"}},
+		{name: "評分範圍", filter: DestinationFilter{MinRating: rating(4.5), MaxRating: rating(4.7)}, expected: []string{"陽明山", "九份老街", "台北101"}},
+		{
+			name:     "座標範圍",
+			filter:   DestinationFilter{BoundingBox: &geo.BoundingBox{MinLat: 25.0, MinLon: 121.5, MaxLat: 25.2, MaxLon: 121.6}},
+			expected: []string{"陽明山", "台北101"},
+		},
+		{
+			name:     "跨越 180 度經線的座標範圍",
+			filter:   DestinationFilter{BoundingBox: &geo.BoundingBox{MinLat: 23.0, MinLon: 121.6, MaxLat: 25.2, MaxLon: 121.5}},
+			expected: []string{"淡水老街", "九份老街", "日月潭"},
+		},
+		{name: "組合條件", filter: DestinationFilter{Category: "nature", Tags: []string{"山"}, MinRating: rating(4.6)}, expected: []string{"日月潭"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			page, err := d.Destination.List(context.Background(), DestinationQuery{DestinationFilter: tt.filter})
+			if err != nil {
+				t.Fatalf("List() 錯誤: %v", err)
+			}
+			if names := destinationNames(page.Items); !slices.Equal(names, tt.expected) {
+				t.Errorf("List() = %v, 期望 %v", names, tt.expected)
+			}
+			if page.Total != int64(len(tt.expected)) {
+				t.Errorf("Total = %d, 期望 %d", page.Total, len(tt.expected))
+			}
+		})
+	}
+}
+
+func TestDestinationDAOFindByIDsBatches(t *testing.T) {
+	d, _ := newTestDAO(t)
+	dests := seedDestinations(t, d)
+
+	// ID 數量超過單批上限時分批查詢，不存在的 ID 會被略過
+	ids := make([]uint, 0, findByIDsBatchSize*2+len(dests))
+	for i := range findByIDsBatchSize * 2 {
+		ids = append(ids, uint(10000+i))
+	}
+	for _, dest := range dests {
+		ids = append(ids, dest.ID)
+	}
+
+	found, err := d.Destination.FindByIDs(context.Background(), ids)
+	if err != nil {
+		t.Fatalf("FindByIDs() 錯誤: %v", err)
+	}
+	if len(found) != len(dests) {
+		t.Fatalf("FindByIDs() 找到 %d 個景點, 期望 %d 個", len(found), len(dests))
+	}
+	for _, dest := range found {
+		if dest.Name == "陽明山" && len(dest.Tags) != 2 {
+			t.Errorf("FindByIDs() 未載入標籤: %+v", dest.Tags)
+		}
+	}
+}
+
+func TestDestinationDAOListPagination(t *testing.T) {
+	d, _ := newTestDAO(t)
+	seedDestinations(t, d)
+	ctx := context.Background()
+
+	// 偏移分頁
+	page, err := d.Destination.List(ctx, DestinationQuery{SortBy: "rating", Desc: true, Limit: 2, Offset: 2})
+	if err != nil {
+		t.Fatalf("List() 錯誤: %v", err)
+	}
+	if names := destinationNames(page.Items); !slices.Equal(names, []string{"九份老街", "陽明山"}) || page.Total != 5 {
+		t.Errorf("偏移分頁 = %v (total %d)", names, page.Total)
+	}
+
+	// 游標分頁依序取完所有景點
+	var names []string
+	query := DestinationQuery{SortBy: "rating", Desc: true, Limit: 2}
+	for i := 0; i < 5; i++ {
+		page, err := d.Destination.List(ctx, query)
+		if err != nil {
+			t.Fatalf("List() 錯誤: %v", err)
+		}
+		names = append(names, destinationNames(page.Items)...)
+		if page.NextCursor == "" {
+			break
+		}
+		query.Cursor = page.NextCursor
+	}
+	expected := []string{"日月潭", "台北101", "九份老街", "陽明山", "淡水老街"}
+	if !slices.Equal(names, expected) {
+		t.Errorf("游標分頁 = %v, 期望 %v", names, expected)
+	}
+
+	// 游標與排序方式不符
+	query.SortBy = "name"
+	if _, err := d.Destination.List(ctx, query); !errors.Is(err, ErrInvalidQuery) {
+		t.Errorf("List(排序不符的游標) 錯誤 = %v, 期望 ErrInvalidQuery", err)
+	}
+	if _, err := d.Destination.List(ctx, DestinationQuery{SortBy: "password"}); !errors.Is(err, ErrInvalidQuery) {
+		t.Errorf("List(不支援的排序) 錯誤 = %v, 期望 ErrInvalidQuery", err)
+	}
+}
+
+// destinationNames 取出景點名稱
+func destinationNames(dests []models.Destination) []string {
+	names := make([]string, len(dests))
+	for i, dest := range dests {
+		names[i] = dest.Name
+	}
+	return names
+}
@@ -0,0 +1,98 @@
+package dao
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/andy2kuo/TourHelper/internal/models"
+)
+
+const (
+	defaultLockoutListLimit = 50  // 未指定筆數時回傳的鎖定記錄數
+	maxLockoutListLimit     = 200 // 最多回傳的鎖定記錄數
+)
+
+// LoginLockoutQuery 鎖定記錄查詢條件，零值的條件不套用
+type LoginLockoutQuery struct {
+	Scope      string
+	Kind       string
+	Subject    string
+	ActiveOnly bool      // 只取得 Now 時仍鎖定中（未到期且未解除）的記錄
+	Now        time.Time // ActiveOnly 判斷用的時間，零值時使用目前時間
+	Limit      int       // 筆數，預設 50，最多 200
+}
+
+// LoginLockoutDAO 登入鎖定記錄資料庫操作介面
+type LoginLockoutDAO interface {
+	// Create 新增鎖定記錄
+	Create(ctx context.Context, lockout *models.LoginLockout) error
+
+	// List 依條件取得鎖定記錄，由新到舊排序
+	List(ctx context.Context, query LoginLockoutQuery) ([]models.LoginLockout, error)
+
+	// MarkUnlocked 將對象鎖定中的記錄標記為已由管理員解除，回傳更新的筆數
+	MarkUnlocked(ctx context.Context, scope, kind, subject, unlockedBy string, at time.Time) (int64, error)
+}
+
+// loginLockoutDAO 登入鎖定記錄資料庫操作實作
+type loginLockoutDAO struct {
+	conn Conn
+}
+
+// NewLoginLockoutDAO 建立登入鎖定記錄 DAO
+func NewLoginLockoutDAO(conn Conn) LoginLockoutDAO {
+	return &loginLockoutDAO{conn: conn}
+}
+
+// Create 新增鎖定記錄
+func (d *loginLockoutDAO) Create(ctx context.Context, lockout *models.LoginLockout) error {
+	if err := d.conn.Writer(ctx).Create(lockout).Error; err != nil {
+		return fmt.Errorf("建立鎖定記錄失敗: %w", err)
+	}
+	return nil
+}
+
+// List 依條件取得鎖定記錄
+func (d *loginLockoutDAO) List(ctx context.Context, query LoginLockoutQuery) ([]models.LoginLockout, error) {
+	db := d.conn.Reader(ctx).Model(&models.LoginLockout{})
+	if query.Scope != "" {
+		db = db.Where("scope = ?", query.Scope)
+	}
+	if query.Kind != "" {
+		db = db.Where("kind = ?", query.Kind)
+	}
+	if query.Subject != "" {
+		db = db.Where("subject = ?", query.Subject)
+	}
+	if query.ActiveOnly {
+		now := query.Now
+		if now.IsZero() {
+			now = time.Now()
+		}
+		db = db.Where("locked_until > ? AND unlocked_at IS NULL", now)
+	}
+
+	limit := query.Limit
+	if limit <= 0 {
+		limit = defaultLockoutListLimit
+	}
+	limit = min(limit, maxLockoutListLimit)
+
+	var lockouts []models.LoginLockout
+	if err := db.Order("id DESC").Limit(limit).Find(&lockouts).Error; err != nil {
+		return nil, fmt.Errorf("查詢鎖定記錄失敗: %w", err)
+	}
+	return lockouts, nil
+}
+
+// MarkUnlocked 將對象鎖定中的記錄標記為已解除
+func (d *loginLockoutDAO) MarkUnlocked(ctx context.Context, scope, kind, subject, unlockedBy string, at time.Time) (int64, error) {
+	result := d.conn.Writer(ctx).Model(&models.LoginLockout{}).
+		Where("scope = ? AND kind = ? AND subject = ? AND locked_until > ? AND unlocked_at IS NULL", scope, kind, subject, at).
+		Updates(map[string]any{"unlocked_at": at, "unlocked_by": unlockedBy})
+	if result.Error != nil {
+		return 0, fmt.Errorf("更新鎖定記錄失敗: %w", result.Error)
+	}
+	return result.RowsAffected, nil
+}
@@ -0,0 +1,79 @@
+package dao
+
+import (
+	"context"
+	"errors"
+	"math/rand/v2"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/jackc/pgx/v5/pgconn"
+	"gorm.io/gorm"
+)
+
+const (
+	maxTxAttempts  = 3                     // 發生死結時交易最多執行的次數（含第一次）
+	txRetryBackoff = 20 * time.Millisecond // 重試前等待的基本時間，每次重試遞增並加上隨機值
+)
+
+// txConn 交易中的連線來源，讀寫都使用同一個交易（Master）
+type txConn struct {
+	tx *gorm.DB
+}
+
+// Reader 取得交易連線（交易中的讀取需看到交易內的寫入，不可改讀 Slave）
+func (c *txConn) Reader(ctx context.Context) *gorm.DB {
+	return c.tx.WithContext(ctx)
+}
+
+// Writer 取得交易連線
+func (c *txConn) Writer(ctx context.Context) *gorm.DB {
+	return c.tx.WithContext(ctx)
+}
+
+// WithTx 使用預設 DAO 執行交易，詳見 DAO.WithTx
+func WithTx(ctx context.Context, fn func(tx *DAO) error) error {
+	return Get().WithTx(ctx, fn)
+}
+
+// WithTx 在 Master 上開啟交易，以交易中的 DAO 執行 fn；fn 回傳錯誤時回滾，否則提交
+// 在交易中的 DAO 上再次呼叫 WithTx 時使用 SAVEPOINT，內層失敗只回滾內層的異動
+// 最外層交易遇到死結（MySQL 1213、PostgreSQL 40P01）時會重新執行 fn，因此 fn 不應有交易以外的副作用
+func (d *DAO) WithTx(ctx context.Context, fn func(tx *DAO) error) error {
+	if conn, ok := d.conn.(*txConn); ok {
+		return conn.tx.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+			return fn(New(&txConn{tx: tx}))
+		})
+	}
+
+	var err error
+	for attempt := 1; ; attempt++ {
+		err = d.conn.Writer(ctx).Transaction(func(tx *gorm.DB) error {
+			return fn(New(&txConn{tx: tx}))
+		})
+		if err == nil || !isDeadlock(err) || attempt >= maxTxAttempts {
+			return err
+		}
+
+		backoff := time.Duration(attempt)*txRetryBackoff + rand.N(txRetryBackoff)
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(backoff):
+		}
+	}
+}
+
+// isDeadlock 判斷錯誤是否為資料庫偵測到的死結（交易已被資料庫回滾，可安全重試）
+func isDeadlock(err error) bool {
+	var mysqlErr *mysql.MySQLError
+	if errors.As(err, &mysqlErr) {
+		return mysqlErr.Number == 1213 // ER_LOCK_DEADLOCK
+	}
+
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		return pgErr.Code == "40P01" // deadlock_detected
+	}
+	return false
+}
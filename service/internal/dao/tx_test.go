@@ -0,0 +1,112 @@
+package dao
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/andy2kuo/TourHelper/internal/models"
+	"github.com/go-sql-driver/mysql"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+func TestWithTx(t *testing.T) {
+	d, db := newTestDAO(t)
+	ctx := context.Background()
+	errRollback := errors.New("回滾")
+
+	countUsers := func() int64 {
+		var n int64
+		db.Model(&models.User{}).Count(&n)
+		return n
+	}
+
+	// 外層成功、內層失敗時只回滾內層的異動
+	err := d.WithTx(ctx, func(tx *DAO) error {
+		if _, err := tx.User.FindOrCreateByExternal(ctx, models.PlatformLine, "outer", ExternalProfile{}); err != nil {
+			return err
+		}
+		err := tx.WithTx(ctx, func(tx *DAO) error {
+			if _, err := tx.User.FindOrCreateByExternal(ctx, models.PlatformLine, "inner", ExternalProfile{}); err != nil {
+				return err
+			}
+			return errRollback
+		})
+		if !errors.Is(err, errRollback) {
+			return fmt.Errorf("內層交易錯誤 = %v", err)
+		}
+
+		// 交易中的讀取看得到交易內尚未提交的寫入
+		user, err := tx.User.FindOrCreateByExternal(ctx, models.PlatformLine, "outer", ExternalProfile{})
+		if err != nil {
+			return err
+		}
+		_, err = tx.User.FindPreferencesByUserID(ctx, user.ID)
+		return err
+	})
+	if err != nil {
+		t.Fatalf("WithTx() 失敗: %v", err)
+	}
+	if n := countUsers(); n != 1 {
+		t.Errorf("使用者數 = %d, 期望 1（僅外層）", n)
+	}
+
+	// 外層失敗時全部回滾
+	err = d.WithTx(ctx, func(tx *DAO) error {
+		if _, err := tx.User.FindOrCreateByExternal(ctx, models.PlatformLine, "rollback", ExternalProfile{}); err != nil {
+			return err
+		}
+		return errRollback
+	})
+	if !errors.Is(err, errRollback) {
+		t.Errorf("WithTx() 錯誤 = %v, 期望 %v", err, errRollback)
+	}
+	if n := countUsers(); n != 1 {
+		t.Errorf("回滾後使用者數 = %d, 期望 1", n)
+	}
+}
+
+func TestWithTxDeadlockRetry(t *testing.T) {
+	d, _ := newTestDAO(t)
+	ctx := context.Background()
+
+	tests := []struct {
+		name     string
+		err      error
+		attempts int
+	}{
+		{"MySQL 死結重試", &mysql.MySQLError{Number: 1213, Message: "Deadlock found"}, maxTxAttempts},
+		{"PostgreSQL 死結重試", fmt.Errorf("更新失敗: %w", &pgconn.PgError{Code: "40P01"}), maxTxAttempts},
+		{"其他錯誤不重試", &mysql.MySQLError{Number: 1062, Message: "Duplicate entry"}, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			attempts := 0
+			err := d.WithTx(ctx, func(tx *DAO) error {
+				attempts++
+				return tt.err
+			})
+			if !errors.Is(err, tt.err) {
+				t.Errorf("WithTx() 錯誤 = %v, 期望 %v", err, tt.err)
+			}
+			if attempts != tt.attempts {
+				t.Errorf("執行次數 = %d, 期望 %d", attempts, tt.attempts)
+			}
+		})
+	}
+
+	// 重試後成功
+	attempts := 0
+	err := d.WithTx(ctx, func(tx *DAO) error {
+		attempts++
+		if attempts == 1 {
+			return &mysql.MySQLError{Number: 1213}
+		}
+		return nil
+	})
+	if err != nil || attempts != 2 {
+		t.Errorf("WithTx() = %v, 執行 %d 次, 期望重試一次後成功", err, attempts)
+	}
+}
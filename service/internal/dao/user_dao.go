@@ -1,20 +1,274 @@
 package dao
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/andy2kuo/TourHelper/internal/models"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
+// ExternalProfile 外部平台提供的使用者資料，空字串的欄位不會覆寫既有資料
+type ExternalProfile struct {
+	Username    string
+	DisplayName string
+}
+
+// ErrMergeConflict 兩位會員都設定了帳號密碼，無法合併（每位會員最多一組帳號密碼）
+var ErrMergeConflict = errors.New("兩位會員都設定了帳號密碼，無法合併")
+
+// errIdentityConflict 建立使用者或平台身分時與既有資料衝突，用於回滾建立使用者的交易
+var errIdentityConflict = errors.New("平台身分已存在")
+
 // UserDAO 使用者資料庫操作介面
 type UserDAO interface {
-	// TODO: 實作 CRUD 方法
+	// FindOrCreateByExternal 依平台身分取得使用者（已連結到其他會員的身分回傳該會員），不存在時建立使用者與平台身分，
+	// 並同步最新的使用者名稱與顯示名稱；使用者沒有偏好設定時會建立預設值；可安全地被同一使用者的並行請求同時呼叫
+	FindOrCreateByExternal(ctx context.Context, platform, externalID string, profile ExternalProfile) (*models.User, error)
+
+	// FindByID 依 ID 取得使用者，查無資料時回傳 gorm.ErrRecordNotFound
+	FindByID(ctx context.Context, id uint) (*models.User, error)
+
+	// FindPreferencesByUserID 取得使用者偏好設定，查無資料時回傳 gorm.ErrRecordNotFound
+	FindPreferencesByUserID(ctx context.Context, userID uint) (*models.UserPreferences, error)
+
+	// SavePreferences 儲存使用者偏好設定（以 prefs.UserID 為準），不存在時建立，所有欄位皆會寫入（包含零值）
+	SavePreferences(ctx context.Context, prefs *models.UserPreferences) error
+
+	// ListIdentities 取得使用者連結的所有平台身分，依連結時間排序
+	ListIdentities(ctx context.Context, userID uint) ([]models.UserIdentity, error)
+
+	// Merge 將 sourceID 使用者合併到 targetID：平台身分、搜尋歷史與帳號密碼移到 targetID，偏好設定保留 targetID 的，最後刪除 sourceID
+	// 所有異動在同一個交易中完成（已在 WithTx 中時使用 SAVEPOINT）；任一使用者不存在時回傳 gorm.ErrRecordNotFound，兩者都有帳號密碼時回傳 ErrMergeConflict
+	Merge(ctx context.Context, targetID, sourceID uint) error
 }
 
 // userDAO 使用者資料庫操作實作
 type userDAO struct {
-	db *gorm.DB
+	conn Conn
 }
 
 // NewUserDAO 建立使用者 DAO
-func NewUserDAO(db *gorm.DB) UserDAO {
-	return &userDAO{db: db}
+func NewUserDAO(conn Conn) UserDAO {
+	return &userDAO{conn: conn}
+}
+
+// FindOrCreateByExternal 依平台身分取得或建立使用者
+// 以唯一索引搭配 ON CONFLICT DO NOTHING 處理並行建立，衝突時改為讀取既有的平台身分
+func (d *userDAO) FindOrCreateByExternal(ctx context.Context, platform, externalID string, profile ExternalProfile) (*models.User, error) {
+	db := d.conn.Writer(ctx)
+
+	identity, err := findIdentity(db, platform, externalID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		identity, err = createIdentity(db, platform, externalID, profile)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// 使用者已刪除時（例如封鎖後重新加入）還原
+	var user models.User
+	if err := db.Unscoped().First(&user, identity.UserID).Error; err != nil {
+		return nil, fmt.Errorf("查詢使用者失敗: %w", err)
+	}
+
+	if updates := profileUpdates(profile, identity.Username, identity.DisplayName); len(updates) > 0 {
+		if err := db.Model(identity).Updates(updates).Error; err != nil {
+			return nil, fmt.Errorf("更新平台身分資料失敗: %w", err)
+		}
+	}
+
+	// 會員的名稱只隨建立會員時的平台身分更新，避免連結多個平台後名稱來回變動
+	updates := map[string]any{}
+	if user.Platform == platform && user.ExternalID == externalID {
+		updates = profileUpdates(profile, user.Username, user.DisplayName)
+	}
+	if user.DeletedAt.Valid {
+		updates["deleted_at"] = nil
+	}
+	if len(updates) > 0 {
+		if err := db.Unscoped().Model(&user).Updates(updates).Error; err != nil {
+			return nil, fmt.Errorf("更新使用者資料失敗: %w", err)
+		}
+		user.DeletedAt = gorm.DeletedAt{}
+	}
+
+	// 預設偏好設定的欄位值由資料表預設值提供
+	prefs := models.UserPreferences{UserID: user.ID}
+	if err := db.Clauses(clause.OnConflict{DoNothing: true}).Create(&prefs).Error; err != nil {
+		return nil, fmt.Errorf("建立預設偏好設定失敗: %w", err)
+	}
+
+	return &user, nil
+}
+
+// findIdentity 依平台與外部 ID 取得平台身分
+func findIdentity(db *gorm.DB, platform, externalID string) (*models.UserIdentity, error) {
+	var identity models.UserIdentity
+	err := db.Where("platform = ? AND external_id = ?", platform, externalID).First(&identity).Error
+	if err != nil {
+		return nil, err
+	}
+	return &identity, nil
+}
+
+// createIdentity 在同一個交易中建立使用者與平台身分，並行建立時回傳先建立的平台身分
+// 不沿用衝突的既有使用者：使用者與平台身分一起建立，衝突代表平台身分已由並行請求建立，或使用者已合併到其他會員（平台身分已移走）
+func createIdentity(db *gorm.DB, platform, externalID string, profile ExternalProfile) (*models.UserIdentity, error) {
+	var identity models.UserIdentity
+	err := db.Transaction(func(tx *gorm.DB) error {
+		user := models.User{
+			ExternalID:  externalID,
+			Platform:    platform,
+			Username:    profile.Username,
+			DisplayName: profile.DisplayName,
+		}
+		result := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&user)
+		if result.Error != nil {
+			return fmt.Errorf("建立使用者失敗: %w", result.Error)
+		}
+		if result.RowsAffected == 0 {
+			return errIdentityConflict
+		}
+
+		identity = models.UserIdentity{
+			UserID:      user.ID,
+			Platform:    platform,
+			ExternalID:  externalID,
+			Username:    profile.Username,
+			DisplayName: profile.DisplayName,
+			LinkedAt:    time.Now(),
+		}
+		result = tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&identity)
+		if result.Error != nil {
+			return fmt.Errorf("建立平台身分失敗: %w", result.Error)
+		}
+		if result.RowsAffected == 0 {
+			return errIdentityConflict
+		}
+		return nil
+	})
+	if !errors.Is(err, errIdentityConflict) {
+		if err != nil {
+			return nil, err
+		}
+		return &identity, nil
+	}
+
+	// 交易已回滾，讀取並行請求建立的平台身分
+	existing, err := findIdentity(db, platform, externalID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, fmt.Errorf("使用者 %s/%s 已存在但沒有平台身分", platform, externalID)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("查詢平台身分失敗: %w", err)
+	}
+	return existing, nil
+}
+
+// profileUpdates 外部平台提供的資料與既有資料不同的欄位，空字串的欄位不覆寫
+func profileUpdates(profile ExternalProfile, username, displayName string) map[string]any {
+	updates := map[string]any{}
+	if profile.Username != "" && profile.Username != username {
+		updates["username"] = profile.Username
+	}
+	if profile.DisplayName != "" && profile.DisplayName != displayName {
+		updates["display_name"] = profile.DisplayName
+	}
+	return updates
+}
+
+// FindByID 依 ID 取得使用者
+func (d *userDAO) FindByID(ctx context.Context, id uint) (*models.User, error) {
+	var user models.User
+	if err := d.conn.Reader(ctx).First(&user, id).Error; err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+// FindPreferencesByUserID 取得使用者偏好設定
+func (d *userDAO) FindPreferencesByUserID(ctx context.Context, userID uint) (*models.UserPreferences, error) {
+	var prefs models.UserPreferences
+	if err := d.conn.Reader(ctx).Where("user_id = ?", userID).First(&prefs).Error; err != nil {
+		return nil, err
+	}
+	return &prefs, nil
+}
+
+// SavePreferences 儲存使用者偏好設定
+// 先確保偏好設定存在再以 map 更新，避免零值（例如最低評分 0）被略過或被資料表預設值取代
+func (d *userDAO) SavePreferences(ctx context.Context, prefs *models.UserPreferences) error {
+	db := d.conn.Writer(ctx)
+	if err := db.Clauses(clause.OnConflict{DoNothing: true}).Create(&models.UserPreferences{UserID: prefs.UserID}).Error; err != nil {
+		return fmt.Errorf("建立偏好設定失敗: %w", err)
+	}
+	return db.Model(&models.UserPreferences{}).Where("user_id = ?", prefs.UserID).Updates(map[string]any{
+		"max_distance":       prefs.MaxDistance,
+		"preferred_weather":  prefs.PreferredWeather,
+		"preferred_category": prefs.PreferredCategory,
+		"min_rating":         prefs.MinRating,
+		"budget":             prefs.Budget,
+	}).Error
+}
+
+// ListIdentities 取得使用者連結的所有平台身分
+func (d *userDAO) ListIdentities(ctx context.Context, userID uint) ([]models.UserIdentity, error) {
+	var identities []models.UserIdentity
+	if err := d.conn.Reader(ctx).Where("user_id = ?", userID).Order("linked_at, id").Find(&identities).Error; err != nil {
+		return nil, err
+	}
+	return identities, nil
+}
+
+// Merge 將 sourceID 使用者合併到 targetID
+// 先鎖定兩位使用者（依 ID 順序，避免互相合併時死結），同一位使用者的並行合併會依序執行，後執行者查無已刪除的使用者
+func (d *userDAO) Merge(ctx context.Context, targetID, sourceID uint) error {
+	return d.conn.Writer(ctx).Transaction(func(tx *gorm.DB) error {
+		var users []models.User
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("id IN ?", []uint{targetID, sourceID}).
+			Order("id").
+			Find(&users).Error
+		if err != nil {
+			return fmt.Errorf("查詢使用者失敗: %w", err)
+		}
+		if len(users) != 2 {
+			return gorm.ErrRecordNotFound
+		}
+
+		var count int64
+		if err := tx.Unscoped().Model(&models.Credential{}).Where("user_id IN ?", []uint{targetID, sourceID}).Count(&count).Error; err != nil {
+			return fmt.Errorf("查詢帳號密碼失敗: %w", err)
+		}
+		if count > 1 {
+			return ErrMergeConflict
+		}
+
+		moves := []struct {
+			name    string
+			model   any
+			updates map[string]any
+		}{
+			{"平台身分", &models.UserIdentity{}, map[string]any{"user_id": targetID, "linked_at": time.Now()}},
+			{"搜尋歷史", &models.SearchHistory{}, map[string]any{"user_id": targetID}},
+			{"帳號密碼", &models.Credential{}, map[string]any{"user_id": targetID}},
+		}
+		for _, m := range moves {
+			if err := tx.Unscoped().Model(m.model).Where("user_id = ?", sourceID).Updates(m.updates).Error; err != nil {
+				return fmt.Errorf("移動%s失敗: %w", m.name, err)
+			}
+		}
+
+		if err := tx.Where("user_id = ?", sourceID).Delete(&models.UserPreferences{}).Error; err != nil {
+			return fmt.Errorf("刪除偏好設定失敗: %w", err)
+		}
+		if err := tx.Delete(&models.User{}, sourceID).Error; err != nil {
+			return fmt.Errorf("刪除使用者失敗: %w", err)
+		}
+		return nil
+	})
 }
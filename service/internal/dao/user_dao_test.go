@@ -0,0 +1,151 @@
+package dao
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/andy2kuo/TourHelper/internal/models"
+	"gorm.io/gorm"
+)
+
+func TestUserDAOFindOrCreateByExternal(t *testing.T) {
+	d, db := newTestDAO(t)
+	ctx := context.Background()
+
+	// 同一使用者的並行 webhook 只會建立一筆使用者與偏好設定
+	var wg sync.WaitGroup
+	ids := make([]uint, 10)
+	for i := range ids {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			user, err := d.User.FindOrCreateByExternal(ctx, models.PlatformLine, "U123", ExternalProfile{DisplayName: "小明"})
+			if err != nil {
+				t.Errorf("FindOrCreateByExternal() 錯誤: %v", err)
+				return
+			}
+			ids[i] = user.ID
+		}()
+	}
+	wg.Wait()
+
+	for _, id := range ids {
+		if id == 0 || id != ids[0] {
+			t.Fatalf("並行呼叫取得的使用者 ID = %v, 期望全部相同", ids)
+		}
+	}
+
+	var users, prefs int64
+	db.Model(&models.User{}).Count(&users)
+	db.Model(&models.UserPreferences{}).Count(&prefs)
+	if users != 1 || prefs != 1 {
+		t.Errorf("使用者數 = %d, 偏好設定數 = %d, 期望各 1 筆", users, prefs)
+	}
+
+	p, err := d.User.FindPreferencesByUserID(ctx, ids[0])
+	if err != nil || p.MaxDistance != 50 || p.MinRating != 3 {
+		t.Errorf("預設偏好設定 = %+v, %v", p, err)
+	}
+
+	// 再次呼叫時更新顯示名稱，空白欄位不覆寫
+	user, err := d.User.FindOrCreateByExternal(ctx, models.PlatformLine, "U123", ExternalProfile{Username: "ming", DisplayName: "王小明"})
+	if err != nil {
+		t.Fatalf("FindOrCreateByExternal() 錯誤: %v", err)
+	}
+	user, _ = d.User.FindOrCreateByExternal(ctx, models.PlatformLine, "U123", ExternalProfile{})
+	if user.ID != ids[0] || user.Username != "ming" || user.DisplayName != "王小明" {
+		t.Errorf("更新後使用者 = %+v", user)
+	}
+
+	// 已刪除的使用者重新加入時還原
+	db.Delete(&models.User{}, user.ID)
+	if restored, err := d.User.FindOrCreateByExternal(ctx, models.PlatformLine, "U123", ExternalProfile{}); err != nil || restored.ID != user.ID {
+		t.Errorf("還原使用者 = %+v, %v, 期望 ID %d", restored, err, user.ID)
+	}
+
+	// 不同平台的相同外部 ID 為不同使用者
+	other, err := d.User.FindOrCreateByExternal(ctx, models.PlatformTelegram, "U123", ExternalProfile{})
+	if err != nil || other.ID == user.ID {
+		t.Errorf("其他平台使用者 = %+v, %v, 期望為新使用者", other, err)
+	}
+
+	// 平台身分不存在時不沿用已刪除的使用者（例如合併後被刪除的會員），避免還原已不存在的會員
+	db.Unscoped().Where("user_id = ?", other.ID).Delete(&models.UserIdentity{})
+	db.Delete(&models.User{}, other.ID)
+	if _, err := d.User.FindOrCreateByExternal(ctx, models.PlatformTelegram, "U123", ExternalProfile{}); err == nil {
+		t.Error("FindOrCreateByExternal(沒有平台身分的已刪除使用者) 應回傳錯誤")
+	}
+	var deleted models.User
+	if db.Unscoped().First(&deleted, other.ID); !deleted.DeletedAt.Valid {
+		t.Errorf("使用者 %d 不應被還原", other.ID)
+	}
+}
+
+func TestUserDAOMerge(t *testing.T) {
+	d, db := newTestDAO(t)
+	ctx := context.Background()
+
+	telegram, _ := d.User.FindOrCreateByExternal(ctx, models.PlatformTelegram, "987654321", ExternalProfile{Username: "xiaoming"})
+	line, _ := d.User.FindOrCreateByExternal(ctx, models.PlatformLine, "U123", ExternalProfile{DisplayName: "小明"})
+	db.Create(&models.SearchHistory{UserID: telegram.ID, SearchLocation: "台北車站"})
+	db.Create(&models.Credential{UserID: telegram.ID, Username: "xiaoming", PasswordHash: "hash"})
+
+	err := d.WithTx(ctx, func(tx *DAO) error {
+		return tx.User.Merge(ctx, line.ID, telegram.ID)
+	})
+	if err != nil {
+		t.Fatalf("Merge() 錯誤: %v", err)
+	}
+
+	// 較早建立的 Telegram 身分以合併的時間作為連結時間，排在會員原本的 LINE 身分之後
+	identities, err := d.User.ListIdentities(ctx, line.ID)
+	if err != nil || len(identities) != 2 {
+		t.Fatalf("ListIdentities() = %+v, %v, 期望 2 筆", identities, err)
+	}
+	if identities[0].Platform != models.PlatformLine || identities[1].Platform != models.PlatformTelegram ||
+		!identities[1].LinkedAt.After(identities[1].CreatedAt) {
+		t.Errorf("ListIdentities() = %+v, 期望依連結時間排序且合併的身分使用合併時間", identities)
+	}
+
+	var histories, credentials, prefs int64
+	db.Model(&models.SearchHistory{}).Where("user_id = ?", line.ID).Count(&histories)
+	db.Model(&models.Credential{}).Where("user_id = ?", line.ID).Count(&credentials)
+	db.Model(&models.UserPreferences{}).Count(&prefs)
+	if histories != 1 || credentials != 1 || prefs != 1 {
+		t.Errorf("合併後搜尋歷史 = %d, 帳號密碼 = %d, 偏好設定 = %d, 期望各 1 筆", histories, credentials, prefs)
+	}
+	if _, err := d.User.FindByID(ctx, telegram.ID); !errors.Is(err, gorm.ErrRecordNotFound) {
+		t.Errorf("FindByID(已合併的會員) 錯誤 = %v, 期望 %v", err, gorm.ErrRecordNotFound)
+	}
+
+	// 已連結的 Telegram 身分對應到合併後的會員，且不改變會員的顯示名稱
+	user, err := d.User.FindOrCreateByExternal(ctx, models.PlatformTelegram, "987654321", ExternalProfile{DisplayName: "Ming"})
+	if err != nil || user.ID != line.ID || user.DisplayName != "小明" {
+		t.Errorf("FindOrCreateByExternal(已連結的身分) = %+v, %v, 期望會員 %d", user, err, line.ID)
+	}
+
+	// 兩位會員都有帳號密碼時不可合併
+	web, _ := d.User.FindOrCreateByExternal(ctx, models.PlatformWeb, "ming2", ExternalProfile{})
+	db.Create(&models.Credential{UserID: web.ID, Username: "ming2", PasswordHash: "hash"})
+	if err := d.User.Merge(ctx, line.ID, web.ID); !errors.Is(err, ErrMergeConflict) {
+		t.Errorf("Merge(都有帳號密碼) 錯誤 = %v, 期望 %v", err, ErrMergeConflict)
+	}
+	if err := d.User.Merge(ctx, line.ID, telegram.ID); !errors.Is(err, gorm.ErrRecordNotFound) {
+		t.Errorf("Merge(不存在的會員) 錯誤 = %v, 期望 %v", err, gorm.ErrRecordNotFound)
+	}
+
+	// 未在 WithTx 中呼叫時也在交易中執行，中途失敗不會留下合併一半的會員
+	hua, _ := d.User.FindOrCreateByExternal(ctx, models.PlatformWeb, "hua", ExternalProfile{})
+	mei, _ := d.User.FindOrCreateByExternal(ctx, models.PlatformWeb, "mei", ExternalProfile{})
+	if err := db.Migrator().DropTable(&models.SearchHistory{}); err != nil {
+		t.Fatalf("刪除資料表失敗: %v", err)
+	}
+	if err := d.User.Merge(ctx, hua.ID, mei.ID); err == nil {
+		t.Fatal("Merge(搜尋歷史資料表不存在) 應回傳錯誤")
+	}
+	if identities, _ := d.User.ListIdentities(ctx, mei.ID); len(identities) != 1 {
+		t.Errorf("合併失敗後 ListIdentities() = %+v, 期望平台身分仍屬於原會員", identities)
+	}
+}
@@ -0,0 +1,61 @@
+package dao
+
+import (
+	"context"
+	"time"
+
+	"github.com/andy2kuo/TourHelper/internal/models"
+	"gorm.io/gorm/clause"
+)
+
+// WeatherDAO 天氣快取資料庫操作介面
+type WeatherDAO interface {
+	// FindValid 取得指定位置鍵值在 now 時仍有效的天氣資料，查無資料時回傳 gorm.ErrRecordNotFound
+	FindValid(ctx context.Context, gridKey string, now time.Time) (*models.WeatherData, error)
+
+	// Save 儲存位置鍵值（data.GridKey）的天氣資料，已有資料時覆寫
+	Save(ctx context.Context, data *models.WeatherData) error
+
+	// DeleteExpired 刪除在 before 之前已過期的天氣資料，回傳刪除筆數
+	DeleteExpired(ctx context.Context, before time.Time) (int64, error)
+}
+
+// weatherDAO 天氣快取資料庫操作實作
+type weatherDAO struct {
+	conn Conn
+}
+
+// NewWeatherDAO 建立天氣快取 DAO
+func NewWeatherDAO(conn Conn) WeatherDAO {
+	return &weatherDAO{conn: conn}
+}
+
+// FindValid 取得仍有效的天氣資料
+func (d *weatherDAO) FindValid(ctx context.Context, gridKey string, now time.Time) (*models.WeatherData, error) {
+	var data models.WeatherData
+	err := d.conn.Reader(ctx).
+		Where("grid_key = ? AND expire_at > ?", gridKey, now).
+		First(&data).Error
+	if err != nil {
+		return nil, err
+	}
+	return &data, nil
+}
+
+// Save 儲存天氣資料，同一位置鍵值只保留一筆
+// 以 grid_key 唯一索引 upsert，並行寫入同一位置時不會產生重複資料
+func (d *weatherDAO) Save(ctx context.Context, data *models.WeatherData) error {
+	return d.conn.Writer(ctx).Clauses(clause.OnConflict{
+		Columns: []clause.Column{{Name: "grid_key"}},
+		DoUpdates: clause.AssignmentColumns([]string{
+			"updated_at", "latitude", "longitude", "temperature", "condition",
+			"description", "humidity", "wind_speed", "fetched_at", "expire_at",
+		}),
+	}).Create(data).Error
+}
+
+// DeleteExpired 刪除已過期的天氣資料，快取資料不需保留，直接實際刪除而非軟刪除
+func (d *weatherDAO) DeleteExpired(ctx context.Context, before time.Time) (int64, error) {
+	result := d.conn.Writer(ctx).Unscoped().Where("expire_at < ?", before).Delete(&models.WeatherData{})
+	return result.RowsAffected, result.Error
+}
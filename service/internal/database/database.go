@@ -21,6 +21,15 @@ func Init(cfg *config.Config) error {
 			return fmt.Errorf("初始化 Redis 失敗: %w", err)
 		}
 		logger.Info("Redis 初始化成功")
+
+		// 讀寫一致性時間窗透過 Redis 跨伺服器共用
+		if cfg.Database.StickyMasterWindow > 0 {
+			client := GetRedis().GetClient()
+			if cfg.Database.StickyRedisInstance != "" {
+				client = GetRedis().GetClientByDB(cfg.Database.StickyRedisInstance)
+			}
+			GetMySQL().SetStickyStore(client)
+		}
 	} else {
 		logger.Warn("未設定 Redis，跳過 Redis 初始化")
 	}
@@ -66,3 +75,8 @@ func GetMySQL() *MySQLManager {
 func GetRedis() *RedisManager {
 	return GetRedisInstance()
 }
+
+// RedisEnabled 檢查 Redis 是否已初始化
+func RedisEnabled() bool {
+	return redisInstance != nil
+}
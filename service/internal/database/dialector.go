@@ -0,0 +1,107 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"gorm.io/driver/mysql"
+	"gorm.io/driver/postgres"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+// 支援的資料庫驅動
+const (
+	DriverMySQL    = "mysql"
+	DriverPostgres = "postgres"
+	DriverSQLite   = "sqlite"
+)
+
+// connParams 建立連線所需的參數（Master 與 Slave 共用）
+type connParams struct {
+	Driver    string
+	Host      string
+	Port      string
+	User      string
+	Password  string
+	DBName    string // SQLite 為資料庫檔案路徑（可附加 ?_busy_timeout=5000 等參數）
+	Charset   string
+	ParseTime bool
+	Loc       string
+	SSLMode   string
+}
+
+// normalizeDriver 正規化驅動名稱，未設定時預設為 MySQL
+func normalizeDriver(driver string) (string, error) {
+	switch strings.ToLower(strings.TrimSpace(driver)) {
+	case "", DriverMySQL:
+		return DriverMySQL, nil
+	case DriverPostgres, "postgresql", "pgsql":
+		return DriverPostgres, nil
+	case DriverSQLite, "sqlite3":
+		return DriverSQLite, nil
+	default:
+		return "", fmt.Errorf("不支援的資料庫驅動: %s", driver)
+	}
+}
+
+// openDialector 依驅動建立 GORM Dialector
+func openDialector(p connParams) (gorm.Dialector, error) {
+	driver, err := normalizeDriver(p.Driver)
+	if err != nil {
+		return nil, err
+	}
+
+	switch driver {
+	case DriverPostgres:
+		return postgres.Open(buildPostgresDSN(p)), nil
+	case DriverSQLite:
+		if p.DBName == "" {
+			return nil, fmt.Errorf("SQLite 需設定 dbname 作為資料庫檔案路徑")
+		}
+		return sqlite.Open(p.DBName), nil
+	default:
+		return mysql.Open(buildMySQLDSN(p)), nil
+	}
+}
+
+// buildMySQLDSN 建立 MySQL DSN 字串
+func buildMySQLDSN(p connParams) string {
+	charset := p.Charset
+	if charset == "" {
+		charset = "utf8mb4"
+	}
+	loc := p.Loc
+	if loc == "" {
+		loc = "Local"
+	}
+	return fmt.Sprintf("%s:%s@tcp(%s:%s)/%s?charset=%s&parseTime=%t&loc=%s",
+		p.User, p.Password, p.Host, p.Port, p.DBName, charset, p.ParseTime, loc)
+}
+
+// buildPostgresDSN 建立 PostgreSQL DSN 字串，Loc 設定為時區名稱時會帶入 TimeZone
+func buildPostgresDSN(p connParams) string {
+	sslMode := p.SSLMode
+	if sslMode == "" {
+		sslMode = "disable"
+	}
+	dsn := fmt.Sprintf("host=%s user=%s password=%s dbname=%s port=%s sslmode=%s",
+		p.Host, p.User, p.Password, p.DBName, p.Port, sslMode)
+	if p.Loc != "" && p.Loc != "Local" {
+		dsn += " TimeZone=" + p.Loc
+	}
+	return dsn
+}
+
+// dialectorWithConn 使用既有的連線池建立 Dialector（交由 DBResolver 使用，不會另外開啟連線池）
+func dialectorWithConn(driver string, conn *sql.DB) gorm.Dialector {
+	switch driver {
+	case DriverPostgres:
+		return postgres.New(postgres.Config{Conn: conn})
+	case DriverSQLite:
+		return &sqlite.Dialector{Conn: conn}
+	default:
+		return mysql.New(mysql.Config{Conn: conn, SkipInitializeWithVersion: true})
+	}
+}
@@ -0,0 +1,91 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"time"
+
+	"github.com/andy2kuo/TourHelper/internal/logger"
+)
+
+// electionReleaseTimeout 卸任時釋放鎖的最長等待時間
+const electionReleaseTimeout = 5 * time.Second
+
+// Election 以分散式鎖進行領導者選舉，同一名稱的選舉在所有程序中同時只有一位領導者
+type Election struct {
+	locker *Locker
+	name   string
+	opts   LockOptions
+
+	leader atomic.Bool
+}
+
+// NewElection 建立名為 name 的領導者選舉
+// opts.RetryInterval 為未當選時重新參選的間隔，未設定時為租約時間的 1/3
+func (l *Locker) NewElection(name string, opts LockOptions) *Election {
+	if opts.RetryInterval <= 0 {
+		opts.RetryInterval = max(opts.withDefaults().TTL/3, defaultLockRetryInterval)
+	}
+	return &Election{
+		locker: l,
+		name:   "election:" + name,
+		opts:   opts.withDefaults(),
+	}
+}
+
+// IsLeader 目前是否為領導者
+func (e *Election) IsLeader() bool {
+	return e.leader.Load()
+}
+
+// Run 參與選舉直到 ctx 結束，當選後執行 lead
+// lead 的 ctx 在失去領導權或 ctx 結束時取消，可透過 FencingToken 取得本次任期的 fencing token；
+// lead 回傳後會卸任並在 RetryInterval 後重新參選
+func (e *Election) Run(ctx context.Context, lead func(ctx context.Context) error) {
+	for {
+		lock, err := e.locker.Acquire(ctx, e.name, e.opts)
+		if err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			logger.Warnf("參與選舉 [%s] 失敗: %v", e.name, err)
+		} else {
+			e.serve(ctx, lock, lead)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(e.opts.RetryInterval):
+		}
+	}
+}
+
+// serve 擔任領導者直到 lead 結束、失去鎖或 ctx 結束，並於卸任時釋放鎖
+func (e *Election) serve(ctx context.Context, lock *Lock, lead func(ctx context.Context) error) {
+	leadCtx, cancel := context.WithCancel(lock.Context())
+	stop := context.AfterFunc(ctx, cancel)
+	defer stop()
+	defer cancel()
+
+	e.leader.Store(true)
+	logger.Infof("當選 [%s] 領導者（fencing token %d）", e.name, lock.Token())
+
+	err := lead(leadCtx)
+	e.leader.Store(false)
+
+	switch {
+	case errors.Is(context.Cause(lock.Context()), ErrLockLost):
+		logger.Warnf("失去 [%s] 領導權", e.name)
+	case err != nil && !errors.Is(err, context.Canceled):
+		logger.Errorf("[%s] 領導者工作異常結束: %v", e.name, err)
+	}
+
+	releaseCtx, releaseCancel := context.WithTimeout(context.WithoutCancel(ctx), electionReleaseTimeout)
+	defer releaseCancel()
+	if err := lock.Release(releaseCtx); err != nil && !errors.Is(err, ErrLockLost) {
+		logger.Warnf("卸任 [%s] 時釋放鎖失敗: %v", e.name, err)
+	}
+	logger.Infof("卸任 [%s] 領導者", e.name)
+}
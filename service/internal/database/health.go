@@ -0,0 +1,102 @@
+package database
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// PingResult 單一連線的連線測試結果
+type PingResult struct {
+	Kind     string        `json:"kind"`             // mysql 或 redis
+	Name     string        `json:"name"`             // Master、Slave 或 Redis 實例名稱
+	Role     string        `json:"role,omitempty"`   // master 或 slave（僅關聯式資料庫）
+	Master   string        `json:"master,omitempty"` // Slave 所屬的 Master 名稱
+	Healthy  bool          `json:"healthy"`
+	Required bool          `json:"required"` // 是否為必要連線（Slave 異常時會改讀 Master，非必要）
+	Latency  time.Duration `json:"latency"`
+	Error    string        `json:"error,omitempty"`
+}
+
+// pingTarget 待測試的連線
+type pingTarget struct {
+	result PingResult
+	ping   func(ctx context.Context) error
+}
+
+// runPings 並行測試所有連線，依傳入順序回傳結果
+func runPings(ctx context.Context, targets []pingTarget) []PingResult {
+	results := make([]PingResult, len(targets))
+
+	var wg sync.WaitGroup
+	for i, target := range targets {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			result := target.result
+			start := time.Now()
+			err := target.ping(ctx)
+			result.Latency = time.Since(start)
+			result.Healthy = err == nil
+			if err != nil {
+				result.Error = err.Error()
+			}
+			results[i] = result
+		}()
+	}
+	wg.Wait()
+
+	return results
+}
+
+// PingEach 並行測試每個 Master 與 Slave 的連線，依設定檔順序回傳結果
+func (m *MySQLManager) PingEach(ctx context.Context) []PingResult {
+	m.mu.RLock()
+	var targets []pingTarget
+	for _, masterCfg := range m.config.Masters {
+		db, ok := m.databases[masterCfg.Name]
+		if !ok {
+			continue
+		}
+
+		result := PingResult{Kind: "mysql", Name: masterCfg.Name, Role: "master", Master: masterCfg.Name, Required: true}
+		sqlDB, err := db.DB()
+		if err != nil {
+			targets = append(targets, pingTarget{result: result, ping: func(context.Context) error { return err }})
+		} else {
+			targets = append(targets, pingTarget{result: result, ping: sqlDB.PingContext})
+		}
+
+		if set, ok := m.replicas[masterCfg.Name]; ok {
+			for _, r := range set.replicas {
+				targets = append(targets, pingTarget{
+					result: PingResult{Kind: "mysql", Name: r.name, Role: "slave", Master: masterCfg.Name},
+					ping:   r.pool.PingContext,
+				})
+			}
+		}
+	}
+	m.mu.RUnlock()
+
+	return runPings(ctx, targets)
+}
+
+// PingEach 並行測試每個 Redis 實例的連線，依設定檔順序回傳結果
+func (m *RedisManager) PingEach(ctx context.Context) []PingResult {
+	m.mu.RLock()
+	var targets []pingTarget
+	for _, instanceCfg := range m.config.Instances {
+		client, ok := m.clients[instanceCfg.Name]
+		if !ok {
+			continue
+		}
+		targets = append(targets, pingTarget{
+			result: PingResult{Kind: "redis", Name: instanceCfg.Name, Required: true},
+			ping:   func(ctx context.Context) error { return client.Ping(ctx).Err() },
+		})
+	}
+	m.mu.RUnlock()
+
+	return runPings(ctx, targets)
+}
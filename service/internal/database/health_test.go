@@ -0,0 +1,75 @@
+package database
+
+import (
+	"context"
+	"net"
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/andy2kuo/TourHelper/internal/config"
+	"github.com/redis/go-redis/v9"
+)
+
+func TestMySQLManagerPingEach(t *testing.T) {
+	dir := t.TempDir()
+	manager, err := NewMySQLManager(config.DatabaseConfig{
+		Masters: []config.MasterDBConfig{
+			{Name: "main", Driver: "sqlite", DBName: filepath.Join(dir, "master.db")},
+		},
+		Slaves: []config.SlaveDBConfig{
+			{Name: "replica", DBName: filepath.Join(dir, "replica.db"), MasterName: "main"},
+		},
+	})
+	if err != nil {
+		t.Fatalf("NewMySQLManager() 失敗: %v", err)
+	}
+	defer manager.Close()
+
+	results := manager.PingEach(context.Background())
+	want := []PingResult{
+		{Kind: "mysql", Name: "main", Role: "master", Master: "main", Healthy: true, Required: true},
+		{Kind: "mysql", Name: "replica", Role: "slave", Master: "main", Healthy: true},
+	}
+	if len(results) != len(want) {
+		t.Fatalf("PingEach() 回傳 %d 筆, 期望 %d", len(results), len(want))
+	}
+	for i, got := range results {
+		got.Latency = 0
+		if got != want[i] {
+			t.Errorf("PingEach()[%d] = %+v, 期望 %+v", i, got, want[i])
+		}
+	}
+}
+
+func TestRedisManagerPingEach(t *testing.T) {
+	mr := miniredis.RunT(t)
+	host, port, _ := net.SplitHostPort(mr.Addr())
+	portNum, _ := strconv.Atoi(port)
+
+	instance := config.RedisInstanceConfig{Name: "cache", Host: host, Port: portNum}
+	manager := &RedisManager{
+		clients: map[string]redis.UniversalClient{},
+		config:  config.RedisConfig{Instances: []config.RedisInstanceConfig{instance}},
+	}
+	client, err := manager.createRedisClient(instance)
+	if err != nil {
+		t.Fatalf("createRedisClient() 失敗: %v", err)
+	}
+	manager.clients["cache"] = client
+	defer manager.Close()
+
+	if results := manager.PingEach(context.Background()); len(results) != 1 || !results[0].Healthy {
+		t.Fatalf("PingEach() = %+v, 期望 cache 正常", results)
+	}
+
+	mr.Close()
+	results := manager.PingEach(context.Background())
+	if len(results) != 1 || results[0].Healthy || results[0].Error == "" {
+		t.Errorf("PingEach() = %+v, 期望 cache 異常並附上錯誤訊息", results)
+	}
+	if err := manager.Ping(); err == nil {
+		t.Error("Ping() 應回傳錯誤")
+	}
+}
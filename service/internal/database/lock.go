@@ -0,0 +1,261 @@
+package database
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/andy2kuo/TourHelper/internal/logger"
+	"github.com/redis/go-redis/v9"
+)
+
+// 分散式鎖預設值
+const (
+	defaultLockTTL           = 30 * time.Second
+	defaultLockRetryInterval = 100 * time.Millisecond
+)
+
+var (
+	// ErrLockNotAcquired 鎖已被其他程序持有
+	ErrLockNotAcquired = errors.New("鎖已被其他程序持有")
+	// ErrLockLost 鎖的租約已過期或已被其他程序取得
+	ErrLockLost = errors.New("鎖的租約已失效")
+)
+
+// acquireLockScript 取得鎖並遞增 fencing token；鎖已被持有時回傳 0
+// 鎖與 fencing token 的 key 使用相同的 hash tag，Cluster 模式下位於同一節點
+var acquireLockScript = redis.NewScript(`
+if redis.call('SET', KEYS[1], ARGV[1], 'NX', 'PX', ARGV[2]) then
+	return redis.call('INCR', KEYS[2])
+end
+return 0
+`)
+
+// refreshLockScript 仍持有鎖時延長租約
+var refreshLockScript = redis.NewScript(`
+if redis.call('GET', KEYS[1]) == ARGV[1] then
+	return redis.call('PEXPIRE', KEYS[1], ARGV[2])
+end
+return 0
+`)
+
+// releaseLockScript 仍持有鎖時釋放
+var releaseLockScript = redis.NewScript(`
+if redis.call('GET', KEYS[1]) == ARGV[1] then
+	return redis.call('DEL', KEYS[1])
+end
+return 0
+`)
+
+// LockOptions 分散式鎖設定
+type LockOptions struct {
+	TTL           time.Duration // 租約時間，持有者異常終止時最長經過此時間後由其他程序接手，預設 30 秒
+	RenewInterval time.Duration // 自動續約間隔，預設為 TTL 的 1/3，負數表示不自動續約
+	RetryInterval time.Duration // Acquire 等待鎖時的重試間隔，預設 100 毫秒
+}
+
+// withDefaults 套用預設值
+func (o LockOptions) withDefaults() LockOptions {
+	if o.TTL <= 0 {
+		o.TTL = defaultLockTTL
+	}
+	if o.RenewInterval == 0 {
+		o.RenewInterval = o.TTL / 3
+	}
+	if o.RetryInterval <= 0 {
+		o.RetryInterval = defaultLockRetryInterval
+	}
+	return o
+}
+
+// Locker 建立在 Redis 上的分散式鎖（租約），適用於協調多台伺服器
+// 每次取得鎖都會得到遞增的 fencing token，寫入外部資源時應帶上 token 並拒絕較舊的 token，
+// 避免持有者暫停（例如 GC）期間租約過期後，舊持有者與新持有者同時寫入
+type Locker struct {
+	client redis.UniversalClient
+}
+
+// NewLocker 建立分散式鎖
+func NewLocker(client redis.UniversalClient) *Locker {
+	return &Locker{client: client}
+}
+
+// Locker 取得使用指定 Redis Database（對應實例的 database 設定）的分散式鎖，未指定時使用預設實例
+func (m *RedisManager) Locker(database ...string) *Locker {
+	if len(database) > 0 && database[0] != "" {
+		return NewLocker(m.GetClientByDB(database[0]))
+	}
+	return NewLocker(m.GetClient())
+}
+
+// TryAcquire 嘗試取得鎖，已被其他程序持有時回傳 ErrLockNotAcquired
+func (l *Locker) TryAcquire(ctx context.Context, name string, opts LockOptions) (*Lock, error) {
+	opts = opts.withDefaults()
+
+	key := lockKey(name)
+	owner := rand.Text()
+	token, err := acquireLockScript.Run(ctx, l.client, []string{key, key + ":fence"}, owner, opts.TTL.Milliseconds()).Int64()
+	if err != nil {
+		return nil, fmt.Errorf("取得鎖 [%s] 失敗: %w", name, err)
+	}
+	if token == 0 {
+		return nil, ErrLockNotAcquired
+	}
+
+	lock := &Lock{
+		client: l.client,
+		name:   name,
+		key:    key,
+		owner:  owner,
+		token:  token,
+		ttl:    opts.TTL,
+		done:   make(chan struct{}),
+	}
+	lock.renewedAt.Store(time.Now().UnixNano())
+
+	// 持有期間的 context 保留取得鎖時的 context 值，但不隨其取消
+	base := context.WithValue(context.WithoutCancel(ctx), fencingTokenKey{}, token)
+	lock.ctx, lock.cancel = context.WithCancelCause(base)
+
+	if opts.RenewInterval > 0 {
+		go lock.keepAlive(opts.RenewInterval)
+	} else {
+		close(lock.done)
+	}
+	return lock, nil
+}
+
+// Acquire 取得鎖，已被持有時每隔 RetryInterval 重試，直到取得或 ctx 結束
+func (l *Locker) Acquire(ctx context.Context, name string, opts LockOptions) (*Lock, error) {
+	opts = opts.withDefaults()
+
+	for {
+		lock, err := l.TryAcquire(ctx, name, opts)
+		if !errors.Is(err, ErrLockNotAcquired) {
+			return lock, err
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(opts.RetryInterval):
+		}
+	}
+}
+
+// Lock 已取得的分散式鎖
+type Lock struct {
+	client redis.UniversalClient
+	name   string
+	key    string
+	owner  string // 持有者識別值，只有持有者能續約與釋放
+	token  int64
+	ttl    time.Duration
+
+	ctx       context.Context
+	cancel    context.CancelCauseFunc
+	done      chan struct{} // 自動續約結束
+	renewedAt atomic.Int64  // 最後一次成功續約的時間（UnixNano）
+	closeOnce sync.Once
+}
+
+// fencingTokenKey Lock.Context 中 fencing token 的 key
+type fencingTokenKey struct{}
+
+// FencingToken 取得 Lock.Context（或由其衍生的 context）對應的 fencing token
+func FencingToken(ctx context.Context) (int64, bool) {
+	token, ok := ctx.Value(fencingTokenKey{}).(int64)
+	return token, ok
+}
+
+// Name 鎖名稱
+func (lk *Lock) Name() string {
+	return lk.name
+}
+
+// Token 取得鎖時配發的 fencing token，同一名稱的鎖每次取得都會遞增
+func (lk *Lock) Token() int64 {
+	return lk.token
+}
+
+// Context 持有鎖期間有效的 context，失去租約或釋放時取消
+// 失去租約時 context.Cause 為 ErrLockLost
+func (lk *Lock) Context() context.Context {
+	return lk.ctx
+}
+
+// Refresh 延長租約，已失去鎖時回傳 ErrLockLost
+func (lk *Lock) Refresh(ctx context.Context) error {
+	ok, err := refreshLockScript.Run(ctx, lk.client, []string{lk.key}, lk.owner, lk.ttl.Milliseconds()).Int64()
+	if err != nil {
+		return fmt.Errorf("續約鎖 [%s] 失敗: %w", lk.name, err)
+	}
+	if ok == 0 {
+		return ErrLockLost
+	}
+	lk.renewedAt.Store(time.Now().UnixNano())
+	return nil
+}
+
+// Release 停止自動續約並釋放鎖，鎖已失效時回傳 ErrLockLost
+func (lk *Lock) Release(ctx context.Context) error {
+	lk.stop(context.Canceled)
+	<-lk.done
+
+	ok, err := releaseLockScript.Run(ctx, lk.client, []string{lk.key}, lk.owner).Int64()
+	if err != nil {
+		return fmt.Errorf("釋放鎖 [%s] 失敗: %w", lk.name, err)
+	}
+	if ok == 0 {
+		return ErrLockLost
+	}
+	return nil
+}
+
+// keepAlive 定期續約，確認失去租約（被其他程序取得或續約失敗直到租約到期）時取消 Context
+func (lk *Lock) keepAlive(interval time.Duration) {
+	defer close(lk.done)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-lk.ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		ctx, cancel := context.WithTimeout(lk.ctx, interval)
+		err := lk.Refresh(ctx)
+		cancel()
+
+		switch {
+		case err == nil:
+		case errors.Is(err, ErrLockLost):
+			logger.Warnf("鎖 [%s] 已被其他程序取得（fencing token %d）", lk.name, lk.token)
+			lk.stop(ErrLockLost)
+			return
+		case time.Since(time.Unix(0, lk.renewedAt.Load())) >= lk.ttl:
+			logger.Warnf("鎖 [%s] 續約失敗直到租約到期: %v", lk.name, err)
+			lk.stop(ErrLockLost)
+			return
+		default:
+			logger.Warnf("鎖 [%s] 續約失敗，將於下次重試: %v", lk.name, err)
+		}
+	}
+}
+
+// stop 取消持有期間的 context
+func (lk *Lock) stop(cause error) {
+	lk.closeOnce.Do(func() { lk.cancel(cause) })
+}
+
+// lockKey 鎖的 Redis key，以 hash tag 讓 fencing token 與鎖位於同一個 Cluster slot
+func lockKey(name string) string {
+	return "tourhelper:lock:{" + name + "}"
+}
@@ -0,0 +1,164 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+func newLockTestLocker(t *testing.T) (*miniredis.Miniredis, *Locker) {
+	t.Helper()
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { client.Close() })
+	return mr, NewLocker(client)
+}
+
+func TestLockerAcquireRelease(t *testing.T) {
+	mr, locker := newLockTestLocker(t)
+	ctx := context.Background()
+	opts := LockOptions{TTL: time.Minute, RenewInterval: -1}
+
+	first, err := locker.TryAcquire(ctx, "job", opts)
+	if err != nil {
+		t.Fatalf("TryAcquire() 失敗: %v", err)
+	}
+	if _, err := locker.TryAcquire(ctx, "job", opts); !errors.Is(err, ErrLockNotAcquired) {
+		t.Errorf("重複取得鎖錯誤 = %v, 期望 %v", err, ErrLockNotAcquired)
+	}
+	if token, ok := FencingToken(first.Context()); !ok || token != first.Token() {
+		t.Errorf("FencingToken() = %d, %v, 期望 %d", token, ok, first.Token())
+	}
+
+	if err := first.Release(ctx); err != nil {
+		t.Fatalf("Release() 失敗: %v", err)
+	}
+	if first.Context().Err() == nil {
+		t.Error("釋放後 Context 應已取消")
+	}
+
+	second, err := locker.TryAcquire(ctx, "job", opts)
+	if err != nil {
+		t.Fatalf("釋放後 TryAcquire() 失敗: %v", err)
+	}
+	if second.Token() <= first.Token() {
+		t.Errorf("fencing token = %d, 期望大於 %d", second.Token(), first.Token())
+	}
+
+	// 租約過期後由其他程序取得，原持有者無法續約或釋放
+	mr.FastForward(2 * time.Minute)
+	third, err := locker.TryAcquire(ctx, "job", opts)
+	if err != nil {
+		t.Fatalf("租約過期後 TryAcquire() 失敗: %v", err)
+	}
+	if err := second.Refresh(ctx); !errors.Is(err, ErrLockLost) {
+		t.Errorf("Refresh() 錯誤 = %v, 期望 %v", err, ErrLockLost)
+	}
+	if err := second.Release(ctx); !errors.Is(err, ErrLockLost) {
+		t.Errorf("Release() 錯誤 = %v, 期望 %v", err, ErrLockLost)
+	}
+	if err := third.Release(ctx); err != nil {
+		t.Errorf("Release() 失敗: %v", err)
+	}
+}
+
+func TestLockAutoRenew(t *testing.T) {
+	mr, locker := newLockTestLocker(t)
+	ctx := context.Background()
+
+	lock, err := locker.TryAcquire(ctx, "renew", LockOptions{TTL: time.Second, RenewInterval: 20 * time.Millisecond})
+	if err != nil {
+		t.Fatalf("TryAcquire() 失敗: %v", err)
+	}
+
+	// 自動續約會把租約延長回完整的 TTL
+	mr.SetTTL(lockKey("renew"), 100*time.Millisecond)
+	time.Sleep(100 * time.Millisecond)
+	if ttl := mr.TTL(lockKey("renew")); ttl <= 100*time.Millisecond {
+		t.Errorf("續約後租約時間 = %v, 期望延長為 1s", ttl)
+	}
+
+	// 鎖被其他程序取得後，Context 以 ErrLockLost 取消
+	mr.Set(lockKey("renew"), "other")
+	select {
+	case <-lock.Context().Done():
+	case <-time.After(time.Second):
+		t.Fatal("失去鎖後 Context 未取消")
+	}
+	if cause := context.Cause(lock.Context()); !errors.Is(cause, ErrLockLost) {
+		t.Errorf("context.Cause() = %v, 期望 %v", cause, ErrLockLost)
+	}
+}
+
+func TestElection(t *testing.T) {
+	_, locker := newLockTestLocker(t)
+	opts := LockOptions{TTL: time.Second, RetryInterval: 10 * time.Millisecond}
+
+	var leaders, maxLeaders atomic.Int32
+	lead := func(ctx context.Context) error {
+		n := leaders.Add(1)
+		defer leaders.Add(-1)
+		for {
+			if m := maxLeaders.Load(); n <= m || maxLeaders.CompareAndSwap(m, n) {
+				break
+			}
+		}
+		<-ctx.Done()
+		return ctx.Err()
+	}
+
+	elections := make([]*Election, 3)
+	cancels := make([]context.CancelFunc, 3)
+	var wg sync.WaitGroup
+	for i := range elections {
+		var ctx context.Context
+		ctx, cancels[i] = context.WithCancel(context.Background())
+		elections[i] = locker.NewElection("worker", opts)
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			elections[i].Run(ctx, lead)
+		}()
+	}
+
+	// waitLeader 等待恰有一位領導者並回傳其索引
+	waitLeader := func() int {
+		deadline := time.Now().Add(time.Second)
+		for time.Now().Before(deadline) {
+			for i, e := range elections {
+				if e.IsLeader() && cancels[i] != nil {
+					return i
+				}
+			}
+			time.Sleep(5 * time.Millisecond)
+		}
+		t.Fatal("等待領導者逾時")
+		return -1
+	}
+
+	// 領導者退出後由其他參選者接手
+	first := waitLeader()
+	cancels[first]()
+	cancels[first] = nil
+	second := waitLeader()
+	if second == first {
+		t.Errorf("領導者退出後仍為 %d", first)
+	}
+
+	for _, cancel := range cancels {
+		if cancel != nil {
+			cancel()
+		}
+	}
+	wg.Wait()
+
+	if maxLeaders.Load() != 1 {
+		t.Errorf("同時存在的領導者最多 %d 位, 期望 1", maxLeaders.Load())
+	}
+}
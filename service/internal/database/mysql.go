@@ -1,23 +1,27 @@
 package database
 
 import (
+	"context"
+	"database/sql"
 	"fmt"
 	"sync"
 	"time"
 
 	"github.com/andy2kuo/TourHelper/internal/config"
 	"github.com/andy2kuo/TourHelper/internal/logger"
-	"gorm.io/driver/mysql"
+	"github.com/redis/go-redis/v9"
 	"gorm.io/gorm"
 	gormLogger "gorm.io/gorm/logger"
 	"gorm.io/plugin/dbresolver"
 )
 
-// MySQLManager MySQL 資料庫管理器（Master-Slave 架構）
-// 使用 GORM DBResolver 插件實現讀寫分離
+// MySQLManager 關聯式資料庫管理器（Master-Slave 架構）
+// 使用 GORM DBResolver 插件實現讀寫分離，每個 Master/Slave 可個別指定驅動（MySQL、PostgreSQL、SQLite）
 type MySQLManager struct {
-	databases map[string]*gorm.DB // 多個資料庫實例，key 為 Master 名稱或 Database
-	mu        sync.RWMutex        // 讀寫鎖
+	databases map[string]*gorm.DB    // 多個資料庫實例，key 為 Master 名稱或 Database
+	replicas  map[string]*replicaSet // 各 Master 的 Slave 集合，key 為 Master 名稱
+	sticky    *stickyTracker         // 讀寫一致性（寫入後一段時間內讀取走 Master）
+	mu        sync.RWMutex           // 讀寫鎖
 
 	config config.DatabaseConfig
 }
@@ -27,19 +31,30 @@ var (
 	mysqlOnce     sync.Once
 )
 
-// InitMySQL 初始化 MySQL 資料庫連線（單例模式）
+// InitMySQL 初始化資料庫連線（單例模式）
 func InitMySQL(cfg config.DatabaseConfig) error {
 	var err error
 	mysqlOnce.Do(func() {
-		mysqlInstance = &MySQLManager{
-			databases: make(map[string]*gorm.DB),
-			config:    cfg,
-		}
-		err = mysqlInstance.initialize()
+		mysqlInstance, err = NewMySQLManager(cfg)
 	})
 	return err
 }
 
+// NewMySQLManager 建立獨立的資料庫管理器（不影響單例，供工具程式與測試使用）
+func NewMySQLManager(cfg config.DatabaseConfig) (*MySQLManager, error) {
+	m := &MySQLManager{
+		databases: make(map[string]*gorm.DB),
+		replicas:  make(map[string]*replicaSet),
+		sticky:    &stickyTracker{window: cfg.StickyMasterWindow},
+		config:    cfg,
+	}
+	if err := m.initialize(); err != nil {
+		m.Close()
+		return nil, err
+	}
+	return m, nil
+}
+
 // GetMySQLInstance 取得 MySQLManager 實例
 func GetMySQLInstance() *MySQLManager {
 	if mysqlInstance == nil {
@@ -102,11 +117,28 @@ func (m *MySQLManager) groupSlavesByMaster() map[string][]config.SlaveDBConfig {
 // createDatabaseWithResolver 建立包含 DBResolver 的資料庫實例
 func (m *MySQLManager) createDatabaseWithResolver(masterCfg config.MasterDBConfig, slaveCfgs []config.SlaveDBConfig) (*gorm.DB, error) {
 	// 1. 建立 Master 連線
-	masterDSN := m.buildDSN(masterCfg.Host, masterCfg.Port, masterCfg.User, masterCfg.Password,
-		masterCfg.DBName, masterCfg.Charset, masterCfg.ParseTime, masterCfg.Loc)
+	masterDriver, err := normalizeDriver(masterCfg.Driver)
+	if err != nil {
+		return nil, err
+	}
+	masterDialector, err := openDialector(connParams{
+		Driver:    masterDriver,
+		Host:      masterCfg.Host,
+		Port:      masterCfg.Port,
+		User:      masterCfg.User,
+		Password:  masterCfg.Password,
+		DBName:    masterCfg.DBName,
+		Charset:   masterCfg.Charset,
+		ParseTime: masterCfg.ParseTime,
+		Loc:       masterCfg.Loc,
+		SSLMode:   masterCfg.SSLMode,
+	})
+	if err != nil {
+		return nil, err
+	}
 
 	// 使用自訂的 GORM logger 將日誌輸出到 Logrus
-	db, err := gorm.Open(mysql.Open(masterDSN), &gorm.Config{
+	db, err := gorm.Open(masterDialector, &gorm.Config{
 		Logger: logger.NewGormLogger(
 			gormLogger.Info,
 			m.config.SlowQueryThreshold,
@@ -125,47 +157,106 @@ func (m *MySQLManager) createDatabaseWithResolver(masterCfg config.MasterDBConfi
 
 	// 3. 如果有 Slave，註冊 DBResolver
 	if len(slaveCfgs) > 0 {
-		// 建立 Replica (Slave) Dialectors
-		replicas := make([]gorm.Dialector, 0, len(slaveCfgs))
-		for _, slaveCfg := range slaveCfgs {
-			slaveDSN := m.buildDSN(slaveCfg.Host, slaveCfg.Port, slaveCfg.User, slaveCfg.Password,
-				slaveCfg.DBName, slaveCfg.Charset, slaveCfg.ParseTime, slaveCfg.Loc)
-			replicas = append(replicas, mysql.Open(slaveDSN))
+		set, err := m.openReplicas(masterCfg.Name, masterDriver, slaveCfgs)
+		if err != nil {
+			return nil, err
 		}
+		m.replicas[masterCfg.Name] = set
 
-		// 取得 Replica 連線池設定（使用第一個 Slave 的設定或全域設定）
-		replicaMaxIdle := m.config.MaxIdleConns
-		replicaMaxOpen := m.config.MaxOpenConns
-		replicaMaxLifetime := m.config.ConnMaxLifetime
-		if len(slaveCfgs) > 0 && slaveCfgs[0].MaxIdleConns != nil {
-			replicaMaxIdle = *slaveCfgs[0].MaxIdleConns
-		}
-		if len(slaveCfgs) > 0 && slaveCfgs[0].MaxOpenConns != nil {
-			replicaMaxOpen = *slaveCfgs[0].MaxOpenConns
+		// Replicas 依序為各 Slave，最後加入 Master 作為所有 Slave 異常時的備援
+		// 由 replicaSet 依權重與健康狀態挑選
+		masterPool, err := db.DB()
+		if err != nil {
+			return nil, fmt.Errorf("取得 Master 連線池失敗: %w", err)
 		}
-		if len(slaveCfgs) > 0 && slaveCfgs[0].ConnMaxLifetime != nil {
-			replicaMaxLifetime = *slaveCfgs[0].ConnMaxLifetime
+		replicas := make([]gorm.Dialector, 0, len(set.replicas)+1)
+		for _, r := range set.replicas {
+			replicas = append(replicas, dialectorWithConn(masterDriver, r.pool))
 		}
+		replicas = append(replicas, dialectorWithConn(masterDriver, masterPool))
 
 		// 建立 DBResolver 設定
+		// 未指定 Sources 時寫入使用上方已設定連線池的 Master 連線
 		resolverConfig := dbresolver.Config{
-			Sources:  []gorm.Dialector{mysql.Open(masterDSN)}, // Master 作為 Source
-			Replicas: replicas,                                // Slaves 作為 Replicas
-			Policy:   dbresolver.RandomPolicy{},               // 使用隨機策略
+			Replicas: replicas,
+			Policy:   set,
 		}
-
-		// 註冊 DBResolver 並設定 Replica 連線池
-		if err := db.Use(dbresolver.Register(resolverConfig).
-			SetMaxIdleConns(replicaMaxIdle).
-			SetMaxOpenConns(replicaMaxOpen).
-			SetConnMaxLifetime(replicaMaxLifetime)); err != nil {
+		// DBResolver 註冊時會沿用 Master 的設定開啟 Replica，停用連線檢查避免 Slave 暫時無法連線導致啟動失敗
+		db.Config.DisableAutomaticPing = true
+		if err := db.Use(dbresolver.Register(resolverConfig)); err != nil {
 			return nil, fmt.Errorf("註冊 DBResolver 失敗: %w", err)
 		}
+
+		if m.sticky.window > 0 {
+			if err := m.sticky.register(db, masterPool); err != nil {
+				return nil, fmt.Errorf("註冊讀寫一致性 callback 失敗: %w", err)
+			}
+		}
+
+		set.start()
 	}
 
 	return db, nil
 }
 
+// openReplicas 開啟 Master 底下所有 Slave 的連線池，驅動需與 Master 相同
+func (m *MySQLManager) openReplicas(masterName, masterDriver string, slaveCfgs []config.SlaveDBConfig) (*replicaSet, error) {
+	set := newReplicaSet(masterName, masterDriver, m.config.ReplicaHealthCheckInterval, m.config.ReplicaHealthCheckTimeout, m.config.ReplicaFailureThreshold, m.config.ReplicaMaxLag)
+
+	for _, slaveCfg := range slaveCfgs {
+		pool, err := m.openReplica(masterDriver, slaveCfg)
+		if err != nil {
+			set.close()
+			return nil, fmt.Errorf("建立 Slave [%s] 連線失敗: %w", slaveCfg.Name, err)
+		}
+		set.add(slaveCfg.Name, slaveCfg.Weight, pool)
+	}
+
+	// 啟動時無法連線的 Slave 先移出輪替，待健康檢查恢復後再加入
+	set.probe(context.Background())
+
+	return set, nil
+}
+
+// openReplica 開啟單一 Slave 的連線池，連線池使用此 Slave 的個別設定或全域設定
+func (m *MySQLManager) openReplica(masterDriver string, slaveCfg config.SlaveDBConfig) (*sql.DB, error) {
+	if slaveCfg.Driver != "" {
+		slaveDriver, err := normalizeDriver(slaveCfg.Driver)
+		if err != nil {
+			return nil, err
+		}
+		if slaveDriver != masterDriver {
+			return nil, fmt.Errorf("驅動 %s 與 Master 的驅動 %s 不同", slaveDriver, masterDriver)
+		}
+	}
+
+	dialector, err := openDialector(connParams{
+		Driver:    masterDriver,
+		Host:      slaveCfg.Host,
+		Port:      slaveCfg.Port,
+		User:      slaveCfg.User,
+		Password:  slaveCfg.Password,
+		DBName:    slaveCfg.DBName,
+		Charset:   slaveCfg.Charset,
+		ParseTime: slaveCfg.ParseTime,
+		Loc:       slaveCfg.Loc,
+		SSLMode:   slaveCfg.SSLMode,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// 僅用於開啟連線池，查詢仍透過 Master 的 DBResolver 執行
+	replicaDB, err := gorm.Open(dialector, &gorm.Config{Logger: gormLogger.Discard, DisableAutomaticPing: true})
+	if err != nil {
+		return nil, err
+	}
+	if err := m.configureConnectionPool(replicaDB, slaveCfg.MaxIdleConns, slaveCfg.MaxOpenConns, slaveCfg.ConnMaxLifetime); err != nil {
+		return nil, fmt.Errorf("設定連線池失敗: %w", err)
+	}
+	return replicaDB.DB()
+}
+
 // configureConnectionPool 設定連線池參數
 func (m *MySQLManager) configureConnectionPool(db *gorm.DB, maxIdle, maxOpen *int, maxLifetime *time.Duration) error {
 	sqlDB, err := db.DB()
@@ -196,12 +287,6 @@ func (m *MySQLManager) configureConnectionPool(db *gorm.DB, maxIdle, maxOpen *in
 	return nil
 }
 
-// buildDSN 建立 MySQL DSN 字串
-func (m *MySQLManager) buildDSN(host, port, user, password, dbName, charset string, parseTime bool, loc string) string {
-	return fmt.Sprintf("%s:%s@tcp(%s:%s)/%s?charset=%s&parseTime=%t&loc=%s",
-		user, password, host, port, dbName, charset, parseTime, loc)
-}
-
 // GetDB 取得資料庫連線（自動讀寫分離）
 // 參數可以是 Master 名稱或 Database 名稱
 func (m *MySQLManager) GetDB(name ...string) *gorm.DB {
@@ -257,6 +342,68 @@ func (m *MySQLManager) GetByDatabase(database string) *gorm.DB {
 	return m.GetDB()
 }
 
+// SetStickyStore 設定讀寫一致性使用的 Redis，讓寫入後的時間窗跨伺服器生效
+// 未設定時只在同一個 context（請求）內生效
+func (m *MySQLManager) SetStickyStore(client redis.Cmdable) {
+	m.sticky.setClient(client)
+}
+
+// PoolStats 連線池統計
+type PoolStats struct {
+	Master       string        `json:"master"`  // 所屬 Master 名稱
+	Name         string        `json:"name"`    // 連線名稱（Master 與 Master 名稱相同）
+	Role         string        `json:"role"`    // master 或 slave
+	Healthy      bool          `json:"healthy"` // Slave 是否在讀取輪替中（Master 固定為 true）
+	Lag          time.Duration `json:"lag"`     // Slave 複寫延遲（僅在設定 replicaMaxLag 時量測）
+	MaxOpen      int           `json:"max_open"`
+	Open         int           `json:"open"`
+	InUse        int           `json:"in_use"`
+	Idle         int           `json:"idle"`
+	WaitCount    int64         `json:"wait_count"`    // 累計等待可用連線的次數
+	WaitDuration time.Duration `json:"wait_duration"` // 累計等待時間
+}
+
+// newPoolStats 由 sql.DBStats 建立連線池統計
+func newPoolStats(master, name, role string, healthy bool, lag time.Duration, stats sql.DBStats) PoolStats {
+	return PoolStats{
+		Master:       master,
+		Name:         name,
+		Role:         role,
+		Healthy:      healthy,
+		Lag:          lag,
+		MaxOpen:      stats.MaxOpenConnections,
+		Open:         stats.OpenConnections,
+		InUse:        stats.InUse,
+		Idle:         stats.Idle,
+		WaitCount:    stats.WaitCount,
+		WaitDuration: stats.WaitDuration,
+	}
+}
+
+// Stats 取得所有 Master 與 Slave 的連線池統計，依設定檔順序排列
+func (m *MySQLManager) Stats() []PoolStats {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var stats []PoolStats
+	for _, masterCfg := range m.config.Masters {
+		db, ok := m.databases[masterCfg.Name]
+		if !ok {
+			continue
+		}
+		if sqlDB, err := db.DB(); err == nil {
+			stats = append(stats, newPoolStats(masterCfg.Name, masterCfg.Name, "master", true, 0, sqlDB.Stats()))
+		}
+
+		if set, ok := m.replicas[masterCfg.Name]; ok {
+			for _, r := range set.replicas {
+				stats = append(stats, newPoolStats(masterCfg.Name, r.name, "slave", set.available(r), time.Duration(r.lag.Load()), r.pool.Stats()))
+			}
+		}
+	}
+	return stats
+}
+
 // Close 關閉所有資料庫連線
 func (m *MySQLManager) Close() error {
 	m.mu.Lock()
@@ -264,7 +411,15 @@ func (m *MySQLManager) Close() error {
 
 	var errs []error
 
-	// 關閉所有資料庫連線（包含 Master 和 Slave）
+	// 停止 Slave 健康檢查並關閉 Slave 連線池
+	for name, set := range m.replicas {
+		for _, err := range set.close() {
+			errs = append(errs, fmt.Errorf("關閉資料庫 [%s] 的 Slave 失敗: %w", name, err))
+		}
+	}
+	m.replicas = make(map[string]*replicaSet)
+
+	// 關閉所有 Master 資料庫連線
 	for name, db := range m.databases {
 		sqlDB, err := db.DB()
 		if err != nil {
@@ -0,0 +1,187 @@
+package database
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/andy2kuo/TourHelper/internal/config"
+	"github.com/andy2kuo/TourHelper/internal/logger"
+)
+
+func TestMain(m *testing.M) {
+	// SQL 日誌會寫入 Logger，於暫存目錄初始化 Logger 避免在專案目錄產生日誌檔
+	dir, err := os.MkdirTemp("", "database-test")
+	if err != nil {
+		panic(err)
+	}
+	if err := os.Chdir(dir); err != nil {
+		panic(err)
+	}
+	if err := logger.Init("database_test", "dev", config.LogConfig{Level: "error"}); err != nil {
+		panic(err)
+	}
+
+	code := m.Run()
+	os.RemoveAll(dir)
+	os.Exit(code)
+}
+
+type record struct {
+	ID   uint
+	Name string
+}
+
+func TestMySQLManagerSQLite(t *testing.T) {
+	dir := t.TempDir()
+	cfg := config.DatabaseConfig{
+		MaxIdleConns: 2,
+		MaxOpenConns: 4,
+		Masters: []config.MasterDBConfig{
+			{Name: "main", Driver: "sqlite", DBName: filepath.Join(dir, "master.db")},
+		},
+		Slaves: []config.SlaveDBConfig{
+			{Name: "replica", DBName: filepath.Join(dir, "replica.db"), MasterName: "main"},
+		},
+	}
+
+	manager, err := NewMySQLManager(cfg)
+	if err != nil {
+		t.Fatalf("NewMySQLManager() 失敗: %v", err)
+	}
+	defer manager.Close()
+
+	// Master 與 Slave 為不同檔案，分別寫入資料以確認讀寫分離
+	master := manager.GetMaster()
+	if err := master.AutoMigrate(&record{}); err != nil {
+		t.Fatalf("建立 Master 資料表失敗: %v", err)
+	}
+	if err := master.Create(&record{Name: "master"}).Error; err != nil {
+		t.Fatalf("寫入 Master 失敗: %v", err)
+	}
+
+	replica, err := NewMySQLManager(config.DatabaseConfig{
+		Masters: []config.MasterDBConfig{{Name: "main", Driver: "sqlite", DBName: filepath.Join(dir, "replica.db")}},
+	})
+	if err != nil {
+		t.Fatalf("開啟 Slave 檔案失敗: %v", err)
+	}
+	defer replica.Close()
+	if err := replica.GetMaster().AutoMigrate(&record{}); err != nil {
+		t.Fatalf("建立 Slave 資料表失敗: %v", err)
+	}
+	if err := replica.GetMaster().Create(&record{Name: "replica"}).Error; err != nil {
+		t.Fatalf("寫入 Slave 失敗: %v", err)
+	}
+
+	tests := []struct {
+		name string
+		got  func() (record, error)
+		want string
+	}{
+		{"GetMaster 讀取 Master", func() (r record, err error) { return r, manager.GetMaster().First(&r).Error }, "master"},
+		{"GetSlave 讀取 Slave", func() (r record, err error) { return r, manager.GetSlave().First(&r).Error }, "replica"},
+		{"GetDB 查詢自動使用 Slave", func() (r record, err error) { return r, manager.GetDB().First(&r).Error }, "replica"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, err := tt.got()
+			if err != nil {
+				t.Fatalf("查詢失敗: %v", err)
+			}
+			if r.Name != tt.want {
+				t.Errorf("Name = %v, 期望 %v", r.Name, tt.want)
+			}
+		})
+	}
+}
+
+func TestMySQLManagerDriverMismatch(t *testing.T) {
+	dir := t.TempDir()
+	_, err := NewMySQLManager(config.DatabaseConfig{
+		Masters: []config.MasterDBConfig{
+			{Name: "main", Driver: "sqlite", DBName: filepath.Join(dir, "master.db")},
+		},
+		Slaves: []config.SlaveDBConfig{
+			{Name: "replica", Driver: "postgres", Host: "localhost", MasterName: "main"},
+		},
+	})
+	if err == nil {
+		t.Errorf("NewMySQLManager() 未回傳錯誤, 期望驅動不一致的錯誤")
+	}
+}
+
+func TestBuildDSN(t *testing.T) {
+	tests := []struct {
+		name   string
+		params connParams
+		build  func(connParams) string
+		want   string
+	}{
+		{
+			name:   "MySQL 預設字元集",
+			params: connParams{Host: "db", Port: "3306", User: "root", Password: "pw", DBName: "tour", ParseTime: true},
+			build:  buildMySQLDSN,
+			want:   "root:pw@tcp(db:3306)/tour?charset=utf8mb4&parseTime=true&loc=Local",
+		},
+		{
+			name:   "PostgreSQL 預設 sslmode",
+			params: connParams{Host: "db", Port: "5432", User: "pg", Password: "pw", DBName: "tour"},
+			build:  buildPostgresDSN,
+			want:   "host=db user=pg password=pw dbname=tour port=5432 sslmode=disable",
+		},
+		{
+			name:   "PostgreSQL 指定時區",
+			params: connParams{Host: "db", Port: "5432", User: "pg", Password: "pw", DBName: "tour", SSLMode: "require", Loc: "Asia/Taipei"},
+			build:  buildPostgresDSN,
+			want:   "host=db user=pg password=pw dbname=tour port=5432 sslmode=require TimeZone=Asia/Taipei",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.build(tt.params); got != tt.want {
+				t.Errorf("DSN = %v, 期望 %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMySQLManagerStats(t *testing.T) {
+	dir := t.TempDir()
+	intPtr := func(v int) *int { return &v }
+
+	manager, err := NewMySQLManager(config.DatabaseConfig{
+		MaxOpenConns: 10,
+		Masters: []config.MasterDBConfig{
+			{Name: "main", Driver: "sqlite", DBName: filepath.Join(dir, "master.db"), MaxOpenConns: intPtr(8)},
+		},
+		Slaves: []config.SlaveDBConfig{
+			{Name: "replica1", DBName: filepath.Join(dir, "replica1.db"), MasterName: "main", MaxOpenConns: intPtr(3)},
+			{Name: "replica2", DBName: filepath.Join(dir, "replica2.db"), MasterName: "main"},
+		},
+	})
+	if err != nil {
+		t.Fatalf("NewMySQLManager() 失敗: %v", err)
+	}
+	defer manager.Close()
+
+	want := []PoolStats{
+		{Master: "main", Name: "main", Role: "master", Healthy: true, MaxOpen: 8},
+		{Master: "main", Name: "replica1", Role: "slave", Healthy: true, MaxOpen: 3},
+		{Master: "main", Name: "replica2", Role: "slave", Healthy: true, MaxOpen: 10},
+	}
+
+	got := manager.Stats()
+	if len(got) != len(want) {
+		t.Fatalf("Stats() 筆數 = %v, 期望 %v", len(got), len(want))
+	}
+	for i := range want {
+		g := got[i]
+		if g.Master != want[i].Master || g.Name != want[i].Name || g.Role != want[i].Role ||
+			g.Healthy != want[i].Healthy || g.MaxOpen != want[i].MaxOpen {
+			t.Errorf("Stats()[%d] = %+v, 期望 %+v", i, g, want[i])
+		}
+	}
+}
@@ -3,6 +3,7 @@ package database
 import (
 	"context"
 	"fmt"
+	"strings"
 	"sync"
 	"time"
 
@@ -11,10 +12,17 @@ import (
 	"github.com/redis/go-redis/v9"
 )
 
-// RedisManager Redis 管理器（支援多個 Redis 實例）
+// Redis 部署模式
+const (
+	RedisModeSingle   = "single"
+	RedisModeSentinel = "sentinel"
+	RedisModeCluster  = "cluster"
+)
+
+// RedisManager Redis 管理器（支援多個 Redis 實例，每個實例可為單機、Sentinel 或 Cluster）
 type RedisManager struct {
-	clients map[string]*redis.Client // 多個 Redis 實例，key 為實例名稱
-	mu      sync.RWMutex             // 讀寫鎖
+	clients map[string]redis.UniversalClient // 多個 Redis 實例，key 為實例名稱
+	mu      sync.RWMutex                     // 讀寫鎖
 
 	config config.RedisConfig
 }
@@ -29,7 +37,7 @@ func InitRedis(cfg config.RedisConfig) error {
 	var err error
 	redisOnce.Do(func() {
 		redisInstance = &RedisManager{
-			clients: make(map[string]*redis.Client),
+			clients: make(map[string]redis.UniversalClient),
 			config:  cfg,
 		}
 		err = redisInstance.initialize()
@@ -67,8 +75,8 @@ func (m *RedisManager) initialize() error {
 			return fmt.Errorf("Redis 實例 [%s] 連線測試失敗: %w", instanceCfg.Name, err)
 		}
 
-		logger.Infof("Redis 實例 [%s] 連線成功: %s:%d (DB: %d)",
-			instanceCfg.Name, instanceCfg.Host, instanceCfg.Port, instanceCfg.DB)
+		logger.Infof("Redis 實例 [%s] 連線成功: %s (DB: %d)",
+			instanceCfg.Name, describeRedisInstance(instanceCfg), instanceCfg.DB)
 
 		// 使用實例名稱作為 key
 		m.clients[instanceCfg.Name] = client
@@ -77,13 +85,14 @@ func (m *RedisManager) initialize() error {
 	return nil
 }
 
-// createRedisClient 建立 Redis 客戶端
-func (m *RedisManager) createRedisClient(cfg config.RedisInstanceConfig) (*redis.Client, error) {
+// createRedisClient 依部署模式建立 Redis 客戶端
+func (m *RedisManager) createRedisClient(cfg config.RedisInstanceConfig) (redis.UniversalClient, error) {
 	// 建立 Redis 選項
-	opts := &redis.Options{
-		Addr:     fmt.Sprintf("%s:%d", cfg.Host, cfg.Port),
-		Password: cfg.Password,
-		DB:       cfg.DB,
+	opts := &redis.UniversalOptions{
+		Password:         cfg.Password,
+		DB:               cfg.DB,
+		MasterName:       cfg.MasterName,
+		SentinelPassword: cfg.SentinelPassword,
 	}
 
 	// 設定連線池參數（使用個別設定或全域設定）
@@ -136,15 +145,52 @@ func (m *RedisManager) createRedisClient(cfg config.RedisInstanceConfig) (*redis
 		opts.WriteTimeout = m.config.WriteTimeout
 	}
 
-	// 建立客戶端
-	client := redis.NewClient(opts)
+	// 依部署模式建立客戶端
+	switch strings.ToLower(cfg.Mode) {
+	case "", RedisModeSingle:
+		opts.Addrs = []string{fmt.Sprintf("%s:%d", cfg.Host, cfg.Port)}
+		return redis.NewClient(opts.Simple()), nil
+
+	case RedisModeSentinel:
+		if cfg.MasterName == "" {
+			return nil, fmt.Errorf("Sentinel 模式需設定 masterName")
+		}
+		if len(cfg.Addrs) == 0 {
+			return nil, fmt.Errorf("Sentinel 模式需設定 addrs")
+		}
+		opts.Addrs = cfg.Addrs
+		return redis.NewFailoverClient(opts.Failover()), nil
 
-	return client, nil
+	case RedisModeCluster:
+		if len(cfg.Addrs) == 0 {
+			return nil, fmt.Errorf("Cluster 模式需設定 addrs")
+		}
+		if cfg.DB != 0 {
+			return nil, fmt.Errorf("Cluster 模式不支援 db %d，只能使用 0", cfg.DB)
+		}
+		opts.Addrs = cfg.Addrs
+		return redis.NewClusterClient(opts.Cluster()), nil
+
+	default:
+		return nil, fmt.Errorf("不支援的 Redis 模式: %s", cfg.Mode)
+	}
+}
+
+// describeRedisInstance 產生實例位址說明（用於日誌）
+func describeRedisInstance(cfg config.RedisInstanceConfig) string {
+	switch strings.ToLower(cfg.Mode) {
+	case RedisModeSentinel:
+		return fmt.Sprintf("sentinel %s %v", cfg.MasterName, cfg.Addrs)
+	case RedisModeCluster:
+		return fmt.Sprintf("cluster %v", cfg.Addrs)
+	default:
+		return fmt.Sprintf("%s:%d", cfg.Host, cfg.Port)
+	}
 }
 
 // GetClient 取得 Redis 客戶端
 // 參數可以是實例名稱，如果不指定則返回預設實例
-func (m *RedisManager) GetClient(name ...string) *redis.Client {
+func (m *RedisManager) GetClient(name ...string) redis.UniversalClient {
 	m.mu.RLock()
 	defer m.mu.RUnlock()
 
@@ -173,7 +219,7 @@ func (m *RedisManager) GetClient(name ...string) *redis.Client {
 
 // GetClientByDB 根據資料庫名稱取得對應的 Redis 客戶端
 // 這個方法會尋找設定中 Database 欄位符合的實例
-func (m *RedisManager) GetClientByDB(database string) *redis.Client {
+func (m *RedisManager) GetClientByDB(database string) redis.UniversalClient {
 	m.mu.RLock()
 	defer m.mu.RUnlock()
 
@@ -193,16 +239,13 @@ func (m *RedisManager) GetClientByDB(database string) *redis.Client {
 
 // Ping 測試所有 Redis 連線
 func (m *RedisManager) Ping() error {
-	m.mu.RLock()
-	defer m.mu.RUnlock()
-
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
 	var errs []error
-	for name, client := range m.clients {
-		if err := client.Ping(ctx).Err(); err != nil {
-			errs = append(errs, fmt.Errorf("Redis 實例 [%s] Ping 失敗: %w", name, err))
+	for _, result := range m.PingEach(ctx) {
+		if !result.Healthy {
+			errs = append(errs, fmt.Errorf("Redis 實例 [%s] Ping 失敗: %s", result.Name, result.Error))
 		}
 	}
 
@@ -0,0 +1,162 @@
+package database
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/andy2kuo/TourHelper/internal/config"
+)
+
+// startFakeSentinel 啟動只支援 go-redis Failover 客戶端所需指令的 Sentinel 替身，回報 master 位址為 masterAddr
+func startFakeSentinel(t *testing.T, masterName, masterAddr string) string {
+	t.Helper()
+
+	host, port, err := net.SplitHostPort(masterAddr)
+	if err != nil {
+		t.Fatalf("解析 master 位址失敗: %v", err)
+	}
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("啟動 Sentinel 替身失敗: %v", err)
+	}
+	t.Cleanup(func() { ln.Close() })
+
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go serveFakeSentinel(conn, masterName, host, port)
+		}
+	}()
+
+	return ln.Addr().String()
+}
+
+// serveFakeSentinel 處理單一連線的 RESP 指令
+func serveFakeSentinel(conn net.Conn, masterName, host, port string) {
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+
+	bulk := func(s string) string { return fmt.Sprintf("$%d\r\n%s\r\n", len(s), s) }
+
+	for {
+		args, err := readRESPCommand(r)
+		if err != nil {
+			return
+		}
+
+		var reply string
+		switch cmd := strings.ToUpper(args[0]); {
+		case cmd == "PING":
+			reply = "+PONG\r\n"
+		case cmd == "SENTINEL" && len(args) == 3 && strings.EqualFold(args[1], "get-master-addr-by-name"):
+			if args[2] == masterName {
+				reply = "*2\r\n" + bulk(host) + bulk(port)
+			} else {
+				reply = "*-1\r\n"
+			}
+		case cmd == "SENTINEL":
+			reply = "*0\r\n"
+		case cmd == "SUBSCRIBE":
+			for i, channel := range args[1:] {
+				reply += "*3\r\n" + bulk("subscribe") + bulk(channel) + ":" + strconv.Itoa(i+1) + "\r\n"
+			}
+		default:
+			reply = "-ERR unknown command '" + args[0] + "'\r\n"
+		}
+
+		if _, err := conn.Write([]byte(reply)); err != nil {
+			return
+		}
+	}
+}
+
+// readRESPCommand 讀取一個 RESP 陣列格式的指令
+func readRESPCommand(r *bufio.Reader) ([]string, error) {
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	n, err := strconv.Atoi(strings.TrimSpace(strings.TrimPrefix(line, "*")))
+	if err != nil || n <= 0 {
+		return nil, fmt.Errorf("無效的指令: %q", line)
+	}
+
+	args := make([]string, n)
+	for i := range args {
+		if _, err := r.ReadString('\n'); err != nil { // $長度
+			return nil, err
+		}
+		arg, err := r.ReadString('\n')
+		if err != nil {
+			return nil, err
+		}
+		args[i] = strings.TrimSuffix(arg, "\r\n")
+	}
+	return args, nil
+}
+
+func TestRedisManagerModes(t *testing.T) {
+	mr := miniredis.RunT(t)
+	host, portText, _ := net.SplitHostPort(mr.Addr())
+	port, _ := strconv.Atoi(portText)
+	sentinelAddr := startFakeSentinel(t, "mymaster", mr.Addr())
+
+	tests := []struct {
+		name     string
+		instance config.RedisInstanceConfig
+	}{
+		{"單機模式", config.RedisInstanceConfig{Name: "single", Host: host, Port: port}},
+		{"Sentinel 模式", config.RedisInstanceConfig{Name: "sentinel", Mode: "sentinel", MasterName: "mymaster", Addrs: []string{sentinelAddr}}},
+		{"Cluster 模式", config.RedisInstanceConfig{Name: "cluster", Mode: "cluster", Addrs: []string{mr.Addr()}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			manager := &RedisManager{config: config.RedisConfig{PoolSize: 2}}
+			client, err := manager.createRedisClient(tt.instance)
+			if err != nil {
+				t.Fatalf("createRedisClient() 失敗: %v", err)
+			}
+			defer client.Close()
+
+			ctx := context.Background()
+			key := "mode:" + tt.instance.Name
+			if err := client.Set(ctx, key, tt.instance.Name, 0).Err(); err != nil {
+				t.Fatalf("Set() 失敗: %v", err)
+			}
+			mr.CheckGet(t, key, tt.instance.Name)
+		})
+	}
+}
+
+func TestRedisManagerInvalidConfig(t *testing.T) {
+	tests := []struct {
+		name     string
+		instance config.RedisInstanceConfig
+	}{
+		{"Sentinel 缺少 masterName", config.RedisInstanceConfig{Mode: "sentinel", Addrs: []string{"localhost:26379"}}},
+		{"Sentinel 缺少 addrs", config.RedisInstanceConfig{Mode: "sentinel", MasterName: "mymaster"}},
+		{"Cluster 缺少 addrs", config.RedisInstanceConfig{Mode: "cluster"}},
+		{"Cluster 使用非 0 的 db", config.RedisInstanceConfig{Mode: "cluster", Addrs: []string{"localhost:6379"}, DB: 1}},
+		{"不支援的模式", config.RedisInstanceConfig{Mode: "proxy"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			manager := &RedisManager{}
+			if _, err := manager.createRedisClient(tt.instance); err == nil {
+				t.Errorf("createRedisClient() 未回傳錯誤")
+			}
+		})
+	}
+}
@@ -0,0 +1,294 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"math"
+	"math/rand/v2"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/andy2kuo/TourHelper/internal/logger"
+	"gorm.io/gorm"
+)
+
+const (
+	defaultReplicaHealthCheckTimeout = 2 * time.Second
+	defaultReplicaFailureThreshold   = 2
+)
+
+// replica 單一 Slave 連線池與健康狀態
+type replica struct {
+	name   string
+	weight int
+	pool   *sql.DB
+
+	healthy  atomic.Bool
+	lag      atomic.Int64 // 最近一次量測的複寫延遲（奈秒）
+	failures int          // 連續檢查失敗次數，僅由健康檢查 goroutine 存取
+}
+
+// report 記錄一次健康檢查結果，回傳健康狀態是否改變
+// 連續失敗達 threshold 次時移出輪替，成功一次即重新加入
+func (r *replica) report(err error, threshold int) bool {
+	if err == nil {
+		r.failures = 0
+		return r.healthy.CompareAndSwap(false, true)
+	}
+
+	r.failures++
+	if r.failures < threshold {
+		return false
+	}
+	return r.healthy.CompareAndSwap(true, false)
+}
+
+// replicaSet 同一個 Master 底下的 Slave 集合
+// 實作 dbresolver.Policy：依權重挑選健康且延遲在容許範圍內的 Slave，全部不可用時改讀 Master
+type replicaSet struct {
+	master   string
+	driver   string
+	replicas []*replica
+
+	interval  time.Duration
+	timeout   time.Duration
+	threshold int
+	maxLag    time.Duration // 容許的複寫延遲，0 表示不量測
+
+	stop chan struct{}
+	wg   sync.WaitGroup
+}
+
+// newReplicaSet 建立 Slave 集合，所有 Slave 初始為健康狀態
+func newReplicaSet(master, driver string, interval, timeout time.Duration, threshold int, maxLag time.Duration) *replicaSet {
+	if timeout <= 0 {
+		timeout = defaultReplicaHealthCheckTimeout
+	}
+	if threshold <= 0 {
+		threshold = defaultReplicaFailureThreshold
+	}
+	return &replicaSet{
+		master:    master,
+		driver:    driver,
+		interval:  interval,
+		timeout:   timeout,
+		threshold: threshold,
+		maxLag:    maxLag,
+		stop:      make(chan struct{}),
+	}
+}
+
+// add 加入 Slave，權重未設定（<= 0）時視為 1
+func (s *replicaSet) add(name string, weight int, pool *sql.DB) {
+	if weight <= 0 {
+		weight = 1
+	}
+	r := &replica{name: name, weight: weight, pool: pool}
+	r.healthy.Store(true)
+	s.replicas = append(s.replicas, r)
+}
+
+// Resolve 實作 dbresolver.Policy
+// connPools 依序為各 Slave 的連線池，最後一個為 Master（所有 Slave 異常時使用）
+func (s *replicaSet) Resolve(connPools []gorm.ConnPool) gorm.ConnPool {
+	if i := s.pick(); i >= 0 && i < len(connPools)-1 {
+		return connPools[i]
+	}
+	return connPools[len(connPools)-1]
+}
+
+// available 判斷 Slave 是否在讀取輪替中（健康且延遲未超過門檻）
+func (s *replicaSet) available(r *replica) bool {
+	if !r.healthy.Load() {
+		return false
+	}
+	return s.maxLag <= 0 || time.Duration(r.lag.Load()) <= s.maxLag
+}
+
+// pick 依權重隨機挑選可用的 Slave，沒有可用的 Slave 時回傳 -1
+func (s *replicaSet) pick() int {
+	total := 0
+	for _, r := range s.replicas {
+		if s.available(r) {
+			total += r.weight
+		}
+	}
+	if total == 0 {
+		return -1
+	}
+
+	n := rand.IntN(total)
+	for i, r := range s.replicas {
+		if !s.available(r) {
+			continue
+		}
+		if n < r.weight {
+			return i
+		}
+		n -= r.weight
+	}
+	return -1
+}
+
+// start 啟動背景健康檢查，interval <= 0 時不檢查
+func (s *replicaSet) start() {
+	if s.interval <= 0 {
+		return
+	}
+
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+
+		ticker := time.NewTicker(s.interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-s.stop:
+				return
+			case <-ticker.C:
+				s.check(context.Background())
+			}
+		}
+	}()
+}
+
+// check 檢查所有 Slave 並更新健康狀態
+func (s *replicaSet) check(ctx context.Context) {
+	for _, r := range s.replicas {
+		pingCtx, cancel := context.WithTimeout(ctx, s.timeout)
+		err := r.pool.PingContext(pingCtx)
+		cancel()
+
+		if r.report(err, s.threshold) {
+			if r.healthy.Load() {
+				logger.Infof("Slave [%s] 已恢復，重新加入 Master [%s] 的讀取輪替", r.name, s.master)
+			} else {
+				logger.Warnf("Slave [%s] 健康檢查失敗，已移出 Master [%s] 的讀取輪替: %v", r.name, s.master, err)
+			}
+		}
+
+		if err == nil && s.maxLag > 0 {
+			s.checkLag(ctx, r)
+		}
+	}
+}
+
+// checkLag 量測 Slave 複寫延遲，延遲超過 maxLag 時暫時不讀取此 Slave
+// 量測失敗（例如帳號沒有查詢複寫狀態的權限）時保留上次的結果
+func (s *replicaSet) checkLag(ctx context.Context, r *replica) {
+	lagCtx, cancel := context.WithTimeout(ctx, s.timeout)
+	lag, err := measureReplicaLag(lagCtx, s.driver, r.pool)
+	cancel()
+	if err != nil {
+		logger.Warnf("量測 Slave [%s] 複寫延遲失敗: %v", r.name, err)
+		return
+	}
+
+	previous := time.Duration(r.lag.Swap(int64(lag)))
+	switch {
+	case lag > s.maxLag && previous <= s.maxLag:
+		logger.Warnf("Slave [%s] 複寫延遲 %v 超過 %v，暫停讀取", r.name, lag, s.maxLag)
+	case lag <= s.maxLag && previous > s.maxLag:
+		logger.Infof("Slave [%s] 複寫延遲已恢復為 %v，重新加入讀取輪替", r.name, lag)
+	}
+}
+
+// probe 立即檢查所有 Slave，無法連線的 Slave 直接移出輪替（用於啟動時）
+func (s *replicaSet) probe(ctx context.Context) {
+	for _, r := range s.replicas {
+		pingCtx, cancel := context.WithTimeout(ctx, s.timeout)
+		err := r.pool.PingContext(pingCtx)
+		cancel()
+
+		if err != nil {
+			r.failures = s.threshold
+			r.healthy.Store(false)
+			logger.Warnf("Slave [%s] 無法連線，暫不加入 Master [%s] 的讀取輪替: %v", r.name, s.master, err)
+		}
+	}
+}
+
+// measureReplicaLag 查詢 Slave 的複寫延遲，SQLite 沒有複寫固定回傳 0
+func measureReplicaLag(ctx context.Context, driver string, pool *sql.DB) (time.Duration, error) {
+	switch driver {
+	case DriverMySQL:
+		return measureMySQLLag(ctx, pool)
+	case DriverPostgres:
+		// 已套用所有收到的 WAL 時視為沒有延遲，避免 Master 閒置時誤判
+		var seconds float64
+		err := pool.QueryRowContext(ctx, `SELECT CASE
+			WHEN pg_last_wal_receive_lsn() = pg_last_wal_replay_lsn() THEN 0
+			ELSE COALESCE(EXTRACT(EPOCH FROM now() - pg_last_xact_replay_timestamp()), 0)
+		END`).Scan(&seconds)
+		if err != nil {
+			return 0, err
+		}
+		return time.Duration(seconds * float64(time.Second)), nil
+	default:
+		return 0, nil
+	}
+}
+
+// measureMySQLLag 由 SHOW REPLICA STATUS（舊版為 SHOW SLAVE STATUS）取得 Seconds_Behind_Source
+func measureMySQLLag(ctx context.Context, pool *sql.DB) (time.Duration, error) {
+	rows, err := pool.QueryContext(ctx, "SHOW REPLICA STATUS")
+	if err != nil {
+		if rows, err = pool.QueryContext(ctx, "SHOW SLAVE STATUS"); err != nil {
+			return 0, err
+		}
+	}
+	defer rows.Close()
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return 0, err
+	}
+	if !rows.Next() {
+		// 沒有複寫狀態（非 Slave）
+		return 0, rows.Err()
+	}
+
+	values := make([]sql.NullString, len(columns))
+	dest := make([]any, len(columns))
+	for i := range values {
+		dest[i] = &values[i]
+	}
+	if err := rows.Scan(dest...); err != nil {
+		return 0, err
+	}
+
+	for i, column := range columns {
+		if column != "Seconds_Behind_Source" && column != "Seconds_Behind_Master" {
+			continue
+		}
+		if !values[i].Valid {
+			// 複寫已停止，視為延遲無限大
+			return time.Duration(math.MaxInt64), nil
+		}
+		seconds, err := strconv.ParseInt(values[i].String, 10, 64)
+		if err != nil {
+			return 0, err
+		}
+		return time.Duration(seconds) * time.Second, nil
+	}
+	return 0, fmt.Errorf("找不到複寫延遲欄位")
+}
+
+// close 停止健康檢查並關閉所有 Slave 連線池
+func (s *replicaSet) close() []error {
+	close(s.stop)
+	s.wg.Wait()
+
+	var errs []error
+	for _, r := range s.replicas {
+		if err := r.pool.Close(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errs
+}
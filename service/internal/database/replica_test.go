@@ -0,0 +1,113 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/andy2kuo/TourHelper/internal/config"
+)
+
+func TestReplicaReport(t *testing.T) {
+	errPing := errors.New("ping failed")
+
+	tests := []struct {
+		name        string
+		results     []error
+		wantHealthy bool
+	}{
+		{"單次失敗未達門檻", []error{errPing}, true},
+		{"連續失敗達門檻後移出", []error{errPing, errPing}, false},
+		{"失敗中斷後重新計算", []error{errPing, nil, errPing}, true},
+		{"移出後成功一次即恢復", []error{errPing, errPing, errPing, nil}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := &replica{name: "replica"}
+			r.healthy.Store(true)
+			for _, err := range tt.results {
+				r.report(err, 2)
+			}
+			if got := r.healthy.Load(); got != tt.wantHealthy {
+				t.Errorf("healthy = %v, 期望 %v", got, tt.wantHealthy)
+			}
+		})
+	}
+}
+
+func TestReplicaSetPick(t *testing.T) {
+	set := newReplicaSet("main", DriverMySQL, 0, 0, 0, time.Second)
+	set.add("heavy", 3, nil)
+	set.add("light", 1, nil)
+	set.add("down", 100, nil)
+	set.add("lagging", 100, nil)
+	set.replicas[2].healthy.Store(false)
+	set.replicas[3].lag.Store(int64(5 * time.Second))
+
+	counts := make([]int, len(set.replicas))
+	for i := 0; i < 4000; i++ {
+		counts[set.pick()]++
+	}
+
+	if counts[2] != 0 {
+		t.Errorf("pick() 選到已移出的 Slave %d 次, 期望 0", counts[2])
+	}
+	if counts[3] != 0 {
+		t.Errorf("pick() 選到延遲過高的 Slave %d 次, 期望 0", counts[3])
+	}
+	// 權重 3:1，容許一定的隨機誤差
+	if ratio := float64(counts[0]) / float64(counts[1]); ratio < 2.5 || ratio > 3.5 {
+		t.Errorf("pick() 權重比例 = %.2f, 期望約 3", ratio)
+	}
+
+	set.replicas[0].healthy.Store(false)
+	set.replicas[1].healthy.Store(false)
+	if got := set.pick(); got != -1 {
+		t.Errorf("pick() = %v, 期望 -1（全部異常）", got)
+	}
+}
+
+func TestReplicaFallbackToMaster(t *testing.T) {
+	dir := t.TempDir()
+	manager, err := NewMySQLManager(config.DatabaseConfig{
+		Masters: []config.MasterDBConfig{
+			{Name: "main", Driver: "sqlite", DBName: filepath.Join(dir, "master.db")},
+		},
+		Slaves: []config.SlaveDBConfig{
+			{Name: "replica", DBName: filepath.Join(dir, "replica.db"), MasterName: "main"},
+		},
+		ReplicaFailureThreshold: 1,
+	})
+	if err != nil {
+		t.Fatalf("NewMySQLManager() 失敗: %v", err)
+	}
+	defer manager.Close()
+
+	if err := manager.GetMaster().AutoMigrate(&record{}); err != nil {
+		t.Fatalf("建立 Master 資料表失敗: %v", err)
+	}
+	if err := manager.GetMaster().Create(&record{Name: "master"}).Error; err != nil {
+		t.Fatalf("寫入 Master 失敗: %v", err)
+	}
+
+	// Slave 沒有資料表，讀取會失敗
+	var r record
+	if err := manager.GetSlave().First(&r).Error; err == nil {
+		t.Fatalf("GetSlave() 讀取成功, 期望讀取 Slave 失敗")
+	}
+
+	// 模擬 Slave 故障：關閉連線池後健康檢查將其移出，讀取改走 Master
+	set := manager.replicas["main"]
+	set.replicas[0].pool.Close()
+	set.check(context.Background())
+
+	if err := manager.GetSlave().First(&r).Error; err != nil {
+		t.Fatalf("GetSlave() 失敗: %v", err)
+	}
+	if r.Name != "master" {
+		t.Errorf("Name = %v, 期望 master", r.Name)
+	}
+}
@@ -0,0 +1,149 @@
+package database
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/andy2kuo/TourHelper/internal/logger"
+	"github.com/redis/go-redis/v9"
+	"gorm.io/gorm"
+)
+
+// stickyKeyPrefix Redis 中記錄寫入時間窗的 key 前綴
+const stickyKeyPrefix = "tourhelper:sticky:"
+
+type stickySessionKey struct{}
+
+// stickySession 請求或使用者的讀寫一致性狀態
+type stickySession struct {
+	key   string       // 跨伺服器共用的識別（例如 user:42），空字串表示只在此請求內有效
+	until atomic.Int64 // 讀取需走 Master 的截止時間（UnixNano）
+}
+
+// WithStickySession 在 context 中建立讀寫一致性範圍（read-your-writes）
+// 範圍內發生寫入後，同一範圍的讀取在 stickyMasterWindow 內改走 Master；
+// key 不為空時會記錄於 Redis，讓其他伺服器處理同一 key 的請求時也讀取 Master
+func WithStickySession(ctx context.Context, key string) context.Context {
+	if s, ok := ctx.Value(stickySessionKey{}).(*stickySession); ok && s.key == key {
+		return ctx
+	}
+	return context.WithValue(ctx, stickySessionKey{}, &stickySession{key: key})
+}
+
+// stickySessionFrom 取得 context 中的讀寫一致性範圍
+func stickySessionFrom(ctx context.Context) *stickySession {
+	if ctx == nil {
+		return nil
+	}
+	s, _ := ctx.Value(stickySessionKey{}).(*stickySession)
+	return s
+}
+
+// stickyTracker 追蹤各範圍最近的寫入，決定讀取是否需要走 Master
+type stickyTracker struct {
+	window time.Duration
+
+	mu     sync.RWMutex
+	client redis.Cmdable // nil 表示只在單一請求內有效
+}
+
+// setClient 設定跨伺服器共用狀態的 Redis 客戶端
+func (t *stickyTracker) setClient(client redis.Cmdable) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.client = client
+}
+
+// redisClient 取得 Redis 客戶端
+func (t *stickyTracker) redisClient() redis.Cmdable {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	return t.client
+}
+
+// markWrite 記錄範圍內發生寫入
+func (t *stickyTracker) markWrite(ctx context.Context) {
+	s := stickySessionFrom(ctx)
+	if s == nil {
+		return
+	}
+	s.until.Store(time.Now().Add(t.window).UnixNano())
+
+	client := t.redisClient()
+	if client == nil || s.key == "" {
+		return
+	}
+	if err := client.Set(context.WithoutCancel(ctx), stickyKeyPrefix+s.key, 1, t.window).Err(); err != nil {
+		logger.Warnf("記錄讀寫一致性時間窗失敗 [%s]: %v", s.key, err)
+	}
+}
+
+// useMaster 判斷範圍內的讀取是否需要走 Master
+// Redis 無法使用時僅依此請求內的寫入判斷，避免所有讀取都落到 Master
+func (t *stickyTracker) useMaster(ctx context.Context) bool {
+	s := stickySessionFrom(ctx)
+	if s == nil {
+		return false
+	}
+
+	now := time.Now()
+	if now.UnixNano() < s.until.Load() {
+		return true
+	}
+
+	client := t.redisClient()
+	if client == nil || s.key == "" {
+		return false
+	}
+
+	ttl, err := client.PTTL(ctx, stickyKeyPrefix+s.key).Result()
+	if err != nil {
+		logger.Warnf("查詢讀寫一致性時間窗失敗 [%s]: %v", s.key, err)
+		return false
+	}
+	if ttl <= 0 {
+		return false
+	}
+
+	// 記住剩餘時間，同一請求後續的讀取不需再查詢 Redis
+	s.until.Store(now.Add(ttl).UnixNano())
+	return true
+}
+
+// register 註冊 GORM callback：寫入成功後記錄時間窗，時間窗內的讀取改用 Master 連線池
+// 需在 DBResolver 註冊之後呼叫，讀取的 callback 會在 DBResolver 選擇連線之後執行
+func (t *stickyTracker) register(db *gorm.DB, master gorm.ConnPool) error {
+	markWrite := func(tx *gorm.DB) {
+		if tx.Error == nil {
+			t.markWrite(tx.Statement.Context)
+		}
+	}
+	useMaster := func(tx *gorm.DB) {
+		if _, inTx := tx.Statement.ConnPool.(gorm.TxCommitter); inTx {
+			return
+		}
+		if t.useMaster(tx.Statement.Context) {
+			tx.Statement.ConnPool = master
+		}
+	}
+
+	callbacks := db.Callback()
+	if err := callbacks.Create().After("*").Register("tourhelper:sticky_mark", markWrite); err != nil {
+		return err
+	}
+	if err := callbacks.Update().After("*").Register("tourhelper:sticky_mark", markWrite); err != nil {
+		return err
+	}
+	if err := callbacks.Delete().After("*").Register("tourhelper:sticky_mark", markWrite); err != nil {
+		return err
+	}
+	if err := callbacks.Raw().After("*").Register("tourhelper:sticky_mark", markWrite); err != nil {
+		return err
+	}
+	if err := callbacks.Query().After("gorm:db_resolver").Before("gorm:query").Register("tourhelper:sticky_read", useMaster); err != nil {
+		return err
+	}
+	return callbacks.Row().After("gorm:db_resolver").Before("gorm:row").Register("tourhelper:sticky_read", useMaster)
+}
@@ -0,0 +1,125 @@
+package database
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/andy2kuo/TourHelper/internal/config"
+	"github.com/redis/go-redis/v9"
+)
+
+// newStickyTestManager 建立 Master 與 Slave 為不同 SQLite 檔案的管理器，並分別寫入可辨識的資料
+func newStickyTestManager(t *testing.T, dir string) *MySQLManager {
+	t.Helper()
+
+	manager, err := NewMySQLManager(config.DatabaseConfig{
+		Masters: []config.MasterDBConfig{
+			{Name: "main", Driver: "sqlite", DBName: filepath.Join(dir, "master.db")},
+		},
+		Slaves: []config.SlaveDBConfig{
+			{Name: "replica", DBName: filepath.Join(dir, "replica.db"), MasterName: "main"},
+		},
+		StickyMasterWindow: time.Minute,
+	})
+	if err != nil {
+		t.Fatalf("NewMySQLManager() 失敗: %v", err)
+	}
+	t.Cleanup(func() { manager.Close() })
+
+	seed := func(file, name string) {
+		db, err := NewMySQLManager(config.DatabaseConfig{
+			Masters: []config.MasterDBConfig{{Name: "main", Driver: "sqlite", DBName: filepath.Join(dir, file)}},
+		})
+		if err != nil {
+			t.Fatalf("開啟 %s 失敗: %v", file, err)
+		}
+		defer db.Close()
+
+		var count int64
+		db.GetMaster().AutoMigrate(&record{})
+		db.GetMaster().Model(&record{}).Count(&count)
+		if count == 0 {
+			db.GetMaster().Create(&record{Name: name})
+		}
+	}
+	seed("master.db", "master")
+	seed("replica.db", "replica")
+
+	return manager
+}
+
+// readName 透過 GetSlave 讀取第一筆資料的名稱，用來判斷實際讀取的資料庫
+func readName(t *testing.T, manager *MySQLManager, ctx context.Context) string {
+	t.Helper()
+
+	var r record
+	if err := manager.GetSlave().WithContext(ctx).Order("id").First(&r).Error; err != nil {
+		t.Fatalf("讀取失敗: %v", err)
+	}
+	return r.Name
+}
+
+func TestStickySessionInRequest(t *testing.T) {
+	manager := newStickyTestManager(t, t.TempDir())
+
+	ctx := WithStickySession(context.Background(), "")
+	if got := readName(t, manager, ctx); got != "replica" {
+		t.Errorf("寫入前讀取 = %v, 期望 replica", got)
+	}
+
+	if err := manager.GetMaster().WithContext(ctx).Create(&record{Name: "new"}).Error; err != nil {
+		t.Fatalf("寫入失敗: %v", err)
+	}
+
+	if got := readName(t, manager, ctx); got != "master" {
+		t.Errorf("寫入後同一範圍讀取 = %v, 期望 master", got)
+	}
+	if got := readName(t, manager, context.Background()); got != "replica" {
+		t.Errorf("寫入後其他請求讀取 = %v, 期望 replica", got)
+	}
+}
+
+func TestStickySessionAcrossServers(t *testing.T) {
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	defer client.Close()
+
+	dir := t.TempDir()
+	server1 := newStickyTestManager(t, dir)
+	server2 := newStickyTestManager(t, dir)
+	server1.SetStickyStore(client)
+	server2.SetStickyStore(client)
+
+	writeCtx := WithStickySession(context.Background(), "user:1")
+	if err := server1.GetMaster().WithContext(writeCtx).Create(&record{Name: "new"}).Error; err != nil {
+		t.Fatalf("寫入失敗: %v", err)
+	}
+
+	tests := []struct {
+		name string
+		key  string
+		want string
+	}{
+		{"同一使用者於其他伺服器讀取 Master", "user:1", "master"},
+		{"其他使用者讀取 Slave", "user:2", "replica"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := WithStickySession(context.Background(), tt.key)
+			if got := readName(t, server2, ctx); got != tt.want {
+				t.Errorf("讀取 = %v, 期望 %v", got, tt.want)
+			}
+		})
+	}
+
+	// 時間窗結束後恢復讀取 Slave
+	mr.FastForward(2 * time.Minute)
+	ctx := WithStickySession(context.Background(), "user:1")
+	if got := readName(t, server2, ctx); got != "replica" {
+		t.Errorf("時間窗結束後讀取 = %v, 期望 replica", got)
+	}
+}
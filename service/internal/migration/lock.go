@@ -0,0 +1,118 @@
+package migration
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"hash/fnv"
+	"sync"
+	"time"
+
+	"github.com/andy2kuo/TourHelper/internal/logger"
+	"gorm.io/gorm"
+)
+
+const (
+	// lockName 遷移使用的資料庫鎖名稱
+	lockName = "tourhelper_schema_migrations"
+	// lockPollInterval PostgreSQL 嘗試取得鎖的間隔
+	lockPollInterval = 500 * time.Millisecond
+)
+
+// localLock 不支援資料庫鎖的資料庫（SQLite）使用的程序內鎖
+var localLock sync.Mutex
+
+// withLock 取得資料庫鎖後執行 fn，確保同一時間只有一個程序執行遷移
+// 鎖綁定在從連線池取出的專屬連線上（MySQL GET_LOCK、PostgreSQL advisory lock），
+// 遷移本身仍透過一般的 Master 連線執行，因此不受讀寫分離的連線切換影響
+func (m *Migrator) withLock(ctx context.Context, fn func(db *gorm.DB) error) error {
+	db := m.db.WithContext(ctx)
+	dialect := db.Dialector.Name()
+
+	if dialect != "mysql" && dialect != "postgres" {
+		localLock.Lock()
+		defer localLock.Unlock()
+		return fn(db)
+	}
+
+	sqlDB, err := db.DB()
+	if err != nil {
+		return fmt.Errorf("取得資料庫連線池失敗: %w", err)
+	}
+	conn, err := sqlDB.Conn(ctx)
+	if err != nil {
+		return fmt.Errorf("取得遷移鎖連線失敗: %w", err)
+	}
+	defer conn.Close()
+
+	if dialect == "mysql" {
+		err = acquireMySQLLock(ctx, conn, m.lockTimeout)
+	} else {
+		err = acquirePostgresLock(ctx, conn, m.lockTimeout)
+	}
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		// 即使 ctx 已取消也要釋放鎖，否則連線歸還連線池後鎖仍會被持有
+		releaseCtx := context.WithoutCancel(ctx)
+		if err := releaseLock(releaseCtx, conn, dialect); err != nil {
+			logger.Warnf("釋放資料表遷移鎖失敗: %v", err)
+		}
+	}()
+
+	return fn(db)
+}
+
+// acquireMySQLLock 以 GET_LOCK 取得 MySQL 具名鎖
+func acquireMySQLLock(ctx context.Context, conn *sql.Conn, timeout time.Duration) error {
+	var got sql.NullInt64
+	seconds := max(1, int(timeout.Seconds()))
+	if err := conn.QueryRowContext(ctx, "SELECT GET_LOCK(?, ?)", lockName, seconds).Scan(&got); err != nil {
+		return fmt.Errorf("取得資料表遷移鎖失敗: %w", err)
+	}
+	if !got.Valid || got.Int64 != 1 {
+		return ErrLockTimeout
+	}
+	return nil
+}
+
+// acquirePostgresLock 以 pg_try_advisory_lock 輪詢取得 PostgreSQL advisory lock，直到逾時
+func acquirePostgresLock(ctx context.Context, conn *sql.Conn, timeout time.Duration) error {
+	deadline := time.Now().Add(timeout)
+	for {
+		var got bool
+		if err := conn.QueryRowContext(ctx, "SELECT pg_try_advisory_lock($1)", lockKey()).Scan(&got); err != nil {
+			return fmt.Errorf("取得資料表遷移鎖失敗: %w", err)
+		}
+		if got {
+			return nil
+		}
+		if time.Now().After(deadline) {
+			return ErrLockTimeout
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(lockPollInterval):
+		}
+	}
+}
+
+// releaseLock 釋放資料庫鎖
+func releaseLock(ctx context.Context, conn *sql.Conn, dialect string) error {
+	var released sql.NullBool
+	if dialect == "mysql" {
+		return conn.QueryRowContext(ctx, "SELECT RELEASE_LOCK(?)", lockName).Scan(&released)
+	}
+	return conn.QueryRowContext(ctx, "SELECT pg_advisory_unlock($1)", lockKey()).Scan(&released)
+}
+
+// lockKey PostgreSQL advisory lock 使用的整數鍵
+func lockKey() int64 {
+	h := fnv.New64a()
+	h.Write([]byte(lockName))
+	return int64(h.Sum64())
+}
@@ -0,0 +1,254 @@
+package migration
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/andy2kuo/TourHelper/internal/logger"
+	"gorm.io/gorm"
+)
+
+// defaultLockTimeout 等待其他程序完成遷移的預設時間
+const defaultLockTimeout = time.Minute
+
+var (
+	// ErrLockTimeout 等待遷移鎖逾時（其他伺服器正在執行遷移）
+	ErrLockTimeout = errors.New("等待資料表遷移鎖逾時")
+	// ErrIrreversible 遷移沒有提供 Down，無法回滾
+	ErrIrreversible = errors.New("遷移無法回滾")
+)
+
+// Migration 單一版本的資料表遷移
+// Up/Down 與版本紀錄在同一個交易中執行，但 MySQL 的 DDL 會隱含提交，失敗時可能留下套用一半的結構且沒有版本紀錄
+// 因此每個遷移應盡量只做一件事並可重複執行（先檢查資料表、欄位與索引是否存在），需要檢查或整理資料時在異動結構前完成
+// 遷移內容不可引用 models 中的結構，應使用當時的結構快照，避免模型日後修改影響既有遷移
+type Migration struct {
+	Version     uint   // 版本號，依序遞增且不可重複
+	Description string // 遷移說明
+	Up          func(tx *gorm.DB) error
+	Down        func(tx *gorm.DB) error // nil 表示無法回滾
+}
+
+// Status 遷移的套用狀態
+type Status struct {
+	Version     uint
+	Description string
+	AppliedAt   *time.Time // nil 表示尚未套用
+	Unknown     bool       // 資料庫中有紀錄，但此版本程式沒有對應的遷移（通常為較新版本已部署）
+}
+
+// schemaMigration 已套用的遷移紀錄
+type schemaMigration struct {
+	Version     uint   `gorm:"primaryKey;autoIncrement:false"`
+	Description string `gorm:"size:255"`
+	AppliedAt   time.Time
+}
+
+// TableName 遷移紀錄資料表名稱
+func (schemaMigration) TableName() string {
+	return "schema_migrations"
+}
+
+var (
+	registry   []Migration
+	registryMu sync.Mutex
+)
+
+// register 註冊遷移（於各遷移檔案的 init 中呼叫），版本重複時 panic
+func register(m Migration) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+
+	for _, existing := range registry {
+		if existing.Version == m.Version {
+			panic(fmt.Sprintf("重複的資料表遷移版本: %d", m.Version))
+		}
+	}
+	registry = append(registry, m)
+}
+
+// All 取得所有已註冊的遷移，依版本排序
+func All() []Migration {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+
+	migrations := append([]Migration(nil), registry...)
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+	return migrations
+}
+
+// Migrator 執行資料表遷移，同一時間只有一個程序能執行（透過資料庫鎖協調）
+type Migrator struct {
+	db          *gorm.DB
+	migrations  []Migration
+	lockTimeout time.Duration
+}
+
+// New 建立遷移執行器，db 需為 Master 連線；lockTimeout 為等待其他程序完成遷移的最長時間
+func New(db *gorm.DB, migrations []Migration, lockTimeout time.Duration) *Migrator {
+	if lockTimeout <= 0 {
+		lockTimeout = defaultLockTimeout
+	}
+
+	sorted := append([]Migration(nil), migrations...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Version < sorted[j].Version })
+
+	return &Migrator{
+		db:          db,
+		migrations:  sorted,
+		lockTimeout: lockTimeout,
+	}
+}
+
+// Up 依序套用所有尚未套用的遷移，回傳本次套用的遷移
+func (m *Migrator) Up(ctx context.Context) ([]Migration, error) {
+	var applied []Migration
+
+	err := m.withLock(ctx, func(db *gorm.DB) error {
+		done, err := m.appliedVersions(db)
+		if err != nil {
+			return err
+		}
+
+		for _, migration := range m.migrations {
+			if _, ok := done[migration.Version]; ok {
+				continue
+			}
+
+			logger.Infof("套用資料表遷移 %d: %s", migration.Version, migration.Description)
+			err := db.Transaction(func(tx *gorm.DB) error {
+				if err := migration.Up(tx); err != nil {
+					return err
+				}
+				return tx.Create(&schemaMigration{
+					Version:     migration.Version,
+					Description: migration.Description,
+					AppliedAt:   time.Now(),
+				}).Error
+			})
+			if err != nil {
+				return fmt.Errorf("套用資料表遷移 %d 失敗: %w", migration.Version, err)
+			}
+			applied = append(applied, migration)
+		}
+		return nil
+	})
+
+	return applied, err
+}
+
+// Down 依版本由新到舊回滾最近 steps 個已套用的遷移，回傳本次回滾的遷移
+func (m *Migrator) Down(ctx context.Context, steps int) ([]Migration, error) {
+	if steps <= 0 {
+		return nil, nil
+	}
+
+	var rolledBack []Migration
+
+	err := m.withLock(ctx, func(db *gorm.DB) error {
+		var records []schemaMigration
+		if err := db.Order("version DESC").Limit(steps).Find(&records).Error; err != nil {
+			return fmt.Errorf("讀取遷移紀錄失敗: %w", err)
+		}
+
+		for _, record := range records {
+			migration, ok := m.find(record.Version)
+			if !ok {
+				return fmt.Errorf("找不到版本 %d 的遷移定義，無法回滾", record.Version)
+			}
+			if migration.Down == nil {
+				return fmt.Errorf("%w: 版本 %d", ErrIrreversible, record.Version)
+			}
+
+			logger.Infof("回滾資料表遷移 %d: %s", migration.Version, migration.Description)
+			err := db.Transaction(func(tx *gorm.DB) error {
+				if err := migration.Down(tx); err != nil {
+					return err
+				}
+				return tx.Delete(&schemaMigration{}, record.Version).Error
+			})
+			if err != nil {
+				return fmt.Errorf("回滾資料表遷移 %d 失敗: %w", migration.Version, err)
+			}
+			rolledBack = append(rolledBack, migration)
+		}
+		return nil
+	})
+
+	return rolledBack, err
+}
+
+// Status 取得所有遷移的套用狀態，依版本排序
+func (m *Migrator) Status(ctx context.Context) ([]Status, error) {
+	db := m.db.WithContext(ctx)
+	if err := db.AutoMigrate(&schemaMigration{}); err != nil {
+		return nil, fmt.Errorf("建立遷移紀錄資料表失敗: %w", err)
+	}
+
+	var records []schemaMigration
+	if err := db.Order("version").Find(&records).Error; err != nil {
+		return nil, fmt.Errorf("讀取遷移紀錄失敗: %w", err)
+	}
+
+	applied := make(map[uint]schemaMigration, len(records))
+	for _, record := range records {
+		applied[record.Version] = record
+	}
+
+	statuses := make([]Status, 0, len(m.migrations)+len(records))
+	for _, migration := range m.migrations {
+		status := Status{Version: migration.Version, Description: migration.Description}
+		if record, ok := applied[migration.Version]; ok {
+			status.AppliedAt = &record.AppliedAt
+			delete(applied, migration.Version)
+		}
+		statuses = append(statuses, status)
+	}
+	for _, record := range applied {
+		statuses = append(statuses, Status{
+			Version:     record.Version,
+			Description: record.Description,
+			AppliedAt:   &record.AppliedAt,
+			Unknown:     true,
+		})
+	}
+
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i].Version < statuses[j].Version })
+	return statuses, nil
+}
+
+// appliedVersions 建立遷移紀錄資料表並取得已套用的版本
+// 資料庫中有此版本程式不認識的版本時僅記錄警告（滾動部署時新版本可能已先完成遷移）
+func (m *Migrator) appliedVersions(db *gorm.DB) (map[uint]struct{}, error) {
+	if err := db.AutoMigrate(&schemaMigration{}); err != nil {
+		return nil, fmt.Errorf("建立遷移紀錄資料表失敗: %w", err)
+	}
+
+	var versions []uint
+	if err := db.Model(&schemaMigration{}).Pluck("version", &versions).Error; err != nil {
+		return nil, fmt.Errorf("讀取遷移紀錄失敗: %w", err)
+	}
+
+	done := make(map[uint]struct{}, len(versions))
+	for _, v := range versions {
+		done[v] = struct{}{}
+		if _, ok := m.find(v); !ok {
+			logger.Warnf("資料庫已套用未知的遷移版本 %d，可能已部署較新版本的程式", v)
+		}
+	}
+	return done, nil
+}
+
+// find 依版本取得遷移定義
+func (m *Migrator) find(version uint) (Migration, bool) {
+	for _, migration := range m.migrations {
+		if migration.Version == version {
+			return migration, true
+		}
+	}
+	return Migration{}, false
+}
@@ -0,0 +1,191 @@
+package migration
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/andy2kuo/TourHelper/internal/config"
+	"github.com/andy2kuo/TourHelper/internal/logger"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	gormLogger "gorm.io/gorm/logger"
+)
+
+func TestMain(m *testing.M) {
+	// 遷移過程會寫入日誌，於暫存目錄初始化 Logger 避免在專案目錄產生日誌檔
+	dir, err := os.MkdirTemp("", "migration-test")
+	if err != nil {
+		panic(err)
+	}
+	if err := os.Chdir(dir); err != nil {
+		panic(err)
+	}
+	if err := logger.Init("migration_test", "dev", config.LogConfig{Level: "error"}); err != nil {
+		panic(err)
+	}
+
+	code := m.Run()
+	os.RemoveAll(dir)
+	os.Exit(code)
+}
+
+// newTestDB 建立暫存 SQLite 資料庫
+func newTestDB(t *testing.T) *gorm.DB {
+	t.Helper()
+
+	db, err := gorm.Open(sqlite.Open(filepath.Join(t.TempDir(), "migration.db")), &gorm.Config{Logger: gormLogger.Discard})
+	if err != nil {
+		t.Fatalf("開啟 SQLite 失敗: %v", err)
+	}
+	return db
+}
+
+func TestMigratorUpDown(t *testing.T) {
+	ctx := context.Background()
+	db := newTestDB(t)
+	migrator := New(db, All(), 0)
+
+	applied, err := migrator.Up(ctx)
+	if err != nil {
+		t.Fatalf("Up() 失敗: %v", err)
+	}
+	if len(applied) != len(All()) {
+		t.Errorf("Up() 套用 %d 個遷移, 期望 %d", len(applied), len(All()))
+	}
+	for _, table := range []string{"users", "user_preferences", "destinations", "tags", "destination_tags", "search_histories", "weather_data", "credentials", "user_identities", "login_lockouts"} {
+		if !db.Migrator().HasTable(table) {
+			t.Errorf("Up() 後缺少資料表 %s", table)
+		}
+	}
+	if !db.Migrator().HasIndex("users", "idx_users_platform_external_id") {
+		t.Errorf("Up() 後缺少索引 idx_users_platform_external_id")
+	}
+	if !db.Migrator().HasIndex("weather_data", "idx_weather_data_grid_key") {
+		t.Errorf("Up() 後缺少索引 idx_weather_data_grid_key")
+	}
+	if !db.Migrator().HasColumn("user_identities", "linked_at") {
+		t.Errorf("Up() 後 user_identities 缺少欄位 linked_at")
+	}
+
+	// 重複執行不應套用任何遷移
+	applied, err = migrator.Up(ctx)
+	if err != nil {
+		t.Fatalf("重複執行 Up() 失敗: %v", err)
+	}
+	if len(applied) != 0 {
+		t.Errorf("重複執行 Up() 套用 %d 個遷移, 期望 0", len(applied))
+	}
+
+	statuses, err := migrator.Status(ctx)
+	if err != nil {
+		t.Fatalf("Status() 失敗: %v", err)
+	}
+	for _, status := range statuses {
+		if status.AppliedAt == nil {
+			t.Errorf("Status() 版本 %d 未套用, 期望已套用", status.Version)
+		}
+	}
+
+	rolledBack, err := migrator.Down(ctx, len(All()))
+	if err != nil {
+		t.Fatalf("Down() 失敗: %v", err)
+	}
+	if len(rolledBack) != len(All()) {
+		t.Errorf("Down() 回滾 %d 個遷移, 期望 %d", len(rolledBack), len(All()))
+	}
+	if db.Migrator().HasTable("users") {
+		t.Errorf("Down() 後資料表 users 仍存在")
+	}
+
+	statuses, err = migrator.Status(ctx)
+	if err != nil {
+		t.Fatalf("Status() 失敗: %v", err)
+	}
+	for _, status := range statuses {
+		if status.AppliedAt != nil {
+			t.Errorf("Status() 版本 %d 已套用, 期望未套用", status.Version)
+		}
+	}
+}
+
+func TestMigratorFailure(t *testing.T) {
+	ctx := context.Background()
+	db := newTestDB(t)
+
+	errBroken := errors.New("broken")
+	migrations := []Migration{
+		{Version: 1, Description: "建立資料表", Up: func(tx *gorm.DB) error {
+			return tx.Exec("CREATE TABLE items (id INTEGER PRIMARY KEY)").Error
+		}},
+		{Version: 2, Description: "失敗的遷移", Up: func(tx *gorm.DB) error {
+			return errBroken
+		}},
+	}
+
+	applied, err := New(db, migrations, 0).Up(ctx)
+	if !errors.Is(err, errBroken) {
+		t.Fatalf("Up() error = %v, 期望 %v", err, errBroken)
+	}
+	if len(applied) != 1 {
+		t.Errorf("Up() 套用 %d 個遷移, 期望 1", len(applied))
+	}
+
+	statuses, err := New(db, migrations, 0).Status(ctx)
+	if err != nil {
+		t.Fatalf("Status() 失敗: %v", err)
+	}
+	if statuses[0].AppliedAt == nil || statuses[1].AppliedAt != nil {
+		t.Errorf("Status() 僅版本 1 應為已套用")
+	}
+
+	// 沒有 Down 的遷移無法回滾
+	if _, err := New(db, migrations, 0).Down(ctx, 1); !errors.Is(err, ErrIrreversible) {
+		t.Errorf("Down() error = %v, 期望 %v", err, ErrIrreversible)
+	}
+}
+
+func TestInitialSchemaLegacyData(t *testing.T) {
+	ctx := context.Background()
+
+	// 舊版 AutoMigrate 建立的資料表沒有唯一索引
+	legacy := func(t *testing.T) *gorm.DB {
+		db := newTestDB(t)
+		err := db.Exec(`CREATE TABLE users (id INTEGER PRIMARY KEY, created_at DATETIME, updated_at DATETIME, deleted_at DATETIME,
+			external_id TEXT NOT NULL, platform TEXT NOT NULL, username TEXT, display_name TEXT)`).Error
+		if err == nil {
+			err = db.Exec(`CREATE TABLE user_preferences (id INTEGER PRIMARY KEY, created_at DATETIME, updated_at DATETIME, deleted_at DATETIME,
+				user_id INTEGER, max_distance REAL, preferred_weather TEXT, preferred_category TEXT, min_rating REAL, budget TEXT)`).Error
+		}
+		if err != nil {
+			t.Fatalf("建立舊版資料表失敗: %v", err)
+		}
+		return db
+	}
+	initial := All()[:1]
+
+	// 重複的偏好設定只保留最新的一筆
+	db := legacy(t)
+	db.Exec("INSERT INTO users (id, external_id, platform) VALUES (1, 'U1', 'line')")
+	db.Exec("INSERT INTO user_preferences (id, user_id, budget) VALUES (1, 1, 'low'), (2, 1, 'high'), (3, 2, 'medium')")
+	if _, err := New(db, initial, 0).Up(ctx); err != nil {
+		t.Fatalf("Up() 失敗: %v", err)
+	}
+	var budgets []string
+	db.Raw("SELECT budget FROM user_preferences ORDER BY id").Scan(&budgets)
+	if len(budgets) != 2 || budgets[0] != "high" {
+		t.Errorf("偏好設定 = %v, 期望每位使用者保留最新的一筆", budgets)
+	}
+
+	// 重複的使用者在異動資料表前回報錯誤
+	db = legacy(t)
+	db.Exec("INSERT INTO users (id, external_id, platform) VALUES (1, 'U1', 'line'), (2, 'U1', 'line')")
+	if _, err := New(db, initial, 0).Up(ctx); err == nil {
+		t.Fatal("Up(重複的使用者) 應回傳錯誤")
+	}
+	if db.Migrator().HasTable("destinations") {
+		t.Error("Up(重複的使用者) 失敗後不應建立其他資料表")
+	}
+}
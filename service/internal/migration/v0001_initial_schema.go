@@ -0,0 +1,157 @@
+package migration
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/andy2kuo/TourHelper/internal/logger"
+	"gorm.io/gorm"
+)
+
+// 版本 1：建立初始資料表（使用者、偏好設定、景點、標籤、搜尋歷史、天氣快取）
+// 以 AutoMigrate 建立，既有以 AutoMigrate 建立的資料庫套用時只會補齊缺少的欄位與索引
+// 舊版的偏好設定沒有唯一索引，建立唯一索引前先移除重複的資料；重複的使用者無法自動處理，在異動資料表前回報錯誤
+func init() {
+	register(Migration{
+		Version:     1,
+		Description: "建立初始資料表",
+		Up: func(tx *gorm.DB) error {
+			type Tag struct {
+				gorm.Model
+				Name string `gorm:"uniqueIndex;not null"`
+			}
+
+			type Destination struct {
+				gorm.Model
+				Name        string `gorm:"not null"`
+				Description string `gorm:"type:text"`
+				Category    string
+				Latitude    float64 `gorm:"not null"`
+				Longitude   float64 `gorm:"not null"`
+				Rating      float64 `gorm:"default:0"`
+				Address     string
+				City        string
+				Region      string
+				Country     string `gorm:"default:Taiwan"`
+				ImageURL    string
+				Website     string
+				Tags        []Tag `gorm:"many2many:destination_tags;"`
+			}
+
+			type UserPreferences struct {
+				gorm.Model
+				UserID            uint    `gorm:"uniqueIndex"`
+				MaxDistance       float64 `gorm:"default:50"`
+				PreferredWeather  string  `gorm:"default:any"`
+				PreferredCategory string
+				MinRating         float64 `gorm:"default:3.0"`
+				Budget            string  `gorm:"default:medium"`
+			}
+
+			type SearchHistory struct {
+				gorm.Model
+				UserID           uint
+				SearchLatitude   float64
+				SearchLongitude  float64
+				SearchLocation   string
+				Weather          string
+				RecommendationID uint
+				Destination      Destination `gorm:"foreignKey:RecommendationID"`
+				Clicked          bool        `gorm:"default:false"`
+				Visited          bool        `gorm:"default:false"`
+			}
+
+			type User struct {
+				gorm.Model
+				ExternalID    string `gorm:"uniqueIndex:idx_users_platform_external_id,priority:2;not null"`
+				Platform      string `gorm:"uniqueIndex:idx_users_platform_external_id,priority:1;not null"`
+				Username      string
+				DisplayName   string
+				Preferences   UserPreferences `gorm:"foreignKey:UserID"`
+				SearchHistory []SearchHistory `gorm:"foreignKey:UserID"`
+			}
+
+			type WeatherData struct {
+				gorm.Model
+				GridKey     string `gorm:"uniqueIndex;size:128;not null"`
+				Latitude    float64
+				Longitude   float64
+				Temperature float64
+				Condition   string
+				Description string
+				Humidity    int
+				WindSpeed   float64
+				FetchedAt   time.Time
+				ExpireAt    time.Time
+			}
+
+			if err := verifyUniqueUsers(tx); err != nil {
+				return err
+			}
+			if err := dedupePreferences(tx); err != nil {
+				return err
+			}
+
+			return tx.AutoMigrate(
+				&User{},
+				&UserPreferences{},
+				&Destination{},
+				&Tag{},
+				&SearchHistory{},
+				&WeatherData{},
+			)
+		},
+		Down: func(tx *gorm.DB) error {
+			return tx.Migrator().DropTable(
+				"destination_tags",
+				"search_histories",
+				"user_preferences",
+				"weather_data",
+				"users",
+				"destinations",
+				"tags",
+			)
+		},
+	})
+}
+
+// verifyUniqueUsers 確認既有的使用者沒有重複的平台與外部 ID，否則無法建立 idx_users_platform_external_id
+func verifyUniqueUsers(tx *gorm.DB) error {
+	if !tx.Migrator().HasTable("users") {
+		return nil
+	}
+
+	var duplicates []struct {
+		Platform   string
+		ExternalID string
+	}
+	err := tx.Raw(`SELECT platform, external_id FROM users
+		GROUP BY platform, external_id HAVING COUNT(*) > 1`).Scan(&duplicates).Error
+	if err != nil {
+		return fmt.Errorf("檢查重複的使用者失敗: %w", err)
+	}
+	if len(duplicates) > 0 {
+		return fmt.Errorf("users 有 %d 組重複的平台與外部 ID（例如 %s/%s），需先合併重複的使用者",
+			len(duplicates), duplicates[0].Platform, duplicates[0].ExternalID)
+	}
+	return nil
+}
+
+// dedupePreferences 每位使用者只保留最新（ID 最大）的一筆偏好設定，讓 user_preferences.user_id 可建立唯一索引
+func dedupePreferences(tx *gorm.DB) error {
+	if !tx.Migrator().HasTable("user_preferences") {
+		return nil
+	}
+
+	// 以衍生資料表包住子查詢，MySQL 不允許在 DELETE 的子查詢中直接讀取同一個資料表
+	result := tx.Exec(`DELETE FROM user_preferences WHERE user_id IS NOT NULL AND id NOT IN (
+		SELECT id FROM (SELECT MAX(id) AS id FROM user_preferences GROUP BY user_id) AS latest
+	)`)
+	if result.Error != nil {
+		return fmt.Errorf("移除重複的偏好設定失敗: %w", result.Error)
+	}
+	if result.RowsAffected > 0 {
+		logger.Warnf("已移除 %d 筆重複的偏好設定", result.RowsAffected)
+	}
+	return nil
+}
@@ -0,0 +1,21 @@
+package migration
+
+import "gorm.io/gorm"
+
+// 版本 2：移除舊版 AutoMigrate 建立的 users.external_id 唯一索引
+// 外部 ID 改為與平台組合唯一（idx_users_platform_external_id），不同平台可使用相同的外部 ID
+func init() {
+	register(Migration{
+		Version:     2,
+		Description: "移除 users.external_id 單欄唯一索引",
+		Up: func(tx *gorm.DB) error {
+			if !tx.Migrator().HasIndex("users", "idx_users_external_id") {
+				return nil
+			}
+			return tx.Migrator().DropIndex("users", "idx_users_external_id")
+		},
+		Down: func(tx *gorm.DB) error {
+			return tx.Exec("CREATE UNIQUE INDEX idx_users_external_id ON users (external_id)").Error
+		},
+	})
+}
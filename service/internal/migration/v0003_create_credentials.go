@@ -0,0 +1,24 @@
+package migration
+
+import "gorm.io/gorm"
+
+// 版本 3：建立網頁會員的帳號密碼資料表
+func init() {
+	register(Migration{
+		Version:     3,
+		Description: "建立 credentials 資料表",
+		Up: func(tx *gorm.DB) error {
+			type Credential struct {
+				gorm.Model
+				UserID       uint   `gorm:"uniqueIndex;not null"`
+				Username     string `gorm:"uniqueIndex;size:32;not null"`
+				PasswordHash string `gorm:"not null"`
+			}
+
+			return tx.AutoMigrate(&Credential{})
+		},
+		Down: func(tx *gorm.DB) error {
+			return tx.Migrator().DropTable("credentials")
+		},
+	})
+}
@@ -0,0 +1,24 @@
+package migration
+
+import "gorm.io/gorm"
+
+// 版本 4：帳號密碼加上角色，營運人員與管理員以同一組帳號登入後台
+func init() {
+	type Credential struct {
+		Role string `gorm:"size:16;not null;default:member"`
+	}
+
+	register(Migration{
+		Version:     4,
+		Description: "credentials 新增 role 欄位",
+		Up: func(tx *gorm.DB) error {
+			if tx.Migrator().HasColumn(&Credential{}, "Role") {
+				return nil
+			}
+			return tx.Migrator().AddColumn(&Credential{}, "Role")
+		},
+		Down: func(tx *gorm.DB) error {
+			return tx.Migrator().DropColumn(&Credential{}, "Role")
+		},
+	})
+}
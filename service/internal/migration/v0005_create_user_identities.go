@@ -0,0 +1,39 @@
+package migration
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// 版本 5：建立會員的平台身分資料表，讓一位會員連結多個平台帳號
+// 既有會員以建立時的平台與外部 ID 各補上一筆身分（包含已刪除的會員，重新加入時才能還原），連結時間為會員的建立時間
+func init() {
+	register(Migration{
+		Version:     5,
+		Description: "建立 user_identities 資料表",
+		Up: func(tx *gorm.DB) error {
+			type UserIdentity struct {
+				gorm.Model
+				UserID      uint   `gorm:"index;not null"`
+				Platform    string `gorm:"uniqueIndex:idx_user_identities_platform_external_id,priority:1;size:16;not null"`
+				ExternalID  string `gorm:"uniqueIndex:idx_user_identities_platform_external_id,priority:2;size:64;not null"`
+				Username    string
+				DisplayName string
+				LinkedAt    time.Time `gorm:"index;not null"`
+			}
+
+			if err := tx.AutoMigrate(&UserIdentity{}); err != nil {
+				return err
+			}
+			return tx.Exec(`INSERT INTO user_identities (created_at, updated_at, user_id, platform, external_id, username, display_name, linked_at)
+				SELECT created_at, updated_at, id, platform, external_id, username, display_name, created_at FROM users
+				WHERE NOT EXISTS (
+					SELECT 1 FROM user_identities i WHERE i.platform = users.platform AND i.external_id = users.external_id
+				)`).Error
+		},
+		Down: func(tx *gorm.DB) error {
+			return tx.Migrator().DropTable("user_identities")
+		},
+	})
+}
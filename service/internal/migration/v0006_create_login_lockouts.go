@@ -0,0 +1,32 @@
+package migration
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// 版本 6：建立登入鎖定記錄資料表
+func init() {
+	register(Migration{
+		Version:     6,
+		Description: "建立 login_lockouts 資料表",
+		Up: func(tx *gorm.DB) error {
+			type LoginLockout struct {
+				gorm.Model
+				Scope       string `gorm:"index:idx_login_lockouts_subject,priority:1;size:16;not null"`
+				Kind        string `gorm:"index:idx_login_lockouts_subject,priority:2;size:16;not null"`
+				Subject     string `gorm:"index:idx_login_lockouts_subject,priority:3;size:64;not null"`
+				ClientIP    string `gorm:"size:64"`
+				Failures    int
+				LockedUntil time.Time `gorm:"index"`
+				UnlockedAt  *time.Time
+				UnlockedBy  string `gorm:"size:64"`
+			}
+			return tx.AutoMigrate(&LoginLockout{})
+		},
+		Down: func(tx *gorm.DB) error {
+			return tx.Migrator().DropTable("login_lockouts")
+		},
+	})
+}
@@ -6,21 +6,42 @@ import (
 	"gorm.io/gorm"
 )
 
-// User 使用者模型
+// 使用者來源平台
+const (
+	PlatformLine     = "line"
+	PlatformTelegram = "telegram"
+	PlatformWeb      = "web"
+)
+
+// User 使用者模型（會員）
+// Platform 與 ExternalID 為建立會員時的平台身分；會員可再連結其他平台的身分（UserIdentity），依平台身分查詢會員時以 UserIdentity 為準
 type User struct {
 	gorm.Model
-	ExternalID    string `gorm:"uniqueIndex;not null"` // Line ID 或 Telegram ID
-	Platform      string `gorm:"not null"`             // line, telegram, web
+	ExternalID    string `gorm:"uniqueIndex:idx_users_platform_external_id,priority:2;not null"` // Line ID 或 Telegram ID
+	Platform      string `gorm:"uniqueIndex:idx_users_platform_external_id,priority:1;not null"` // line, telegram, web
 	Username      string
 	DisplayName   string
 	Preferences   UserPreferences `gorm:"foreignKey:UserID"`
 	SearchHistory []SearchHistory `gorm:"foreignKey:UserID"`
+	Identities    []UserIdentity  `gorm:"foreignKey:UserID"`
+}
+
+// UserIdentity 會員在各平台的身分，同一人的 LINE、Telegram、網頁帳號可連結到同一位會員
+// 同一平台的 ExternalID 不可重複；連結或合併會員時只會改變 UserID 與 LinkedAt，不會刪除
+type UserIdentity struct {
+	gorm.Model
+	UserID      uint      `gorm:"index;not null"`
+	Platform    string    `gorm:"uniqueIndex:idx_user_identities_platform_external_id,priority:1;size:16;not null"` // line, telegram, web
+	ExternalID  string    `gorm:"uniqueIndex:idx_user_identities_platform_external_id,priority:2;size:64;not null"` // 該平台的使用者 ID
+	Username    string    // 該平台最新的使用者名稱
+	DisplayName string    // 該平台最新的顯示名稱
+	LinkedAt    time.Time `gorm:"index;not null"` // 連結到目前會員的時間（建立身分或合併會員時）
 }
 
 // UserPreferences 使用者偏好設定
 type UserPreferences struct {
 	gorm.Model
-	UserID            uint
+	UserID            uint    `gorm:"uniqueIndex"` // 每位使用者只有一筆偏好設定
 	MaxDistance       float64 `gorm:"default:50"`  // 最大距離（公里）
 	PreferredWeather  string  `gorm:"default:any"` // sunny, cloudy, rainy, any
 	PreferredCategory string  // nature, culture, food, shopping, adventure
@@ -70,23 +91,48 @@ type SearchHistory struct {
 // WeatherData 天氣資料快取
 type WeatherData struct {
 	gorm.Model
-	Latitude    float64
-	Longitude   float64
+	GridKey     string  `gorm:"uniqueIndex;size:128;not null"` // 位置鍵值（格子座標與縣市、鄉鎮市區），見 weather.LocationKey
+	Latitude    float64 // 對齊格子後的緯度
+	Longitude   float64 // 對齊格子後的經度
 	Temperature float64
 	Condition   string // sunny, cloudy, rainy, snowy
+	Description string
 	Humidity    int
 	WindSpeed   float64
 	FetchedAt   time.Time
 	ExpireAt    time.Time
 }
 
+// Credential 網頁會員的帳號密碼，每位使用者最多一筆
+type Credential struct {
+	gorm.Model
+	UserID       uint   `gorm:"uniqueIndex;not null"`
+	Username     string `gorm:"uniqueIndex;size:32;not null"`    // 登入帳號（小寫）
+	PasswordHash string `gorm:"not null"`                        // PHC 格式的雜湊值，例如 $argon2id$v=19$...
+	Role         string `gorm:"size:16;not null;default:member"` // member, operator, admin, super_admin（後三者可登入後台）
+}
+
+// LoginLockout 登入失敗次數過多造成的鎖定記錄，供管理員查詢與解除
+type LoginLockout struct {
+	gorm.Model
+	Scope       string     `gorm:"index:idx_login_lockouts_subject,priority:1;size:16;not null"` // member（Lobby）, admin（Backend）
+	Kind        string     `gorm:"index:idx_login_lockouts_subject,priority:2;size:16;not null"` // username, ip
+	Subject     string     `gorm:"index:idx_login_lockouts_subject,priority:3;size:64;not null"` // 被鎖定的帳號（小寫）或 IP
+	ClientIP    string     `gorm:"size:64"`                                                      // 觸發鎖定的最後一次嘗試的來源 IP
+	Failures    int        // 鎖定前滑動視窗內的失敗次數
+	LockedUntil time.Time  `gorm:"index"`
+	UnlockedAt  *time.Time // 管理員提前解除的時間
+	UnlockedBy  string     `gorm:"size:64"` // 解除鎖定的管理員 ID
+}
+
 // RecommendationRequest 推薦請求結構
 type RecommendationRequest struct {
-	Latitude    float64 `json:"latitude" binding:"required"`
-	Longitude   float64 `json:"longitude" binding:"required"`
-	UserID      uint    `json:"user_id,omitempty"`
-	Category    string  `json:"category,omitempty"`
-	MaxDistance float64 `json:"max_distance,omitempty"`
+	Latitude    float64    `json:"latitude" binding:"required"`
+	Longitude   float64    `json:"longitude" binding:"required"`
+	UserID      uint       `json:"user_id,omitempty"`
+	Category    string     `json:"category,omitempty"`
+	MaxDistance float64    `json:"max_distance,omitempty"`
+	TargetTime  *time.Time `json:"target_time,omitempty"` // 預計出發時間，未指定表示現在
 }
 
 // RecommendationResponse 推薦回應結構
@@ -108,7 +154,29 @@ type DestinationWithDistance struct {
 // WeatherInfo 天氣資訊
 type WeatherInfo struct {
 	Temperature float64 `json:"temperature"`
-	Condition   string  `json:"condition"`
+	Condition   string  `json:"condition"` // sunny, cloudy, rainy, snowy
 	Humidity    int     `json:"humidity"`
+	WindSpeed   float64 `json:"wind_speed"` // 風速（m/s）
 	Description string  `json:"description"`
 }
+
+// WeatherForecast 天氣預報
+type WeatherForecast struct {
+	Hourly []ForecastPeriod `json:"hourly"` // 逐時（或逐數小時）預報，依時間排序
+	Daily  []ForecastPeriod `json:"daily"`  // 逐日預報，依日期排序
+}
+
+// ForecastPeriod 單一預報時段
+type ForecastPeriod struct {
+	StartTime time.Time `json:"start_time"`
+	EndTime   time.Time `json:"end_time"`
+	WeatherInfo
+	TemperatureMin      float64 `json:"temperature_min"`
+	TemperatureMax      float64 `json:"temperature_max"`
+	PrecipitationChance int     `json:"precipitation_chance"` // 降雨機率 (0-100)
+}
+
+// Contains 檢查時間是否落在此預報時段內
+func (p ForecastPeriod) Contains(t time.Time) bool {
+	return !t.Before(p.StartTime) && t.Before(p.EndTime)
+}
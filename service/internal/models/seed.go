@@ -1,76 +1,11 @@
 package models
 
 import (
-	"fmt"
 	"log"
 
-	"gorm.io/driver/mysql"
-	"gorm.io/driver/postgres"
-	"gorm.io/driver/sqlite"
 	"gorm.io/gorm"
-	"gorm.io/gorm/logger"
 )
 
-// DatabaseConfig 資料庫設定
-type DatabaseConfig struct {
-	Type     string
-	Host     string
-	Port     string
-	User     string
-	Password string
-	DBName   string
-	SSLMode  string
-}
-
-// InitDB 初始化資料庫連線
-func InitDB(config DatabaseConfig) (*gorm.DB, error) {
-	var db *gorm.DB
-	var err error
-
-	gormConfig := &gorm.Config{
-		Logger: logger.Default.LogMode(logger.Info),
-	}
-
-	switch config.Type {
-	case "sqlite":
-		db, err = gorm.Open(sqlite.Open(config.DBName), gormConfig)
-	case "mysql":
-		dsn := fmt.Sprintf("%s:%s@tcp(%s:%s)/%s?charset=utf8mb4&parseTime=True&loc=Local",
-			config.User, config.Password, config.Host, config.Port, config.DBName)
-		db, err = gorm.Open(mysql.Open(dsn), gormConfig)
-	case "postgres":
-		dsn := fmt.Sprintf("host=%s user=%s password=%s dbname=%s port=%s sslmode=%s",
-			config.Host, config.User, config.Password, config.DBName, config.Port, config.SSLMode)
-		db, err = gorm.Open(postgres.Open(dsn), gormConfig)
-	default:
-		return nil, fmt.Errorf("不支援的資料庫類型: %s", config.Type)
-	}
-
-	if err != nil {
-		return nil, fmt.Errorf("連線資料庫失敗: %w", err)
-	}
-
-	// 自動遷移資料表結構
-	if err := autoMigrate(db); err != nil {
-		return nil, fmt.Errorf("資料表遷移失敗: %w", err)
-	}
-
-	log.Println("資料庫連線成功")
-	return db, nil
-}
-
-// autoMigrate 自動遷移所有資料表
-func autoMigrate(db *gorm.DB) error {
-	return db.AutoMigrate(
-		&User{},
-		&UserPreferences{},
-		&Destination{},
-		&Tag{},
-		&SearchHistory{},
-		&WeatherData{},
-	)
-}
-
 // SeedSampleData 填充範例資料（開發用）
 func SeedSampleData(db *gorm.DB) error {
 	// 檢查是否已有資料
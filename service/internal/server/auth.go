@@ -0,0 +1,115 @@
+package server
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/andy2kuo/TourHelper/internal/auth"
+	"github.com/andy2kuo/TourHelper/internal/logger"
+	"github.com/andy2kuo/TourHelper/internal/services"
+	"github.com/gin-gonic/gin"
+)
+
+// claimsContextKey 驗證通過的 Token 內容存放於 gin.Context 的鍵
+const claimsContextKey = "auth.claims"
+
+// RequireToken 驗證 Authorization: Bearer Token 的中介層
+// Token 無效或受眾不符時回應 401；指定 roles 時需具有其中任一角色，否則回應 403
+// 驗證通過後可透過 ClaimsFromContext 取得 Token 內容
+func RequireToken(audience string, roles ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		token := BearerToken(c)
+		if token == "" {
+			abortUnauthorized(c, "缺少 Token")
+			return
+		}
+
+		claims, err := services.Get().Token.Verify(c.Request.Context(), token, audience)
+		if err != nil {
+			abortUnauthorized(c, TokenErrorMessage(err))
+			return
+		}
+		if len(roles) > 0 && !claims.HasRole(roles...) {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{
+				"success": false,
+				"message": "權限不足",
+			})
+			return
+		}
+
+		c.Set(claimsContextKey, claims)
+		c.Next()
+	}
+}
+
+// ClaimsFromContext 取得 RequireToken 驗證通過的 Token 內容
+func ClaimsFromContext(c *gin.Context) (*auth.Claims, bool) {
+	value, ok := c.Get(claimsContextKey)
+	if !ok {
+		return nil, false
+	}
+	claims, ok := value.(*auth.Claims)
+	return claims, ok
+}
+
+// BearerToken 取得 Authorization 標頭中的 Bearer Token，沒有時回傳空字串
+func BearerToken(c *gin.Context) string {
+	scheme, token, ok := strings.Cut(c.GetHeader("Authorization"), " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") {
+		return ""
+	}
+	return strings.TrimSpace(token)
+}
+
+// TokenErrorMessage 將 Token 驗證錯誤轉為回應給客戶端的訊息（不透露驗證失敗的細節）
+func TokenErrorMessage(err error) string {
+	switch {
+	case errors.Is(err, auth.ErrTokenExpired):
+		return "Token 已過期"
+	case errors.Is(err, auth.ErrSessionRevoked):
+		return "已登出，請重新登入"
+	case errors.Is(err, auth.ErrInvalidToken), errors.Is(err, auth.ErrUnknownKey):
+		return "無效的 Token"
+	default:
+		logger.Errorf("驗證 Token 失敗: %v", err)
+		return "無效的 Token"
+	}
+}
+
+// SetRetryAfter 設定 Retry-After 標頭（秒，無條件進位）
+func SetRetryAfter(c *gin.Context, d time.Duration) {
+	if d <= 0 {
+		return
+	}
+	c.Header("Retry-After", strconv.FormatInt(int64((d+time.Second-1)/time.Second), 10))
+}
+
+// RegisterJWKS 註冊 /.well-known/jwks.json，公開 Token 驗證用的公鑰，讓其他伺服器離線驗證 Token
+func RegisterJWKS(r gin.IRoutes) {
+	r.GET("/.well-known/jwks.json", func(c *gin.Context) {
+		jwks, err := services.Get().Token.JWKS()
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{
+				"success": false,
+				"message": err.Error(),
+			})
+			return
+		}
+
+		// 公鑰輪替時新舊金鑰會同時存在一段時間，短暫快取不影響驗證
+		c.Header("Cache-Control", "public, max-age=300")
+		c.JSON(http.StatusOK, jwks)
+	})
+}
+
+// abortUnauthorized 回應 401 並中止後續處理
+func abortUnauthorized(c *gin.Context, message string) {
+	c.Header("WWW-Authenticate", `Bearer realm="tourhelper"`)
+	c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
+		"success": false,
+		"message": message,
+	})
+}
@@ -0,0 +1,226 @@
+package backend
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/andy2kuo/TourHelper/internal/dao"
+	"github.com/andy2kuo/TourHelper/internal/logger"
+	"github.com/andy2kuo/TourHelper/internal/models"
+	"github.com/andy2kuo/TourHelper/internal/services"
+	"github.com/andy2kuo/TourHelper/pkg/geo"
+	"github.com/gin-gonic/gin"
+)
+
+// DestinationRequest 建立或更新景點的請求結構
+type DestinationRequest struct {
+	Name        string   `json:"name" binding:"required"`
+	Description string   `json:"description"`
+	Category    string   `json:"category"` // nature, culture, food, shopping, adventure
+	Latitude    *float64 `json:"latitude" binding:"required"`
+	Longitude   *float64 `json:"longitude" binding:"required"`
+	Rating      float64  `json:"rating"`
+	Address     string   `json:"address"`
+	City        string   `json:"city"`
+	Region      string   `json:"region"`
+	Country     string   `json:"country"`
+	ImageURL    string   `json:"image_url"`
+	Website     string   `json:"website"`
+	Tags        []string `json:"tags"` // 更新時未提供表示保留原有標籤
+}
+
+// DestinationItem 景點回應結構
+type DestinationItem struct {
+	ID          uint      `json:"id"`
+	Name        string    `json:"name"`
+	Description string    `json:"description"`
+	Category    string    `json:"category"`
+	Latitude    float64   `json:"latitude"`
+	Longitude   float64   `json:"longitude"`
+	Rating      float64   `json:"rating"`
+	Address     string    `json:"address"`
+	City        string    `json:"city"`
+	Region      string    `json:"region"`
+	Country     string    `json:"country"`
+	ImageURL    string    `json:"image_url"`
+	Website     string    `json:"website"`
+	Tags        []string  `json:"tags"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// toModel 將請求轉換為景點模型
+func (r *DestinationRequest) toModel() *models.Destination {
+	dest := &models.Destination{
+		Name:        r.Name,
+		Description: r.Description,
+		Category:    r.Category,
+		Latitude:    *r.Latitude,
+		Longitude:   *r.Longitude,
+		Rating:      r.Rating,
+		Address:     r.Address,
+		City:        r.City,
+		Region:      r.Region,
+		Country:     r.Country,
+		ImageURL:    r.ImageURL,
+		Website:     r.Website,
+	}
+	if dest.Country == "" {
+		dest.Country = "Taiwan"
+	}
+
+	if r.Tags != nil {
+		dest.Tags = make([]models.Tag, 0, len(r.Tags))
+		for _, name := range r.Tags {
+			dest.Tags = append(dest.Tags, models.Tag{Name: name})
+		}
+	}
+	return dest
+}
+
+// newDestinationItem 將景點模型轉換為回應結構
+func newDestinationItem(dest *models.Destination) DestinationItem {
+	tags := make([]string, 0, len(dest.Tags))
+	for _, tag := range dest.Tags {
+		tags = append(tags, tag.Name)
+	}
+
+	return DestinationItem{
+		ID:          dest.ID,
+		Name:        dest.Name,
+		Description: dest.Description,
+		Category:    dest.Category,
+		Latitude:    dest.Latitude,
+		Longitude:   dest.Longitude,
+		Rating:      dest.Rating,
+		Address:     dest.Address,
+		City:        dest.City,
+		Region:      dest.Region,
+		Country:     dest.Country,
+		ImageURL:    dest.ImageURL,
+		Website:     dest.Website,
+		Tags:        tags,
+		CreatedAt:   dest.CreatedAt,
+		UpdatedAt:   dest.UpdatedAt,
+	}
+}
+
+// parseDestinationQuery 解析景點列表的查詢參數
+func parseDestinationQuery(c *gin.Context) (dao.DestinationQuery, error) {
+	query := dao.DestinationQuery{
+		DestinationFilter: dao.DestinationFilter{
+			Category: c.Query("category"),
+			City:     c.Query("city"),
+			Region:   c.Query("region"),
+		},
+		SortBy: c.Query("sort"),
+		Cursor: c.Query("cursor"),
+	}
+
+	if tags := c.Query("tags"); tags != "" {
+		query.Tags = strings.Split(tags, ",")
+	}
+
+	switch strings.ToLower(c.DefaultQuery("order", "asc")) {
+	case "asc":
+	case "desc":
+		query.Desc = true
+	default:
+		return query, fmt.Errorf("order 需為 asc 或 desc")
+	}
+
+	var err error
+	if query.MinRating, err = parseOptionalFloat(c, "min_rating"); err != nil {
+		return query, err
+	}
+	if query.MaxRating, err = parseOptionalFloat(c, "max_rating"); err != nil {
+		return query, err
+	}
+	if query.Limit, err = parseOptionalInt(c, "limit"); err != nil {
+		return query, err
+	}
+	if query.Offset, err = parseOptionalInt(c, "offset"); err != nil {
+		return query, err
+	}
+
+	if bbox := c.Query("bbox"); bbox != "" {
+		parts := strings.Split(bbox, ",")
+		if len(parts) != 4 {
+			return query, fmt.Errorf("bbox 格式需為 minLat,minLon,maxLat,maxLon")
+		}
+		values := make([]float64, 4)
+		for i, part := range parts {
+			if values[i], err = strconv.ParseFloat(strings.TrimSpace(part), 64); err != nil {
+				return query, fmt.Errorf("bbox 格式需為 minLat,minLon,maxLat,maxLon")
+			}
+		}
+		query.BoundingBox = &geo.BoundingBox{MinLat: values[0], MinLon: values[1], MaxLat: values[2], MaxLon: values[3]}
+	}
+
+	return query, nil
+}
+
+// parseOptionalFloat 解析選填的浮點數查詢參數
+func parseOptionalFloat(c *gin.Context, key string) (*float64, error) {
+	text := c.Query(key)
+	if text == "" {
+		return nil, nil
+	}
+	v, err := strconv.ParseFloat(text, 64)
+	if err != nil {
+		return nil, fmt.Errorf("%s 需為數字", key)
+	}
+	return &v, nil
+}
+
+// parseOptionalInt 解析選填的整數查詢參數，未提供時回傳 0
+func parseOptionalInt(c *gin.Context, key string) (int, error) {
+	text := c.Query(key)
+	if text == "" {
+		return 0, nil
+	}
+	v, err := strconv.Atoi(text)
+	if err != nil || v < 0 {
+		return 0, fmt.Errorf("%s 需為非負整數", key)
+	}
+	return v, nil
+}
+
+// parseDestinationID 解析路徑中的景點 ID，格式錯誤時直接回應 400
+func parseDestinationID(c *gin.Context) (uint, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil || id == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "無效的景點 ID",
+		})
+		return 0, false
+	}
+	return uint(id), true
+}
+
+// respondDestinationError 依景點服務的錯誤類型回應對應的 HTTP 狀態碼
+func respondDestinationError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrDestinationNotFound):
+		c.JSON(http.StatusNotFound, gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+	case errors.Is(err, services.ErrInvalidDestination):
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+	default:
+		logger.Errorf("景點操作失敗: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "景點操作失敗",
+		})
+	}
+}
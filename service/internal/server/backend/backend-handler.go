@@ -1,9 +1,17 @@
 package backend
 
 import (
+	"errors"
 	"net/http"
+	"strings"
 
+	"github.com/andy2kuo/TourHelper/internal/auth"
+	"github.com/andy2kuo/TourHelper/internal/dao"
+	"github.com/andy2kuo/TourHelper/internal/database"
 	"github.com/andy2kuo/TourHelper/internal/logger"
+	"github.com/andy2kuo/TourHelper/internal/models"
+	"github.com/andy2kuo/TourHelper/internal/server"
+	"github.com/andy2kuo/TourHelper/internal/services"
 	"github.com/gin-gonic/gin"
 )
 
@@ -15,11 +23,14 @@ type AdminLoginRequest struct {
 
 // AdminLoginResponse 後台管理員登入回應結構
 type AdminLoginResponse struct {
-	Success  bool   `json:"success"`
-	Message  string `json:"message"`
-	Token    string `json:"token,omitempty"`
-	AdminID  string `json:"admin_id,omitempty"`
-	RoleName string `json:"role_name,omitempty"` // 管理員角色: super_admin, admin, operator
+	Success          bool   `json:"success"`
+	Message          string `json:"message"`
+	Token            string `json:"token,omitempty"`              // Access Token
+	ExpiresAt        int64  `json:"expires_at,omitempty"`         // Access Token 過期時間（Unix 秒）
+	RefreshToken     string `json:"refresh_token,omitempty"`      // 換發 Token 用，每次換發後舊的即失效
+	RefreshExpiresAt int64  `json:"refresh_expires_at,omitempty"` // 未換發時工作階段的到期時間（Unix 秒）
+	AdminID          string `json:"admin_id,omitempty"`
+	RoleName         string `json:"role_name,omitempty"` // 管理員角色: super_admin, admin, operator
 }
 
 // handleAdminLogin 處理後台管理員登入驗證
@@ -37,55 +48,194 @@ func (s *BackendServer) handleAdminLogin(c *gin.Context) {
 		"username": req.Username,
 	}).Info("收到後台管理員登入請求")
 
-	// TODO: 實作後台管理員登入驗證邏輯
-	// 1. 驗證管理員帳號密碼
-	// 2. 檢查管理員權限等級
-	// 3. 產生 JWT Token (包含角色資訊)
-	// 4. 記錄登入日誌
-	// 5. 更新最後登入時間
-
-	// 暫時回傳成功(實際實作時需要真正的驗證)
-	c.JSON(http.StatusOK, AdminLoginResponse{
-		Success:  true,
-		Message:  "登入成功(功能待實作)",
-		Token:    "dummy_admin_token",
-		AdminID:  req.Username,
-		RoleName: "admin",
+	// 依帳號與來源 IP 限制失敗次數，失敗過多時需等待或暫時鎖定
+	var cred *models.Credential
+	err := services.Get().LoginThrottle.Attempt(c.Request.Context(), auth.AudienceAdmin, req.Username, c.ClientIP(), func() error {
+		var err error
+		cred, err = services.Get().Auth.AdminLogin(c.Request.Context(), req.Username, req.Password)
+		return err
 	})
+	if err != nil {
+		var throttleErr *services.LoginThrottleError
+		if errors.As(err, &throttleErr) {
+			logger.WithFields(map[string]interface{}{
+				"username":  req.Username,
+				"client_ip": c.ClientIP(),
+			}).Warn("後台管理員登入受限制")
+			server.SetRetryAfter(c, throttleErr.RetryAfter)
+			c.JSON(http.StatusTooManyRequests, AdminLoginResponse{Success: false, Message: err.Error()})
+			return
+		}
+		if errors.Is(err, services.ErrInvalidCredentials) {
+			c.JSON(http.StatusUnauthorized, AdminLoginResponse{Success: false, Message: err.Error()})
+			return
+		}
+		logger.Errorf("後台管理員登入失敗: %v", err)
+		c.JSON(http.StatusInternalServerError, AdminLoginResponse{Success: false, Message: "登入失敗，請稍後再試"})
+		return
+	}
+
+	pair, err := services.Get().Token.IssueAdmin(c.Request.Context(), cred.UserID, cred.Role)
+	if err != nil {
+		logger.Errorf("簽發管理員 Token 失敗: %v", err)
+		c.JSON(http.StatusInternalServerError, AdminLoginResponse{Success: false, Message: "登入失敗，請稍後再試"})
+		return
+	}
+
+	logger.WithFields(map[string]interface{}{
+		"admin_id": pair.Claims.Subject,
+		"role":     cred.Role,
+	}).Info("後台管理員登入成功")
+
+	respondAdminTokenPair(c, "登入成功", pair)
+}
+
+// AdminRefreshTokenRequest 換發管理員 Token 請求結構
+type AdminRefreshTokenRequest struct {
+	RefreshToken string `json:"refresh_token" binding:"required"`
+}
+
+// handleAdminRefreshToken 以 Refresh Token 換發新的管理員 Access Token 與 Refresh Token
+func (s *BackendServer) handleAdminRefreshToken(c *gin.Context) {
+	var req AdminRefreshTokenRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, AdminLoginResponse{
+			Success: false,
+			Message: "無效的請求格式",
+		})
+		return
+	}
+
+	pair, err := services.Get().Token.Refresh(c.Request.Context(), req.RefreshToken, auth.AudienceAdmin)
+	if err != nil {
+		if errors.Is(err, auth.ErrRefreshTokenReused) {
+			logger.WithFields(map[string]interface{}{
+				"client_ip": c.ClientIP(),
+			}).Warn("管理員 Refresh Token 被重複使用，已撤銷整個工作階段")
+		}
+		respondAdminTokenError(c, err)
+		return
+	}
+
+	respondAdminTokenPair(c, "Token 換發成功", pair)
+}
+
+// AdminLogoutRequest 後台管理員登出請求結構
+type AdminLogoutRequest struct {
+	RefreshToken string `json:"refresh_token"` // Access Token 已過期時以 Refresh Token 登出
 }
 
 // handleAdminLogout 處理後台管理員登出
+// 以 Authorization: Bearer 的 Access Token 或請求中的 Refresh Token 撤銷工作階段，撤銷後此工作階段的 Token 立即失效
 func (s *BackendServer) handleAdminLogout(c *gin.Context) {
-	// TODO: 實作後台管理員登出邏輯
-	// 1. 驗證 Token
-	// 2. 清除 Session
-	// 3. 將 Token 加入黑名單 (Redis)
-	// 4. 記錄登出日誌
+	var req AdminLogoutRequest
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, AdminLoginResponse{
+				Success: false,
+				Message: "無效的請求格式",
+			})
+			return
+		}
+	}
 
-	logger.Info("收到後台管理員登出請求")
+	accessToken := server.BearerToken(c)
+	if accessToken == "" && req.RefreshToken == "" {
+		c.JSON(http.StatusBadRequest, AdminLoginResponse{
+			Success: false,
+			Message: "缺少 Token",
+		})
+		return
+	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"success": true,
-		"message": "登出成功(功能待實作)",
+	if err := services.Get().Token.Logout(c.Request.Context(), accessToken, req.RefreshToken, auth.AudienceAdmin); err != nil {
+		respondAdminTokenError(c, err)
+		return
+	}
+
+	logger.WithFields(map[string]interface{}{
+		"client_ip": c.ClientIP(),
+	}).Info("後台管理員登出")
+
+	c.JSON(http.StatusOK, AdminLoginResponse{
+		Success: true,
+		Message: "登出成功",
+	})
+}
+
+// respondAdminTokenPair 回應登入或換發取得的管理員 Token
+func respondAdminTokenPair(c *gin.Context, message string, pair *services.TokenPair) {
+	var role string
+	if len(pair.Claims.Roles) > 0 {
+		role = pair.Claims.Roles[0]
+	}
+	c.JSON(http.StatusOK, AdminLoginResponse{
+		Success:          true,
+		Message:          message,
+		Token:            pair.AccessToken,
+		ExpiresAt:        pair.Claims.ExpiresAt,
+		RefreshToken:     pair.RefreshToken,
+		RefreshExpiresAt: pair.RefreshExpiresAt,
+		AdminID:          pair.Claims.Subject,
+		RoleName:         role,
 	})
 }
 
-// handleVerifyToken 驗證 Token 是否有效
+// respondAdminTokenError 依換發或登出的錯誤類型回應對應的 HTTP 狀態碼
+func respondAdminTokenError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, auth.ErrInvalidRefreshToken), errors.Is(err, auth.ErrRefreshTokenReused):
+		c.JSON(http.StatusUnauthorized, AdminLoginResponse{Success: false, Message: err.Error()})
+	case errors.Is(err, auth.ErrInvalidToken):
+		c.JSON(http.StatusUnauthorized, AdminLoginResponse{Success: false, Message: "無效的 Token"})
+	case errors.Is(err, services.ErrSessionsUnavailable):
+		c.JSON(http.StatusServiceUnavailable, AdminLoginResponse{Success: false, Message: err.Error()})
+	default:
+		logger.Errorf("處理管理員 Token 失敗: %v", err)
+		c.JSON(http.StatusInternalServerError, AdminLoginResponse{Success: false, Message: "處理失敗，請稍後再試"})
+	}
+}
+
+// AdminVerifyTokenRequest 驗證管理員 Token 請求結構（也可改用 Authorization 標頭）
+type AdminVerifyTokenRequest struct {
+	Token string `json:"token"`
+}
+
+// handleVerifyToken 驗證管理員 Token 是否有效
+// Token 放在 JSON 內容（與 Lobby 相同）或 Authorization 標頭，兩者都有時以 JSON 內容為準
 func (s *BackendServer) handleVerifyToken(c *gin.Context) {
-	// TODO: 實作 Token 驗證邏輯
-	// 1. 解析 JWT Token
-	// 2. 驗證簽章
-	// 3. 檢查過期時間
-	// 4. 檢查是否在黑名單中
-	// 5. 回傳管理員資訊和權限
+	var req AdminVerifyTokenRequest
+	token := server.BearerToken(c)
+	if err := c.ShouldBindJSON(&req); err == nil && strings.TrimSpace(req.Token) != "" {
+		token = strings.TrimSpace(req.Token)
+	}
+	if token == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"valid":   false,
+			"message": "缺少 Token",
+		})
+		return
+	}
 
-	logger.Info("收到 Token 驗證請求")
+	claims, err := services.Get().Token.Verify(c.Request.Context(), token, auth.AudienceAdmin)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"valid":   false,
+			"message": server.TokenErrorMessage(err),
+		})
+		return
+	}
 
+	var role string
+	if len(claims.Roles) > 0 {
+		role = claims.Roles[0]
+	}
 	c.JSON(http.StatusOK, gin.H{
-		"valid":     true,
-		"admin_id":  "dummy_admin_id",
-		"role_name": "admin",
-		"message":   "Token 驗證成功(功能待實作)",
+		"valid":      true,
+		"admin_id":   claims.Subject,
+		"role_name":  role,
+		"expires_at": claims.ExpiresAt,
+		"message":    "Token 驗證成功",
 	})
 }
 
@@ -176,6 +326,62 @@ func (s *BackendServer) handleDeleteMember(c *gin.Context) {
 	})
 }
 
+// MergeMembersRequest 合併會員請求結構
+type MergeMembersRequest struct {
+	TargetID uint `json:"target_id" binding:"required"` // 保留的會員
+	SourceID uint `json:"source_id" binding:"required"` // 合併後刪除的會員
+}
+
+// handleMergeMembers 將重複的會員合併到保留的會員
+// 平台身分、搜尋歷史與帳號密碼移到保留的會員，偏好設定保留 target_id 的；source_id 的所有工作階段隨即撤銷
+func (s *BackendServer) handleMergeMembers(c *gin.Context) {
+	var req MergeMembersRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "無效的請求格式",
+		})
+		return
+	}
+
+	user, err := services.Get().User.Merge(c.Request.Context(), req.TargetID, req.SourceID)
+	if err != nil {
+		status := http.StatusInternalServerError
+		message := "合併會員失敗，請稍後再試"
+		switch {
+		case errors.Is(err, services.ErrUserNotFound):
+			status, message = http.StatusNotFound, err.Error()
+		case errors.Is(err, services.ErrAlreadyLinked), errors.Is(err, services.ErrMergeConflict):
+			status, message = http.StatusConflict, err.Error()
+		default:
+			logger.Errorf("合併會員失敗: %v", err)
+		}
+		c.JSON(status, gin.H{
+			"success": false,
+			"message": message,
+		})
+		return
+	}
+
+	fields := map[string]interface{}{
+		"target_id": req.TargetID,
+		"source_id": req.SourceID,
+	}
+	if claims, ok := server.ClaimsFromContext(c); ok {
+		fields["admin_id"] = claims.Subject
+	}
+	logger.WithFields(fields).Info("合併會員")
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data": gin.H{
+			"id":           user.ID,
+			"display_name": user.DisplayName,
+		},
+		"message": "會員合併成功",
+	})
+}
+
 // handleGetTourStatus 取得 Tour Server 狀態
 func (s *BackendServer) handleGetTourStatus(c *gin.Context) {
 	// TODO: 實作 Tour Server 狀態查詢
@@ -197,77 +403,143 @@ func (s *BackendServer) handleGetTourStatus(c *gin.Context) {
 }
 
 // handleGetDestinations 取得景點列表
+// 查詢參數：category, city, region, tags（逗號分隔，需同時具有）, min_rating, max_rating,
+// bbox（minLat,minLon,maxLat,maxLon，minLon 大於 maxLon 表示跨越 180 度經線）, sort, order（asc/desc）, limit, offset, cursor
 func (s *BackendServer) handleGetDestinations(c *gin.Context) {
-	// TODO: 實作景點列表查詢
-	// 1. 驗證管理員權限
-	// 2. 解析分頁和篩選參數
-	// 3. 從資料庫查詢景點列表
-	// 4. 回傳分頁資料
+	query, err := parseDestinationQuery(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+		return
+	}
+
+	page, err := services.Get().Destination.List(c.Request.Context(), query)
+	if err != nil {
+		if errors.Is(err, dao.ErrInvalidQuery) {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"success": false,
+				"message": err.Error(),
+			})
+			return
+		}
+		logger.Errorf("查詢景點列表失敗: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "查詢景點列表失敗",
+		})
+		return
+	}
+
+	items := make([]DestinationItem, 0, len(page.Items))
+	for i := range page.Items {
+		items = append(items, newDestinationItem(&page.Items[i]))
+	}
 
-	logger.Info("查詢景點列表")
+	data := gin.H{
+		"items":       items,
+		"next_cursor": page.NextCursor,
+	}
+	if page.Total >= 0 {
+		data["total"] = page.Total
+	}
 
 	c.JSON(http.StatusOK, gin.H{
 		"success": true,
-		"data": gin.H{
-			"total": 0,
-			"items": []interface{}{},
-		},
-		"message": "功能待實作",
+		"data":    data,
 	})
 }
 
 // handleCreateDestination 建立新景點
 func (s *BackendServer) handleCreateDestination(c *gin.Context) {
-	// TODO: 實作景點建立
-	// 1. 驗證管理員權限
-	// 2. 驗證景點資料 (名稱、座標、標籤等)
-	// 3. 儲存到資料庫
-	// 4. 記錄操作日誌
+	var req DestinationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "無效的請求格式",
+		})
+		return
+	}
 
-	logger.Info("建立新景點")
+	dest := req.toModel()
+	if err := services.Get().Destination.Create(c.Request.Context(), dest); err != nil {
+		respondDestinationError(c, err)
+		return
+	}
 
-	c.JSON(http.StatusOK, gin.H{
+	logger.WithFields(map[string]interface{}{
+		"destination_id": dest.ID,
+		"name":           dest.Name,
+	}).Info("建立新景點")
+
+	c.JSON(http.StatusCreated, gin.H{
 		"success": true,
-		"message": "景點建立成功(功能待實作)",
+		"data":    newDestinationItem(dest),
+		"message": "景點建立成功",
 	})
 }
 
-// handleUpdateDestination 更新景點資訊
+// handleUpdateDestination 更新景點資訊（覆寫所有欄位，未提供 tags 時保留原有標籤）
 func (s *BackendServer) handleUpdateDestination(c *gin.Context) {
-	destinationID := c.Param("id")
+	id, ok := parseDestinationID(c)
+	if !ok {
+		return
+	}
 
-	// TODO: 實作景點更新
-	// 1. 驗證管理員權限
-	// 2. 驗證更新資料
-	// 3. 更新資料庫
-	// 4. 記錄操作日誌
+	var req DestinationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "無效的請求格式",
+		})
+		return
+	}
+
+	ctx := c.Request.Context()
+	dest := req.toModel()
+	dest.ID = id
+	if err := services.Get().Destination.Update(ctx, dest); err != nil {
+		respondDestinationError(c, err)
+		return
+	}
 
 	logger.WithFields(map[string]interface{}{
-		"destination_id": destinationID,
+		"destination_id": id,
 	}).Info("更新景點資訊")
 
+	// 重新讀取以取得完整標籤與時間欄位
+	updated, err := services.Get().Destination.Get(ctx, id)
+	if err != nil {
+		updated = dest
+	}
+
 	c.JSON(http.StatusOK, gin.H{
 		"success": true,
-		"message": "景點更新成功(功能待實作)",
+		"data":    newDestinationItem(updated),
+		"message": "景點更新成功",
 	})
 }
 
-// handleDeleteDestination 刪除景點
+// handleDeleteDestination 刪除景點（軟刪除）
 func (s *BackendServer) handleDeleteDestination(c *gin.Context) {
-	destinationID := c.Param("id")
+	id, ok := parseDestinationID(c)
+	if !ok {
+		return
+	}
 
-	// TODO: 實作景點刪除
-	// 1. 驗證管理員權限
-	// 2. 軟刪除景點資料
-	// 3. 記錄操作日誌
+	if err := services.Get().Destination.Delete(c.Request.Context(), id); err != nil {
+		respondDestinationError(c, err)
+		return
+	}
 
 	logger.WithFields(map[string]interface{}{
-		"destination_id": destinationID,
+		"destination_id": id,
 	}).Info("刪除景點")
 
 	c.JSON(http.StatusOK, gin.H{
 		"success": true,
-		"message": "景點刪除成功(功能待實作)",
+		"message": "景點刪除成功",
 	})
 }
 
@@ -324,17 +596,76 @@ func (s *BackendServer) handleGetSystemLogs(c *gin.Context) {
 	})
 }
 
-// authMiddleware 驗證中介層
-func (s *BackendServer) authMiddleware() gin.HandlerFunc {
-	return func(c *gin.Context) {
-		// TODO: 實作驗證中介層
-		// 1. 從 Header 取得 Token
-		// 2. 驗證 Token 有效性
-		// 3. 檢查管理員權限
-		// 4. 將管理員資訊存入 Context
-		// 5. 如果驗證失敗,回傳 401
+// handleGetDatabaseStats 取得各 Master/Slave 的連線池統計（使用中、閒置、等待次數），用於調整連線池大小
+func (s *BackendServer) handleGetDatabaseStats(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    database.GetMySQL().Stats(),
+	})
+}
+
+// SetRoleRequest 變更帳號角色請求結構
+type SetRoleRequest struct {
+	Username string `json:"username" binding:"required"`
+	Role     string `json:"role" binding:"required"` // member, operator, admin, super_admin
+}
+
+// handleSetRole 變更帳號角色，已登入的管理員於下次換發 Token 時套用新角色
+func (s *BackendServer) handleSetRole(c *gin.Context) {
+	var req SetRoleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "無效的請求格式",
+		})
+		return
+	}
+
+	cred, err := services.Get().Auth.SetRole(c.Request.Context(), req.Username, req.Role)
+	switch {
+	case errors.Is(err, services.ErrInvalidRole):
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+		return
+	case errors.Is(err, services.ErrCredentialNotFound):
+		c.JSON(http.StatusNotFound, gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+		return
+	case err != nil:
+		logger.Errorf("變更帳號角色失敗: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "變更帳號角色失敗，請稍後再試",
+		})
+		return
+	}
+
+	var adminID string
+	if claims, ok := server.ClaimsFromContext(c); ok {
+		adminID = claims.Subject
+	}
+	logger.WithFields(map[string]interface{}{
+		"admin_id": adminID,
+		"username": cred.Username,
+		"user_id":  cred.UserID,
+		"role":     cred.Role,
+	}).Info("變更帳號角色")
+
+	c.JSON(http.StatusOK, gin.H{
+		"success":  true,
+		"username": cred.Username,
+		"role":     cred.Role,
+	})
+}
 
-		logger.Info("TODO: 實作驗證中介層")
-		c.Next()
+// authMiddleware 驗證管理員 Token 的中介層，未指定 roles 時允許所有後台角色
+func (s *BackendServer) authMiddleware(roles ...string) gin.HandlerFunc {
+	if len(roles) == 0 {
+		roles = []string{auth.RoleOperator, auth.RoleAdmin, auth.RoleSuperAdmin}
 	}
+	return server.RequireToken(auth.AudienceAdmin, roles...)
 }
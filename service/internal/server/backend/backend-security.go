@@ -0,0 +1,155 @@
+package backend
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/andy2kuo/TourHelper/internal/auth"
+	"github.com/andy2kuo/TourHelper/internal/dao"
+	"github.com/andy2kuo/TourHelper/internal/logger"
+	"github.com/andy2kuo/TourHelper/internal/models"
+	"github.com/andy2kuo/TourHelper/internal/server"
+	"github.com/andy2kuo/TourHelper/internal/services"
+	"github.com/gin-gonic/gin"
+)
+
+// LockoutItem 登入鎖定記錄
+type LockoutItem struct {
+	ID          uint       `json:"id"`
+	Scope       string     `json:"scope"` // member（Lobby）, admin（Backend）
+	Kind        string     `json:"kind"`  // username, ip
+	Subject     string     `json:"subject"`
+	ClientIP    string     `json:"client_ip,omitempty"`
+	Failures    int        `json:"failures"`
+	LockedAt    time.Time  `json:"locked_at"`
+	LockedUntil time.Time  `json:"locked_until"`
+	UnlockedAt  *time.Time `json:"unlocked_at,omitempty"`
+	UnlockedBy  string     `json:"unlocked_by,omitempty"`
+}
+
+// newLockoutItem 將鎖定記錄轉為 API 回應格式
+func newLockoutItem(lockout models.LoginLockout) LockoutItem {
+	return LockoutItem{
+		ID:          lockout.ID,
+		Scope:       lockout.Scope,
+		Kind:        lockout.Kind,
+		Subject:     lockout.Subject,
+		ClientIP:    lockout.ClientIP,
+		Failures:    lockout.Failures,
+		LockedAt:    lockout.CreatedAt,
+		LockedUntil: lockout.LockedUntil,
+		UnlockedAt:  lockout.UnlockedAt,
+		UnlockedBy:  lockout.UnlockedBy,
+	}
+}
+
+// handleGetLockouts 取得登入鎖定記錄
+// 查詢參數：scope（member/admin）, kind（username/ip）, subject, active（true 時只取得鎖定中的記錄）, limit
+func (s *BackendServer) handleGetLockouts(c *gin.Context) {
+	limit, err := parseOptionalInt(c, "limit")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+		return
+	}
+
+	lockouts, err := services.Get().LoginThrottle.ListLockouts(c.Request.Context(), dao.LoginLockoutQuery{
+		Scope:      c.Query("scope"),
+		Kind:       c.Query("kind"),
+		Subject:    c.Query("subject"),
+		ActiveOnly: c.Query("active") == "true",
+		Limit:      limit,
+	})
+	if err != nil {
+		logger.Errorf("查詢登入鎖定記錄失敗: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "查詢登入鎖定記錄失敗",
+		})
+		return
+	}
+
+	items := make([]LockoutItem, len(lockouts))
+	for i, lockout := range lockouts {
+		items[i] = newLockoutItem(lockout)
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    items,
+	})
+}
+
+// UnlockRequest 解除登入鎖定請求結構
+type UnlockRequest struct {
+	Scope   string `json:"scope"`                      // member（預設）或 admin
+	Kind    string `json:"kind"`                       // username（預設）或 ip
+	Subject string `json:"subject" binding:"required"` // 帳號或 IP
+}
+
+// handleUnlock 提前解除帳號或來源 IP 的登入鎖定
+func (s *BackendServer) handleUnlock(c *gin.Context) {
+	var req UnlockRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "無效的請求格式",
+		})
+		return
+	}
+	if req.Scope == "" {
+		req.Scope = auth.AudienceMember
+	}
+	if req.Kind == "" {
+		req.Kind = auth.ThrottleUsername
+	}
+	if req.Scope != auth.AudienceMember && req.Scope != auth.AudienceAdmin {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "scope 需為 member 或 admin",
+		})
+		return
+	}
+
+	var adminID string
+	if claims, ok := server.ClaimsFromContext(c); ok {
+		adminID = claims.Subject
+	}
+
+	unlocked, err := services.Get().LoginThrottle.Unlock(c.Request.Context(), req.Scope, req.Kind, req.Subject, adminID)
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidLockoutKind) {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"success": false,
+				"message": err.Error(),
+			})
+			return
+		}
+		logger.Errorf("解除登入鎖定失敗: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "解除登入鎖定失敗，請稍後再試",
+		})
+		return
+	}
+
+	logger.WithFields(map[string]interface{}{
+		"admin_id": adminID,
+		"scope":    req.Scope,
+		"kind":     req.Kind,
+		"subject":  req.Subject,
+		"unlocked": unlocked,
+	}).Info("解除登入鎖定")
+
+	message := "已解除鎖定"
+	if !unlocked {
+		message = "目前未被鎖定，已清除失敗記錄"
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"success":  true,
+		"unlocked": unlocked,
+		"message":  message,
+	})
+}
@@ -4,9 +4,12 @@ import (
 	"context"
 	"fmt"
 	"net/http"
+	"time"
 
+	"github.com/andy2kuo/TourHelper/internal/auth"
 	"github.com/andy2kuo/TourHelper/internal/logger"
 	"github.com/andy2kuo/TourHelper/internal/server"
+	"github.com/andy2kuo/TourHelper/internal/services"
 	"github.com/gin-gonic/gin"
 )
 
@@ -17,7 +20,7 @@ type BackendServer struct {
 	opt        *server.Options
 	httpServer *http.Server
 	// TODO: 新增 Redis 客戶端
-	// redisClient *redis.Client
+	// redisClient redis.UniversalClient
 }
 
 // Init 初始化伺服器
@@ -33,6 +36,11 @@ func (s *BackendServer) Init(opts *server.Options) error {
 	// 建立 Gin router
 	r := gin.Default()
 
+	// 只採用可信任代理設定的 X-Forwarded-For，登入限制依來源 IP 計數
+	if err := r.SetTrustedProxies(opts.Config.Server.TrustedProxies); err != nil {
+		return fmt.Errorf("設定可信任代理失敗: %w", err)
+	}
+
 	s.router = r
 	s.opt = opts
 
@@ -44,33 +52,31 @@ func (s *BackendServer) Init(opts *server.Options) error {
 
 // setupRoutes 設定所有路由
 func (s *BackendServer) setupRoutes() {
-	// 健康檢查路由
-	s.router.GET("/health", func(c *gin.Context) {
-		c.JSON(200, gin.H{
-			"status":  "ok",
-			"service": s.opt.ServiceName,
-			"env":     s.opt.ServiceEnv,
-			"version": s.opt.Version,
-		})
-	})
+	// 健康檢查路由（/livez、/readyz、/health）
+	server.NewHealthHandler(s.opt).Register(s.router)
+
+	// Token 驗證公鑰（/.well-known/jwks.json）
+	server.RegisterJWKS(s.router)
 
 	// 後台管理員登入驗證路由
-	auth := s.router.Group("/admin/auth")
+	authGroup := s.router.Group("/admin/auth")
 	{
-		// TODO: 實作後台管理員登入驗證
-		auth.POST("/login", s.handleAdminLogin)
+		// 後台管理員登入驗證
+		authGroup.POST("/login", s.handleAdminLogin)
 
-		// TODO: 實作後台管理員登出
-		auth.POST("/logout", s.handleAdminLogout)
+		// 以 Refresh Token 換發 Token
+		authGroup.POST("/refresh", s.handleAdminRefreshToken)
 
-		// TODO: 實作 Token 驗證
-		auth.POST("/verify", s.handleVerifyToken)
+		// 後台管理員登出（撤銷工作階段）
+		authGroup.POST("/logout", s.handleAdminLogout)
+
+		// Token 驗證
+		authGroup.POST("/verify", s.handleVerifyToken)
 	}
 
 	// 會員管理路由 (需要驗證)
-	// TODO: 實作中介層驗證 Token
 	member := s.router.Group("/admin/member")
-	// member.Use(s.authMiddleware()) // TODO: 實作驗證中介層
+	member.Use(s.authMiddleware())
 	{
 		// TODO: 實作會員列表查詢
 		member.GET("/list", s.handleGetMemberList)
@@ -83,25 +89,28 @@ func (s *BackendServer) setupRoutes() {
 
 		// TODO: 實作會員刪除
 		member.DELETE("/:id", s.handleDeleteMember)
+
+		// 合併重複的會員（同一人在不同平台建立的會員），僅限管理員
+		member.POST("/merge", s.authMiddleware(auth.RoleAdmin, auth.RoleSuperAdmin), s.handleMergeMembers)
 	}
 
 	// Tour Server 管理路由 (需要驗證)
 	tour := s.router.Group("/admin/tour")
-	// tour.Use(s.authMiddleware()) // TODO: 實作驗證中介層
+	tour.Use(s.authMiddleware())
 	{
 		// TODO: 實作 Tour Server 狀態查詢
 		tour.GET("/status", s.handleGetTourStatus)
 
-		// TODO: 實作景點管理
+		// 景點管理
 		tour.GET("/destinations", s.handleGetDestinations)
 		tour.POST("/destinations", s.handleCreateDestination)
 		tour.PUT("/destinations/:id", s.handleUpdateDestination)
 		tour.DELETE("/destinations/:id", s.handleDeleteDestination)
 	}
 
-	// 系統設定路由 (需要驗證)
+	// 系統設定路由 (需要驗證，僅限管理員)
 	system := s.router.Group("/admin/system")
-	// system.Use(s.authMiddleware()) // TODO: 實作驗證中介層
+	system.Use(s.authMiddleware(auth.RoleAdmin, auth.RoleSuperAdmin))
 	{
 		// TODO: 實作系統設定查詢
 		system.GET("/config", s.handleGetSystemConfig)
@@ -111,6 +120,16 @@ func (s *BackendServer) setupRoutes() {
 
 		// TODO: 實作系統日誌查詢
 		system.GET("/logs", s.handleGetSystemLogs)
+
+		// 資料庫連線池統計
+		system.GET("/db-stats", s.handleGetDatabaseStats)
+
+		// 變更帳號角色（授予或移除後台權限），僅限最高權限管理員
+		system.PUT("/roles", s.authMiddleware(auth.RoleSuperAdmin), s.handleSetRole)
+
+		// 登入鎖定記錄與解除鎖定
+		system.GET("/lockouts", s.handleGetLockouts)
+		system.POST("/lockouts/unlock", s.handleUnlock)
 	}
 
 	logger.Info("Backend 路由已設定完成")
@@ -160,6 +179,25 @@ func (s *BackendServer) Stop(ctx context.Context) error {
 	return nil
 }
 
+// Workers 背景工作（多台 Backend 伺服器時只由當選的一台執行）
+func (s *BackendServer) Workers() []server.Worker {
+	return []server.Worker{
+		{Name: "weather-data-cleanup", Interval: time.Hour, Run: s.cleanupWeatherData},
+	}
+}
+
+// cleanupWeatherData 刪除已過期的天氣資料
+func (s *BackendServer) cleanupWeatherData(ctx context.Context) error {
+	count, err := services.Get().Weather.PurgeExpired(ctx)
+	if err != nil {
+		return err
+	}
+	if count > 0 {
+		logger.Infof("已刪除 %d 筆過期天氣資料", count)
+	}
+	return nil
+}
+
 // Name 返回伺服器名稱
 func (s *BackendServer) Name() string {
 	return "Backend Server"
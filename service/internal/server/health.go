@@ -0,0 +1,112 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	"github.com/andy2kuo/TourHelper/internal/database"
+	"github.com/gin-gonic/gin"
+)
+
+// readinessTimeout 就緒檢查中每個連線測試的最長時間
+const readinessTimeout = 2 * time.Second
+
+// draining 伺服器是否正在關閉，關閉期間就緒檢查回應 503 讓負載平衡器停止導入新流量
+var draining atomic.Bool
+
+// IsDraining 伺服器是否正在關閉
+func IsDraining() bool {
+	return draining.Load()
+}
+
+// dependencyStatus 就緒檢查中單一相依服務的狀態
+type dependencyStatus struct {
+	Kind      string  `json:"kind"`
+	Name      string  `json:"name"`
+	Role      string  `json:"role,omitempty"`
+	Master    string  `json:"master,omitempty"`
+	Healthy   bool    `json:"healthy"`
+	Required  bool    `json:"required"`
+	LatencyMs float64 `json:"latency_ms"`
+	Error     string  `json:"error,omitempty"`
+}
+
+// HealthHandler 存活與就緒檢查處理器，所有伺服器類型共用
+type HealthHandler struct {
+	opts *Options
+}
+
+// NewHealthHandler 建立存活與就緒檢查處理器
+func NewHealthHandler(opts *Options) *HealthHandler {
+	return &HealthHandler{opts: opts}
+}
+
+// Register 註冊健康檢查路由
+//   - /livez：程序存活即回應 200，不檢查相依服務（避免資料庫異常時所有伺服器被重啟）
+//   - /readyz：檢查資料庫與 Redis，必要連線異常或伺服器關閉中回應 503
+//   - /health：與 /livez 相同，保留給既有的監控設定
+func (h *HealthHandler) Register(r gin.IRoutes) {
+	r.GET("/livez", h.Livez)
+	r.GET("/readyz", h.Readyz)
+	r.GET("/health", h.Livez)
+}
+
+// Livez 存活檢查
+func (h *HealthHandler) Livez(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "ok",
+		"service": h.opts.ServiceName,
+		"env":     h.opts.ServiceEnv,
+		"version": h.opts.Version,
+	})
+}
+
+// Readyz 就緒檢查，回報每個 Master、Slave 與 Redis 實例的連線狀態與延遲
+// status 為 ok（全部正常）、degraded（僅非必要連線異常）、unavailable（必要連線異常）或 draining（關閉中）
+func (h *HealthHandler) Readyz(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(c.Request.Context(), readinessTimeout)
+	defer cancel()
+
+	var results []database.PingResult
+	results = append(results, database.GetMySQL().PingEach(ctx)...)
+	if database.RedisEnabled() {
+		results = append(results, database.GetRedis().PingEach(ctx)...)
+	}
+
+	status := "ok"
+	code := http.StatusOK
+	checks := make([]dependencyStatus, 0, len(results))
+	for _, result := range results {
+		checks = append(checks, dependencyStatus{
+			Kind:      result.Kind,
+			Name:      result.Name,
+			Role:      result.Role,
+			Master:    result.Master,
+			Healthy:   result.Healthy,
+			Required:  result.Required,
+			LatencyMs: float64(result.Latency.Microseconds()) / 1000,
+			Error:     result.Error,
+		})
+
+		switch {
+		case result.Healthy:
+		case result.Required:
+			status, code = "unavailable", http.StatusServiceUnavailable
+		case status == "ok":
+			status = "degraded"
+		}
+	}
+	if IsDraining() {
+		status, code = "draining", http.StatusServiceUnavailable
+	}
+
+	c.JSON(code, gin.H{
+		"status":  status,
+		"service": h.opts.ServiceName,
+		"env":     h.opts.ServiceEnv,
+		"version": h.opts.Version,
+		"checks":  checks,
+	})
+}
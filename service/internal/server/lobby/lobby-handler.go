@@ -1,9 +1,17 @@
 package lobby
 
 import (
+	"encoding/json"
+	"errors"
+	"fmt"
 	"net/http"
+	"strconv"
 
+	"github.com/andy2kuo/TourHelper/internal/auth"
 	"github.com/andy2kuo/TourHelper/internal/logger"
+	"github.com/andy2kuo/TourHelper/internal/models"
+	"github.com/andy2kuo/TourHelper/internal/server"
+	"github.com/andy2kuo/TourHelper/internal/services"
 	"github.com/gin-gonic/gin"
 )
 
@@ -16,13 +24,16 @@ type LoginRequest struct {
 
 // LoginResponse 登入回應結構
 type LoginResponse struct {
-	Success  bool   `json:"success"`
-	Message  string `json:"message"`
-	Token    string `json:"token,omitempty"`
-	MemberID string `json:"member_id,omitempty"`
+	Success          bool   `json:"success"`
+	Message          string `json:"message"`
+	Token            string `json:"token,omitempty"`              // Access Token
+	ExpiresAt        int64  `json:"expires_at,omitempty"`         // Access Token 過期時間（Unix 秒）
+	RefreshToken     string `json:"refresh_token,omitempty"`      // 換發 Token 用，每次換發後舊的即失效
+	RefreshExpiresAt int64  `json:"refresh_expires_at,omitempty"` // 未換發時工作階段的到期時間（Unix 秒）
+	MemberID         string `json:"member_id,omitempty"`
 }
 
-// handleLogin 處理玩家登入驗證
+// handleLogin 處理網頁會員帳號密碼登入
 func (s *LobbyServer) handleLogin(c *gin.Context) {
 	var req LoginRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -38,28 +49,183 @@ func (s *LobbyServer) handleLogin(c *gin.Context) {
 		"platform": req.Platform,
 	}).Info("收到登入請求")
 
-	// TODO: 實作登入驗證邏輯
-	// 1. 驗證使用者帳號密碼
-	// 2. 產生 JWT Token
-	// 3. 更新會員線上狀態
-	// 4. 通知 Tour Server 會員已登入
+	// 帳號密碼僅適用網頁會員，LINE、Telegram 會員需透過第三方登入
+	if req.Platform != "" && req.Platform != models.PlatformWeb {
+		c.JSON(http.StatusBadRequest, LoginResponse{
+			Success: false,
+			Message: "此平台不支援帳號密碼登入",
+		})
+		return
+	}
+
+	// 依帳號與來源 IP 限制失敗次數，失敗過多時需等待或暫時鎖定
+	var user *models.User
+	err := services.Get().LoginThrottle.Attempt(c.Request.Context(), auth.AudienceMember, req.Username, c.ClientIP(), func() error {
+		var err error
+		user, err = services.Get().Auth.Login(c.Request.Context(), req.Username, req.Password)
+		return err
+	})
+	if err != nil {
+		respondAuthError(c, err)
+		return
+	}
+
+	pair, err := services.Get().Token.IssueMember(c.Request.Context(), user.ID)
+	if err != nil {
+		respondAuthError(c, err)
+		return
+	}
+
+	// TODO: 更新會員線上狀態並通知 Tour Server 會員已登入
+
+	respondTokenPair(c, "登入成功", pair)
+}
+
+// RefreshTokenRequest 換發 Token 請求結構
+type RefreshTokenRequest struct {
+	RefreshToken string `json:"refresh_token" binding:"required"`
+}
+
+// handleRefreshToken 以 Refresh Token 換發新的 Access Token 與 Refresh Token
+func (s *LobbyServer) handleRefreshToken(c *gin.Context) {
+	var req RefreshTokenRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, LoginResponse{
+			Success: false,
+			Message: "無效的請求格式",
+		})
+		return
+	}
+
+	pair, err := services.Get().Token.Refresh(c.Request.Context(), req.RefreshToken, auth.AudienceMember)
+	if err != nil {
+		if errors.Is(err, auth.ErrRefreshTokenReused) {
+			logger.WithFields(map[string]interface{}{
+				"client_ip": c.ClientIP(),
+			}).Warn("會員 Refresh Token 被重複使用，已撤銷整個工作階段")
+		}
+		respondAuthError(c, err)
+		return
+	}
+
+	respondTokenPair(c, "Token 換發成功", pair)
+}
 
-	// 暫時回傳成功(實際實作時需要真正的驗證)
+// respondTokenPair 回應登入或換發取得的 Token
+func respondTokenPair(c *gin.Context, message string, pair *services.TokenPair) {
 	c.JSON(http.StatusOK, LoginResponse{
+		Success:          true,
+		Message:          message,
+		Token:            pair.AccessToken,
+		ExpiresAt:        pair.Claims.ExpiresAt,
+		RefreshToken:     pair.RefreshToken,
+		RefreshExpiresAt: pair.RefreshExpiresAt,
+		MemberID:         pair.Claims.Subject,
+	})
+}
+
+// RegisterRequest 註冊請求結構
+type RegisterRequest struct {
+	Username    string `json:"username" binding:"required"`
+	Password    string `json:"password" binding:"required"`
+	DisplayName string `json:"display_name"` // 未提供時使用帳號
+}
+
+// handleRegister 處理網頁會員註冊
+func (s *LobbyServer) handleRegister(c *gin.Context) {
+	var req RegisterRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, LoginResponse{
+			Success: false,
+			Message: "無效的請求格式",
+		})
+		return
+	}
+
+	user, err := services.Get().Auth.Register(c.Request.Context(), services.RegisterInput{
+		Username:    req.Username,
+		Password:    req.Password,
+		DisplayName: req.DisplayName,
+	})
+	if err != nil {
+		respondAuthError(c, err)
+		return
+	}
+
+	logger.WithFields(map[string]interface{}{
+		"member_id": user.ID,
+		"username":  user.Username,
+	}).Info("新會員註冊")
+
+	c.JSON(http.StatusCreated, LoginResponse{
 		Success:  true,
-		Message:  "登入成功(功能待實作)",
-		Token:    "dummy_token",
-		MemberID: req.Username,
+		Message:  "註冊成功",
+		MemberID: strconv.FormatUint(uint64(user.ID), 10),
 	})
 }
 
-// LineLoginRequest LINE 登入請求結構
+// respondAuthError 依帳號密碼驗證服務的錯誤類型回應對應的 HTTP 狀態碼
+func respondAuthError(c *gin.Context, err error) {
+	var throttleErr *services.LoginThrottleError
+	switch {
+	case errors.As(err, &throttleErr):
+		server.SetRetryAfter(c, throttleErr.RetryAfter)
+		c.JSON(http.StatusTooManyRequests, LoginResponse{Success: false, Message: err.Error()})
+	case errors.Is(err, services.ErrInvalidRegistration):
+		c.JSON(http.StatusBadRequest, LoginResponse{Success: false, Message: err.Error()})
+	case errors.Is(err, services.ErrInvalidCredentials):
+		c.JSON(http.StatusUnauthorized, LoginResponse{Success: false, Message: err.Error()})
+	case errors.Is(err, services.ErrUsernameTaken):
+		c.JSON(http.StatusConflict, LoginResponse{Success: false, Message: err.Error()})
+	case errors.Is(err, auth.ErrInvalidRefreshToken), errors.Is(err, auth.ErrRefreshTokenReused):
+		c.JSON(http.StatusUnauthorized, LoginResponse{Success: false, Message: err.Error()})
+	case errors.Is(err, auth.ErrInvalidToken):
+		c.JSON(http.StatusUnauthorized, LoginResponse{Success: false, Message: "無效的 Token"})
+	case errors.Is(err, services.ErrInvalidLoginState):
+		c.JSON(http.StatusBadRequest, LoginResponse{Success: false, Message: err.Error()})
+	case errors.Is(err, auth.ErrLineLogin):
+		logger.Warnf("LINE 登入驗證失敗: %v", err)
+		c.JSON(http.StatusUnauthorized, LoginResponse{Success: false, Message: auth.ErrLineLogin.Error()})
+	case errors.Is(err, auth.ErrTelegramLogin):
+		logger.Warnf("Telegram 登入驗證失敗: %v", err)
+		c.JSON(http.StatusUnauthorized, LoginResponse{Success: false, Message: auth.ErrTelegramLogin.Error()})
+	case errors.Is(err, services.ErrSessionsUnavailable), errors.Is(err, services.ErrLineLoginUnavailable),
+		errors.Is(err, services.ErrTelegramLoginUnavailable):
+		c.JSON(http.StatusServiceUnavailable, LoginResponse{Success: false, Message: err.Error()})
+	default:
+		logger.Errorf("會員驗證失敗: %v", err)
+		c.JSON(http.StatusInternalServerError, LoginResponse{Success: false, Message: "會員驗證失敗，請稍後再試"})
+	}
+}
+
+// lineStateCookie 保存 LINE 登入 state 的 Cookie，將 state 綁定在發起登入的瀏覽器
+const lineStateCookie = "tourhelper_line_state"
+
+// handleLineAuthorize 產生 LINE 授權頁網址，前端將使用者導向此網址
+// state 同時寫入 HttpOnly Cookie，完成登入時需與 LINE 導回的 state 相同
+func (s *LobbyServer) handleLineAuthorize(c *gin.Context) {
+	authorizeURL, state, err := services.Get().LineLogin.AuthorizeURL(c.Request.Context())
+	if err != nil {
+		respondAuthError(c, err)
+		return
+	}
+
+	setLineStateCookie(c, state, 0)
+
+	c.JSON(http.StatusOK, gin.H{
+		"success":       true,
+		"authorize_url": authorizeURL,
+	})
+}
+
+// LineLoginRequest LINE 登入請求結構（LINE 導回 Callback URL 時附帶的參數）
 type LineLoginRequest struct {
 	Code  string `json:"code" binding:"required"`  // LINE 授權碼
 	State string `json:"state" binding:"required"` // 防 CSRF 攻擊的 state
 }
 
 // handleLineLogin 處理 LINE 第三方登入驗證
+// 驗證 state、以授權碼向 LINE 換取 Token 並驗證 ID Token 後，以 LINE 使用者 ID 對應會員並簽發會員 Token
 func (s *LobbyServer) handleLineLogin(c *gin.Context) {
 	var req LineLoginRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -70,57 +236,142 @@ func (s *LobbyServer) handleLineLogin(c *gin.Context) {
 		return
 	}
 
+	boundState, _ := c.Cookie(lineStateCookie)
+	user, err := services.Get().LineLogin.Login(c.Request.Context(), req.Code, req.State, boundState)
+	if boundState != "" && !errors.Is(err, services.ErrInvalidLoginState) {
+		// state 已使用過，清除 Cookie
+		setLineStateCookie(c, "", -1)
+	}
+	if err != nil {
+		respondAuthError(c, err)
+		return
+	}
+
+	pair, err := services.Get().Token.IssueMember(c.Request.Context(), user.ID)
+	if err != nil {
+		respondAuthError(c, err)
+		return
+	}
+
 	logger.WithFields(map[string]interface{}{
-		"code":  req.Code,
-		"state": req.State,
-	}).Info("收到 LINE 登入請求")
-
-	// TODO: LINE 第三方登入驗證功能待補
-	// 1. 驗證 state 參數防止 CSRF 攻擊
-	// 2. 使用 code 向 LINE 伺服器換取 access_token
-	// 3. 使用 access_token 取得使用者資訊
-	// 4. 檢查使用者是否已註冊,未註冊則自動註冊
-	// 5. 產生 JWT Token
-	// 6. 通知 Tour Server 會員已登入
-
-	// 參考文件:
-	// https://developers.line.biz/en/docs/line-login/integrate-line-login/
-
-	c.JSON(http.StatusNotImplemented, LoginResponse{
-		Success: false,
-		Message: "LINE 第三方登入功能尚未實作",
+		"member_id": user.ID,
+		"platform":  user.Platform,
+	}).Info("LINE 會員登入成功")
+
+	// TODO: 通知 Tour Server 會員已登入
+
+	respondTokenPair(c, "登入成功", pair)
+}
+
+// setLineStateCookie 設定或清除（maxAge < 0）LINE 登入 state 的 Cookie
+// 使用 SameSite=Lax：使用者從 LINE 授權頁導回屬於頂層導覽，之後前端對 Lobby 的請求仍會帶上 Cookie
+func setLineStateCookie(c *gin.Context, state string, maxAge int) {
+	http.SetCookie(c.Writer, &http.Cookie{
+		Name:     lineStateCookie,
+		Value:    state,
+		Path:     "/auth/line",
+		MaxAge:   maxAge,
+		HttpOnly: true,
+		Secure:   true,
+		SameSite: http.SameSiteLaxMode,
 	})
 }
 
+// handleTelegramLogin 處理 Telegram Login Widget 登入
+// 請求內容為 Login Widget 回傳的資料（id、first_name、username、auth_date、hash 等欄位），驗證後簽發會員 Token
+func (s *LobbyServer) handleTelegramLogin(c *gin.Context) {
+	data, err := telegramLoginData(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, LoginResponse{
+			Success: false,
+			Message: "無效的請求格式",
+		})
+		return
+	}
+
+	user, err := services.Get().TelegramLogin.Login(c.Request.Context(), data)
+	if err != nil {
+		respondAuthError(c, err)
+		return
+	}
+
+	pair, err := services.Get().Token.IssueMember(c.Request.Context(), user.ID)
+	if err != nil {
+		respondAuthError(c, err)
+		return
+	}
+
+	logger.WithFields(map[string]interface{}{
+		"member_id": user.ID,
+		"platform":  user.Platform,
+	}).Info("Telegram 會員登入成功")
+
+	respondTokenPair(c, "登入成功", pair)
+}
+
+// telegramLoginData 將 Login Widget 的 JSON 資料轉為字串欄位
+// 簽章以欄位的原始文字計算，數字欄位（id、auth_date）需保留原本的寫法
+func telegramLoginData(c *gin.Context) (map[string]string, error) {
+	var raw map[string]any
+	decoder := json.NewDecoder(c.Request.Body)
+	decoder.UseNumber()
+	if err := decoder.Decode(&raw); err != nil {
+		return nil, err
+	}
+
+	data := make(map[string]string, len(raw))
+	for key, value := range raw {
+		switch v := value.(type) {
+		case string:
+			data[key] = v
+		case json.Number:
+			data[key] = v.String()
+		default:
+			return nil, fmt.Errorf("欄位 %s 的格式錯誤", key)
+		}
+	}
+	return data, nil
+}
+
 // LogoutRequest 登出請求結構
 type LogoutRequest struct {
-	Token string `json:"token" binding:"required"`
+	RefreshToken string `json:"refresh_token"` // Access Token 已過期時以 Refresh Token 登出
 }
 
-// handleLogout 處理玩家登出
+// handleLogout 處理會員登出
+// 以 Authorization: Bearer 的 Access Token 或請求中的 Refresh Token 撤銷工作階段，
+// 撤銷後 Lobby、Backend、Tour 都會立即拒絕此工作階段的 Token
 func (s *LobbyServer) handleLogout(c *gin.Context) {
 	var req LogoutRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"success": false,
-			"message": "無效的請求格式",
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, LoginResponse{
+				Success: false,
+				Message: "無效的請求格式",
+			})
+			return
+		}
+	}
+
+	accessToken := server.BearerToken(c)
+	if accessToken == "" && req.RefreshToken == "" {
+		c.JSON(http.StatusBadRequest, LoginResponse{
+			Success: false,
+			Message: "缺少 Token",
 		})
 		return
 	}
 
-	logger.WithFields(map[string]interface{}{
-		"token": req.Token,
-	}).Info("收到登出請求")
+	if err := services.Get().Token.Logout(c.Request.Context(), accessToken, req.RefreshToken, auth.AudienceMember); err != nil {
+		respondAuthError(c, err)
+		return
+	}
 
-	// TODO: 實作登出邏輯
-	// 1. 驗證 Token
-	// 2. 更新會員離線狀態
-	// 3. 清除 Session
-	// 4. 通知 Tour Server 會員已登出
+	// TODO: 更新會員離線狀態並通知 Tour Server 會員已登出
 
-	c.JSON(http.StatusOK, gin.H{
-		"success": true,
-		"message": "登出成功(功能待實作)",
+	c.JSON(http.StatusOK, LoginResponse{
+		Success: true,
+		Message: "登出成功",
 	})
 }
 
@@ -131,12 +382,14 @@ type VerifyTokenRequest struct {
 
 // VerifyTokenResponse 驗證 Token 回應結構
 type VerifyTokenResponse struct {
-	Valid    bool   `json:"valid"`
-	MemberID string `json:"member_id,omitempty"`
-	Message  string `json:"message,omitempty"`
+	Valid     bool     `json:"valid"`
+	MemberID  string   `json:"member_id,omitempty"`
+	Roles     []string `json:"roles,omitempty"`
+	ExpiresAt int64    `json:"expires_at,omitempty"` // Token 過期時間（Unix 秒）
+	Message   string   `json:"message,omitempty"`
 }
 
-// handleVerifyToken 驗證 Token 是否有效
+// handleVerifyToken 驗證會員 Token 是否有效
 func (s *LobbyServer) handleVerifyToken(c *gin.Context) {
 	var req VerifyTokenRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -147,16 +400,21 @@ func (s *LobbyServer) handleVerifyToken(c *gin.Context) {
 		return
 	}
 
-	// TODO: 實作 Token 驗證邏輯
-	// 1. 解析 JWT Token
-	// 2. 驗證簽章
-	// 3. 檢查過期時間
-	// 4. 回傳會員資訊
+	claims, err := services.Get().Token.Verify(c.Request.Context(), req.Token, auth.AudienceMember)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, VerifyTokenResponse{
+			Valid:   false,
+			Message: server.TokenErrorMessage(err),
+		})
+		return
+	}
 
 	c.JSON(http.StatusOK, VerifyTokenResponse{
-		Valid:    true,
-		MemberID: "dummy_member_id",
-		Message:  "Token 驗證成功(功能待實作)",
+		Valid:     true,
+		MemberID:  claims.Subject,
+		Roles:     claims.Roles,
+		ExpiresAt: claims.ExpiresAt,
+		Message:   "Token 驗證成功",
 	})
 }
 
@@ -220,3 +478,254 @@ func (s *LobbyServer) handleUpdateMemberInfo(c *gin.Context) {
 		"message": "會員資訊更新成功(功能待實作)",
 	})
 }
+
+// authMiddleware 驗證會員 Token 的中介層
+func (s *LobbyServer) authMiddleware() gin.HandlerFunc {
+	return server.RequireToken(auth.AudienceMember)
+}
+
+// currentMemberID 取得 RequireToken 驗證通過的會員 ID
+func currentMemberID(c *gin.Context) (uint, bool) {
+	claims, ok := server.ClaimsFromContext(c)
+	if !ok {
+		return 0, false
+	}
+	id, err := strconv.ParseUint(claims.Subject, 10, 64)
+	if err != nil {
+		return 0, false
+	}
+	return uint(id), true
+}
+
+// IdentityItem 會員連結的平台身分
+type IdentityItem struct {
+	Platform    string `json:"platform"`
+	ExternalID  string `json:"external_id"`
+	Username    string `json:"username,omitempty"`
+	DisplayName string `json:"display_name,omitempty"`
+	LinkedAt    int64  `json:"linked_at"` // 連結到目前會員的時間（Unix 秒）
+}
+
+// handleListIdentities 取得目前會員連結的所有平台身分
+func (s *LobbyServer) handleListIdentities(c *gin.Context) {
+	memberID, ok := currentMemberID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"success": false, "message": "無效的 Token"})
+		return
+	}
+
+	identities, err := services.Get().User.ListIdentities(services.WithUser(c.Request.Context(), memberID), memberID)
+	if err != nil {
+		respondLinkError(c, err)
+		return
+	}
+
+	items := make([]IdentityItem, len(identities))
+	for i, identity := range identities {
+		items[i] = IdentityItem{
+			Platform:    identity.Platform,
+			ExternalID:  identity.ExternalID,
+			Username:    identity.Username,
+			DisplayName: identity.DisplayName,
+			LinkedAt:    identity.LinkedAt.Unix(),
+		}
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    items,
+	})
+}
+
+// handleIssueLinkCode 產生帳號連結驗證碼，會員在其他平台（LINE 或 Telegram Bot）輸入後即可連結
+func (s *LobbyServer) handleIssueLinkCode(c *gin.Context) {
+	memberID, ok := currentMemberID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"success": false, "message": "無效的 Token"})
+		return
+	}
+
+	code, err := services.Get().User.IssueLinkCode(c.Request.Context(), memberID)
+	if err != nil {
+		respondLinkError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success":    true,
+		"code":       code.Code,
+		"expires_at": code.ExpiresAt.Unix(),
+	})
+}
+
+// LinkAccountRequest 連結帳號請求結構
+type LinkAccountRequest struct {
+	Code string `json:"code" binding:"required"` // 在其他平台取得的驗證碼
+}
+
+// handleLinkAccount 以在其他平台取得的驗證碼連結帳號
+// 目前會員合併到產生驗證碼的會員，原本的所有工作階段隨即撤銷（見 UserService.Merge），並簽發合併後會員的 Token
+func (s *LobbyServer) handleLinkAccount(c *gin.Context) {
+	memberID, ok := currentMemberID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"success": false, "message": "無效的 Token"})
+		return
+	}
+
+	var req LinkAccountRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, LoginResponse{
+			Success: false,
+			Message: "無效的請求格式",
+		})
+		return
+	}
+
+	ctx := c.Request.Context()
+	user, err := services.Get().User.Link(ctx, memberID, req.Code, c.ClientIP())
+	if err != nil {
+		respondLinkError(c, err)
+		return
+	}
+
+	logger.WithFields(map[string]interface{}{
+		"member_id": user.ID,
+		"merged_id": memberID,
+	}).Info("會員已連結帳號")
+
+	pair, err := services.Get().Token.IssueMember(ctx, user.ID)
+	if err != nil {
+		respondAuthError(c, err)
+		return
+	}
+
+	respondTokenPair(c, "帳號連結成功", pair)
+}
+
+// PreferencesItem 會員偏好設定
+type PreferencesItem struct {
+	MaxDistance       float64 `json:"max_distance"`       // 最大距離（公里）
+	PreferredWeather  string  `json:"preferred_weather"`  // sunny, cloudy, rainy, any
+	PreferredCategory string  `json:"preferred_category"` // nature, culture, food, shopping, adventure，空字串表示不限
+	MinRating         float64 `json:"min_rating"`         // 最低評分
+	Budget            string  `json:"budget"`             // low, medium, high
+}
+
+// UpdatePreferencesRequest 更新偏好設定請求結構，未提供的欄位維持原設定
+type UpdatePreferencesRequest struct {
+	MaxDistance       *float64 `json:"max_distance"`
+	PreferredWeather  *string  `json:"preferred_weather"`
+	PreferredCategory *string  `json:"preferred_category"`
+	MinRating         *float64 `json:"min_rating"`
+	Budget            *string  `json:"budget"`
+}
+
+// handleGetPreferences 取得目前會員的偏好設定
+func (s *LobbyServer) handleGetPreferences(c *gin.Context) {
+	memberID, ok := currentMemberID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"success": false, "message": "無效的 Token"})
+		return
+	}
+
+	prefs, err := services.Get().User.GetPreferences(services.WithUser(c.Request.Context(), memberID), memberID)
+	if err != nil {
+		respondPreferencesError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    preferencesItem(prefs),
+	})
+}
+
+// handleUpdatePreferences 更新目前會員的偏好設定，之後的推薦立即使用新設定
+func (s *LobbyServer) handleUpdatePreferences(c *gin.Context) {
+	memberID, ok := currentMemberID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"success": false, "message": "無效的 Token"})
+		return
+	}
+
+	var req UpdatePreferencesRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": "無效的請求格式"})
+		return
+	}
+
+	ctx := services.WithUser(c.Request.Context(), memberID)
+	prefs, err := services.Get().User.GetPreferences(ctx, memberID)
+	if err != nil {
+		respondPreferencesError(c, err)
+		return
+	}
+	if req.MaxDistance != nil {
+		prefs.MaxDistance = *req.MaxDistance
+	}
+	if req.PreferredWeather != nil {
+		prefs.PreferredWeather = *req.PreferredWeather
+	}
+	if req.PreferredCategory != nil {
+		prefs.PreferredCategory = *req.PreferredCategory
+	}
+	if req.MinRating != nil {
+		prefs.MinRating = *req.MinRating
+	}
+	if req.Budget != nil {
+		prefs.Budget = *req.Budget
+	}
+
+	updated, err := services.Get().User.UpdatePreferences(ctx, memberID, *prefs)
+	if err != nil {
+		respondPreferencesError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    preferencesItem(updated),
+	})
+}
+
+// preferencesItem 將偏好設定轉換為回應格式
+func preferencesItem(prefs *models.UserPreferences) PreferencesItem {
+	return PreferencesItem{
+		MaxDistance:       prefs.MaxDistance,
+		PreferredWeather:  prefs.PreferredWeather,
+		PreferredCategory: prefs.PreferredCategory,
+		MinRating:         prefs.MinRating,
+		Budget:            prefs.Budget,
+	}
+}
+
+// respondPreferencesError 依偏好設定的錯誤類型回應對應的 HTTP 狀態碼
+func respondPreferencesError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrInvalidPreferences):
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": err.Error()})
+	case errors.Is(err, services.ErrUserNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"success": false, "message": err.Error()})
+	default:
+		logger.Errorf("偏好設定失敗: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "message": "偏好設定失敗，請稍後再試"})
+	}
+}
+
+// respondLinkError 依帳號連結的錯誤類型回應對應的 HTTP 狀態碼
+func respondLinkError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrInvalidLinkCode):
+		c.JSON(http.StatusBadRequest, LoginResponse{Success: false, Message: err.Error()})
+	case errors.Is(err, services.ErrTooManyLinkAttempts):
+		c.JSON(http.StatusTooManyRequests, LoginResponse{Success: false, Message: err.Error()})
+	case errors.Is(err, services.ErrAlreadyLinked), errors.Is(err, services.ErrMergeConflict):
+		c.JSON(http.StatusConflict, LoginResponse{Success: false, Message: err.Error()})
+	case errors.Is(err, services.ErrUserNotFound):
+		c.JSON(http.StatusNotFound, LoginResponse{Success: false, Message: err.Error()})
+	case errors.Is(err, services.ErrLinkUnavailable):
+		c.JSON(http.StatusServiceUnavailable, LoginResponse{Success: false, Message: err.Error()})
+	default:
+		logger.Errorf("帳號連結失敗: %v", err)
+		c.JSON(http.StatusInternalServerError, LoginResponse{Success: false, Message: "帳號連結失敗，請稍後再試"})
+	}
+}
@@ -17,7 +17,7 @@ type LobbyServer struct {
 	opt        *server.Options
 	httpServer *http.Server
 	// TODO: 新增 Redis 客戶端
-	// redisClient *redis.Client
+	// redisClient redis.UniversalClient
 }
 
 // Init 初始化伺服器
@@ -33,6 +33,11 @@ func (s *LobbyServer) Init(opts *server.Options) error {
 	// 建立 Gin router
 	r := gin.Default()
 
+	// 只採用可信任代理設定的 X-Forwarded-For，登入限制依來源 IP 計數
+	if err := r.SetTrustedProxies(opts.Config.Server.TrustedProxies); err != nil {
+		return fmt.Errorf("設定可信任代理失敗: %w", err)
+	}
+
 	s.router = r
 	s.opt = opts
 
@@ -44,26 +49,32 @@ func (s *LobbyServer) Init(opts *server.Options) error {
 
 // setupRoutes 設定所有路由
 func (s *LobbyServer) setupRoutes() {
-	// 健康檢查路由
-	s.router.GET("/health", func(c *gin.Context) {
-		c.JSON(200, gin.H{
-			"status":  "ok",
-			"service": s.opt.ServiceName,
-			"env":     s.opt.ServiceEnv,
-			"version": s.opt.Version,
-		})
-	})
+	// 健康檢查路由（/livez、/readyz、/health）
+	server.NewHealthHandler(s.opt).Register(s.router)
+
+	// Token 驗證公鑰（/.well-known/jwks.json），供 Tour Server 離線驗證會員 Token
+	server.RegisterJWKS(s.router)
 
 	// 登入相關路由
 	auth := s.router.Group("/auth")
 	{
+		// 網頁會員註冊
+		auth.POST("/register", s.handleRegister)
+
 		// 會員登入驗證
 		auth.POST("/login", s.handleLogin)
 
-		// TODO: LINE 第三方登入驗證功能待補
-		// auth.POST("/line/callback", s.handleLineLogin)
+		// LINE 第三方登入：取得授權頁網址，使用者授權後以 code 與 state 完成登入
+		auth.GET("/line/authorize", s.handleLineAuthorize)
+		auth.POST("/line/callback", s.handleLineLogin)
+
+		// Telegram Login Widget 登入
+		auth.POST("/telegram", s.handleTelegramLogin)
+
+		// 以 Refresh Token 換發 Token
+		auth.POST("/refresh", s.handleRefreshToken)
 
-		// 登出
+		// 登出（撤銷工作階段）
 		auth.POST("/logout", s.handleLogout)
 
 		// 驗證 Token
@@ -80,6 +91,24 @@ func (s *LobbyServer) setupRoutes() {
 		member.PUT("/:id", s.handleUpdateMemberInfo)
 	}
 
+	// 會員帳號路由：跨平台帳號連結與偏好設定（需要會員 Token）
+	account := s.router.Group("/account")
+	account.Use(s.authMiddleware())
+	{
+		// 取得已連結的平台身分
+		account.GET("/identities", s.handleListIdentities)
+
+		// 產生帳號連結驗證碼
+		account.POST("/link-code", s.handleIssueLinkCode)
+
+		// 以其他平台取得的驗證碼連結帳號
+		account.POST("/link", s.handleLinkAccount)
+
+		// 取得與更新偏好設定（推薦景點時使用）
+		account.GET("/preferences", s.handleGetPreferences)
+		account.PUT("/preferences", s.handleUpdatePreferences)
+	}
+
 	logger.Info("Lobby 路由已設定完成")
 }
 
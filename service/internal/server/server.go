@@ -12,6 +12,8 @@ import (
 	"github.com/andy2kuo/TourHelper/internal/config"
 	"github.com/andy2kuo/TourHelper/internal/database"
 	"github.com/andy2kuo/TourHelper/internal/logger"
+	"github.com/andy2kuo/TourHelper/internal/migration"
+	"github.com/andy2kuo/TourHelper/internal/services"
 )
 
 // Server 介面定義了所有伺服器類型需要實作的方法
@@ -89,6 +91,16 @@ func StartServer(srv Server, opts *Options) error {
 		return fmt.Errorf("資料庫初始化失敗: %w", err)
 	}
 
+	// 套用資料表遷移（多台伺服器同時啟動時透過資料庫鎖確保只執行一次）
+	if opts.Config.Database.AutoMigrate {
+		if err := runMigrations(opts.Config.Database.MigrationLockTimeout); err != nil {
+			return fmt.Errorf("資料表遷移失敗: %w", err)
+		}
+	}
+
+	// 設定業務邏輯層使用的應用程式設定
+	services.SetConfig(opts.Config)
+
 	if err := srv.Init(opts); err != nil {
 		return err
 	}
@@ -100,16 +112,50 @@ func StartServer(srv Server, opts *Options) error {
 		}
 	}()
 
-	waitForShutdown(srv)
+	// 啟動背景工作（多台伺服器透過領導者選舉，每個工作只由一台執行）
+	var workers *workerGroup
+	if ws, ok := srv.(WorkerServer); ok {
+		workers = startWorkers(ws.Workers(), opts.Config.Server.LeaderLeaseTTL)
+	}
+
+	waitForShutdown(srv, workers, opts.Config.Server.DrainDelay)
 
 	return nil
 }
 
-func waitForShutdown(srv Server) {
+// runMigrations 於預設 Master 資料庫套用尚未執行的遷移
+func runMigrations(lockTimeout time.Duration) error {
+	ctx := context.Background()
+	migrator := migration.New(database.GetMySQL().GetMaster(), migration.All(), lockTimeout)
+
+	applied, err := migrator.Up(ctx)
+	if err != nil {
+		return err
+	}
+	if len(applied) > 0 {
+		logger.Infof("已套用 %d 個資料表遷移", len(applied))
+	}
+	return nil
+}
+
+func waitForShutdown(srv Server, workers *workerGroup, drainDelay time.Duration) {
 	quit := make(chan os.Signal, 1)
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
 	<-quit
 
+	// 就緒檢查改回應 503，等待負載平衡器停止導入新流量後再關閉
+	draining.Store(true)
+	if drainDelay > 0 {
+		logger.Infof("等待 %v 讓負載平衡器停止導入流量...", drainDelay)
+		time.Sleep(drainDelay)
+	}
+
+	// 先停止背景工作並釋放領導權，讓其他伺服器立即接手
+	if workers != nil {
+		logger.Info("正在停止背景工作...")
+		workers.stop()
+	}
+
 	logger.Info("正在關閉伺服器...")
 
 	ctx, cancel := context.WithTimeout(context.TODO(), 5*time.Second)
@@ -9,6 +9,7 @@ import (
 	"github.com/andy2kuo/TourHelper/internal/bot/telegram"
 	"github.com/andy2kuo/TourHelper/internal/logger"
 	"github.com/andy2kuo/TourHelper/internal/server"
+	"github.com/andy2kuo/TourHelper/internal/services"
 	"github.com/gin-gonic/gin"
 )
 
@@ -60,15 +61,15 @@ func (s *TourServer) setupRoutes() {
 	s.router.GET("/ws/info", wsHandler.HandleWebSocketInfo)
 	logger.Info("WebSocket 路由已設定: /ws")
 
-	// 健康檢查路由
-	healthCheck := NewHealthCheckHandler(s.opt.ServiceName, s.opt.ServiceEnv, s.opt.Version)
-	s.router.GET("/health", healthCheck.Handle)
+	// 健康檢查路由（/livez、/readyz、/health）
+	server.NewHealthHandler(s.opt).Register(s.router)
 
 	// Line Bot webhook
 	if s.opt.Config.Line.Enabled {
 		lineBot := line.NewBot(
 			s.opt.Config.Line.ChannelSecret,
 			s.opt.Config.Line.ChannelAccessToken,
+			services.Get().User,
 		)
 		s.router.POST("/webhook/line", lineBot.HandleWebhook)
 		logger.Info("Line Bot 已啟用")
@@ -76,7 +77,7 @@ func (s *TourServer) setupRoutes() {
 
 	// Telegram Bot webhook
 	if s.opt.Config.Telegram.Enabled {
-		telegramBot := telegram.NewBot(s.opt.Config.Telegram.Token)
+		telegramBot := telegram.NewBot(s.opt.Config.Telegram.Token, services.Get().User)
 		s.router.POST("/webhook/telegram", telegramBot.HandleWebhook)
 		logger.Info("Telegram Bot 已啟用")
 	}
@@ -3,7 +3,10 @@ package tour
 import (
 	"net/http"
 
+	"github.com/andy2kuo/TourHelper/internal/auth"
 	"github.com/andy2kuo/TourHelper/internal/logger"
+	"github.com/andy2kuo/TourHelper/internal/server"
+	"github.com/andy2kuo/TourHelper/internal/services"
 	"github.com/gin-gonic/gin"
 	"github.com/gorilla/websocket"
 )
@@ -30,7 +33,27 @@ func NewWebSocketHandler(hub *Hub) *WebSocketHandler {
 }
 
 // HandleWebSocket 處理 WebSocket 連線請求
+// 提供會員 Token（查詢參數 token 或 Authorization 標頭）時，以 Lobby 公開的公鑰離線驗證，並以會員 ID 作為客戶端 ID
 func (h *WebSocketHandler) HandleWebSocket(c *gin.Context) {
+	// 瀏覽器的 WebSocket API 無法設定標頭，因此也接受查詢參數
+	token := c.Query("token")
+	if token == "" {
+		token = server.BearerToken(c)
+	}
+
+	var memberID string
+	if token != "" {
+		claims, err := services.Get().Token.Verify(c.Request.Context(), token, auth.AudienceMember)
+		if err != nil {
+			c.JSON(http.StatusUnauthorized, gin.H{
+				"success": false,
+				"message": server.TokenErrorMessage(err),
+			})
+			return
+		}
+		memberID = claims.Subject
+	}
+
 	// 升級 HTTP 連線為 WebSocket
 	conn, err := upgrader.Upgrade(c.Writer, c.Request, nil)
 	if err != nil {
@@ -38,11 +61,14 @@ func (h *WebSocketHandler) HandleWebSocket(c *gin.Context) {
 		return
 	}
 
-	// 從查詢參數或 Header 取得客戶端 ID（可選）
+	// 從查詢參數或 Header 取得客戶端 ID（可選），已驗證的會員一律使用會員 ID
 	clientID := c.Query("client_id")
 	if clientID == "" {
 		clientID = c.GetHeader("X-Client-ID")
 	}
+	if memberID != "" {
+		clientID = "member:" + memberID
+	}
 
 	// 建立新客戶端
 	client := &Client{
@@ -52,6 +78,9 @@ func (h *WebSocketHandler) HandleWebSocket(c *gin.Context) {
 		ID:       clientID,
 		Metadata: make(map[string]interface{}),
 	}
+	if memberID != "" {
+		client.Metadata["member_id"] = memberID
+	}
 
 	// 註冊客戶端
 	h.hub.register <- client
@@ -71,6 +100,6 @@ func (h *WebSocketHandler) HandleWebSocketInfo(c *gin.Context) {
 		"client_ids":   h.hub.GetClientIDs(),
 		"endpoint":     "/ws",
 		"description":  "WebSocket endpoint for real-time communication",
-		"query_params": "client_id (optional) - Unique identifier for the client",
+		"query_params": "client_id (optional) - Unique identifier for the client; token (optional) - Member token issued by the Lobby",
 	})
 }
@@ -0,0 +1,97 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/andy2kuo/TourHelper/internal/database"
+	"github.com/andy2kuo/TourHelper/internal/logger"
+)
+
+// Worker 在所有伺服器副本中只執行一份的背景工作（例如：天氣資料更新、過期資料清理）
+type Worker struct {
+	Name     string                          // 工作名稱，同時作為選舉名稱，需在所有伺服器類型間唯一
+	Interval time.Duration                   // 執行間隔，當選後立即執行一次；0 表示 Run 為常駐工作，應持續執行直到 ctx 取消
+	Run      func(ctx context.Context) error // ctx 在失去領導權或伺服器關閉時取消，可透過 database.FencingToken 取得 fencing token
+}
+
+// WorkerServer 有背景工作的伺服器可實作此介面
+// StartServer 會為每個工作進行領導者選舉，確保同一工作只在一台伺服器上執行
+type WorkerServer interface {
+	Workers() []Worker
+}
+
+// workerGroup 執行中的背景工作
+type workerGroup struct {
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// startWorkers 啟動背景工作，leaseTTL 為領導權租約時間（伺服器異常終止時其他伺服器最長等待此時間後接手）
+// 未啟用 Redis 時無法跨伺服器協調，工作會直接在本機執行
+func startWorkers(workers []Worker, leaseTTL time.Duration) *workerGroup {
+	ctx, cancel := context.WithCancel(context.Background())
+	g := &workerGroup{cancel: cancel}
+
+	var locker *database.Locker
+	if database.RedisEnabled() {
+		locker = database.GetRedis().Locker()
+	}
+
+	for _, worker := range workers {
+		g.wg.Add(1)
+		go func() {
+			defer g.wg.Done()
+
+			if locker == nil {
+				logger.Warnf("未啟用 Redis，背景工作 [%s] 不經選舉直接執行（多台伺服器時會重複執行）", worker.Name)
+				worker.loop(ctx)
+				return
+			}
+
+			election := locker.NewElection("worker:"+worker.Name, database.LockOptions{TTL: leaseTTL})
+			election.Run(ctx, func(ctx context.Context) error {
+				worker.loop(ctx)
+				return nil
+			})
+		}()
+	}
+
+	return g
+}
+
+// stop 停止所有背景工作並等待結束（當選的工作會釋放領導權，讓其他伺服器接手）
+func (g *workerGroup) stop() {
+	g.cancel()
+	g.wg.Wait()
+}
+
+// loop 執行工作直到 ctx 取消
+func (w Worker) loop(ctx context.Context) {
+	if w.Interval <= 0 {
+		w.run(ctx)
+		return
+	}
+
+	ticker := time.NewTicker(w.Interval)
+	defer ticker.Stop()
+
+	for {
+		w.run(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// run 執行一次工作並記錄錯誤
+func (w Worker) run(ctx context.Context) {
+	if err := w.Run(ctx); err != nil && !errors.Is(err, context.Canceled) {
+		logger.Errorf("背景工作 [%s] 執行失敗: %v", w.Name, err)
+	}
+}
@@ -0,0 +1,266 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/andy2kuo/TourHelper/internal/auth"
+	"github.com/andy2kuo/TourHelper/internal/dao"
+	"github.com/andy2kuo/TourHelper/internal/logger"
+	"github.com/andy2kuo/TourHelper/internal/models"
+	"gorm.io/gorm"
+)
+
+// 帳號密碼規則
+const (
+	minPasswordLength    = 8
+	maxPasswordLength    = 72 // 與 bcrypt 的上限一致，避免日後切換演算法時既有密碼被截斷
+	maxDisplayNameLength = 50
+)
+
+// usernamePattern 帳號格式：3 到 32 個英文小寫、數字、底線、點或連字號
+var usernamePattern = regexp.MustCompile(`^[a-z0-9_.-]{3,32}$`)
+
+var (
+	// ErrInvalidRegistration 註冊資料驗證失敗
+	ErrInvalidRegistration = errors.New("註冊資料錯誤")
+	// ErrUsernameTaken 帳號已被使用
+	ErrUsernameTaken = errors.New("帳號已被使用")
+	// ErrInvalidCredentials 帳號或密碼錯誤（不區分帳號不存在或密碼錯誤，避免洩漏帳號是否存在）
+	ErrInvalidCredentials = errors.New("帳號或密碼錯誤")
+	// ErrInvalidRole 不支援的帳號角色
+	ErrInvalidRole = errors.New("角色需為 member、operator、admin 或 super_admin")
+	// ErrCredentialNotFound 帳號不存在（僅用於管理操作，登入時一律回傳 ErrInvalidCredentials）
+	ErrCredentialNotFound = errors.New("帳號不存在")
+)
+
+// dummyPasswordHash 帳號不存在時用來比對的雜湊，讓回應時間與密碼錯誤時相同
+var dummyPasswordHash = sync.OnceValue(func() string {
+	hash, _ := auth.HashPassword("tourhelper-dummy-password")
+	return hash
+})
+
+// RegisterInput 註冊資料
+type RegisterInput struct {
+	Username    string
+	Password    string
+	DisplayName string // 未提供時使用帳號
+	Role        string // 帳號角色，未提供時為 member；僅供內部呼叫（例如建立管理員的指令），不可由註冊請求帶入
+}
+
+// AuthService 帳號密碼驗證服務介面（網頁會員與後台帳號）
+type AuthService interface {
+	// Register 驗證資料並建立網頁會員（含預設偏好設定與帳號密碼）
+	// 資料錯誤時回傳 ErrInvalidRegistration，帳號已被使用時回傳 ErrUsernameTaken
+	Register(ctx context.Context, input RegisterInput) (*models.User, error)
+
+	// Login 驗證帳號密碼並回傳會員，帳號不存在或密碼錯誤時回傳 ErrInvalidCredentials
+	Login(ctx context.Context, username, password string) (*models.User, error)
+
+	// AdminLogin 驗證後台帳號密碼並回傳帳號資料，帳號不存在、密碼錯誤或不具後台角色時回傳 ErrInvalidCredentials
+	AdminLogin(ctx context.Context, username, password string) (*models.Credential, error)
+
+	// SetRole 變更帳號的角色並回傳更新後的帳號資料（授予或移除後台權限）
+	// 角色不支援時回傳 ErrInvalidRole，帳號不存在時回傳 ErrCredentialNotFound
+	SetRole(ctx context.Context, username, role string) (*models.Credential, error)
+}
+
+// authService 網頁會員帳號密碼驗證服務實作
+type authService struct {
+	dao *dao.DAO
+}
+
+// NewAuthService 建立帳號密碼驗證服務
+func NewAuthService(d *dao.DAO) AuthService {
+	return &authService{dao: d}
+}
+
+// Register 註冊網頁會員
+func (s *authService) Register(ctx context.Context, input RegisterInput) (*models.User, error) {
+	input.Username = normalizeUsername(input.Username)
+	input.DisplayName = strings.TrimSpace(input.DisplayName)
+	if err := validateRegistration(input); err != nil {
+		return nil, err
+	}
+	if input.DisplayName == "" {
+		input.DisplayName = input.Username
+	}
+	if input.Role == "" {
+		input.Role = auth.RoleMember
+	}
+	if !IsValidRole(input.Role) {
+		return nil, ErrInvalidRole
+	}
+
+	hash, err := auth.HashPassword(input.Password)
+	if err != nil {
+		return nil, fmt.Errorf("雜湊密碼失敗: %w", err)
+	}
+
+	var user *models.User
+	err = s.dao.WithTx(ctx, func(tx *dao.DAO) error {
+		// 網頁會員以帳號作為外部 ID，與 LINE、Telegram 會員共用 users 資料表
+		var err error
+		user, err = tx.User.FindOrCreateByExternal(ctx, models.PlatformWeb, input.Username, dao.ExternalProfile{
+			Username:    input.Username,
+			DisplayName: input.DisplayName,
+		})
+		if err != nil {
+			return err
+		}
+
+		// 以使用者範圍寫入，讓註冊後立即登入時讀取使用者資料改走 Master
+		return tx.Credential.Create(WithUser(ctx, user.ID), &models.Credential{
+			UserID:       user.ID,
+			Username:     input.Username,
+			PasswordHash: hash,
+			Role:         input.Role,
+		})
+	})
+	if err != nil {
+		if errors.Is(err, dao.ErrCredentialExists) {
+			return nil, ErrUsernameTaken
+		}
+		return nil, fmt.Errorf("註冊會員失敗: %w", err)
+	}
+	return user, nil
+}
+
+// Login 驗證帳號密碼
+func (s *authService) Login(ctx context.Context, username, password string) (*models.User, error) {
+	cred, err := s.verify(ctx, username, password)
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := s.dao.User.FindByID(WithUser(ctx, cred.UserID), cred.UserID)
+	if err != nil {
+		return nil, fmt.Errorf("查詢會員失敗: %w", err)
+	}
+	return user, nil
+}
+
+// AdminLogin 驗證後台帳號密碼
+func (s *authService) AdminLogin(ctx context.Context, username, password string) (*models.Credential, error) {
+	cred, err := s.verify(ctx, username, password)
+	if err != nil {
+		return nil, err
+	}
+	if !IsAdminRole(cred.Role) {
+		return nil, ErrInvalidCredentials
+	}
+	return cred, nil
+}
+
+// SetRole 變更帳號的角色
+func (s *authService) SetRole(ctx context.Context, username, role string) (*models.Credential, error) {
+	if !IsValidRole(role) {
+		return nil, ErrInvalidRole
+	}
+
+	cred, err := s.dao.Credential.FindByUsername(ctx, normalizeUsername(username))
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrCredentialNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("查詢帳號失敗: %w", err)
+	}
+
+	if err := s.dao.Credential.UpdateRole(ctx, cred.ID, role); err != nil {
+		return nil, err
+	}
+	cred.Role = role
+	return cred, nil
+}
+
+// IsValidRole 是否為支援的帳號角色
+func IsValidRole(role string) bool {
+	return role == auth.RoleMember || IsAdminRole(role)
+}
+
+// IsAdminRole 角色是否可登入後台
+func IsAdminRole(role string) bool {
+	switch role {
+	case auth.RoleOperator, auth.RoleAdmin, auth.RoleSuperAdmin:
+		return true
+	}
+	return false
+}
+
+// verify 驗證帳號密碼並回傳帳號資料
+func (s *authService) verify(ctx context.Context, username, password string) (*models.Credential, error) {
+	cred, err := s.dao.Credential.FindByUsername(ctx, normalizeUsername(username))
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		// 帳號不存在時仍執行一次雜湊比對，避免以回應時間判斷帳號是否存在
+		_, _ = auth.VerifyPassword(password, dummyPasswordHash())
+		return nil, ErrInvalidCredentials
+	}
+	if err != nil {
+		return nil, fmt.Errorf("查詢帳號失敗: %w", err)
+	}
+
+	ok, err := auth.VerifyPassword(password, cred.PasswordHash)
+	if err != nil {
+		return nil, fmt.Errorf("驗證密碼失敗: %w", err)
+	}
+	if !ok {
+		return nil, ErrInvalidCredentials
+	}
+
+	if auth.NeedsRehash(cred.PasswordHash) {
+		s.rehash(ctx, cred, password)
+	}
+	return cred, nil
+}
+
+// rehash 以目前的演算法與參數重新雜湊密碼，失敗時僅記錄警告（下次登入會再嘗試）
+func (s *authService) rehash(ctx context.Context, cred *models.Credential, password string) {
+	hash, err := auth.HashPassword(password)
+	if err == nil {
+		err = s.dao.Credential.UpdatePasswordHash(ctx, cred.ID, hash)
+	}
+	if err != nil {
+		logger.Warnf("更新會員 %d 的密碼雜湊失敗: %v", cred.UserID, err)
+	}
+}
+
+// normalizeUsername 去除前後空白並轉為小寫（帳號不區分大小寫）
+func normalizeUsername(username string) string {
+	return strings.ToLower(strings.TrimSpace(username))
+}
+
+// validateRegistration 驗證註冊資料，帳號需已正規化
+func validateRegistration(input RegisterInput) error {
+	if !usernamePattern.MatchString(input.Username) {
+		return fmt.Errorf("%w: 帳號需為 3 到 32 個英文字母、數字、底線、點或連字號", ErrInvalidRegistration)
+	}
+	if utf8.RuneCountInString(input.DisplayName) > maxDisplayNameLength {
+		return fmt.Errorf("%w: 顯示名稱不可超過 %d 個字", ErrInvalidRegistration, maxDisplayNameLength)
+	}
+
+	if n := utf8.RuneCountInString(input.Password); n < minPasswordLength || len(input.Password) > maxPasswordLength {
+		return fmt.Errorf("%w: 密碼長度需介於 %d 到 %d 個字元", ErrInvalidRegistration, minPasswordLength, maxPasswordLength)
+	}
+	var hasLetter, hasDigit bool
+	for _, r := range input.Password {
+		switch {
+		case unicode.IsLetter(r):
+			hasLetter = true
+		case unicode.IsDigit(r):
+			hasDigit = true
+		}
+	}
+	if !hasLetter || !hasDigit {
+		return fmt.Errorf("%w: 密碼需同時包含英文字母與數字", ErrInvalidRegistration)
+	}
+	if strings.EqualFold(input.Password, input.Username) {
+		return fmt.Errorf("%w: 密碼不可與帳號相同", ErrInvalidRegistration)
+	}
+	return nil
+}
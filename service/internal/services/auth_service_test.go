@@ -0,0 +1,105 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/andy2kuo/TourHelper/internal/auth"
+	"github.com/andy2kuo/TourHelper/internal/models"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func TestAuthServiceRegisterAndLogin(t *testing.T) {
+	daos, db := newTestDAO(t)
+	svc := NewAuthService(daos)
+	ctx := context.Background()
+
+	user, err := svc.Register(ctx, RegisterInput{Username: " Alice ", Password: "secret123"})
+	if err != nil {
+		t.Fatalf("Register() 失敗: %v", err)
+	}
+	if user.Platform != models.PlatformWeb || user.ExternalID != "alice" || user.DisplayName != "alice" {
+		t.Errorf("Register() = %+v, 期望網頁會員 alice", user)
+	}
+
+	registerTests := []struct {
+		name  string
+		input RegisterInput
+		want  error
+	}{
+		{"帳號重複（不分大小寫）", RegisterInput{Username: "ALICE", Password: "secret123"}, ErrUsernameTaken},
+		{"帳號過短", RegisterInput{Username: "ab", Password: "secret123"}, ErrInvalidRegistration},
+		{"帳號含不允許的字元", RegisterInput{Username: "bob smith", Password: "secret123"}, ErrInvalidRegistration},
+		{"密碼過短", RegisterInput{Username: "bob", Password: "abc123"}, ErrInvalidRegistration},
+		{"密碼缺少數字", RegisterInput{Username: "bob", Password: "password"}, ErrInvalidRegistration},
+		{"密碼與帳號相同", RegisterInput{Username: "bob12345", Password: "BOB12345"}, ErrInvalidRegistration},
+	}
+	for _, tt := range registerTests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := svc.Register(ctx, tt.input); !errors.Is(err, tt.want) {
+				t.Errorf("Register() 錯誤 = %v, 期望 %v", err, tt.want)
+			}
+		})
+	}
+
+	loginTests := []struct {
+		name     string
+		username string
+		password string
+		want     error
+	}{
+		{"登入成功", "alice", "secret123", nil},
+		{"帳號不分大小寫", "Alice", "secret123", nil},
+		{"密碼錯誤", "alice", "secret124", ErrInvalidCredentials},
+		{"帳號不存在", "nobody", "secret123", ErrInvalidCredentials},
+	}
+	for _, tt := range loginTests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := svc.Login(ctx, tt.username, tt.password)
+			if !errors.Is(err, tt.want) {
+				t.Fatalf("Login() 錯誤 = %v, 期望 %v", err, tt.want)
+			}
+			if err == nil && got.ID != user.ID {
+				t.Errorf("Login() 會員 = %d, 期望 %d", got.ID, user.ID)
+			}
+		})
+	}
+
+	// 一般會員不可登入後台，具後台角色後才可登入
+	if _, err := svc.AdminLogin(ctx, "alice", "secret123"); !errors.Is(err, ErrInvalidCredentials) {
+		t.Errorf("AdminLogin() 錯誤 = %v, 期望 %v", err, ErrInvalidCredentials)
+	}
+	if _, err := svc.SetRole(ctx, "alice", "root"); !errors.Is(err, ErrInvalidRole) {
+		t.Errorf("SetRole(不支援的角色) 錯誤 = %v, 期望 %v", err, ErrInvalidRole)
+	}
+	if _, err := svc.SetRole(ctx, "nobody", auth.RoleAdmin); !errors.Is(err, ErrCredentialNotFound) {
+		t.Errorf("SetRole(不存在的帳號) 錯誤 = %v, 期望 %v", err, ErrCredentialNotFound)
+	}
+	if _, err := svc.SetRole(ctx, "ALICE", auth.RoleAdmin); err != nil {
+		t.Fatalf("SetRole() 失敗: %v", err)
+	}
+	if cred, err := svc.AdminLogin(ctx, "alice", "secret123"); err != nil || cred.Role != auth.RoleAdmin {
+		t.Errorf("AdminLogin() = %+v, %v, 期望角色 %s", cred, err, auth.RoleAdmin)
+	}
+
+	// 建立管理員的指令以註冊時帶入的角色建立帳號
+	if _, err := svc.Register(ctx, RegisterInput{Username: "root", Password: "secret123", Role: auth.RoleSuperAdmin}); err != nil {
+		t.Fatalf("Register(super_admin) 失敗: %v", err)
+	}
+	if cred, err := svc.AdminLogin(ctx, "root", "secret123"); err != nil || cred.Role != auth.RoleSuperAdmin {
+		t.Errorf("AdminLogin() = %+v, %v, 期望角色 %s", cred, err, auth.RoleSuperAdmin)
+	}
+
+	// 舊的 bcrypt 雜湊登入成功後改存 argon2id
+	legacy, _ := bcrypt.GenerateFromPassword([]byte("secret123"), bcrypt.MinCost)
+	db.Model(&models.Credential{}).Where("username = ?", "alice").Update("password_hash", string(legacy))
+	if _, err := svc.Login(ctx, "alice", "secret123"); err != nil {
+		t.Fatalf("Login() bcrypt 雜湊失敗: %v", err)
+	}
+	var cred models.Credential
+	db.Where("username = ?", "alice").First(&cred)
+	if cred.PasswordHash == string(legacy) {
+		t.Error("Login() 後 bcrypt 雜湊應更新為 argon2id")
+	}
+}
@@ -0,0 +1,111 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/andy2kuo/TourHelper/internal/dao"
+	"github.com/andy2kuo/TourHelper/internal/logger"
+	"github.com/andy2kuo/TourHelper/internal/models"
+	"github.com/andy2kuo/TourHelper/pkg/geo"
+)
+
+// destinationIndexRefreshInterval 空間索引增量更新的最短間隔
+const destinationIndexRefreshInterval = time.Minute
+
+// DestinationIndex 景點空間索引
+// 首次使用時從資料庫完整載入，之後依 UpdatedAt/DeletedAt 增量同步異動的景點
+type DestinationIndex struct {
+	dao   *dao.DAO
+	index *geo.Index
+
+	mu         sync.Mutex // 確保同一時間只有一個同步作業
+	synced     bool
+	lastChange time.Time // 已同步的最新異動時間
+	lastSync   time.Time // 上次同步的時間
+}
+
+// NewDestinationIndex 建立景點空間索引
+func NewDestinationIndex(d *dao.DAO) *DestinationIndex {
+	return &DestinationIndex{
+		dao:   d,
+		index: geo.NewIndex(geo.DefaultPrecision),
+	}
+}
+
+// Nearby 查詢指定座標 radiusKm 公里內的景點，結果依距離由近至遠排序
+// 索引超過更新間隔時會先同步資料庫的異動
+func (i *DestinationIndex) Nearby(ctx context.Context, lat, lon, radiusKm float64) ([]geo.Hit, error) {
+	if err := i.refreshIfStale(ctx); err != nil {
+		return nil, err
+	}
+	return i.index.Radius(lat, lon, radiusKm), nil
+}
+
+// Refresh 立即同步資料庫中的景點異動
+func (i *DestinationIndex) Refresh(ctx context.Context) error {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	return i.refreshLocked(ctx)
+}
+
+// refreshIfStale 索引尚未載入或超過更新間隔時進行同步
+// 已載入過的索引同步失敗時僅記錄警告並沿用現有資料
+func (i *DestinationIndex) refreshIfStale(ctx context.Context) error {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	if i.synced && time.Since(i.lastSync) < destinationIndexRefreshInterval {
+		return nil
+	}
+
+	if err := i.refreshLocked(ctx); err != nil {
+		if !i.synced {
+			return err
+		}
+		logger.Warnf("景點空間索引同步失敗，沿用現有索引: %v", err)
+	}
+	return nil
+}
+
+// refreshLocked 載入 lastChange 之後異動的景點並更新索引（呼叫端需持有 mu）
+func (i *DestinationIndex) refreshLocked(ctx context.Context) error {
+	changed, err := i.dao.Destination.FindChangedSince(ctx, i.lastChange)
+	if err != nil {
+		return fmt.Errorf("載入異動景點失敗: %w", err)
+	}
+
+	for _, dest := range changed {
+		if dest.DeletedAt.Valid {
+			i.index.Remove(dest.ID)
+			if dest.DeletedAt.Time.After(i.lastChange) {
+				i.lastChange = dest.DeletedAt.Time
+			}
+		} else {
+			i.index.Upsert(geo.Point{ID: dest.ID, Latitude: dest.Latitude, Longitude: dest.Longitude})
+		}
+		if dest.UpdatedAt.After(i.lastChange) {
+			i.lastChange = dest.UpdatedAt
+		}
+	}
+
+	if !i.synced {
+		logger.Infof("景點空間索引已載入 %d 個景點", i.index.Len())
+	}
+	i.synced = true
+	i.lastSync = time.Now()
+	return nil
+}
+
+// Upsert 將景點加入或更新至索引（供同一程序內的寫入立即生效，其他程序的異動仍由同步取得）
+func (i *DestinationIndex) Upsert(dest *models.Destination) {
+	i.index.Upsert(geo.Point{ID: dest.ID, Latitude: dest.Latitude, Longitude: dest.Longitude})
+}
+
+// Remove 從索引移除景點
+func (i *DestinationIndex) Remove(id uint) {
+	i.index.Remove(id)
+}
@@ -0,0 +1,157 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/andy2kuo/TourHelper/internal/cache"
+	"github.com/andy2kuo/TourHelper/internal/dao"
+	"github.com/andy2kuo/TourHelper/internal/logger"
+	"github.com/andy2kuo/TourHelper/internal/models"
+	"github.com/redis/go-redis/v9"
+	"gorm.io/gorm"
+)
+
+// destinationsCacheTag 依賴景點資料的快取標籤（例如推薦結果），景點異動時一併清除
+const destinationsCacheTag = "destinations"
+
+var (
+	// ErrDestinationNotFound 景點不存在或已刪除
+	ErrDestinationNotFound = errors.New("景點不存在")
+	// ErrInvalidDestination 景點資料驗證失敗
+	ErrInvalidDestination = errors.New("景點資料錯誤")
+)
+
+// DestinationService 景點管理服務介面
+type DestinationService interface {
+	// List 依條件查詢景點列表，查詢條件錯誤時回傳 dao.ErrInvalidQuery
+	List(ctx context.Context, query dao.DestinationQuery) (*dao.DestinationPage, error)
+
+	// Get 取得景點，不存在時回傳 ErrDestinationNotFound
+	Get(ctx context.Context, id uint) (*models.Destination, error)
+
+	// Create 驗證並建立景點，驗證失敗時回傳 ErrInvalidDestination
+	Create(ctx context.Context, dest *models.Destination) error
+
+	// Update 驗證並覆寫景點資料，Tags 為 nil 時保留原有標籤
+	Update(ctx context.Context, dest *models.Destination) error
+
+	// Delete 軟刪除景點，不存在時回傳 ErrDestinationNotFound
+	Delete(ctx context.Context, id uint) error
+}
+
+// destinationService 景點管理服務實作
+// 寫入成功後會同步更新景點空間索引並清除依賴景點的快取，讓推薦立即反映異動
+type destinationService struct {
+	dao   *dao.DAO
+	index *DestinationIndex
+	cache redis.UniversalClient // nil 表示未使用 Redis 快取
+}
+
+// NewDestinationService 建立景點管理服務，cacheClient 需與推薦結果快取使用相同的 Redis 實例
+func NewDestinationService(d *dao.DAO, index *DestinationIndex, cacheClient redis.UniversalClient) DestinationService {
+	return &destinationService{
+		dao:   d,
+		index: index,
+		cache: cacheClient,
+	}
+}
+
+// List 依條件查詢景點列表
+func (s *destinationService) List(ctx context.Context, query dao.DestinationQuery) (*dao.DestinationPage, error) {
+	page, err := s.dao.Destination.List(ctx, query)
+	if err != nil {
+		if errors.Is(err, dao.ErrInvalidQuery) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("查詢景點列表失敗: %w", err)
+	}
+	return page, nil
+}
+
+// Get 取得景點
+func (s *destinationService) Get(ctx context.Context, id uint) (*models.Destination, error) {
+	dest, err := s.dao.Destination.Get(ctx, id)
+	if err != nil {
+		return nil, destinationError("查詢景點失敗", err)
+	}
+	return dest, nil
+}
+
+// Create 建立景點
+func (s *destinationService) Create(ctx context.Context, dest *models.Destination) error {
+	if err := validateDestination(dest); err != nil {
+		return err
+	}
+	if err := s.dao.Destination.Create(ctx, dest); err != nil {
+		return fmt.Errorf("建立景點失敗: %w", err)
+	}
+
+	s.index.Upsert(dest)
+	s.invalidateCaches(ctx)
+	return nil
+}
+
+// Update 更新景點
+func (s *destinationService) Update(ctx context.Context, dest *models.Destination) error {
+	if err := validateDestination(dest); err != nil {
+		return err
+	}
+	if err := s.dao.Destination.Update(ctx, dest); err != nil {
+		return destinationError("更新景點失敗", err)
+	}
+
+	s.index.Upsert(dest)
+	s.invalidateCaches(ctx)
+	return nil
+}
+
+// Delete 軟刪除景點
+func (s *destinationService) Delete(ctx context.Context, id uint) error {
+	if err := s.dao.Destination.SoftDelete(ctx, id); err != nil {
+		return destinationError("刪除景點失敗", err)
+	}
+
+	s.index.Remove(id)
+	s.invalidateCaches(ctx)
+	return nil
+}
+
+// invalidateCaches 清除依賴景點資料的快取，失敗時僅記錄警告（快取會在過期後更新）
+func (s *destinationService) invalidateCaches(ctx context.Context) {
+	if err := cache.InvalidateTags(ctx, s.cache, destinationsCacheTag); err != nil {
+		logger.Warnf("清除景點相關快取失敗: %v", err)
+	}
+}
+
+// validateDestination 驗證景點資料並去除名稱前後空白
+func validateDestination(dest *models.Destination) error {
+	dest.Name = strings.TrimSpace(dest.Name)
+	switch {
+	case dest.Name == "":
+		return fmt.Errorf("%w: 名稱不可為空", ErrInvalidDestination)
+	case dest.Latitude < -90 || dest.Latitude > 90:
+		return fmt.Errorf("%w: 緯度需介於 -90 到 90", ErrInvalidDestination)
+	case dest.Longitude < -180 || dest.Longitude > 180:
+		return fmt.Errorf("%w: 經度需介於 -180 到 180", ErrInvalidDestination)
+	case dest.Rating < 0 || dest.Rating > 5:
+		return fmt.Errorf("%w: 評分需介於 0 到 5", ErrInvalidDestination)
+	}
+
+	if dest.Category != "" {
+		if _, ok := categoryWeatherFit[dest.Category]; !ok {
+			return fmt.Errorf("%w: 不支援的分類 %s", ErrInvalidDestination, dest.Category)
+		}
+	}
+	return nil
+}
+
+// destinationError 將查無資料轉換為 ErrDestinationNotFound，其他錯誤加上說明
+func destinationError(message string, err error) error {
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return ErrDestinationNotFound
+	}
+	return fmt.Errorf("%s: %w", message, err)
+}
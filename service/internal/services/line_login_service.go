@@ -0,0 +1,142 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/andy2kuo/TourHelper/internal/auth"
+	"github.com/andy2kuo/TourHelper/internal/dao"
+	"github.com/andy2kuo/TourHelper/internal/database"
+	"github.com/andy2kuo/TourHelper/internal/logger"
+	"github.com/andy2kuo/TourHelper/internal/models"
+	"github.com/redis/go-redis/v9"
+)
+
+// defaultLineStateTTL state 與 nonce 預設的有效時間
+const defaultLineStateTTL = 10 * time.Minute
+
+var (
+	// ErrLineLoginUnavailable 未啟用 LINE Login 或未啟用 Redis（無法保存 state）
+	ErrLineLoginUnavailable = errors.New("LINE 登入未啟用")
+	// ErrInvalidLoginState state 不存在、已使用過、已過期，或與發起登入的瀏覽器不符
+	ErrInvalidLoginState = errors.New("無效或已過期的登入狀態，請重新登入")
+)
+
+// LineLoginService LINE Login v2.1 第三方登入服務介面
+type LineLoginService interface {
+	// AuthorizeURL 產生 state 與 nonce 並回傳 LINE 授權頁網址與 state
+	// 呼叫端需將 state 保存在發起登入的瀏覽器（例如 HttpOnly Cookie），完成登入時再交給 Login 比對
+	AuthorizeURL(ctx context.Context) (authorizeURL, state string, err error)
+
+	// Login 確認 state 與瀏覽器保存的 boundState 相同並驗證 state（只能使用一次）、以授權碼換取 Token 並驗證 ID Token，
+	// 回傳 LINE 使用者對應的會員（不存在時建立）
+	// state 無效或與 boundState 不符時回傳 ErrInvalidLoginState，LINE 拒絕授權碼或 ID Token 驗證失敗時回傳 auth.ErrLineLogin
+	Login(ctx context.Context, code, state, boundState string) (*models.User, error)
+}
+
+// lineLoginService LINE Login 服務實作
+type lineLoginService struct {
+	dao      *dao.DAO
+	client   *auth.LineClient
+	redis    redis.UniversalClient
+	stateTTL time.Duration
+}
+
+// NewLineLoginService 建立 LINE Login 服務；client 或 redisClient 為 nil 時一律回傳 ErrLineLoginUnavailable
+// state 存放於 Redis，讓使用者從 LINE 導回時不論由哪一台 Lobby 處理都能驗證
+func NewLineLoginService(d *dao.DAO, client *auth.LineClient, redisClient redis.UniversalClient, stateTTL time.Duration) LineLoginService {
+	if stateTTL <= 0 {
+		stateTTL = defaultLineStateTTL
+	}
+	return &lineLoginService{dao: d, client: client, redis: redisClient, stateTTL: stateTTL}
+}
+
+// AuthorizeURL 產生 LINE 授權頁網址
+func (s *lineLoginService) AuthorizeURL(ctx context.Context) (string, string, error) {
+	if s.client == nil || s.redis == nil {
+		return "", "", ErrLineLoginUnavailable
+	}
+
+	state, nonce := rand.Text(), rand.Text()
+	if err := s.redis.Set(ctx, lineStateKey(state), nonce, s.stateTTL).Err(); err != nil {
+		return "", "", fmt.Errorf("保存 LINE 登入狀態失敗: %w", err)
+	}
+	return s.client.AuthorizeURL(state, nonce), state, nil
+}
+
+// Login 完成 LINE 登入
+func (s *lineLoginService) Login(ctx context.Context, code, state, boundState string) (*models.User, error) {
+	if s.client == nil || s.redis == nil {
+		return nil, ErrLineLoginUnavailable
+	}
+	// 先比對瀏覽器保存的 state 再取出，避免攻擊者把自己的授權碼與 state 交給受害者的瀏覽器完成登入（login CSRF），
+	// 也避免不符的請求消耗掉合法的 state
+	if state == "" || subtle.ConstantTimeCompare([]byte(state), []byte(boundState)) != 1 {
+		return nil, ErrInvalidLoginState
+	}
+
+	// 取出即刪除，同一個 state 只能使用一次
+	nonce, err := s.redis.GetDel(ctx, lineStateKey(state)).Result()
+	if errors.Is(err, redis.Nil) {
+		return nil, ErrInvalidLoginState
+	}
+	if err != nil {
+		return nil, fmt.Errorf("讀取 LINE 登入狀態失敗: %w", err)
+	}
+
+	tokens, err := s.client.Exchange(ctx, code)
+	if err != nil {
+		return nil, err
+	}
+	idToken, err := s.client.VerifyIDToken(tokens.IDToken, nonce)
+	if err != nil {
+		return nil, err
+	}
+	profile, err := s.client.Profile(ctx, tokens.AccessToken)
+	if err != nil {
+		return nil, err
+	}
+	if profile.UserID != idToken.Subject {
+		return nil, fmt.Errorf("%w: 使用者資料與 ID Token 不符", auth.ErrLineLogin)
+	}
+
+	displayName := profile.DisplayName
+	if displayName == "" {
+		displayName = idToken.Name
+	}
+	user, err := s.dao.User.FindOrCreateByExternal(ctx, models.PlatformLine, idToken.Subject, dao.ExternalProfile{
+		DisplayName: displayName,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("取得 LINE 會員失敗: %w", err)
+	}
+	return user, nil
+}
+
+// lineStateKey LINE 登入 state 的 Redis key
+func lineStateKey(state string) string {
+	return "tourhelper:line-login:" + state
+}
+
+// newLineLoginService 依設定建立 LINE Login 服務，未啟用或設定錯誤時所有操作回傳 ErrLineLoginUnavailable
+func newLineLoginService(d *dao.DAO) LineLoginService {
+	lineCfg := authConfig().Line
+	if !lineCfg.Enabled {
+		return NewLineLoginService(d, nil, nil, 0)
+	}
+
+	client, err := auth.NewLineClient(lineCfg)
+	if err != nil {
+		logger.Errorf("建立 LINE Login 客戶端失敗，LINE 登入無法使用: %v", err)
+		return NewLineLoginService(d, nil, nil, 0)
+	}
+	if !database.RedisEnabled() {
+		logger.Warn("未啟用 Redis，無法保存登入狀態，LINE 登入無法使用")
+		return NewLineLoginService(d, nil, nil, 0)
+	}
+	return NewLineLoginService(d, client, database.GetRedis().GetClientByDB("session"), lineCfg.StateTTL)
+}
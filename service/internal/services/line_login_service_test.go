@@ -0,0 +1,196 @@
+package services
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/andy2kuo/TourHelper/internal/auth"
+	"github.com/andy2kuo/TourHelper/internal/config"
+	"github.com/andy2kuo/TourHelper/internal/models"
+	"github.com/redis/go-redis/v9"
+)
+
+// fakeLineServer 本機的假 LINE 伺服器，授權碼對應到 nonce（模擬使用者在 LINE 授權頁同意後取得的授權碼）
+type fakeLineServer struct {
+	*httptest.Server
+	secret string
+	mu     sync.Mutex
+	codes  map[string]string
+}
+
+func newFakeLineServer(t *testing.T, channelID, secret, userID string) *fakeLineServer {
+	t.Helper()
+
+	f := &fakeLineServer{secret: secret, codes: make(map[string]string)}
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /oauth2/v2.1/token", func(w http.ResponseWriter, r *http.Request) {
+		f.mu.Lock()
+		nonce, ok := f.codes[r.PostFormValue("code")]
+		delete(f.codes, r.PostFormValue("code"))
+		f.mu.Unlock()
+		if !ok || r.PostFormValue("client_secret") != secret {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"error":"invalid_grant","error_description":"invalid authorization code"}`))
+			return
+		}
+		json.NewEncoder(w).Encode(auth.LineTokens{
+			AccessToken: "line-access-token",
+			IDToken: f.idToken(auth.LineIDToken{
+				Issuer:    "https://access.line.me",
+				Subject:   userID,
+				Audience:  channelID,
+				ExpiresAt: time.Now().Add(time.Hour).Unix(),
+				IssuedAt:  time.Now().Unix(),
+				Nonce:     nonce,
+			}),
+			TokenType: "Bearer",
+		})
+	})
+	mux.HandleFunc("GET /v2/profile", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer line-access-token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		json.NewEncoder(w).Encode(auth.LineProfile{UserID: userID, DisplayName: "小明"})
+	})
+	f.Server = httptest.NewServer(mux)
+	t.Cleanup(f.Close)
+	return f
+}
+
+// authorize 模擬使用者在授權頁同意，回傳 LINE 導回時附帶的 code 與 state
+func (f *fakeLineServer) authorize(t *testing.T, authorizeURL string) (code, state string) {
+	t.Helper()
+
+	u, err := url.Parse(authorizeURL)
+	if err != nil {
+		t.Fatalf("解析授權頁網址失敗: %v", err)
+	}
+	code = "code-" + u.Query().Get("state")
+	f.mu.Lock()
+	f.codes[code] = u.Query().Get("nonce")
+	f.mu.Unlock()
+	return code, u.Query().Get("state")
+}
+
+// idToken 以 Channel Secret 簽署 HS256 ID Token
+func (f *fakeLineServer) idToken(claims auth.LineIDToken) string {
+	payload, _ := json.Marshal(claims)
+	input := base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"HS256","typ":"JWT"}`)) + "." + base64.RawURLEncoding.EncodeToString(payload)
+	mac := hmac.New(sha256.New, []byte(f.secret))
+	mac.Write([]byte(input))
+	return input + "." + base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func TestLineLoginService(t *testing.T) {
+	daos, _ := newTestDAO(t)
+	mr := miniredis.RunT(t)
+	redisClient := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { redisClient.Close() })
+
+	line := newFakeLineServer(t, "1650000000", "channel-secret", "U1234567890abcdef")
+	client, err := auth.NewLineClient(config.LineLoginConfig{
+		ChannelID:     "1650000000",
+		ChannelSecret: "channel-secret",
+		RedirectURI:   "https://tourhelper.example.com/auth/line/callback",
+		AuthorizeURL:  line.URL + "/oauth2/v2.1/authorize",
+		TokenURL:      line.URL + "/oauth2/v2.1/token",
+		ProfileURL:    line.URL + "/v2/profile",
+	})
+	if err != nil {
+		t.Fatalf("NewLineClient() 失敗: %v", err)
+	}
+	svc := NewLineLoginService(daos, client, redisClient, time.Minute)
+	ctx := context.Background()
+
+	authorizeURL, boundState, err := svc.AuthorizeURL(ctx)
+	if err != nil {
+		t.Fatalf("AuthorizeURL() 失敗: %v", err)
+	}
+	code, state := line.authorize(t, authorizeURL)
+	if state != boundState {
+		t.Fatalf("AuthorizeURL() state = %q, 授權頁網址的 state = %q", boundState, state)
+	}
+
+	// 發起登入的瀏覽器以外的請求無法使用 state，且不會消耗掉 state
+	if _, err := svc.Login(ctx, code, state, "other-browser-state"); !errors.Is(err, ErrInvalidLoginState) {
+		t.Errorf("Login() 瀏覽器不符錯誤 = %v, 期望 %v", err, ErrInvalidLoginState)
+	}
+	if _, err := svc.Login(ctx, code, state, ""); !errors.Is(err, ErrInvalidLoginState) {
+		t.Errorf("Login() 缺少瀏覽器 state 錯誤 = %v, 期望 %v", err, ErrInvalidLoginState)
+	}
+
+	user, err := svc.Login(ctx, code, state, boundState)
+	if err != nil {
+		t.Fatalf("Login() 失敗: %v", err)
+	}
+	if user.Platform != models.PlatformLine || user.ExternalID != "U1234567890abcdef" || user.DisplayName != "小明" {
+		t.Errorf("Login() = %+v, 期望 LINE 會員 U1234567890abcdef", user)
+	}
+
+	// 同一位 LINE 使用者再次登入對應到同一位會員
+	code, state = line.authorize(t, mustAuthorizeURL(t, svc))
+	if again, err := svc.Login(ctx, code, state, state); err != nil || again.ID != user.ID {
+		t.Errorf("Login() 再次登入 = %+v, %v, 期望會員 %d", again, err, user.ID)
+	}
+
+	_, usedState := line.authorize(t, mustAuthorizeURL(t, svc))
+	svc.Login(ctx, "wrong-code", usedState, usedState)
+
+	expiredCode, expiredState := line.authorize(t, mustAuthorizeURL(t, svc))
+	mr.FastForward(2 * time.Minute)
+
+	tests := []struct {
+		name  string
+		code  string
+		state string
+		want  error
+	}{
+		{"缺少 state", "wrong-code", "", ErrInvalidLoginState},
+		{"state 不存在", "wrong-code", "forged-state", ErrInvalidLoginState},
+		{"state 已使用過", "wrong-code", usedState, ErrInvalidLoginState},
+		{"state 已過期", expiredCode, expiredState, ErrInvalidLoginState},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := svc.Login(ctx, tt.code, tt.state, tt.state); !errors.Is(err, tt.want) {
+				t.Errorf("Login() 錯誤 = %v, 期望 %v", err, tt.want)
+			}
+		})
+	}
+
+	// LINE 拒絕授權碼
+	_, state = line.authorize(t, mustAuthorizeURL(t, svc))
+	if _, err := svc.Login(ctx, "wrong-code", state, state); !errors.Is(err, auth.ErrLineLogin) {
+		t.Errorf("Login() 授權碼錯誤 = %v, 期望 %v", err, auth.ErrLineLogin)
+	}
+
+	// ID Token 的 nonce 與 state 對應的 nonce 不符（例如授權碼來自另一次授權）
+	otherCode, _ := line.authorize(t, mustAuthorizeURL(t, svc))
+	_, state = line.authorize(t, mustAuthorizeURL(t, svc))
+	if _, err := svc.Login(ctx, otherCode, state, state); !errors.Is(err, auth.ErrLineLogin) {
+		t.Errorf("Login() nonce 不符 = %v, 期望 %v", err, auth.ErrLineLogin)
+	}
+}
+
+// mustAuthorizeURL 取得授權頁網址
+func mustAuthorizeURL(t *testing.T, svc LineLoginService) string {
+	t.Helper()
+
+	authorizeURL, _, err := svc.AuthorizeURL(context.Background())
+	if err != nil {
+		t.Fatalf("AuthorizeURL() 失敗: %v", err)
+	}
+	return authorizeURL
+}
@@ -0,0 +1,293 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/andy2kuo/TourHelper/internal/auth"
+	"github.com/andy2kuo/TourHelper/internal/config"
+	"github.com/andy2kuo/TourHelper/internal/dao"
+	"github.com/andy2kuo/TourHelper/internal/database"
+	"github.com/andy2kuo/TourHelper/internal/logger"
+	"github.com/andy2kuo/TourHelper/internal/models"
+)
+
+// maxThrottleSubjectLength 計數對象的長度上限，與 login_lockouts.subject 欄位長度一致
+// 帳號最長 32 字元，正規化後超過此長度的帳號不可能存在，直接拒絕而不驗證密碼
+const maxThrottleSubjectLength = 64
+
+var (
+	// ErrLoginThrottled 登入失敗次數過多，需等待一段時間才能再次嘗試
+	ErrLoginThrottled = errors.New("登入嘗試過於頻繁，請稍後再試")
+	// ErrLoginLocked 帳號或來源 IP 因登入失敗次數過多而暫時鎖定
+	ErrLoginLocked = errors.New("登入失敗次數過多，已暫時鎖定，請稍後再試或聯絡管理員")
+	// ErrInvalidLockoutKind 不支援的鎖定對象
+	ErrInvalidLockoutKind = errors.New("鎖定對象需為 username 或 ip")
+)
+
+// LoginThrottleError 登入被限制的錯誤，RetryAfter 為需等待的時間
+type LoginThrottleError struct {
+	Err        error // ErrLoginThrottled 或 ErrLoginLocked
+	RetryAfter time.Duration
+}
+
+// Error 回傳錯誤訊息
+func (e *LoginThrottleError) Error() string {
+	return e.Err.Error()
+}
+
+// Unwrap 讓 errors.Is 可比對 ErrLoginThrottled 與 ErrLoginLocked
+func (e *LoginThrottleError) Unwrap() error {
+	return e.Err
+}
+
+// LoginThrottleService 帳號密碼登入的暴力破解防護服務介面
+type LoginThrottleService interface {
+	// Attempt 檢查帳號與來源 IP 是否受限制後執行 login，並依結果更新失敗次數
+	// 受限制時不執行 login 並回傳 *LoginThrottleError；login 回傳 ErrInvalidCredentials 時記錄失敗，成功時清除帳號的失敗記錄
+	// 帳號的失敗只會延遲下次嘗試，所有帳號合計的失敗次數達到門檻（大規模攻擊）時才會鎖定帳號，避免他人輪換 IP 即可鎖住任意帳號
+	// 帳號過長（不可能存在）時不執行 login 並回傳 ErrInvalidCredentials
+	// scope 區分會員（auth.AudienceMember）與後台（auth.AudienceAdmin）登入，兩者分別計數
+	Attempt(ctx context.Context, scope, username, clientIP string, login func() error) error
+
+	// ListLockouts 取得鎖定記錄，由新到舊排序
+	ListLockouts(ctx context.Context, query dao.LoginLockoutQuery) ([]models.LoginLockout, error)
+
+	// Unlock 提前解除帳號（kind 為 auth.ThrottleUsername）或來源 IP（auth.ThrottleIP）的鎖定並清除失敗記錄
+	// 回傳原本是否處於鎖定；kind 不支援時回傳 ErrInvalidLockoutKind
+	Unlock(ctx context.Context, scope, kind, subject, adminID string) (bool, error)
+}
+
+// loginThrottleService 登入暴力破解防護服務實作
+type loginThrottleService struct {
+	dao               *dao.DAO
+	throttle          *auth.LoginThrottle
+	user              auth.ThrottleConfig // 帳號的限制，MaxFailures 只在大規模攻擊時套用
+	ip                auth.ThrottleConfig
+	maxGlobalFailures int // 所有帳號合計的失敗次數達到此值時開始鎖定帳號，0 表示不鎖定帳號
+	now               func() time.Time
+}
+
+// NewLoginThrottleService 建立登入暴力破解防護服務，throttle 為 nil 時不限制登入
+func NewLoginThrottleService(d *dao.DAO, throttle *auth.LoginThrottle, cfg config.LoginThrottleConfig) LoginThrottleService {
+	return &loginThrottleService{
+		dao:      d,
+		throttle: throttle,
+		user: auth.ThrottleConfig{
+			Window:          cfg.Window,
+			MaxFailures:     cfg.MaxUserFailures,
+			FreeAttempts:    cfg.FreeAttempts,
+			BaseDelay:       cfg.BaseDelay,
+			MaxDelay:        cfg.MaxDelay,
+			LockoutDuration: cfg.LockoutDuration,
+		},
+		// 同一 IP 可能有多位使用者（例如公司或行動網路），只在達到門檻時鎖定，不逐次延遲
+		ip: auth.ThrottleConfig{
+			Window:          cfg.Window,
+			MaxFailures:     cfg.MaxIPFailures,
+			LockoutDuration: cfg.IPLockoutDuration,
+		},
+		maxGlobalFailures: cfg.MaxGlobalFailures,
+		now:               time.Now,
+	}
+}
+
+// Attempt 在登入限制下執行 login
+// 檢查限制時即預先記錄本次嘗試，login 完成前並行的其他嘗試已將其計入，無法同時通過檢查
+func (s *loginThrottleService) Attempt(ctx context.Context, scope, username, clientIP string, login func() error) error {
+	if s.throttle == nil {
+		return login()
+	}
+
+	username = normalizeUsername(username)
+	if len(username) > maxThrottleSubjectLength {
+		return ErrInvalidCredentials
+	}
+
+	r, err := s.reserve(ctx, scope, username, clientIP)
+	if err != nil {
+		return err
+	}
+
+	err = login()
+	switch {
+	case errors.Is(err, ErrInvalidCredentials):
+		s.recordFailure(ctx, r.user, clientIP, r.userConfig)
+		s.recordFailure(ctx, r.ip, clientIP, s.ip)
+		if s.maxGlobalFailures > 0 {
+			if err := s.throttle.RecordGlobalFailure(ctx, scope, s.user.Window, s.now()); err != nil {
+				logger.Warnf("記錄登入失敗總數失敗: %v", err)
+			}
+		}
+	case err == nil:
+		// 只清除帳號的失敗記錄，IP 的記錄保留，避免以自己的帳號登入成功來重置對其他帳號的嘗試次數
+		if err := s.throttle.Reset(ctx, scope, auth.ThrottleUsername, username); err != nil {
+			logger.Warnf("清除帳號 %s 的登入失敗記錄失敗: %v", username, err)
+		}
+		s.release(ctx, r)
+	default:
+		s.release(ctx, r)
+	}
+	return err
+}
+
+// attemptReservations 一次登入嘗試在帳號與來源 IP 預先記錄的嘗試，未記錄（Redis 異常或沒有來源 IP）時為 nil
+type attemptReservations struct {
+	user       *auth.ThrottleReservation
+	ip         *auth.ThrottleReservation
+	userConfig auth.ThrottleConfig // 本次嘗試套用的帳號限制（是否鎖定依當時所有帳號合計的失敗次數）
+}
+
+// reserve 檢查帳號與來源 IP 是否受限制並預先記錄本次嘗試
+// 讀寫 Redis 失敗時不限制（僅記錄警告），避免 Redis 異常時無法登入
+func (s *loginThrottleService) reserve(ctx context.Context, scope, username, clientIP string) (attemptReservations, error) {
+	now := s.now()
+	var r attemptReservations
+
+	ipStatus := auth.ThrottleStatus{}
+	if clientIP != "" {
+		reservation, status, err := s.throttle.Reserve(ctx, scope, auth.ThrottleIP, clientIP, s.ip, now)
+		if err != nil {
+			logger.Warnf("檢查 IP %s 的登入限制失敗: %v", clientIP, err)
+		}
+		r.ip, ipStatus = reservation, status
+	}
+
+	userStatus := auth.ThrottleStatus{}
+	if !ipStatus.Locked && ipStatus.RetryAfter <= 0 {
+		r.userConfig = s.userConfig(ctx, scope, now)
+		reservation, status, err := s.throttle.Reserve(ctx, scope, auth.ThrottleUsername, username, r.userConfig, now)
+		if err != nil {
+			logger.Warnf("檢查帳號 %s 的登入限制失敗: %v", username, err)
+		}
+		r.user, userStatus = reservation, status
+	}
+
+	retryAfter := max(userStatus.RetryAfter, ipStatus.RetryAfter)
+	switch {
+	case userStatus.Locked || ipStatus.Locked:
+		s.release(ctx, r)
+		return attemptReservations{}, &LoginThrottleError{Err: ErrLoginLocked, RetryAfter: retryAfter}
+	case retryAfter > 0:
+		s.release(ctx, r)
+		return attemptReservations{}, &LoginThrottleError{Err: ErrLoginThrottled, RetryAfter: retryAfter}
+	}
+	return r, nil
+}
+
+// userConfig 本次嘗試套用的帳號限制：所有帳號合計的失敗次數未達門檻時只延遲不鎖定
+// 讀取 Redis 失敗時視為未達門檻，與其他限制相同不因 Redis 異常而鎖定帳號
+func (s *loginThrottleService) userConfig(ctx context.Context, scope string, now time.Time) auth.ThrottleConfig {
+	cfg := s.user
+	if s.maxGlobalFailures <= 0 {
+		cfg.MaxFailures = 0
+		return cfg
+	}
+
+	failures, err := s.throttle.GlobalFailures(ctx, scope, cfg.Window, now)
+	if err != nil {
+		logger.Warnf("查詢登入失敗總數失敗: %v", err)
+	}
+	if failures < s.maxGlobalFailures {
+		cfg.MaxFailures = 0
+	}
+	return cfg
+}
+
+// release 取消預先記錄的嘗試
+func (s *loginThrottleService) release(ctx context.Context, r attemptReservations) {
+	for _, reservation := range []*auth.ThrottleReservation{r.user, r.ip} {
+		if reservation == nil {
+			continue
+		}
+		if err := s.throttle.Release(ctx, reservation); err != nil {
+			logger.Warnf("取消登入嘗試記錄失敗: %v", err)
+		}
+	}
+}
+
+// recordFailure 確認預先記錄的嘗試失敗，觸發鎖定時新增鎖定記錄供管理員查詢
+func (s *loginThrottleService) recordFailure(ctx context.Context, r *auth.ThrottleReservation, clientIP string, cfg auth.ThrottleConfig) {
+	if r == nil {
+		return
+	}
+	scope, kind, subject := r.Scope(), r.Kind(), r.Subject()
+
+	now := s.now()
+	failures, locked, err := s.throttle.Fail(ctx, r, cfg, now)
+	if err != nil {
+		logger.Warnf("記錄 %s %s 的登入失敗失敗: %v", kind, subject, err)
+		return
+	}
+	if !locked {
+		return
+	}
+
+	logger.WithFields(map[string]interface{}{
+		"scope":     scope,
+		"kind":      kind,
+		"subject":   subject,
+		"client_ip": clientIP,
+		"failures":  failures,
+	}).Warn("登入失敗次數過多，已暫時鎖定")
+
+	err = s.dao.Lockout.Create(ctx, &models.LoginLockout{
+		Scope:       scope,
+		Kind:        kind,
+		Subject:     subject,
+		ClientIP:    clientIP,
+		Failures:    failures,
+		LockedUntil: now.Add(cfg.LockoutDuration),
+	})
+	if err != nil {
+		logger.Errorf("新增登入鎖定記錄失敗: %v", err)
+	}
+}
+
+// ListLockouts 取得鎖定記錄
+func (s *loginThrottleService) ListLockouts(ctx context.Context, query dao.LoginLockoutQuery) ([]models.LoginLockout, error) {
+	if query.ActiveOnly && query.Now.IsZero() {
+		query.Now = s.now()
+	}
+	return s.dao.Lockout.List(ctx, query)
+}
+
+// Unlock 提前解除鎖定
+func (s *loginThrottleService) Unlock(ctx context.Context, scope, kind, subject, adminID string) (bool, error) {
+	switch kind {
+	case auth.ThrottleUsername:
+		subject = normalizeUsername(subject)
+	case auth.ThrottleIP:
+	default:
+		return false, ErrInvalidLockoutKind
+	}
+
+	var locked bool
+	if s.throttle != nil {
+		var err error
+		if locked, err = s.throttle.Unlock(ctx, scope, kind, subject); err != nil {
+			return false, err
+		}
+	}
+
+	n, err := s.dao.Lockout.MarkUnlocked(ctx, scope, kind, subject, adminID, s.now())
+	if err != nil {
+		return false, fmt.Errorf("解除登入鎖定失敗: %w", err)
+	}
+	return locked || n > 0, nil
+}
+
+// newLoginThrottleService 依設定建立登入暴力破解防護服務，未啟用或未啟用 Redis 時不限制登入
+func newLoginThrottleService(d *dao.DAO) LoginThrottleService {
+	throttleCfg := authConfig().LoginThrottle
+	if !throttleCfg.Enabled {
+		return NewLoginThrottleService(d, nil, throttleCfg)
+	}
+	if !database.RedisEnabled() {
+		logger.Warn("未啟用 Redis，無法記錄登入失敗次數，登入不受限制")
+		return NewLoginThrottleService(d, nil, throttleCfg)
+	}
+	return NewLoginThrottleService(d, auth.NewLoginThrottle(database.GetRedis().GetClientByDB("session")), throttleCfg)
+}
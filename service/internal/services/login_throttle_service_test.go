@@ -0,0 +1,149 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/andy2kuo/TourHelper/internal/auth"
+	"github.com/andy2kuo/TourHelper/internal/config"
+	"github.com/andy2kuo/TourHelper/internal/dao"
+	"github.com/redis/go-redis/v9"
+)
+
+func TestLoginThrottleService(t *testing.T) {
+	daos, _ := newTestDAO(t)
+	mr := miniredis.RunT(t)
+	svc := NewLoginThrottleService(daos, auth.NewLoginThrottle(redis.NewClient(&redis.Options{Addr: mr.Addr()})), config.LoginThrottleConfig{
+		Window:            time.Minute,
+		MaxUserFailures:   3,
+		MaxIPFailures:     5,
+		MaxGlobalFailures: 6,
+		FreeAttempts:      1,
+		BaseDelay:         time.Second,
+		MaxDelay:          time.Second,
+		LockoutDuration:   10 * time.Minute,
+		IPLockoutDuration: time.Hour,
+	}).(*loginThrottleService)
+	now := time.UnixMilli(time.Now().UnixMilli())
+	svc.now = func() time.Time { return now }
+	ctx := context.Background()
+
+	wrong := func() error { return ErrInvalidCredentials }
+	attempt := func(username, ip string, login func() error) error {
+		return svc.Attempt(ctx, auth.AudienceMember, username, ip, login)
+	}
+
+	// 第一次失敗不需等待，第二次失敗後需等待（即使換了 IP）
+	attempt("Alice", "192.0.2.1", wrong)
+	attempt("alice", "192.0.2.2", wrong)
+	var throttleErr *LoginThrottleError
+	if err := attempt("alice", "192.0.2.3", wrong); !errors.As(err, &throttleErr) || !errors.Is(err, ErrLoginThrottled) || throttleErr.RetryAfter != time.Second {
+		t.Fatalf("Attempt(未等待) 錯誤 = %v, 期望 %v 並需等待 1s", err, ErrLoginThrottled)
+	}
+
+	// 未達所有帳號合計的門檻時，輪換 IP 的失敗達到 maxUserFailures 也只延遲，帳號本人等待後仍可登入
+	now = now.Add(time.Second)
+	attempt("alice", "192.0.2.3", wrong)
+	now = now.Add(time.Second)
+	if err := attempt("alice", "198.51.100.1", func() error { return nil }); err != nil {
+		t.Fatalf("Attempt(未達攻擊門檻) 錯誤 = %v, 期望可登入", err)
+	}
+	if lockouts, _ := svc.ListLockouts(ctx, dao.LoginLockoutQuery{ActiveOnly: true}); len(lockouts) != 0 {
+		t.Fatalf("ListLockouts() = %+v, 期望未達攻擊門檻時不鎖定帳號", lockouts)
+	}
+
+	// 所有帳號合計失敗達到門檻後，帳號失敗達到 maxUserFailures 時鎖定帳號並新增鎖定記錄
+	for _, name := range []string{"bob", "carol", "dave"} {
+		attempt(name, "203.0.113.1", wrong)
+	}
+	attempt("alice", "203.0.113.2", wrong)
+	attempt("alice", "203.0.113.3", wrong)
+	now = now.Add(time.Second)
+	attempt("alice", "203.0.113.4", wrong)
+	called := false
+	err := attempt("alice", "198.51.100.1", func() error { called = true; return nil })
+	if !errors.Is(err, ErrLoginLocked) || called {
+		t.Fatalf("Attempt(鎖定中) 錯誤 = %v, 執行登入 = %v, 期望 %v 且不執行登入", err, called, ErrLoginLocked)
+	}
+	lockouts, _ := svc.ListLockouts(ctx, dao.LoginLockoutQuery{ActiveOnly: true})
+	if len(lockouts) != 1 || lockouts[0].Kind != auth.ThrottleUsername || lockouts[0].Subject != "alice" || lockouts[0].Failures != 3 {
+		t.Fatalf("ListLockouts() = %+v, 期望 alice 的帳號鎖定記錄", lockouts)
+	}
+
+	// 管理員解除鎖定後可再登入
+	if unlocked, err := svc.Unlock(ctx, auth.AudienceMember, auth.ThrottleUsername, "ALICE", "1"); err != nil || !unlocked {
+		t.Fatalf("Unlock() = %v, %v, 期望解除鎖定", unlocked, err)
+	}
+	if err := attempt("alice", "198.51.100.1", func() error { return nil }); err != nil {
+		t.Errorf("Attempt(解除鎖定後) 錯誤 = %v", err)
+	}
+	if lockouts, _ := svc.ListLockouts(ctx, dao.LoginLockoutQuery{ActiveOnly: true}); len(lockouts) != 0 {
+		t.Errorf("ListLockouts(解除後) = %+v, 期望沒有鎖定中的記錄", lockouts)
+	}
+
+	// 同一 IP 對不同帳號失敗過多時鎖定 IP（先前已失敗 3 次）
+	attempt("erin", "203.0.113.1", wrong)
+	attempt("frank", "203.0.113.1", wrong)
+	if err := attempt("grace", "203.0.113.1", func() error { return nil }); !errors.Is(err, ErrLoginLocked) {
+		t.Errorf("Attempt(IP 鎖定中) 錯誤 = %v, 期望 %v", err, ErrLoginLocked)
+	}
+
+	// 超長的帳號不可能存在，直接拒絕且不驗證密碼、不計數
+	longName := strings.Repeat("x", maxThrottleSubjectLength+1)
+	called = false
+	if err := attempt(longName, "192.0.2.100", func() error { called = true; return nil }); !errors.Is(err, ErrInvalidCredentials) || called {
+		t.Errorf("Attempt(超長帳號) 錯誤 = %v, 執行登入 = %v, 期望 %v 且不執行登入", err, called, ErrInvalidCredentials)
+	}
+	if mr.Exists("tourhelper:login-fail:{member:ip:192.0.2.100}") {
+		t.Error("Attempt(超長帳號) 不應記錄失敗次數")
+	}
+
+	if _, err := svc.Unlock(ctx, auth.AudienceMember, "email", "alice", "1"); !errors.Is(err, ErrInvalidLockoutKind) {
+		t.Errorf("Unlock(不支援的對象) 錯誤 = %v, 期望 %v", err, ErrInvalidLockoutKind)
+	}
+}
+
+func TestLoginThrottleServiceConcurrentAttempts(t *testing.T) {
+	daos, _ := newTestDAO(t)
+	mr := miniredis.RunT(t)
+	svc := NewLoginThrottleService(daos, auth.NewLoginThrottle(redis.NewClient(&redis.Options{Addr: mr.Addr()})), config.LoginThrottleConfig{
+		Window:            time.Minute,
+		MaxUserFailures:   5,
+		MaxIPFailures:     100,
+		FreeAttempts:      1,
+		BaseDelay:         time.Second,
+		MaxDelay:          time.Second,
+		LockoutDuration:   10 * time.Minute,
+		IPLockoutDuration: time.Hour,
+	})
+	ctx := context.Background()
+
+	// 密碼驗證較慢時，並行的嘗試在驗證完成前就已計入，只有不需等待的前兩個嘗試會執行驗證
+	release := make(chan struct{})
+	var calls atomic.Int32
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			svc.Attempt(ctx, auth.AudienceMember, "alice", "192.0.2.1", func() error {
+				calls.Add(1)
+				<-release
+				return ErrInvalidCredentials
+			})
+		}()
+	}
+	time.Sleep(100 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	if n := calls.Load(); n != 2 {
+		t.Errorf("執行驗證次數 = %d, 期望 2", n)
+	}
+}
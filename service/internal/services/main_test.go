@@ -0,0 +1,28 @@
+package services
+
+import (
+	"os"
+	"testing"
+
+	"github.com/andy2kuo/TourHelper/internal/config"
+	"github.com/andy2kuo/TourHelper/internal/logger"
+)
+
+func TestMain(m *testing.M) {
+	// 服務層會寫入 Logger（例如帳號連結或登入受限制時），未初始化時呼叫會 panic
+	// 日誌目錄相對於工作目錄，切換到暫存目錄再初始化，避免在專案目錄產生日誌檔
+	dir, err := os.MkdirTemp("", "services-test")
+	if err != nil {
+		panic(err)
+	}
+	if err := os.Chdir(dir); err != nil {
+		panic(err)
+	}
+	if err := logger.Init("services_test", "dev", config.LogConfig{Level: "error"}); err != nil {
+		panic(err)
+	}
+
+	code := m.Run()
+	os.RemoveAll(dir)
+	os.Exit(code)
+}
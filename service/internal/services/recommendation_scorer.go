@@ -0,0 +1,121 @@
+package services
+
+import (
+	"math"
+
+	"github.com/andy2kuo/TourHelper/internal/models"
+)
+
+// 各評分項目的權重（總和為 100）
+const (
+	weightDistance   = 40.0 // 距離越近分數越高
+	weightRating     = 25.0 // 評分越高分數越高
+	weightWeather    = 20.0 // 景點類型與天氣的契合度
+	weightPreference = 15.0 // 與使用者偏好類型的契合度
+)
+
+// weatherMatchThreshold 天氣契合度達此門檻即視為適合當前天氣
+const weatherMatchThreshold = 0.6
+
+// categoryWeatherFit 景點類型在各種天氣下的契合度 (0-1)
+var categoryWeatherFit = map[string]map[string]float64{
+	"nature": {
+		"sunny":  1.0,
+		"cloudy": 0.8,
+		"rainy":  0.2,
+		"snowy":  0.5,
+	},
+	"adventure": {
+		"sunny":  1.0,
+		"cloudy": 0.8,
+		"rainy":  0.1,
+		"snowy":  0.3,
+	},
+	"culture": {
+		"sunny":  0.8,
+		"cloudy": 0.9,
+		"rainy":  0.6,
+		"snowy":  0.6,
+	},
+	"food": {
+		"sunny":  0.8,
+		"cloudy": 0.9,
+		"rainy":  0.9,
+		"snowy":  0.8,
+	},
+	"shopping": {
+		"sunny":  0.7,
+		"cloudy": 0.9,
+		"rainy":  1.0,
+		"snowy":  0.9,
+	},
+}
+
+// scoreInput 單一景點的評分輸入
+type scoreInput struct {
+	Destination *models.Destination
+	Distance    float64                 // 與使用者的距離（公里）
+	MaxDistance float64                 // 可接受的最大距離（公里）
+	Preferences *models.UserPreferences // 使用者偏好
+	Weather     *models.WeatherInfo     // 當前天氣，nil 表示無法取得
+}
+
+// scoreDestination 計算景點適合度評分 (0-100) 與是否適合當前天氣
+func scoreDestination(in scoreInput) (float64, bool) {
+	weatherFit, weatherMatch := weatherScore(in.Destination.Category, in.Weather)
+
+	score := weightDistance*distanceScore(in.Distance, in.MaxDistance) +
+		weightRating*ratingScore(in.Destination.Rating) +
+		weightWeather*weatherFit +
+		weightPreference*preferenceScore(in.Destination.Category, in.Preferences)
+
+	return math.Round(clamp(score, 0, 100)*10) / 10, weatherMatch
+}
+
+// distanceScore 距離分數：距離為 0 時得滿分，達到最大距離時為 0
+func distanceScore(distance, maxDistance float64) float64 {
+	if maxDistance <= 0 {
+		return 0
+	}
+	return clamp(1-distance/maxDistance, 0, 1)
+}
+
+// ratingScore 評分分數：以 5 分為滿分換算
+func ratingScore(rating float64) float64 {
+	return clamp(rating/5, 0, 1)
+}
+
+// weatherScore 天氣契合度分數，無天氣資訊或未知類型時給予中性分數
+func weatherScore(category string, weather *models.WeatherInfo) (float64, bool) {
+	if weather == nil || weather.Condition == "" {
+		return 0.5, true
+	}
+
+	fits, ok := categoryWeatherFit[category]
+	if !ok {
+		return 0.5, true
+	}
+
+	fit, ok := fits[weather.Condition]
+	if !ok {
+		return 0.5, true
+	}
+
+	return fit, fit >= weatherMatchThreshold
+}
+
+// preferenceScore 偏好分數：符合偏好類型得滿分，未設定偏好時給予中性分數
+func preferenceScore(category string, prefs *models.UserPreferences) float64 {
+	if prefs == nil || prefs.PreferredCategory == "" {
+		return 0.5
+	}
+	if prefs.PreferredCategory == category {
+		return 1
+	}
+	return 0
+}
+
+// clamp 將數值限制在 [min, max] 區間
+func clamp(v, min, max float64) float64 {
+	return math.Max(min, math.Min(max, v))
+}
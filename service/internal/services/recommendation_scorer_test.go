@@ -0,0 +1,82 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/andy2kuo/TourHelper/internal/models"
+)
+
+func TestScoreDestination(t *testing.T) {
+	sunny := &models.WeatherInfo{Condition: "sunny"}
+	rainy := &models.WeatherInfo{Condition: "rainy"}
+
+	tests := []struct {
+		name          string
+		category      string
+		rating        float64
+		distance      float64
+		prefs         *models.UserPreferences
+		weather       *models.WeatherInfo
+		expected      float64
+		expectedMatch bool
+	}{
+		{
+			name:          "晴天、零距離、滿分且符合偏好",
+			category:      "nature",
+			rating:        5,
+			distance:      0,
+			prefs:         &models.UserPreferences{PreferredCategory: "nature"},
+			weather:       sunny,
+			expected:      100,
+			expectedMatch: true,
+		},
+		{
+			name:          "雨天的戶外景點",
+			category:      "nature",
+			rating:        5,
+			distance:      0,
+			prefs:         &models.UserPreferences{PreferredCategory: "nature"},
+			weather:       rainy,
+			expected:      84, // 40 + 25 + 20*0.2 + 15
+			expectedMatch: false,
+		},
+		{
+			name:          "達最大距離且不符偏好",
+			category:      "shopping",
+			rating:        4,
+			distance:      50,
+			prefs:         &models.UserPreferences{PreferredCategory: "nature"},
+			weather:       rainy,
+			expected:      40, // 0 + 25*0.8 + 20*1.0 + 0
+			expectedMatch: true,
+		},
+		{
+			name:          "無天氣資訊且未設定偏好",
+			category:      "culture",
+			rating:        4,
+			distance:      25,
+			prefs:         nil,
+			weather:       nil,
+			expected:      57.5, // 40*0.5 + 25*0.8 + 20*0.5 + 15*0.5
+			expectedMatch: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			score, match := scoreDestination(scoreInput{
+				Destination: &models.Destination{Category: tt.category, Rating: tt.rating},
+				Distance:    tt.distance,
+				MaxDistance: 50,
+				Preferences: tt.prefs,
+				Weather:     tt.weather,
+			})
+			if score != tt.expected {
+				t.Errorf("scoreDestination() 分數 = %v, 期望 %v", score, tt.expected)
+			}
+			if match != tt.expectedMatch {
+				t.Errorf("scoreDestination() 天氣契合 = %v, 期望 %v", match, tt.expectedMatch)
+			}
+		})
+	}
+}
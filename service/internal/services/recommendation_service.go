@@ -1,20 +1,281 @@
 package services
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/andy2kuo/TourHelper/internal/cache"
+	"github.com/andy2kuo/TourHelper/internal/config"
 	"github.com/andy2kuo/TourHelper/internal/dao"
+	"github.com/andy2kuo/TourHelper/internal/logger"
+	"github.com/andy2kuo/TourHelper/internal/models"
+	"github.com/andy2kuo/TourHelper/internal/weather"
+	"github.com/andy2kuo/TourHelper/pkg/utils"
+	"github.com/redis/go-redis/v9"
+	"golang.org/x/sync/errgroup"
+	"gorm.io/gorm"
+)
+
+const (
+	defaultMaxDistance  = 50.0 // 預設最大距離（公里），與 UserPreferences 預設值一致
+	defaultMinRating    = 3.0  // 預設最低評分，與 UserPreferences 預設值一致
+	defaultAverageSpeed = 40.0 // 預估旅行時間使用的平均車速（km/h）
+	maxRecommendations  = 10   // 最多回傳的推薦數量
+
+	recommendationCachePrefix = "cache:recommendation" // 推薦結果快取的 Redis key 前綴
+	recommendationGridSize    = 0.001                  // 快取推薦結果時座標對齊的格子大小（度），約 100 公尺
+
+	maxDestinationWeatherLookups  = 30 // 每次推薦最多查詢幾個景點所在鄉鎮的天氣，避免快取未命中時大量呼叫天氣 API
+	destinationWeatherConcurrency = 5  // 同時查詢景點天氣的數量
 )
 
 // RecommendationService 推薦服務介面
 type RecommendationService interface {
-	// TODO: 實作推薦業務邏輯
+	// Recommend 根據位置、天氣與使用者偏好推薦景點，依適合度由高至低排序
+	Recommend(ctx context.Context, req models.RecommendationRequest) (*models.RecommendationResponse, error)
 }
 
 // recommendationService 推薦服務實作
 type recommendationService struct {
-	dao *dao.DAO
+	dao     *dao.DAO
+	weather WeatherService
+	index   *DestinationIndex
+	cache   *cache.Cache[models.RecommendationResponse] // nil 表示不快取推薦結果
 }
 
 // NewRecommendationService 建立推薦服務
-func NewRecommendationService(d *dao.DAO) RecommendationService {
-	return &recommendationService{dao: d}
+// redisClient 為 nil 或 cfg.RecommendationTTL 為 0 時不快取推薦結果
+func NewRecommendationService(d *dao.DAO, weather WeatherService, index *DestinationIndex, redisClient redis.UniversalClient, cfg config.CacheConfig) RecommendationService {
+	svc := &recommendationService{dao: d, weather: weather, index: index}
+
+	if redisClient != nil && cfg.RecommendationTTL > 0 {
+		codec, err := cache.CodecByName(cfg.Codec)
+		if err != nil {
+			logger.Warnf("%v，推薦結果快取改用 JSON", err)
+			codec = cache.JSON
+		}
+		svc.cache = cache.New[models.RecommendationResponse](redisClient, cache.Options{
+			Prefix: recommendationCachePrefix,
+			TTL:    cfg.RecommendationTTL,
+			Jitter: cfg.Jitter,
+			Codec:  codec,
+		})
+	}
+	return svc
+}
+
+// Recommend 根據位置、天氣與使用者偏好推薦景點
+// 啟用快取時，座標會對齊到約 100 公尺的格子、出發時間取到分鐘，鄰近的相同查詢共用結果；
+// 景點異動時會清除所有推薦結果快取
+func (s *recommendationService) Recommend(ctx context.Context, req models.RecommendationRequest) (*models.RecommendationResponse, error) {
+	// 使用者剛更新偏好設定時，避免從延遲的 Slave 讀到舊資料
+	if req.UserID != 0 {
+		ctx = WithUser(ctx, req.UserID)
+	}
+
+	if s.cache == nil {
+		return s.recommend(ctx, req)
+	}
+
+	req.Latitude, req.Longitude = weather.SnapToGrid(req.Latitude, req.Longitude, recommendationGridSize)
+	if req.TargetTime != nil {
+		at := req.TargetTime.Truncate(time.Minute)
+		req.TargetTime = &at
+	}
+
+	tags := []string{destinationsCacheTag}
+	if req.UserID != 0 {
+		tags = append(tags, userCacheTag(req.UserID))
+	}
+
+	resp, err := s.cache.GetOrLoad(ctx, recommendationCacheKey(req), func(ctx context.Context) (models.RecommendationResponse, error) {
+		resp, err := s.recommend(ctx, req)
+		if err != nil {
+			return models.RecommendationResponse{}, err
+		}
+		return *resp, nil
+	}, tags...)
+	if err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// recommend 計算推薦結果（不經過快取）
+func (s *recommendationService) recommend(ctx context.Context, req models.RecommendationRequest) (*models.RecommendationResponse, error) {
+	prefs, err := s.loadPreferences(ctx, req.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	// 請求中的條件優先於使用者偏好
+	maxDistance := prefs.MaxDistance
+	if req.MaxDistance > 0 {
+		maxDistance = req.MaxDistance
+	}
+	if maxDistance <= 0 {
+		maxDistance = defaultMaxDistance
+	}
+
+	// 出發地的天氣：回傳給使用者，並作為景點天氣取得失敗時的備援
+	// 有指定出發時間時使用該時段的預報；天氣資訊取得失敗時仍繼續推薦，天氣分數以中性值計算
+	var weather *models.WeatherInfo
+	if req.TargetTime != nil {
+		weather, err = s.weather.GetWeatherAt(ctx, req.Latitude, req.Longitude, *req.TargetTime)
+	} else {
+		weather, err = s.weather.GetCurrentWeather(ctx, req.Latitude, req.Longitude)
+	}
+	if err != nil {
+		logger.Warnf("取得天氣資訊失敗，以無天氣資訊進行推薦: %v", err)
+		weather = nil
+	}
+
+	// 透過空間索引找出最大距離內的景點，再載入完整資料
+	hits, err := s.index.Nearby(ctx, req.Latitude, req.Longitude, maxDistance)
+	if err != nil {
+		return nil, fmt.Errorf("查詢鄰近景點失敗: %w", err)
+	}
+
+	ids := make([]uint, len(hits))
+	for i, hit := range hits {
+		ids[i] = hit.ID
+	}
+
+	candidates, err := s.dao.Destination.FindByIDs(ctx, ids)
+	if err != nil {
+		return nil, fmt.Errorf("查詢候選景點失敗: %w", err)
+	}
+
+	type candidate struct {
+		dest     *models.Destination
+		distance float64
+	}
+	matched := make([]candidate, 0, len(candidates))
+	for i := range candidates {
+		dest := &candidates[i]
+
+		if req.Category != "" && dest.Category != req.Category {
+			continue
+		}
+		if dest.Rating < prefs.MinRating {
+			continue
+		}
+
+		// 索引同步前景點座標可能已被修改，以資料庫中的座標重新計算距離
+		distance := utils.CalculateDistance(req.Latitude, req.Longitude, dest.Latitude, dest.Longitude)
+		if distance > maxDistance {
+			continue
+		}
+		matched = append(matched, candidate{dest: dest, distance: distance})
+	}
+
+	// 以景點所在鄉鎮的天氣評分，距離近的景點優先查詢
+	sort.SliceStable(matched, func(i, j int) bool { return matched[i].distance < matched[j].distance })
+	dests := make([]*models.Destination, len(matched))
+	for i, c := range matched {
+		dests[i] = c.dest
+	}
+	destWeather := s.destinationWeather(ctx, dests, req.TargetTime, weather)
+
+	results := make([]models.DestinationWithDistance, 0, len(matched))
+	for i, c := range matched {
+		suitability, weatherMatch := scoreDestination(scoreInput{
+			Destination: c.dest,
+			Distance:    c.distance,
+			MaxDistance: maxDistance,
+			Preferences: prefs,
+			Weather:     destWeather[i],
+		})
+
+		results = append(results, models.DestinationWithDistance{
+			Destination:  *c.dest,
+			Distance:     c.distance,
+			TravelTime:   utils.EstimateTravelTime(c.distance, defaultAverageSpeed),
+			Suitability:  suitability,
+			WeatherMatch: weatherMatch,
+		})
+	}
+
+	// 適合度高者優先，同分時距離近者優先
+	sort.SliceStable(results, func(i, j int) bool {
+		if results[i].Suitability != results[j].Suitability {
+			return results[i].Suitability > results[j].Suitability
+		}
+		return results[i].Distance < results[j].Distance
+	})
+	if len(results) > maxRecommendations {
+		results = results[:maxRecommendations]
+	}
+
+	resp := &models.RecommendationResponse{
+		Destinations: results,
+	}
+	if weather != nil {
+		resp.Weather = *weather
+	}
+	if len(results) == 0 {
+		resp.Message = "附近沒有符合條件的景點"
+	}
+
+	return resp, nil
+}
+
+// destinationWeather 取得各景點所在鄉鎮在出發時間的天氣，回傳順序與 dests 相同
+// 只查詢前 maxDestinationWeatherLookups 個景點（呼叫端依距離排序），其餘景點及查詢失敗時使用出發地的天氣 fallback
+func (s *recommendationService) destinationWeather(ctx context.Context, dests []*models.Destination, targetTime *time.Time, fallback *models.WeatherInfo) []*models.WeatherInfo {
+	result := make([]*models.WeatherInfo, len(dests))
+	for i := range result {
+		result[i] = fallback
+	}
+
+	var at time.Time
+	if targetTime != nil {
+		at = *targetTime
+	}
+
+	var g errgroup.Group
+	g.SetLimit(destinationWeatherConcurrency)
+	for i, dest := range dests[:min(len(dests), maxDestinationWeatherLookups)] {
+		g.Go(func() error {
+			info, err := s.weather.GetDestinationWeather(ctx, dest, at)
+			if err != nil {
+				logger.Warnf("取得景點 %d 的天氣失敗，改用出發地天氣: %v", dest.ID, err)
+				return nil
+			}
+			result[i] = info
+			return nil
+		})
+	}
+	g.Wait()
+
+	return result
+}
+
+// loadPreferences 取得使用者偏好，未登入或尚未設定時使用預設值
+func (s *recommendationService) loadPreferences(ctx context.Context, userID uint) (*models.UserPreferences, error) {
+	if userID == 0 {
+		return defaultPreferences(userID), nil
+	}
+
+	prefs, err := s.dao.User.FindPreferencesByUserID(ctx, userID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return defaultPreferences(userID), nil
+		}
+		return nil, fmt.Errorf("查詢使用者偏好失敗: %w", err)
+	}
+
+	return prefs, nil
+}
+
+// recommendationCacheKey 推薦結果的快取 key（座標需先對齊格子）
+func recommendationCacheKey(req models.RecommendationRequest) string {
+	var at int64
+	if req.TargetTime != nil {
+		at = req.TargetTime.Unix()
+	}
+	return fmt.Sprintf("%.6f:%.6f:u%d:c%s:d%g:t%d", req.Latitude, req.Longitude, req.UserID, req.Category, req.MaxDistance, at)
 }
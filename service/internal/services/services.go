@@ -1,34 +1,113 @@
 package services
 
 import (
+	"context"
+	"fmt"
 	"sync"
 
+	"github.com/andy2kuo/TourHelper/internal/config"
 	"github.com/andy2kuo/TourHelper/internal/dao"
+	"github.com/andy2kuo/TourHelper/internal/database"
+	"github.com/andy2kuo/TourHelper/internal/logger"
+	"github.com/andy2kuo/TourHelper/internal/weather"
+	"github.com/redis/go-redis/v9"
 )
 
 // Services 集中管理所有 service 實例
 type Services struct {
 	Recommendation RecommendationService
 	Weather        WeatherService
-	// 未來可以新增其他 service，例如：
-	// User           UserService
-	// Destination    DestinationService
+	Destination    DestinationService
+	User           UserService
+	Auth           AuthService
+	Token          TokenService
+	LineLogin      LineLoginService
+	TelegramLogin  TelegramLoginService
+	LoginThrottle  LoginThrottleService
 }
 
 var (
 	instance *Services
 	once     sync.Once
+	cfg      *config.Config
 )
 
+// SetConfig 設定 service 使用的應用程式設定（需在 Get 之前呼叫）
+func SetConfig(c *config.Config) {
+	cfg = c
+}
+
 // Get 取得 services 實例（單例模式）
 func Get() *Services {
 	once.Do(func() {
 		daos := dao.Get()
+		cacheClient := newCacheRedisClient()
+		weatherSvc := NewWeatherService(newWeatherProvider(), daos, cacheClient, weatherConfig())
+		index := NewDestinationIndex(daos)
 		instance = &Services{
-			Recommendation: NewRecommendationService(daos),
-			Weather:        NewWeatherService(),
+			Recommendation: NewRecommendationService(daos, weatherSvc, index, cacheClient, cacheConfig()),
+			Weather:        weatherSvc,
+			Destination:    NewDestinationService(daos, index, cacheClient),
+			User:           newUserService(daos, cacheClient),
+			Auth:           NewAuthService(daos),
+			Token:          newTokenService(daos),
+			LineLogin:      newLineLoginService(daos),
+			TelegramLogin:  newTelegramLoginService(daos),
+			LoginThrottle:  newLoginThrottleService(daos),
 			// 初始化其他 service
 		}
 	})
 	return instance
 }
+
+// WithUser 標記 context 屬於指定使用者，讓此使用者寫入後的一段時間內讀取改走 Master（跨伺服器共用）
+// 處理已知使用者的請求時，應在呼叫 service 前以此包裝 context
+func WithUser(ctx context.Context, userID uint) context.Context {
+	return database.WithStickySession(ctx, fmt.Sprintf("user:%d", userID))
+}
+
+// newWeatherProvider 根據設定建立天氣資料來源，失敗時回傳 nil（天氣服務將無法使用）
+func newWeatherProvider() weather.Provider {
+	if cfg == nil {
+		logger.Warn("未設定應用程式設定，天氣服務無法使用")
+		return nil
+	}
+
+	provider, err := weather.NewProvider(cfg.Weather)
+	if err != nil {
+		logger.Errorf("建立天氣資料來源失敗，天氣服務無法使用: %v", err)
+		return nil
+	}
+
+	logger.Infof("天氣資料來源: %s", provider.Name())
+	return provider
+}
+
+// weatherConfig 取得天氣服務設定，未設定時使用零值（各 service 會套用預設值）
+func weatherConfig() config.WeatherConfig {
+	if cfg == nil {
+		return config.WeatherConfig{}
+	}
+	return cfg.Weather
+}
+
+// cacheConfig 取得快取設定，未設定時使用零值（不快取推薦結果）
+func cacheConfig() config.CacheConfig {
+	if cfg == nil {
+		return config.CacheConfig{}
+	}
+	return cfg.Cache
+}
+
+// userCacheTag 依賴使用者資料（例如偏好設定）的快取標籤，使用者資料異動時應一併清除
+func userCacheTag(userID uint) string {
+	return fmt.Sprintf("user:%d", userID)
+}
+
+// newCacheRedisClient 取得快取使用的 Redis 客戶端，未啟用 Redis 時回傳 nil
+func newCacheRedisClient() redis.UniversalClient {
+	if !database.RedisEnabled() {
+		return nil
+	}
+	return database.GetRedis().GetClientByDB("cache")
+}
@@ -0,0 +1,31 @@
+package services
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/andy2kuo/TourHelper/internal/dao"
+	"github.com/andy2kuo/TourHelper/internal/models"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	gormLogger "gorm.io/gorm/logger"
+)
+
+// newTestDAO 建立使用暫存 SQLite 資料庫的 DAO，並建立服務層測試用到的所有資料表
+func newTestDAO(t *testing.T) (*dao.DAO, *gorm.DB) {
+	t.Helper()
+
+	dsn := filepath.Join(t.TempDir(), "services.db") + "?_busy_timeout=5000"
+	db, err := gorm.Open(sqlite.Open(dsn), &gorm.Config{Logger: gormLogger.Discard})
+	if err != nil {
+		t.Fatalf("開啟 SQLite 失敗: %v", err)
+	}
+	err = db.AutoMigrate(
+		&models.User{}, &models.UserIdentity{}, &models.UserPreferences{}, &models.SearchHistory{},
+		&models.Credential{}, &models.LoginLockout{}, &models.WeatherData{},
+	)
+	if err != nil {
+		t.Fatalf("建立資料表失敗: %v", err)
+	}
+	return dao.New(dao.NewDBConn(db)), db
+}
@@ -0,0 +1,73 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/andy2kuo/TourHelper/internal/auth"
+	"github.com/andy2kuo/TourHelper/internal/dao"
+	"github.com/andy2kuo/TourHelper/internal/models"
+)
+
+// defaultTelegramLoginMaxAge Telegram 登入資料預設的有效時間
+const defaultTelegramLoginMaxAge = time.Hour
+
+// ErrTelegramLoginUnavailable 未設定 Telegram Bot Token，無法驗證 Telegram 登入
+var ErrTelegramLoginUnavailable = errors.New("Telegram 登入未啟用")
+
+// TelegramLoginService Telegram Login Widget 登入服務介面
+type TelegramLoginService interface {
+	// Login 驗證 Login Widget 回傳的資料，回傳 Telegram 使用者對應的會員（與 Telegram Bot 建立的會員相同，不存在時建立）
+	// 資料驗證失敗時回傳 auth.ErrTelegramLogin
+	Login(ctx context.Context, data map[string]string) (*models.User, error)
+}
+
+// telegramLoginService Telegram 登入服務實作
+type telegramLoginService struct {
+	dao      *dao.DAO
+	botToken string
+	maxAge   time.Duration
+	now      func() time.Time
+}
+
+// NewTelegramLoginService 建立 Telegram 登入服務，botToken 為空時一律回傳 ErrTelegramLoginUnavailable
+func NewTelegramLoginService(d *dao.DAO, botToken string, maxAge time.Duration) TelegramLoginService {
+	if maxAge <= 0 {
+		maxAge = defaultTelegramLoginMaxAge
+	}
+	return &telegramLoginService{dao: d, botToken: botToken, maxAge: maxAge, now: time.Now}
+}
+
+// Login 完成 Telegram 登入
+func (s *telegramLoginService) Login(ctx context.Context, data map[string]string) (*models.User, error) {
+	if s.botToken == "" {
+		return nil, ErrTelegramLoginUnavailable
+	}
+
+	login, err := auth.VerifyTelegramLogin(data, s.botToken, s.maxAge, s.now())
+	if err != nil {
+		return nil, err
+	}
+
+	// 外部 ID 與使用者資料的格式與 Telegram Bot 建立會員時相同，對應到同一筆會員
+	user, err := s.dao.User.FindOrCreateByExternal(ctx, models.PlatformTelegram, strconv.FormatInt(login.ID, 10), dao.ExternalProfile{
+		Username:    login.Username,
+		DisplayName: login.DisplayName(),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("取得 Telegram 會員失敗: %w", err)
+	}
+	return user, nil
+}
+
+// newTelegramLoginService 依設定建立 Telegram 登入服務
+func newTelegramLoginService(d *dao.DAO) TelegramLoginService {
+	var botToken string
+	if cfg != nil {
+		botToken = cfg.Telegram.Token
+	}
+	return NewTelegramLoginService(d, botToken, authConfig().TelegramLoginMaxAge)
+}
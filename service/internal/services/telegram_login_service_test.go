@@ -0,0 +1,51 @@
+package services
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/andy2kuo/TourHelper/internal/dao"
+	"github.com/andy2kuo/TourHelper/internal/models"
+)
+
+func TestTelegramLoginMatchesBotUser(t *testing.T) {
+	daos, _ := newTestDAO(t)
+	ctx := context.Background()
+
+	// Telegram Bot 收到訊息時建立的會員
+	botUser, err := daos.User.FindOrCreateByExternal(ctx, models.PlatformTelegram, "987654321", dao.ExternalProfile{Username: "xiaoming", DisplayName: "小明"})
+	if err != nil {
+		t.Fatalf("FindOrCreateByExternal() 失敗: %v", err)
+	}
+
+	const botToken = "123456:ABC-DEF"
+	authDate := strconv.FormatInt(time.Now().Unix(), 10)
+	secret := sha256.Sum256([]byte(botToken))
+	mac := hmac.New(sha256.New, secret[:])
+	mac.Write([]byte("auth_date=" + authDate + "\nfirst_name=小明\nid=987654321\nusername=xiaoming"))
+	data := map[string]string{
+		"id":         "987654321",
+		"first_name": "小明",
+		"username":   "xiaoming",
+		"auth_date":  authDate,
+		"hash":       hex.EncodeToString(mac.Sum(nil)),
+	}
+
+	user, err := NewTelegramLoginService(daos, botToken, time.Hour).Login(ctx, data)
+	if err != nil {
+		t.Fatalf("Login() 失敗: %v", err)
+	}
+	if user.ID != botUser.ID {
+		t.Errorf("Login() 會員 = %d, 期望與 Telegram Bot 建立的會員 %d 相同", user.ID, botUser.ID)
+	}
+
+	if _, err := NewTelegramLoginService(daos, "", time.Hour).Login(ctx, data); !errors.Is(err, ErrTelegramLoginUnavailable) {
+		t.Errorf("Login() 未設定 Bot Token 錯誤 = %v, 期望 %v", err, ErrTelegramLoginUnavailable)
+	}
+}
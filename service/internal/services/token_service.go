@@ -0,0 +1,351 @@
+package services
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/andy2kuo/TourHelper/internal/auth"
+	"github.com/andy2kuo/TourHelper/internal/config"
+	"github.com/andy2kuo/TourHelper/internal/dao"
+	"github.com/andy2kuo/TourHelper/internal/database"
+	"github.com/andy2kuo/TourHelper/internal/logger"
+	"gorm.io/gorm"
+)
+
+// Token 預設值
+const (
+	defaultTokenIssuer      = "tourhelper"
+	defaultMemberTokenTTL   = 15 * time.Minute
+	defaultAdminTokenTTL    = 15 * time.Minute
+	defaultMemberSessionTTL = 30 * 24 * time.Hour
+	defaultAdminSessionTTL  = 12 * time.Hour
+)
+
+var (
+	// ErrTokenIssuingUnavailable 此伺服器沒有簽章私鑰（只從 JWKS 取得公鑰驗證），無法簽發 Token
+	ErrTokenIssuingUnavailable = errors.New("此伺服器無法簽發 Token")
+	// ErrSessionsUnavailable 未啟用 Redis，無法換發或撤銷工作階段
+	ErrSessionsUnavailable = errors.New("未啟用工作階段，無法換發或撤銷 Token")
+)
+
+// TokenPair 登入或換發後取得的 Token
+type TokenPair struct {
+	AccessToken      string
+	Claims           *auth.Claims // Access Token 的內容
+	RefreshToken     string       // 未啟用工作階段時為空
+	RefreshExpiresAt int64        // 工作階段閒置到期時間（Unix 秒），未啟用工作階段時為 0
+}
+
+// TokenService 會員與管理員 Token 服務介面
+// 登入時建立工作階段並簽發短效的 Access Token 與可輪替的 Refresh Token；
+// 工作階段存放於 Redis，登出後 Lobby、Backend、Tour 驗證 Token 時都會立即拒絕
+type TokenService interface {
+	// IssueMember 建立會員工作階段並簽發 Token（受眾 member）
+	IssueMember(ctx context.Context, userID uint) (*TokenPair, error)
+
+	// IssueAdmin 建立管理員工作階段並簽發 Token（受眾 admin），角色寫入 roles
+	IssueAdmin(ctx context.Context, userID uint, role string) (*TokenPair, error)
+
+	// Refresh 以 Refresh Token 換發新的 Access Token 與 Refresh Token，舊的 Refresh Token 隨即失效
+	// 換發時依資料庫重新產生角色；會員已不存在或帳號已失去後台權限時撤銷工作階段並回傳 auth.ErrInvalidRefreshToken
+	// Token 無效或受眾不符時回傳 auth.ErrInvalidRefreshToken；重複使用舊 Token 時撤銷工作階段並回傳 auth.ErrRefreshTokenReused
+	Refresh(ctx context.Context, refreshToken, audience string) (*TokenPair, error)
+
+	// Verify 驗證 Token 的簽章、受眾與有效時間，並確認所屬工作階段尚未登出（已登出時回傳 auth.ErrSessionRevoked）
+	Verify(ctx context.Context, token, audience string) (*auth.Claims, error)
+
+	// Logout 撤銷工作階段，可提供 Access Token 或 Refresh Token（兩者皆無效時回傳 auth.ErrInvalidToken）
+	Logout(ctx context.Context, accessToken, refreshToken, audience string) error
+
+	// JWKS 驗證用的公鑰組，沒有簽章私鑰時回傳 ErrTokenIssuingUnavailable
+	JWKS() (auth.JWKS, error)
+}
+
+// tokenService Token 服務實作
+type tokenService struct {
+	dao      *dao.DAO     // nil 表示換發時不重新檢查會員與角色
+	keys     *auth.KeySet // nil 表示只驗證不簽發
+	issuer   *auth.Issuer
+	verifier *auth.Verifier
+	sessions *auth.SessionStore // nil 表示未啟用工作階段，Token 只能等待過期
+	cfg      config.AuthConfig
+}
+
+// NewTokenService 建立 Token 服務；keys 為 nil 時只能以 resolver 驗證 Token（例如從 JWKS 取得公鑰），兩者皆為 nil 時所有 Token 都無法通過驗證
+// sessions 為 nil 時不建立工作階段，簽發的 Token 無法換發也無法登出；d 為 nil 時換發沿用登入時的角色
+func NewTokenService(d *dao.DAO, keys *auth.KeySet, resolver auth.KeyResolver, sessions *auth.SessionStore, cfg config.AuthConfig) TokenService {
+	if cfg.Issuer == "" {
+		cfg.Issuer = defaultTokenIssuer
+	}
+	if cfg.MemberTokenTTL <= 0 {
+		cfg.MemberTokenTTL = defaultMemberTokenTTL
+	}
+	if cfg.AdminTokenTTL <= 0 {
+		cfg.AdminTokenTTL = defaultAdminTokenTTL
+	}
+	if cfg.MemberSessionTTL <= 0 {
+		cfg.MemberSessionTTL = defaultMemberSessionTTL
+	}
+	if cfg.AdminSessionTTL <= 0 {
+		cfg.AdminSessionTTL = defaultAdminSessionTTL
+	}
+
+	s := &tokenService{dao: d, keys: keys, sessions: sessions, cfg: cfg}
+	switch {
+	case keys != nil:
+		s.issuer = auth.NewIssuer(keys, cfg.Issuer)
+		resolver = keys
+	case resolver == nil:
+		resolver = noKeys{}
+	}
+	s.verifier = auth.NewVerifier(resolver, cfg.Issuer)
+	return s
+}
+
+// IssueMember 簽發會員 Token
+func (s *tokenService) IssueMember(ctx context.Context, userID uint) (*TokenPair, error) {
+	return s.issue(ctx, strconv.FormatUint(uint64(userID), 10), auth.AudienceMember, []string{auth.RoleMember})
+}
+
+// IssueAdmin 簽發管理員 Token
+func (s *tokenService) IssueAdmin(ctx context.Context, userID uint, role string) (*TokenPair, error) {
+	return s.issue(ctx, strconv.FormatUint(uint64(userID), 10), auth.AudienceAdmin, []string{role})
+}
+
+// issue 建立工作階段並簽發 Token，未啟用工作階段時只簽發 Access Token
+func (s *tokenService) issue(ctx context.Context, subject, audience string, roles []string) (*TokenPair, error) {
+	if s.issuer == nil {
+		return nil, ErrTokenIssuingUnavailable
+	}
+
+	if s.sessions == nil {
+		token, claims, err := s.issuer.Issue(subject, audience, roles, s.accessTTL(audience))
+		if err != nil {
+			return nil, err
+		}
+		return &TokenPair{AccessToken: token, Claims: claims}, nil
+	}
+
+	session, refreshToken, err := s.sessions.Create(ctx, subject, audience, roles, s.sessionTTL(audience))
+	if err != nil {
+		return nil, err
+	}
+	return s.pair(session, refreshToken)
+}
+
+// Refresh 換發 Token
+func (s *tokenService) Refresh(ctx context.Context, refreshToken, audience string) (*TokenPair, error) {
+	if s.sessions == nil {
+		return nil, ErrSessionsUnavailable
+	}
+	if s.issuer == nil {
+		return nil, ErrTokenIssuingUnavailable
+	}
+
+	// 先查詢工作階段的主體並確認目前的角色，再輪替 Refresh Token，資料庫暫時無法使用時原 Token 仍可重試
+	session, err := s.sessions.Lookup(ctx, refreshToken, audience)
+	if err != nil {
+		return nil, err
+	}
+	roles, err := s.currentRoles(ctx, session)
+	if errors.Is(err, errStaleSession) {
+		// 只有持有相符 Refresh Token 時才撤銷，避免僅憑工作階段 ID 撤銷他人的工作階段
+		if err := s.sessions.RevokeByRefreshToken(ctx, refreshToken); err != nil {
+			return nil, err
+		}
+		return nil, fmt.Errorf("%w: 會員已不存在或權限已變更", auth.ErrInvalidRefreshToken)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	session, next, err := s.sessions.Rotate(ctx, refreshToken, audience, roles, s.sessionTTL(audience))
+	if err != nil {
+		return nil, err
+	}
+	return s.pair(session, next)
+}
+
+// errStaleSession 工作階段的會員已不存在，或管理員帳號已失去後台權限
+var errStaleSession = errors.New("工作階段的會員或角色已失效")
+
+// currentRoles 依資料庫中的會員與帳號重新產生工作階段的角色，會員已合併或刪除、帳號已失去後台權限時回傳 errStaleSession
+func (s *tokenService) currentRoles(ctx context.Context, session *auth.Session) ([]string, error) {
+	if s.dao == nil {
+		return session.Roles, nil
+	}
+	id, err := strconv.ParseUint(session.Subject, 10, 64)
+	if err != nil {
+		return nil, errStaleSession
+	}
+	userID := uint(id)
+
+	if session.Audience == auth.AudienceAdmin {
+		cred, err := s.dao.Credential.FindByUserID(ctx, userID)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errStaleSession
+		}
+		if err != nil {
+			return nil, fmt.Errorf("查詢帳號失敗: %w", err)
+		}
+		if !IsAdminRole(cred.Role) {
+			return nil, errStaleSession
+		}
+		return []string{cred.Role}, nil
+	}
+
+	_, err = s.dao.User.FindByID(WithUser(ctx, userID), userID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, errStaleSession
+	}
+	if err != nil {
+		return nil, fmt.Errorf("查詢會員失敗: %w", err)
+	}
+	return []string{auth.RoleMember}, nil
+}
+
+// pair 簽發屬於工作階段的 Access Token
+func (s *tokenService) pair(session *auth.Session, refreshToken string) (*TokenPair, error) {
+	token, claims, err := s.issuer.IssueSession(session, s.accessTTL(session.Audience))
+	if err != nil {
+		return nil, err
+	}
+	return &TokenPair{
+		AccessToken:      token,
+		Claims:           claims,
+		RefreshToken:     refreshToken,
+		RefreshExpiresAt: time.Now().Add(s.sessionTTL(session.Audience)).Unix(),
+	}, nil
+}
+
+// Verify 驗證 Token
+func (s *tokenService) Verify(ctx context.Context, token, audience string) (*auth.Claims, error) {
+	claims, err := s.verifier.Verify(ctx, token, audience)
+	if err != nil || s.sessions == nil {
+		return claims, err
+	}
+
+	// 啟用工作階段後，不屬於任何工作階段的 Token 無法登出，一律拒絕
+	if claims.SessionID == "" {
+		return nil, fmt.Errorf("%w: 缺少工作階段", auth.ErrInvalidToken)
+	}
+	active, err := s.sessions.Active(ctx, claims.SessionID)
+	if err != nil {
+		return nil, err
+	}
+	if !active {
+		return nil, auth.ErrSessionRevoked
+	}
+	return claims, nil
+}
+
+// Logout 撤銷工作階段
+func (s *tokenService) Logout(ctx context.Context, accessToken, refreshToken, audience string) error {
+	if s.sessions == nil {
+		return ErrSessionsUnavailable
+	}
+
+	// Access Token 可能已過期，因此優先使用 Refresh Token
+	if refreshToken != "" {
+		err := s.sessions.RevokeByRefreshToken(ctx, refreshToken)
+		if !errors.Is(err, auth.ErrInvalidRefreshToken) {
+			return err
+		}
+	}
+	if accessToken != "" {
+		claims, err := s.Verify(ctx, accessToken, audience)
+		if err == nil {
+			return s.sessions.Revoke(ctx, claims.SessionID)
+		}
+		if !errors.Is(err, auth.ErrInvalidToken) && !errors.Is(err, auth.ErrTokenExpired) &&
+			!errors.Is(err, auth.ErrUnknownKey) && !errors.Is(err, auth.ErrSessionRevoked) {
+			return err
+		}
+	}
+	return auth.ErrInvalidToken
+}
+
+// accessTTL 依受眾取得 Access Token 有效時間
+func (s *tokenService) accessTTL(audience string) time.Duration {
+	if audience == auth.AudienceAdmin {
+		return s.cfg.AdminTokenTTL
+	}
+	return s.cfg.MemberTokenTTL
+}
+
+// sessionTTL 依受眾取得工作階段閒置到期時間
+func (s *tokenService) sessionTTL(audience string) time.Duration {
+	if audience == auth.AudienceAdmin {
+		return s.cfg.AdminSessionTTL
+	}
+	return s.cfg.MemberSessionTTL
+}
+
+// JWKS 驗證用的公鑰組
+func (s *tokenService) JWKS() (auth.JWKS, error) {
+	if s.keys == nil {
+		return auth.JWKS{}, ErrTokenIssuingUnavailable
+	}
+	return s.keys.JWKS(), nil
+}
+
+// noKeys 沒有任何可用公鑰的金鑰組
+type noKeys struct{}
+
+// PublicKey 一律回傳 auth.ErrUnknownKey
+func (noKeys) PublicKey(_ context.Context, kid string) (*ecdsa.PublicKey, error) {
+	return nil, fmt.Errorf("%w: %s", auth.ErrUnknownKey, kid)
+}
+
+// newTokenService 依設定建立 Token 服務
+//   - 設定了簽章金鑰：可簽發與驗證（Lobby、Backend）
+//   - 只設定 JWKS 位址：從 JWKS 取得公鑰離線驗證（Tour）
+//   - 都未設定：產生臨時金鑰，僅適用單機開發，重新啟動後先前的 Token 全部失效
+func newTokenService(daos *dao.DAO) TokenService {
+	authCfg := authConfig()
+	sessions := newSessionStore()
+
+	if len(authCfg.Keys) > 0 {
+		keys, err := auth.NewKeySet(authCfg)
+		if err != nil {
+			// 金鑰設定錯誤時不改用臨時金鑰，避免多台伺服器各自簽發互不相認的 Token
+			logger.Errorf("載入 Token 簽章金鑰失敗，無法簽發與驗證 Token: %v", err)
+			return NewTokenService(daos, nil, nil, sessions, authCfg)
+		}
+		return NewTokenService(daos, keys, nil, sessions, authCfg)
+	}
+
+	if authCfg.JWKSURL != "" {
+		logger.Infof("Token 驗證公鑰來源: %s", authCfg.JWKSURL)
+		return NewTokenService(daos, nil, auth.NewRemoteKeySet(authCfg.JWKSURL, authCfg.JWKSRefreshInterval), sessions, authCfg)
+	}
+
+	logger.Warn("未設定 Token 簽章金鑰，使用臨時金鑰（僅適用單機開發）")
+	keys, err := auth.GenerateKeySet("dev")
+	if err != nil {
+		logger.Errorf("產生臨時簽章金鑰失敗: %v", err)
+		return NewTokenService(daos, nil, nil, sessions, authCfg)
+	}
+	return NewTokenService(daos, keys, nil, sessions, authCfg)
+}
+
+// newSessionStore 建立工作階段儲存（使用 database 為 session 的 Redis 實例），未啟用 Redis 時回傳 nil
+func newSessionStore() *auth.SessionStore {
+	if !database.RedisEnabled() {
+		logger.Warn("未啟用 Redis，不建立工作階段：Token 無法換發，登出後仍可使用至過期")
+		return nil
+	}
+	return auth.NewSessionStore(database.GetRedis().GetClientByDB("session"))
+}
+
+// authConfig 取得 Token 設定，未設定時使用零值
+func authConfig() config.AuthConfig {
+	if cfg == nil {
+		return config.AuthConfig{}
+	}
+	return cfg.Auth
+}
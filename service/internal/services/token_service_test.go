@@ -0,0 +1,158 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/andy2kuo/TourHelper/internal/auth"
+	"github.com/andy2kuo/TourHelper/internal/config"
+	"github.com/andy2kuo/TourHelper/internal/models"
+	"github.com/redis/go-redis/v9"
+)
+
+func TestTokenServiceSessions(t *testing.T) {
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { client.Close() })
+
+	keys, err := auth.GenerateKeySet("test")
+	if err != nil {
+		t.Fatalf("GenerateKeySet() 失敗: %v", err)
+	}
+	svc := NewTokenService(nil, keys, nil, auth.NewSessionStore(client), config.AuthConfig{})
+	ctx := context.Background()
+
+	first, err := svc.IssueMember(ctx, 42)
+	if err != nil {
+		t.Fatalf("IssueMember() 失敗: %v", err)
+	}
+	if first.RefreshToken == "" || first.Claims.SessionID == "" {
+		t.Fatalf("IssueMember() = %+v, 期望包含 Refresh Token 與工作階段", first)
+	}
+
+	// 受眾不符的 Refresh Token 不可換發，也不影響原工作階段
+	if _, err := svc.Refresh(ctx, first.RefreshToken, auth.AudienceAdmin); !errors.Is(err, auth.ErrInvalidRefreshToken) {
+		t.Errorf("Refresh() 受眾不符錯誤 = %v, 期望 %v", err, auth.ErrInvalidRefreshToken)
+	}
+
+	second, err := svc.Refresh(ctx, first.RefreshToken, auth.AudienceMember)
+	if err != nil {
+		t.Fatalf("Refresh() 失敗: %v", err)
+	}
+	if second.RefreshToken == first.RefreshToken || second.Claims.SessionID != first.Claims.SessionID || second.Claims.Subject != "42" {
+		t.Errorf("Refresh() = %+v, 期望同一工作階段的新 Refresh Token", second)
+	}
+
+	// 重複使用已輪替的 Refresh Token：撤銷整個工作階段，新舊 Token 全部失效
+	if _, err := svc.Refresh(ctx, first.RefreshToken, auth.AudienceMember); !errors.Is(err, auth.ErrRefreshTokenReused) {
+		t.Errorf("Refresh() 重複使用錯誤 = %v, 期望 %v", err, auth.ErrRefreshTokenReused)
+	}
+	if _, err := svc.Refresh(ctx, second.RefreshToken, auth.AudienceMember); !errors.Is(err, auth.ErrInvalidRefreshToken) {
+		t.Errorf("Refresh() 撤銷後錯誤 = %v, 期望 %v", err, auth.ErrInvalidRefreshToken)
+	}
+	if _, err := svc.Verify(ctx, second.AccessToken, auth.AudienceMember); !errors.Is(err, auth.ErrSessionRevoked) {
+		t.Errorf("Verify() 撤銷後錯誤 = %v, 期望 %v", err, auth.ErrSessionRevoked)
+	}
+
+	// 登出後尚未過期的 Access Token 立即失效
+	tests := []struct {
+		name         string
+		accessToken  bool
+		refreshToken bool
+	}{
+		{"以 Access Token 登出", true, false},
+		{"以 Refresh Token 登出", false, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pair, err := svc.IssueAdmin(ctx, 7, auth.RoleAdmin)
+			if err != nil {
+				t.Fatalf("IssueAdmin() 失敗: %v", err)
+			}
+			if _, err := svc.Verify(ctx, pair.AccessToken, auth.AudienceAdmin); err != nil {
+				t.Fatalf("Verify() 登出前失敗: %v", err)
+			}
+
+			var accessToken, refreshToken string
+			if tt.accessToken {
+				accessToken = pair.AccessToken
+			}
+			if tt.refreshToken {
+				refreshToken = pair.RefreshToken
+			}
+			if err := svc.Logout(ctx, accessToken, refreshToken, auth.AudienceAdmin); err != nil {
+				t.Fatalf("Logout() 失敗: %v", err)
+			}
+			if _, err := svc.Verify(ctx, pair.AccessToken, auth.AudienceAdmin); !errors.Is(err, auth.ErrSessionRevoked) {
+				t.Errorf("Verify() 登出後錯誤 = %v, 期望 %v", err, auth.ErrSessionRevoked)
+			}
+			if err := svc.Logout(ctx, accessToken, refreshToken, auth.AudienceAdmin); !errors.Is(err, auth.ErrInvalidToken) {
+				t.Errorf("Logout() 重複登出錯誤 = %v, 期望 %v", err, auth.ErrInvalidToken)
+			}
+		})
+	}
+}
+
+func TestTokenServiceRefreshReloadsRoles(t *testing.T) {
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { client.Close() })
+
+	keys, err := auth.GenerateKeySet("test")
+	if err != nil {
+		t.Fatalf("GenerateKeySet() 失敗: %v", err)
+	}
+	d, db := newTestDAO(t)
+	svc := NewTokenService(d, keys, nil, auth.NewSessionStore(client), config.AuthConfig{})
+	authSvc := NewAuthService(d)
+	ctx := context.Background()
+
+	user, err := authSvc.Register(ctx, RegisterInput{Username: "root", Password: "secret123", Role: auth.RoleAdmin})
+	if err != nil {
+		t.Fatalf("Register() 失敗: %v", err)
+	}
+
+	// 角色變更後，換發的 Token 使用新角色
+	pair, err := svc.IssueAdmin(ctx, user.ID, auth.RoleAdmin)
+	if err != nil {
+		t.Fatalf("IssueAdmin() 失敗: %v", err)
+	}
+	if _, err := authSvc.SetRole(ctx, "root", auth.RoleOperator); err != nil {
+		t.Fatalf("SetRole() 失敗: %v", err)
+	}
+	pair, err = svc.Refresh(ctx, pair.RefreshToken, auth.AudienceAdmin)
+	if err != nil {
+		t.Fatalf("Refresh() 失敗: %v", err)
+	}
+	if !pair.Claims.HasRole(auth.RoleOperator) || pair.Claims.HasRole(auth.RoleAdmin) {
+		t.Errorf("Refresh() 角色 = %v, 期望 [%s]", pair.Claims.Roles, auth.RoleOperator)
+	}
+
+	// 失去後台權限後無法換發，工作階段一併撤銷
+	if _, err := authSvc.SetRole(ctx, "root", auth.RoleMember); err != nil {
+		t.Fatalf("SetRole() 失敗: %v", err)
+	}
+	if _, err := svc.Refresh(ctx, pair.RefreshToken, auth.AudienceAdmin); !errors.Is(err, auth.ErrInvalidRefreshToken) {
+		t.Errorf("Refresh() 失去權限後錯誤 = %v, 期望 %v", err, auth.ErrInvalidRefreshToken)
+	}
+	if _, err := svc.Verify(ctx, pair.AccessToken, auth.AudienceAdmin); !errors.Is(err, auth.ErrSessionRevoked) {
+		t.Errorf("Verify() 失去權限後錯誤 = %v, 期望 %v", err, auth.ErrSessionRevoked)
+	}
+
+	// 會員已刪除（例如合併到其他會員）後無法換發
+	member, err := svc.IssueMember(ctx, user.ID)
+	if err != nil {
+		t.Fatalf("IssueMember() 失敗: %v", err)
+	}
+	if err := db.Delete(&models.User{}, user.ID).Error; err != nil {
+		t.Fatalf("刪除會員失敗: %v", err)
+	}
+	if _, err := svc.Refresh(ctx, member.RefreshToken, auth.AudienceMember); !errors.Is(err, auth.ErrInvalidRefreshToken) {
+		t.Errorf("Refresh() 會員已刪除錯誤 = %v, 期望 %v", err, auth.ErrInvalidRefreshToken)
+	}
+	if _, err := svc.Verify(ctx, member.AccessToken, auth.AudienceMember); !errors.Is(err, auth.ErrSessionRevoked) {
+		t.Errorf("Verify() 會員已刪除錯誤 = %v, 期望 %v", err, auth.ErrSessionRevoked)
+	}
+}
@@ -0,0 +1,477 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"math/big"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/andy2kuo/TourHelper/internal/auth"
+	"github.com/andy2kuo/TourHelper/internal/cache"
+	"github.com/andy2kuo/TourHelper/internal/dao"
+	"github.com/andy2kuo/TourHelper/internal/database"
+	"github.com/andy2kuo/TourHelper/internal/logger"
+	"github.com/andy2kuo/TourHelper/internal/models"
+	"github.com/andy2kuo/TourHelper/internal/weather"
+	"github.com/redis/go-redis/v9"
+	"gorm.io/gorm"
+)
+
+// 帳號連結驗證碼規則
+const (
+	linkCodeDigits         = 8                // 驗證碼位數（純數字，方便在聊天室輸入）
+	defaultLinkCodeTTL     = 10 * time.Minute // 驗證碼預設的有效時間
+	maxLinkAttempts        = 5                // 驗證碼有效時間內每位會員最多輸錯的次數
+	maxLinkCodeGenerations = 3                // 驗證碼與他人重複時重新產生的次數上限
+	maxLinkIPFailures      = 20               // 驗證碼有效時間內同一來源 IP 最多輸錯的次數
+	maxLinkGlobalFailures  = 1000             // 驗證碼有效時間內所有會員合計最多輸錯的次數
+)
+
+var (
+	// ErrInvalidExternalUser 外部平台使用者資料不完整
+	ErrInvalidExternalUser = errors.New("缺少平台或外部使用者 ID")
+	// ErrLinkUnavailable 未啟用 Redis（無法保存驗證碼），無法連結帳號
+	ErrLinkUnavailable = errors.New("帳號連結功能未啟用")
+	// ErrInvalidLinkCode 驗證碼不存在、已使用過或已過期
+	ErrInvalidLinkCode = errors.New("無效或已過期的驗證碼")
+	// ErrTooManyLinkAttempts 輸錯驗證碼的次數過多
+	ErrTooManyLinkAttempts = errors.New("驗證碼輸入錯誤次數過多，請稍後再試")
+	// ErrAlreadyLinked 兩個帳號已是同一位會員
+	ErrAlreadyLinked = errors.New("帳號已連結到同一位會員")
+	// ErrMergeConflict 兩位會員都設定了帳號密碼，無法合併
+	ErrMergeConflict = dao.ErrMergeConflict
+	// ErrUserNotFound 會員不存在
+	ErrUserNotFound = errors.New("會員不存在")
+	// ErrInvalidPreferences 偏好設定的值不在允許範圍內
+	ErrInvalidPreferences = errors.New("無效的偏好設定")
+)
+
+// 偏好設定允許的值
+var (
+	preferredWeathers   = []string{"any", weather.ConditionSunny, weather.ConditionCloudy, weather.ConditionRainy}
+	preferredCategories = []string{"", "nature", "culture", "food", "shopping", "adventure"}
+	budgets             = []string{"low", "medium", "high"}
+)
+
+// maxPreferredDistance 偏好設定允許的最大距離（公里）
+const maxPreferredDistance = 500.0
+
+// LinkCode 帳號連結驗證碼
+type LinkCode struct {
+	Code      string
+	ExpiresAt time.Time
+}
+
+// UserService 使用者服務介面
+type UserService interface {
+	// FindOrCreateByExternal 依平台與外部 ID 取得使用者，不存在時建立（含預設偏好設定）
+	// 並同步外部平台提供的最新使用者名稱與顯示名稱
+	FindOrCreateByExternal(ctx context.Context, platform, externalID string, profile dao.ExternalProfile) (*models.User, error)
+
+	// ListIdentities 取得會員連結的所有平台身分
+	ListIdentities(ctx context.Context, userID uint) ([]models.UserIdentity, error)
+
+	// IssueLinkCode 產生一次性的帳號連結驗證碼（同一位會員再次產生時舊的驗證碼失效）
+	// 會員在一個管道（例如 LINE Bot）取得驗證碼後，在另一個管道（例如 Telegram Bot 或網頁）輸入即可連結
+	IssueLinkCode(ctx context.Context, userID uint) (*LinkCode, error)
+
+	// Link 以驗證碼連結帳號：輸入驗證碼的會員合併到產生驗證碼的會員，回傳合併後的會員
+	// 輸錯次數依會員、來源 IP（clientIP，Bot 等沒有來源 IP 時為空）與所有會員合計分別限制
+	// 驗證碼無效時回傳 ErrInvalidLinkCode，輸錯過多時回傳 ErrTooManyLinkAttempts，兩者已是同一位會員時回傳 ErrAlreadyLinked
+	Link(ctx context.Context, userID uint, code, clientIP string) (*models.User, error)
+
+	// GetPreferences 取得會員的偏好設定，尚未設定時回傳預設值
+	GetPreferences(ctx context.Context, userID uint) (*models.UserPreferences, error)
+
+	// UpdatePreferences 更新會員的偏好設定並清除依賴偏好設定的快取（例如推薦結果），回傳更新後的設定
+	// 設定值不在允許範圍內時回傳 ErrInvalidPreferences
+	UpdatePreferences(ctx context.Context, userID uint, prefs models.UserPreferences) (*models.UserPreferences, error)
+
+	// Merge 將 sourceID 會員合併到 targetID（供管理員處理既有的重複會員），撤銷 sourceID 的所有工作階段並回傳合併後的會員
+	// 任一會員不存在時回傳 ErrUserNotFound，兩者都有帳號密碼時回傳 ErrMergeConflict
+	Merge(ctx context.Context, targetID, sourceID uint) (*models.User, error)
+}
+
+// userService 使用者服務實作
+type userService struct {
+	dao         *dao.DAO
+	redis       redis.UniversalClient
+	sessions    *auth.SessionStore // 以 redis 建立，合併會員後撤銷被合併會員的工作階段
+	cache       redis.UniversalClient
+	linkCodeTTL time.Duration
+}
+
+// NewUserService 建立使用者服務；redisClient 為 nil 時帳號連結驗證碼相關操作回傳 ErrLinkUnavailable
+// redisClient 需與工作階段使用同一個 Redis（合併會員時撤銷工作階段）；驗證碼存放於 Redis，讓 Bot 與 Lobby 在不同伺服器上也能驗證；cacheClient 用於合併會員後清除快取，可為 nil
+func NewUserService(d *dao.DAO, redisClient, cacheClient redis.UniversalClient, linkCodeTTL time.Duration) UserService {
+	if linkCodeTTL <= 0 {
+		linkCodeTTL = defaultLinkCodeTTL
+	}
+	s := &userService{dao: d, redis: redisClient, cache: cacheClient, linkCodeTTL: linkCodeTTL}
+	if redisClient != nil {
+		s.sessions = auth.NewSessionStore(redisClient)
+	}
+	return s
+}
+
+// FindOrCreateByExternal 依平台與外部 ID 取得或建立使用者
+func (s *userService) FindOrCreateByExternal(ctx context.Context, platform, externalID string, profile dao.ExternalProfile) (*models.User, error) {
+	platform = strings.ToLower(strings.TrimSpace(platform))
+	externalID = strings.TrimSpace(externalID)
+	if platform == "" || externalID == "" {
+		return nil, ErrInvalidExternalUser
+	}
+
+	profile.Username = strings.TrimSpace(profile.Username)
+	profile.DisplayName = strings.TrimSpace(profile.DisplayName)
+
+	return s.dao.User.FindOrCreateByExternal(ctx, platform, externalID, profile)
+}
+
+// ListIdentities 取得會員連結的所有平台身分
+func (s *userService) ListIdentities(ctx context.Context, userID uint) ([]models.UserIdentity, error) {
+	identities, err := s.dao.User.ListIdentities(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("查詢平台身分失敗: %w", err)
+	}
+	return identities, nil
+}
+
+// IssueLinkCode 產生帳號連結驗證碼
+func (s *userService) IssueLinkCode(ctx context.Context, userID uint) (*LinkCode, error) {
+	if s.redis == nil {
+		return nil, ErrLinkUnavailable
+	}
+
+	// 先讓同一位會員先前產生的驗證碼失效
+	previous, err := s.redis.GetDel(ctx, linkCodeOwnerKey(userID)).Result()
+	if err != nil && !errors.Is(err, redis.Nil) {
+		return nil, fmt.Errorf("讀取帳號連結驗證碼失敗: %w", err)
+	}
+	if previous != "" {
+		if err := s.redis.Del(ctx, linkCodeKey(previous)).Err(); err != nil {
+			return nil, fmt.Errorf("清除帳號連結驗證碼失敗: %w", err)
+		}
+	}
+
+	owner := strconv.FormatUint(uint64(userID), 10)
+	for range maxLinkCodeGenerations {
+		code, err := newLinkCode()
+		if err != nil {
+			return nil, fmt.Errorf("產生帳號連結驗證碼失敗: %w", err)
+		}
+
+		// 驗證碼與其他會員尚未使用的驗證碼重複時重新產生
+		ok, err := s.redis.SetNX(ctx, linkCodeKey(code), owner, s.linkCodeTTL).Result()
+		if err != nil {
+			return nil, fmt.Errorf("保存帳號連結驗證碼失敗: %w", err)
+		}
+		if !ok {
+			continue
+		}
+		if err := s.redis.Set(ctx, linkCodeOwnerKey(userID), code, s.linkCodeTTL).Err(); err != nil {
+			return nil, fmt.Errorf("保存帳號連結驗證碼失敗: %w", err)
+		}
+		return &LinkCode{Code: code, ExpiresAt: time.Now().Add(s.linkCodeTTL)}, nil
+	}
+	return nil, errors.New("產生帳號連結驗證碼失敗: 驗證碼重複次數過多")
+}
+
+// Link 以驗證碼連結帳號
+func (s *userService) Link(ctx context.Context, userID uint, code, clientIP string) (*models.User, error) {
+	if s.redis == nil {
+		return nil, ErrLinkUnavailable
+	}
+	code = strings.TrimSpace(code)
+	if code == "" {
+		return nil, ErrInvalidLinkCode
+	}
+
+	reserved, err := s.reserveLinkAttempt(ctx, clientIP)
+	if err != nil {
+		return nil, err
+	}
+
+	// 先計入本次嘗試再比對，並行送出的大量嘗試無法在計數前一起通過檢查
+	attempts, err := s.countLinkAttempt(ctx, userID)
+	if err != nil {
+		s.releaseLinkAttempt(ctx, reserved)
+		return nil, err
+	}
+	if attempts > maxLinkAttempts {
+		s.releaseLinkAttempt(ctx, reserved)
+		return nil, ErrTooManyLinkAttempts
+	}
+
+	owner, ttl, err := s.redeemLinkCode(ctx, code)
+	if errors.Is(err, ErrInvalidLinkCode) {
+		// 輸錯的嘗試保留在來源 IP 與所有會員合計的計數中
+		return nil, err
+	}
+	s.releaseLinkAttempt(ctx, reserved)
+	if err != nil {
+		return nil, err
+	}
+
+	targetID, err := strconv.ParseUint(owner, 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("帳號連結驗證碼的內容錯誤: %w", err)
+	}
+
+	user, err := s.Merge(ctx, uint(targetID), userID)
+	if err != nil {
+		// 未完成連結（例如兩位會員都有帳號密碼），歸還驗證碼讓會員處理後可再次使用
+		s.restoreLinkCode(ctx, code, owner, uint(targetID), ttl)
+		return nil, err
+	}
+
+	if err := s.redis.Del(ctx, linkCodeOwnerKey(uint(targetID)), linkAttemptsKey(userID)).Err(); err != nil {
+		logger.Warnf("清除帳號連結驗證碼記錄失敗: %v", err)
+	}
+	return user, nil
+}
+
+// redeemLinkCode 取出並刪除驗證碼，同一個驗證碼只能使用一次；回傳產生驗證碼的會員 ID 與驗證碼剩餘的有效時間
+// 驗證碼不存在、已使用過或已過期時回傳 ErrInvalidLinkCode
+func (s *userService) redeemLinkCode(ctx context.Context, code string) (string, time.Duration, error) {
+	key := linkCodeKey(code)
+	pipe := s.redis.TxPipeline()
+	ttl := pipe.PTTL(ctx, key)
+	owner := pipe.GetDel(ctx, key)
+	_, err := pipe.Exec(ctx)
+	switch {
+	case errors.Is(err, redis.Nil):
+		return "", 0, ErrInvalidLinkCode
+	case err != nil:
+		return "", 0, fmt.Errorf("讀取帳號連結驗證碼失敗: %w", err)
+	}
+	return owner.Val(), ttl.Val(), nil
+}
+
+// restoreLinkCode 連結失敗時歸還已取出的驗證碼，維持原本的到期時間
+// 會員在這段期間已重新產生驗證碼時舊的驗證碼應失效，因此歸還後若已不是會員目前的驗證碼就再次刪除
+func (s *userService) restoreLinkCode(ctx context.Context, code, owner string, ownerID uint, ttl time.Duration) {
+	if ttl <= 0 {
+		return
+	}
+	if err := s.redis.SetNX(ctx, linkCodeKey(code), owner, ttl).Err(); err != nil {
+		logger.Warnf("歸還帳號連結驗證碼失敗: %v", err)
+		return
+	}
+
+	current, err := s.redis.Get(ctx, linkCodeOwnerKey(ownerID)).Result()
+	if err != nil && !errors.Is(err, redis.Nil) {
+		logger.Warnf("讀取帳號連結驗證碼失敗: %v", err)
+		return
+	}
+	if current != code {
+		if err := s.redis.Del(ctx, linkCodeKey(code)).Err(); err != nil {
+			logger.Warnf("清除帳號連結驗證碼失敗: %v", err)
+		}
+	}
+}
+
+// countLinkAttempt 計入會員的一次驗證碼嘗試並回傳有效時間內的嘗試次數（驗證成功時清除），計數在驗證碼有效時間後重置
+func (s *userService) countLinkAttempt(ctx context.Context, userID uint) (int64, error) {
+	key := linkAttemptsKey(userID)
+	pipe := s.redis.TxPipeline()
+	attempts := pipe.Incr(ctx, key)
+	pipe.ExpireNX(ctx, key, s.linkCodeTTL)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return 0, fmt.Errorf("記錄驗證碼嘗試次數失敗: %w", err)
+	}
+	return attempts.Val(), nil
+}
+
+// reserveLinkAttempt 檢查來源 IP 與所有會員合計的輸錯次數並預先計入本次嘗試，回傳已計入的 Redis key；輸錯過多時回傳 ErrTooManyLinkAttempts
+// 預先計入的嘗試在驗證碼正確或比對前失敗時以 releaseLinkAttempt 取消，只有輸錯的嘗試留在計數中
+func (s *userService) reserveLinkAttempt(ctx context.Context, clientIP string) ([]string, error) {
+	type check struct {
+		key   string
+		limit int64
+	}
+	checks := []check{{linkFailuresKey("global"), maxLinkGlobalFailures}}
+	if clientIP != "" {
+		checks = append(checks, check{linkFailuresKey("ip:" + clientIP), maxLinkIPFailures})
+	}
+
+	var reserved []string
+	for _, c := range checks {
+		// 每個 key 各自以交易計數，Cluster 模式下不同 key 可能位於不同節點
+		pipe := s.redis.TxPipeline()
+		failures := pipe.Incr(ctx, c.key)
+		pipe.ExpireNX(ctx, c.key, s.linkCodeTTL)
+		if _, err := pipe.Exec(ctx); err != nil {
+			s.releaseLinkAttempt(ctx, reserved)
+			return nil, fmt.Errorf("記錄驗證碼嘗試次數失敗: %w", err)
+		}
+		reserved = append(reserved, c.key)
+		if failures.Val() > c.limit {
+			s.releaseLinkAttempt(ctx, reserved)
+			logger.WithFields(map[string]interface{}{
+				"key":      c.key,
+				"failures": failures.Val() - 1,
+			}).Warn("帳號連結驗證碼輸錯次數過多，暫停嘗試")
+			return nil, ErrTooManyLinkAttempts
+		}
+	}
+	return reserved, nil
+}
+
+// releaseLinkAttempt 取消預先計入的嘗試
+func (s *userService) releaseLinkAttempt(ctx context.Context, keys []string) {
+	for _, key := range keys {
+		if err := s.redis.Decr(ctx, key).Err(); err != nil {
+			logger.Warnf("取消驗證碼嘗試記錄失敗: %v", err)
+		}
+	}
+}
+
+// Merge 將 sourceID 會員合併到 targetID
+func (s *userService) Merge(ctx context.Context, targetID, sourceID uint) (*models.User, error) {
+	if targetID == sourceID {
+		return nil, ErrAlreadyLinked
+	}
+
+	// 合併後立即讀取合併結果，需讀取 Master
+	ctx = WithUser(ctx, targetID)
+	err := s.dao.WithTx(ctx, func(tx *dao.DAO) error {
+		return tx.User.Merge(ctx, targetID, sourceID)
+	})
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		return nil, ErrUserNotFound
+	case errors.Is(err, dao.ErrMergeConflict):
+		return nil, ErrMergeConflict
+	case err != nil:
+		return nil, fmt.Errorf("合併會員失敗: %w", err)
+	}
+
+	// 被合併的會員已刪除，撤銷其所有工作階段（會員與後台），尚未過期的 Access Token 立即失效
+	if s.sessions != nil {
+		if err := s.sessions.RevokeSubject(ctx, strconv.FormatUint(uint64(sourceID), 10)); err != nil {
+			logger.Errorf("撤銷被合併會員 %d 的工作階段失敗: %v", sourceID, err)
+		}
+	}
+
+	// 合併後偏好設定與搜尋歷史改變，清除依賴兩位會員資料的快取
+	if err := cache.InvalidateTags(ctx, s.cache, userCacheTag(targetID), userCacheTag(sourceID)); err != nil {
+		logger.Warnf("清除會員相關快取失敗: %v", err)
+	}
+
+	user, err := s.dao.User.FindByID(ctx, targetID)
+	if err != nil {
+		return nil, fmt.Errorf("查詢會員失敗: %w", err)
+	}
+	return user, nil
+}
+
+// GetPreferences 取得會員的偏好設定
+func (s *userService) GetPreferences(ctx context.Context, userID uint) (*models.UserPreferences, error) {
+	prefs, err := s.dao.User.FindPreferencesByUserID(ctx, userID)
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		return defaultPreferences(userID), nil
+	case err != nil:
+		return nil, fmt.Errorf("查詢使用者偏好失敗: %w", err)
+	}
+	return prefs, nil
+}
+
+// UpdatePreferences 更新會員的偏好設定
+func (s *userService) UpdatePreferences(ctx context.Context, userID uint, prefs models.UserPreferences) (*models.UserPreferences, error) {
+	prefs.PreferredWeather = strings.ToLower(strings.TrimSpace(prefs.PreferredWeather))
+	prefs.PreferredCategory = strings.ToLower(strings.TrimSpace(prefs.PreferredCategory))
+	prefs.Budget = strings.ToLower(strings.TrimSpace(prefs.Budget))
+	switch {
+	case prefs.MaxDistance <= 0 || prefs.MaxDistance > maxPreferredDistance:
+		return nil, fmt.Errorf("%w: 最大距離需介於 0 到 %g 公里", ErrInvalidPreferences, maxPreferredDistance)
+	case prefs.MinRating < 0 || prefs.MinRating > 5:
+		return nil, fmt.Errorf("%w: 最低評分需介於 0 到 5", ErrInvalidPreferences)
+	case !slices.Contains(preferredWeathers, prefs.PreferredWeather):
+		return nil, fmt.Errorf("%w: 不支援的天氣偏好 %q", ErrInvalidPreferences, prefs.PreferredWeather)
+	case !slices.Contains(preferredCategories, prefs.PreferredCategory):
+		return nil, fmt.Errorf("%w: 不支援的景點類型 %q", ErrInvalidPreferences, prefs.PreferredCategory)
+	case !slices.Contains(budgets, prefs.Budget):
+		return nil, fmt.Errorf("%w: 不支援的預算 %q", ErrInvalidPreferences, prefs.Budget)
+	}
+
+	// 更新後立即讀取與推薦需讀取 Master
+	ctx = WithUser(ctx, userID)
+	if _, err := s.dao.User.FindByID(ctx, userID); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrUserNotFound
+		}
+		return nil, fmt.Errorf("查詢會員失敗: %w", err)
+	}
+
+	prefs.ID = 0
+	prefs.UserID = userID
+	if err := s.dao.User.SavePreferences(ctx, &prefs); err != nil {
+		return nil, fmt.Errorf("更新使用者偏好失敗: %w", err)
+	}
+
+	// 清除以舊偏好設定計算的推薦結果
+	if err := cache.InvalidateTags(ctx, s.cache, userCacheTag(userID)); err != nil {
+		logger.Warnf("清除會員相關快取失敗: %v", err)
+	}
+
+	return s.GetPreferences(ctx, userID)
+}
+
+// defaultPreferences 尚未設定偏好時使用的預設值，與 UserPreferences 的資料表預設值一致
+func defaultPreferences(userID uint) *models.UserPreferences {
+	return &models.UserPreferences{
+		UserID:           userID,
+		MaxDistance:      defaultMaxDistance,
+		PreferredWeather: "any",
+		MinRating:        defaultMinRating,
+		Budget:           "medium",
+	}
+}
+
+// newLinkCode 產生隨機的數字驗證碼
+func newLinkCode() (string, error) {
+	max := big.NewInt(1)
+	for range linkCodeDigits {
+		max.Mul(max, big.NewInt(10))
+	}
+	n, err := rand.Int(rand.Reader, max)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%0*d", linkCodeDigits, n), nil
+}
+
+// linkCodeKey 帳號連結驗證碼的 Redis key，值為產生驗證碼的會員 ID
+func linkCodeKey(code string) string {
+	return "tourhelper:link-code:" + code
+}
+
+// linkCodeOwnerKey 會員目前有效的帳號連結驗證碼的 Redis key
+func linkCodeOwnerKey(userID uint) string {
+	return fmt.Sprintf("tourhelper:link-code-owner:%d", userID)
+}
+
+// linkAttemptsKey 會員嘗試驗證碼次數的 Redis key
+func linkAttemptsKey(userID uint) string {
+	return fmt.Sprintf("tourhelper:link-attempts:%d", userID)
+}
+
+// linkFailuresKey 來源 IP（ip:{IP}）或所有會員合計（global）輸錯驗證碼次數的 Redis key
+func linkFailuresKey(subject string) string {
+	return "tourhelper:link-failures:" + subject
+}
+
+// newUserService 依設定建立使用者服務，未啟用 Redis 時無法連結帳號
+func newUserService(d *dao.DAO, cacheClient redis.UniversalClient) UserService {
+	if !database.RedisEnabled() {
+		return NewUserService(d, nil, nil, authConfig().LinkCodeTTL)
+	}
+	return NewUserService(d, database.GetRedis().GetClientByDB("session"), cacheClient, authConfig().LinkCodeTTL)
+}
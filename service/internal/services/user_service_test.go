@@ -0,0 +1,204 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/andy2kuo/TourHelper/internal/auth"
+	"github.com/andy2kuo/TourHelper/internal/cache"
+	"github.com/andy2kuo/TourHelper/internal/dao"
+	"github.com/andy2kuo/TourHelper/internal/models"
+	"github.com/redis/go-redis/v9"
+)
+
+func TestUserServiceLink(t *testing.T) {
+	daos, db := newTestDAO(t)
+	mr := miniredis.RunT(t)
+	svc := NewUserService(daos, redis.NewClient(&redis.Options{Addr: mr.Addr()}), nil, time.Minute)
+	ctx := context.Background()
+
+	line, _ := svc.FindOrCreateByExternal(ctx, models.PlatformLine, "U123", dao.ExternalProfile{DisplayName: "小明"})
+	telegram, _ := svc.FindOrCreateByExternal(ctx, models.PlatformTelegram, "987654321", dao.ExternalProfile{})
+
+	// 重新產生驗證碼後舊的失效
+	old, err := svc.IssueLinkCode(ctx, line.ID)
+	if err != nil {
+		t.Fatalf("IssueLinkCode() 失敗: %v", err)
+	}
+	code, _ := svc.IssueLinkCode(ctx, line.ID)
+	if len(code.Code) != linkCodeDigits || code.Code == old.Code {
+		t.Fatalf("IssueLinkCode() = %q, 期望 %d 位數且與舊驗證碼 %q 不同", code.Code, linkCodeDigits, old.Code)
+	}
+	if _, err := svc.Link(ctx, telegram.ID, old.Code, "192.0.2.1"); !errors.Is(err, ErrInvalidLinkCode) {
+		t.Errorf("Link(舊驗證碼) 錯誤 = %v, 期望 %v", err, ErrInvalidLinkCode)
+	}
+
+	// 空白的驗證碼直接視為無效
+	if _, err := svc.Link(ctx, telegram.ID, " ", "192.0.2.1"); !errors.Is(err, ErrInvalidLinkCode) {
+		t.Errorf("Link(空白驗證碼) 錯誤 = %v, 期望 %v", err, ErrInvalidLinkCode)
+	}
+
+	// 自己產生的驗證碼不可連結自己，驗證碼不會因此失效
+	if _, err := svc.Link(ctx, line.ID, code.Code, "192.0.2.1"); !errors.Is(err, ErrAlreadyLinked) {
+		t.Errorf("Link(自己的驗證碼) 錯誤 = %v, 期望 %v", err, ErrAlreadyLinked)
+	}
+
+	// 合併後被合併會員的所有工作階段撤銷，保留的會員不受影響
+	sessions := auth.NewSessionStore(redis.NewClient(&redis.Options{Addr: mr.Addr()}))
+	memberSession, _, _ := sessions.Create(ctx, strconv.FormatUint(uint64(telegram.ID), 10), auth.AudienceMember, nil, time.Hour)
+	adminSession, _, _ := sessions.Create(ctx, strconv.FormatUint(uint64(telegram.ID), 10), auth.AudienceAdmin, nil, time.Minute)
+	targetSession, _, _ := sessions.Create(ctx, strconv.FormatUint(uint64(line.ID), 10), auth.AudienceMember, nil, time.Hour)
+
+	user, err := svc.Link(ctx, telegram.ID, code.Code, "192.0.2.1")
+	if err != nil || user.ID != line.ID {
+		t.Fatalf("Link() = %+v, %v, 期望合併到會員 %d", user, err, line.ID)
+	}
+	for _, session := range []*auth.Session{memberSession, adminSession} {
+		if active, _ := sessions.Active(ctx, session.ID); active {
+			t.Errorf("Link() 後被合併會員的 %s 工作階段仍有效", session.Audience)
+		}
+	}
+	if active, _ := sessions.Active(ctx, targetSession.ID); !active {
+		t.Error("Link() 後保留會員的工作階段應仍有效")
+	}
+	if identities, _ := svc.ListIdentities(ctx, line.ID); len(identities) != 2 {
+		t.Errorf("ListIdentities() = %+v, 期望 2 筆", identities)
+	}
+	if again, _ := svc.FindOrCreateByExternal(ctx, models.PlatformTelegram, "987654321", dao.ExternalProfile{}); again.ID != line.ID {
+		t.Errorf("FindOrCreateByExternal(已連結的 Telegram) = %d, 期望 %d", again.ID, line.ID)
+	}
+
+	// 驗證碼只能使用一次，輸錯過多後暫停
+	web, _ := svc.FindOrCreateByExternal(ctx, models.PlatformWeb, "ming", dao.ExternalProfile{})
+	for range maxLinkAttempts {
+		if _, err := svc.Link(ctx, web.ID, code.Code, "192.0.2.1"); !errors.Is(err, ErrInvalidLinkCode) {
+			t.Fatalf("Link(已使用的驗證碼) 錯誤 = %v, 期望 %v", err, ErrInvalidLinkCode)
+		}
+	}
+	code, _ = svc.IssueLinkCode(ctx, line.ID)
+	if _, err := svc.Link(ctx, web.ID, code.Code, "192.0.2.1"); !errors.Is(err, ErrTooManyLinkAttempts) {
+		t.Errorf("Link(輸錯過多) 錯誤 = %v, 期望 %v", err, ErrTooManyLinkAttempts)
+	}
+
+	// 兩位會員都有帳號密碼時無法合併，驗證碼歸還後仍可由其他會員使用
+	owner, _ := svc.FindOrCreateByExternal(ctx, models.PlatformWeb, "hua", dao.ExternalProfile{})
+	other, _ := svc.FindOrCreateByExternal(ctx, models.PlatformWeb, "mei", dao.ExternalProfile{})
+	db.Create(&models.Credential{UserID: owner.ID, Username: "hua", PasswordHash: "hash"})
+	db.Create(&models.Credential{UserID: other.ID, Username: "mei", PasswordHash: "hash"})
+	code, _ = svc.IssueLinkCode(ctx, owner.ID)
+	if _, err := svc.Link(ctx, other.ID, code.Code, "192.0.2.1"); !errors.Is(err, ErrMergeConflict) {
+		t.Errorf("Link(都有帳號密碼) 錯誤 = %v, 期望 %v", err, ErrMergeConflict)
+	}
+	bot, _ := svc.FindOrCreateByExternal(ctx, models.PlatformTelegram, "123456789", dao.ExternalProfile{})
+	if user, err := svc.Link(ctx, bot.ID, code.Code, ""); err != nil || user.ID != owner.ID {
+		t.Errorf("Link(合併失敗後的驗證碼) = %+v, %v, 期望合併到會員 %d", user, err, owner.ID)
+	}
+
+	if _, err := NewUserService(daos, nil, nil, 0).IssueLinkCode(ctx, line.ID); !errors.Is(err, ErrLinkUnavailable) {
+		t.Errorf("IssueLinkCode() 未啟用 Redis 錯誤 = %v, 期望 %v", err, ErrLinkUnavailable)
+	}
+}
+
+func TestUserServiceLinkThrottle(t *testing.T) {
+	daos, _ := newTestDAO(t)
+	mr := miniredis.RunT(t)
+	svc := NewUserService(daos, redis.NewClient(&redis.Options{Addr: mr.Addr()}), nil, time.Minute)
+	ctx := context.Background()
+
+	owner, _ := svc.FindOrCreateByExternal(ctx, models.PlatformLine, "U123", dao.ExternalProfile{})
+	member, _ := svc.FindOrCreateByExternal(ctx, models.PlatformTelegram, "1", dao.ExternalProfile{})
+
+	// 並行送出的嘗試在比對前就已計數，同一位會員最多比對 maxLinkAttempts 次
+	var invalid atomic.Int32
+	var wg sync.WaitGroup
+	for i := range 20 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := svc.Link(ctx, member.ID, "00000000", fmt.Sprintf("198.51.100.%d", i))
+			if errors.Is(err, ErrInvalidLinkCode) {
+				invalid.Add(1)
+			} else if !errors.Is(err, ErrTooManyLinkAttempts) {
+				t.Errorf("Link(並行) 錯誤 = %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+	if n := invalid.Load(); n != maxLinkAttempts {
+		t.Errorf("比對驗證碼次數 = %d, 期望 %d", n, maxLinkAttempts)
+	}
+
+	// 同一來源 IP 以不同會員輸錯過多後暫停，即使驗證碼正確
+	for i := range maxLinkIPFailures {
+		other, _ := svc.FindOrCreateByExternal(ctx, models.PlatformTelegram, fmt.Sprintf("ip-%d", i), dao.ExternalProfile{})
+		if _, err := svc.Link(ctx, other.ID, "00000000", "192.0.2.1"); !errors.Is(err, ErrInvalidLinkCode) {
+			t.Fatalf("Link(第 %d 次輸錯) 錯誤 = %v, 期望 %v", i+1, err, ErrInvalidLinkCode)
+		}
+	}
+	web, _ := svc.FindOrCreateByExternal(ctx, models.PlatformWeb, "ming", dao.ExternalProfile{})
+	code, _ := svc.IssueLinkCode(ctx, owner.ID)
+	if _, err := svc.Link(ctx, web.ID, code.Code, "192.0.2.1"); !errors.Is(err, ErrTooManyLinkAttempts) {
+		t.Errorf("Link(IP 輸錯過多) 錯誤 = %v, 期望 %v", err, ErrTooManyLinkAttempts)
+	}
+
+	// 其他來源不受影響，被拒絕的嘗試也沒有消耗驗證碼
+	if user, err := svc.Link(ctx, web.ID, code.Code, "203.0.113.1"); err != nil || user.ID != owner.ID {
+		t.Errorf("Link(其他 IP) = %+v, %v, 期望合併到會員 %d", user, err, owner.ID)
+	}
+}
+
+func TestUserServiceUpdatePreferences(t *testing.T) {
+	daos, _ := newTestDAO(t)
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	svc := NewUserService(daos, client, client, time.Minute)
+	ctx := context.Background()
+
+	user, _ := svc.FindOrCreateByExternal(ctx, models.PlatformLine, "U123", dao.ExternalProfile{})
+	prefs, err := svc.GetPreferences(ctx, user.ID)
+	if err != nil || prefs.MaxDistance != 50 || prefs.MinRating != 3 {
+		t.Fatalf("GetPreferences() = %+v, %v, 期望預設值", prefs, err)
+	}
+
+	// 以舊偏好設定計算的推薦結果快取
+	recommendations := cache.New[int](client, cache.Options{Prefix: "cache:test", TTL: time.Minute})
+	if err := recommendations.Set(ctx, "r", 1, userCacheTag(user.ID)); err != nil {
+		t.Fatalf("Set() 失敗: %v", err)
+	}
+
+	invalid := *prefs
+	invalid.Budget = "luxury"
+	if _, err := svc.UpdatePreferences(ctx, user.ID, invalid); !errors.Is(err, ErrInvalidPreferences) {
+		t.Errorf("UpdatePreferences(無效預算) 錯誤 = %v, 期望 %v", err, ErrInvalidPreferences)
+	}
+	if !mr.Exists("cache:test:r") {
+		t.Error("更新失敗時不應清除快取")
+	}
+
+	// 零值（最低評分 0）也會寫入，不被資料表預設值取代
+	next := *prefs
+	next.MaxDistance = 20
+	next.MinRating = 0
+	next.PreferredCategory = "Nature"
+	updated, err := svc.UpdatePreferences(ctx, user.ID, next)
+	if err != nil {
+		t.Fatalf("UpdatePreferences() 失敗: %v", err)
+	}
+	if updated.MaxDistance != 20 || updated.MinRating != 0 || updated.PreferredCategory != "nature" {
+		t.Errorf("UpdatePreferences() = %+v", updated)
+	}
+	if mr.Exists("cache:test:r") {
+		t.Error("更新偏好設定後應清除依賴偏好設定的快取")
+	}
+
+	if _, err := svc.UpdatePreferences(ctx, 9999, next); !errors.Is(err, ErrUserNotFound) {
+		t.Errorf("UpdatePreferences(不存在的會員) 錯誤 = %v, 期望 %v", err, ErrUserNotFound)
+	}
+}
@@ -1,16 +1,294 @@
 package services
 
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/andy2kuo/TourHelper/internal/config"
+	"github.com/andy2kuo/TourHelper/internal/dao"
+	"github.com/andy2kuo/TourHelper/internal/logger"
+	"github.com/andy2kuo/TourHelper/internal/models"
+	"github.com/andy2kuo/TourHelper/internal/weather"
+	"github.com/redis/go-redis/v9"
+	"golang.org/x/sync/singleflight"
+	"gorm.io/gorm"
+)
+
+const (
+	defaultWeatherCacheTTL = 30 * time.Minute // 未設定快取時間時使用的預設值
+	currentWeatherWindow   = time.Hour        // 與現在相差此範圍內的時間直接使用即時天氣
+)
+
+// ErrWeatherUnavailable 天氣資訊無法取得
+var ErrWeatherUnavailable = errors.New("天氣資訊無法取得")
+
 // WeatherService 天氣服務介面
 type WeatherService interface {
-	// TODO: 實作天氣相關業務邏輯
+	// GetCurrentWeather 取得指定座標的即時天氣
+	GetCurrentWeather(ctx context.Context, lat, lon float64) (*models.WeatherInfo, error)
+
+	// GetForecast 取得指定座標的逐時與逐日預報，days 會被限制在 3-7 天
+	GetForecast(ctx context.Context, lat, lon float64, days int) (*models.WeatherForecast, error)
+
+	// GetWeatherAt 取得指定座標在指定時間的天氣：接近現在時使用即時天氣，否則使用預報
+	// 超出預報範圍時回傳 weather.ErrForecastOutOfRange
+	GetWeatherAt(ctx context.Context, lat, lon float64, at time.Time) (*models.WeatherInfo, error)
+
+	// GetDestinationWeather 取得景點在指定時間的天氣，會帶入景點的縣市與鄉鎮市區供資料來源使用
+	GetDestinationWeather(ctx context.Context, dest *models.Destination, at time.Time) (*models.WeatherInfo, error)
+
+	// PurgeExpired 刪除 weather_data 資料表中已過期的天氣資料，回傳刪除筆數
+	PurgeExpired(ctx context.Context) (int64, error)
 }
 
 // weatherService 天氣服務實作
+// 查詢順序：Redis -> weather_data 資料表 -> 天氣資料來源，座標會先對齊到格子
 type weatherService struct {
-	// 可能需要天氣 API 相關設定
+	provider weather.Provider // nil 表示未設定天氣資料來源
+	dao      *dao.DAO
+	redis    redis.UniversalClient // nil 表示不使用 Redis 快取
+	gridSize float64
+	cacheTTL time.Duration
+
+	group singleflight.Group // 合併同一格子的並行查詢
 }
 
 // NewWeatherService 建立天氣服務
-func NewWeatherService() WeatherService {
-	return &weatherService{}
+// provider 為 nil 時快取未命中的查詢皆回傳 ErrWeatherUnavailable；redisClient 為 nil 時只使用資料表快取
+func NewWeatherService(provider weather.Provider, d *dao.DAO, redisClient redis.UniversalClient, cfg config.WeatherConfig) WeatherService {
+	ttl := cfg.CacheTTL
+	if ttl <= 0 {
+		ttl = defaultWeatherCacheTTL
+	}
+
+	return &weatherService{
+		provider: provider,
+		dao:      d,
+		redis:    redisClient,
+		gridSize: cfg.GridSize,
+		cacheTTL: ttl,
+	}
+}
+
+// GetCurrentWeather 取得指定座標的即時天氣
+func (s *weatherService) GetCurrentWeather(ctx context.Context, lat, lon float64) (*models.WeatherInfo, error) {
+	return s.current(ctx, weather.Location{Latitude: lat, Longitude: lon})
+}
+
+// GetForecast 取得指定座標的逐時與逐日預報
+func (s *weatherService) GetForecast(ctx context.Context, lat, lon float64, days int) (*models.WeatherForecast, error) {
+	return s.forecast(ctx, weather.Location{Latitude: lat, Longitude: lon}, days)
+}
+
+// GetWeatherAt 取得指定座標在指定時間的天氣
+func (s *weatherService) GetWeatherAt(ctx context.Context, lat, lon float64, at time.Time) (*models.WeatherInfo, error) {
+	return s.weatherAt(ctx, weather.Location{Latitude: lat, Longitude: lon}, at)
+}
+
+// GetDestinationWeather 取得景點在指定時間的天氣
+func (s *weatherService) GetDestinationWeather(ctx context.Context, dest *models.Destination, at time.Time) (*models.WeatherInfo, error) {
+	return s.weatherAt(ctx, weather.DestinationLocation(dest), at)
+}
+
+// PurgeExpired 刪除已過期的天氣資料
+func (s *weatherService) PurgeExpired(ctx context.Context) (int64, error) {
+	count, err := s.dao.Weather.DeleteExpired(ctx, time.Now())
+	if err != nil {
+		return 0, fmt.Errorf("刪除過期天氣資料失敗: %w", err)
+	}
+	return count, nil
+}
+
+// current 取得指定位置的即時天氣，快取以對齊格子後的座標及縣市、鄉鎮市區為準
+func (s *weatherService) current(ctx context.Context, loc weather.Location) (*models.WeatherInfo, error) {
+	loc.Latitude, loc.Longitude = weather.SnapToGrid(loc.Latitude, loc.Longitude, s.gridSize)
+	key := weatherCacheKey(loc)
+
+	var cached models.WeatherInfo
+	if s.getFromRedis(ctx, key, &cached) {
+		return &cached, nil
+	}
+
+	// 同一位置的並行查詢只會執行一次資料表查詢與 API 呼叫
+	v, err, _ := s.group.Do(key, func() (any, error) {
+		// 共用查詢不應因第一個呼叫者取消而讓其他呼叫者一併失敗
+		return s.load(context.WithoutCancel(ctx), key, loc)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	info := *v.(*models.WeatherInfo)
+	return &info, nil
+}
+
+// forecast 取得指定位置的逐時與逐日預報
+// 預報只快取在 Redis，每次向資料來源取得最大天數後再依 days 截取
+func (s *weatherService) forecast(ctx context.Context, loc weather.Location, days int) (*models.WeatherForecast, error) {
+	loc.Latitude, loc.Longitude = weather.SnapToGrid(loc.Latitude, loc.Longitude, s.gridSize)
+	key := weatherForecastCacheKey(loc)
+
+	var forecast models.WeatherForecast
+	if !s.getFromRedis(ctx, key, &forecast) {
+		v, err, _ := s.group.Do(key, func() (any, error) {
+			return s.loadForecast(context.WithoutCancel(ctx), key, loc)
+		})
+		if err != nil {
+			return nil, err
+		}
+		forecast = *v.(*models.WeatherForecast)
+	}
+
+	daily := forecast.Daily
+	if limit := weather.ClampForecastDays(days); len(daily) > limit {
+		daily = daily[:limit]
+	}
+
+	return &models.WeatherForecast{
+		Hourly: append([]models.ForecastPeriod(nil), forecast.Hourly...),
+		Daily:  append([]models.ForecastPeriod(nil), daily...),
+	}, nil
+}
+
+// weatherAt 取得指定位置在指定時間的天氣：接近現在時使用即時天氣，否則使用預報
+func (s *weatherService) weatherAt(ctx context.Context, loc weather.Location, at time.Time) (*models.WeatherInfo, error) {
+	now := time.Now()
+	if at.IsZero() || (at.After(now.Add(-currentWeatherWindow)) && at.Before(now.Add(currentWeatherWindow))) {
+		return s.current(ctx, loc)
+	}
+	if at.Before(now) {
+		return nil, weather.ErrForecastOutOfRange
+	}
+
+	forecast, err := s.forecast(ctx, loc, weather.MaxForecastDays)
+	if err != nil {
+		return nil, err
+	}
+
+	period, err := weather.FindPeriod(forecast, at)
+	if err != nil {
+		return nil, err
+	}
+
+	info := period.WeatherInfo
+	return &info, nil
+}
+
+// loadForecast 從天氣資料來源載入預報，並回寫 Redis 快取
+func (s *weatherService) loadForecast(ctx context.Context, key string, loc weather.Location) (*models.WeatherForecast, error) {
+	if s.provider == nil {
+		return nil, ErrWeatherUnavailable
+	}
+
+	forecast, err := s.provider.Forecast(ctx, loc, weather.MaxForecastDays)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrWeatherUnavailable, err)
+	}
+
+	s.setToRedis(ctx, key, forecast, s.cacheTTL)
+	return forecast, nil
+}
+
+// load 依序從資料表與天氣資料來源載入天氣，並回寫快取
+func (s *weatherService) load(ctx context.Context, key string, loc weather.Location) (*models.WeatherInfo, error) {
+	now := time.Now()
+
+	locationKey := weather.LocationKey(loc)
+	data, err := s.dao.Weather.FindValid(ctx, locationKey, now)
+	switch {
+	case err == nil:
+		info := weatherInfoFromData(data)
+		s.setToRedis(ctx, key, info, data.ExpireAt.Sub(now))
+		return info, nil
+	case !errors.Is(err, gorm.ErrRecordNotFound):
+		logger.Warnf("查詢天氣快取資料表失敗: %v", err)
+	}
+
+	if s.provider == nil {
+		return nil, ErrWeatherUnavailable
+	}
+
+	info, err := s.provider.Current(ctx, loc)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrWeatherUnavailable, err)
+	}
+
+	data = &models.WeatherData{
+		GridKey:     locationKey,
+		Latitude:    loc.Latitude,
+		Longitude:   loc.Longitude,
+		Temperature: info.Temperature,
+		Condition:   info.Condition,
+		Description: info.Description,
+		Humidity:    info.Humidity,
+		WindSpeed:   info.WindSpeed,
+		FetchedAt:   now,
+		ExpireAt:    now.Add(s.cacheTTL),
+	}
+	if err := s.dao.Weather.Save(ctx, data); err != nil {
+		logger.Warnf("寫入天氣快取資料表失敗: %v", err)
+	}
+	s.setToRedis(ctx, key, info, s.cacheTTL)
+
+	return info, nil
+}
+
+// getFromRedis 從 Redis 讀取天氣快取到 out，未命中或發生錯誤時回傳 false
+func (s *weatherService) getFromRedis(ctx context.Context, key string, out any) bool {
+	if s.redis == nil {
+		return false
+	}
+
+	raw, err := s.redis.Get(ctx, key).Bytes()
+	if err != nil {
+		if !errors.Is(err, redis.Nil) {
+			logger.Warnf("讀取天氣 Redis 快取失敗: %v", err)
+		}
+		return false
+	}
+
+	if err := json.Unmarshal(raw, out); err != nil {
+		logger.Warnf("解析天氣 Redis 快取失敗: %v", err)
+		return false
+	}
+	return true
+}
+
+// setToRedis 寫入天氣快取到 Redis
+func (s *weatherService) setToRedis(ctx context.Context, key string, value any, ttl time.Duration) {
+	if s.redis == nil || ttl <= 0 {
+		return
+	}
+
+	raw, err := json.Marshal(value)
+	if err != nil {
+		return
+	}
+	if err := s.redis.Set(ctx, key, raw, ttl).Err(); err != nil {
+		logger.Warnf("寫入天氣 Redis 快取失敗: %v", err)
+	}
+}
+
+// weatherCacheKey 天氣快取的 Redis key（座標需先對齊格子）
+func weatherCacheKey(loc weather.Location) string {
+	return "weather:current:" + weather.LocationKey(loc)
+}
+
+// weatherForecastCacheKey 天氣預報快取的 Redis key（座標需先對齊格子）
+func weatherForecastCacheKey(loc weather.Location) string {
+	return "weather:forecast:" + weather.LocationKey(loc)
+}
+
+// weatherInfoFromData 將資料表快取轉換為天氣資訊
+func weatherInfoFromData(data *models.WeatherData) *models.WeatherInfo {
+	return &models.WeatherInfo{
+		Temperature: data.Temperature,
+		Condition:   data.Condition,
+		Humidity:    data.Humidity,
+		WindSpeed:   data.WindSpeed,
+		Description: data.Description,
+	}
 }
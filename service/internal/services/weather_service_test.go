@@ -0,0 +1,210 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/andy2kuo/TourHelper/internal/config"
+	"github.com/andy2kuo/TourHelper/internal/models"
+	"github.com/andy2kuo/TourHelper/internal/weather"
+	"github.com/redis/go-redis/v9"
+	"gorm.io/gorm"
+)
+
+// countingProvider 記錄呼叫次數的天氣資料來源
+type countingProvider struct {
+	calls   atomic.Int32
+	release chan struct{} // 非 nil 時會等待關閉後才回傳
+}
+
+func (p *countingProvider) Name() string { return "counting" }
+
+func (p *countingProvider) Current(ctx context.Context, loc weather.Location) (*models.WeatherInfo, error) {
+	p.calls.Add(1)
+	if p.release != nil {
+		<-p.release
+	}
+	// 描述帶入鄉鎮市區，用來確認不同層級的查詢結果沒有互相覆寫
+	return &models.WeatherInfo{Temperature: 25, Condition: weather.ConditionSunny, Description: loc.District + "晴天"}, nil
+}
+
+func (p *countingProvider) Forecast(ctx context.Context, loc weather.Location, days int) (*models.WeatherForecast, error) {
+	p.calls.Add(1)
+	fake := &weather.FakeProvider{Fixed: &models.WeatherInfo{Temperature: 18, Condition: weather.ConditionRainy}}
+	return fake.Forecast(ctx, loc, days)
+}
+
+// newTestWeatherService 建立使用暫存 SQLite 資料庫與 miniredis 的天氣服務
+func newTestWeatherService(t *testing.T, provider weather.Provider) (*weatherService, *gorm.DB, *miniredis.Miniredis) {
+	t.Helper()
+
+	d, db := newTestDAO(t)
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { client.Close() })
+
+	svc := NewWeatherService(provider, d, client, config.WeatherConfig{GridSize: 0.05, CacheTTL: time.Hour})
+	return svc.(*weatherService), db, mr
+}
+
+func TestWeatherServiceCacheLayers(t *testing.T) {
+	provider := &countingProvider{}
+	svc, db, mr := newTestWeatherService(t, provider)
+	ctx := context.Background()
+
+	// 第一次查詢：呼叫資料來源並寫入 Redis 與資料表
+	if _, err := svc.GetCurrentWeather(ctx, 25.0340, 121.5645); err != nil {
+		t.Fatalf("GetCurrentWeather() 錯誤: %v", err)
+	}
+	if !mr.Exists(weatherCacheKey(weather.Location{Latitude: 25.025, Longitude: 121.575})) {
+		t.Error("查詢後 Redis 應有對齊格子後的快取")
+	}
+	var count int64
+	db.Model(&models.WeatherData{}).Count(&count)
+	if count != 1 {
+		t.Errorf("weather_data 筆數 = %d, 期望 1", count)
+	}
+
+	// 同一格子內的其他座標命中 Redis
+	if _, err := svc.GetCurrentWeather(ctx, 25.0010, 121.5990); err != nil {
+		t.Fatalf("GetCurrentWeather() 錯誤: %v", err)
+	}
+
+	// Redis 清空後命中資料表
+	mr.FlushAll()
+	info, err := svc.GetCurrentWeather(ctx, 25.0340, 121.5645)
+	if err != nil {
+		t.Fatalf("GetCurrentWeather() 錯誤: %v", err)
+	}
+	if info.Condition != weather.ConditionSunny || info.Description != "晴天" {
+		t.Errorf("資料表快取內容 = %+v", info)
+	}
+
+	if calls := provider.calls.Load(); calls != 1 {
+		t.Errorf("資料來源呼叫次數 = %d, 期望 1", calls)
+	}
+}
+
+func TestWeatherServiceDestinationWeatherKeyedByDistrict(t *testing.T) {
+	provider := &countingProvider{}
+	svc, db, mr := newTestWeatherService(t, provider)
+	ctx := context.Background()
+
+	// 同一格子內的縣市層級與鄉鎮層級查詢分別快取
+	beitou := &models.Destination{City: "台北市", Address: "台北市北投區中山路", Latitude: 25.1370, Longitude: 121.5070}
+	if _, err := svc.GetCurrentWeather(ctx, beitou.Latitude, beitou.Longitude); err != nil {
+		t.Fatalf("GetCurrentWeather() 錯誤: %v", err)
+	}
+	info, err := svc.GetDestinationWeather(ctx, beitou, time.Time{})
+	if err != nil {
+		t.Fatalf("GetDestinationWeather() 錯誤: %v", err)
+	}
+	if info.Description != "北投區晴天" {
+		t.Errorf("GetDestinationWeather() 描述 = %q, 期望鄉鎮層級的 %q", info.Description, "北投區晴天")
+	}
+
+	var count int64
+	db.Model(&models.WeatherData{}).Count(&count)
+	if count != 2 {
+		t.Errorf("weather_data 筆數 = %d, 期望縣市與鄉鎮層級各 1 筆", count)
+	}
+
+	// Redis 清空後由資料表讀回，兩個層級的結果仍各自獨立
+	mr.FlushAll()
+	if info, _ := svc.GetDestinationWeather(ctx, beitou, time.Time{}); info == nil || info.Description != "北投區晴天" {
+		t.Errorf("GetDestinationWeather() 從資料表讀取 = %+v, 期望鄉鎮層級的資料", info)
+	}
+	if info, _ := svc.GetCurrentWeather(ctx, beitou.Latitude, beitou.Longitude); info == nil || info.Description != "晴天" {
+		t.Errorf("GetCurrentWeather() 從資料表讀取 = %+v, 期望座標層級的資料", info)
+	}
+	if calls := provider.calls.Load(); calls != 2 {
+		t.Errorf("資料來源呼叫次數 = %d, 期望 2", calls)
+	}
+}
+
+func TestWeatherServicePurgeExpired(t *testing.T) {
+	svc, db, mr := newTestWeatherService(t, &countingProvider{})
+	ctx := context.Background()
+
+	if _, err := svc.GetCurrentWeather(ctx, 25.0340, 121.5645); err != nil {
+		t.Fatalf("GetCurrentWeather() 錯誤: %v", err)
+	}
+	db.Model(&models.WeatherData{}).Where("1 = 1").Update("expire_at", time.Now().Add(-time.Minute))
+
+	count, err := svc.PurgeExpired(ctx)
+	if err != nil || count != 1 {
+		t.Fatalf("PurgeExpired() = %d, %v, 期望刪除 1 筆", count, err)
+	}
+
+	// 清理後重新查詢同一格子，資料表仍只有一筆（不保留軟刪除的資料）
+	mr.FlushAll()
+	if _, err := svc.GetCurrentWeather(ctx, 25.0340, 121.5645); err != nil {
+		t.Fatalf("GetCurrentWeather() 錯誤: %v", err)
+	}
+	var rows int64
+	db.Unscoped().Model(&models.WeatherData{}).Count(&rows)
+	if rows != 1 {
+		t.Errorf("weather_data 筆數（含軟刪除） = %d, 期望 1", rows)
+	}
+}
+
+func TestWeatherServiceCoalescesConcurrentRequests(t *testing.T) {
+	provider := &countingProvider{release: make(chan struct{})}
+	svc, _, _ := newTestWeatherService(t, provider)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := svc.GetCurrentWeather(context.Background(), 25.0340, 121.5645); err != nil {
+				t.Errorf("GetCurrentWeather() 錯誤: %v", err)
+			}
+		}()
+	}
+
+	time.Sleep(50 * time.Millisecond)
+	close(provider.release)
+	wg.Wait()
+
+	if calls := provider.calls.Load(); calls != 1 {
+		t.Errorf("資料來源呼叫次數 = %d, 期望 1", calls)
+	}
+}
+
+func TestWeatherServiceGetWeatherAt(t *testing.T) {
+	provider := &countingProvider{}
+	svc, _, _ := newTestWeatherService(t, provider)
+	ctx := context.Background()
+
+	// 接近現在的時間使用即時天氣
+	info, err := svc.GetWeatherAt(ctx, 25.0340, 121.5645, time.Now().Add(10*time.Minute))
+	if err != nil || info.Condition != weather.ConditionSunny {
+		t.Fatalf("GetWeatherAt(現在) = %+v, %v, 期望即時天氣", info, err)
+	}
+
+	// 兩天後使用預報，再次查詢命中快取
+	for i := 0; i < 2; i++ {
+		info, err = svc.GetWeatherAt(ctx, 25.0340, 121.5645, time.Now().Add(50*time.Hour))
+		if err != nil || info.Condition != weather.ConditionRainy {
+			t.Fatalf("GetWeatherAt(兩天後) = %+v, %v, 期望預報天氣", info, err)
+		}
+	}
+	if calls := provider.calls.Load(); calls != 2 {
+		t.Errorf("資料來源呼叫次數 = %d, 期望 2", calls)
+	}
+
+	if _, err := svc.GetWeatherAt(ctx, 25.0340, 121.5645, time.Now().AddDate(0, 0, 30)); !errors.Is(err, weather.ErrForecastOutOfRange) {
+		t.Errorf("GetWeatherAt(30 天後) 錯誤 = %v, 期望 ErrForecastOutOfRange", err)
+	}
+
+	forecast, err := svc.GetForecast(ctx, 25.0340, 121.5645, 3)
+	if err != nil || len(forecast.Daily) != 3 {
+		t.Errorf("GetForecast(3) 逐日預報數 = %d, %v, 期望 3", len(forecast.Daily), err)
+	}
+}
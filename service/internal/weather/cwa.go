@@ -0,0 +1,428 @@
+package weather
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/andy2kuo/TourHelper/internal/config"
+	"github.com/andy2kuo/TourHelper/internal/models"
+	"github.com/andy2kuo/TourHelper/pkg/utils"
+)
+
+const (
+	defaultCWAURL = "https://opendata.cwa.gov.tw"
+
+	// 不指定縣市時使用的全臺各縣市預報資料集
+	cwaCountyDataset       = "F-D0047-089" // 全臺各縣市未來 3 天逐 3 小時預報
+	cwaCountyWeeklyDataset = "F-D0047-091" // 全臺各縣市未來 1 週逐 12 小時預報
+
+	cwaTimeLayout = "2006-01-02 15:04:05"
+)
+
+// cwaTimeZone CWA 資料時間皆為臺灣時間
+var cwaTimeZone = time.FixedZone("CST", 8*60*60)
+
+// cwaCityDatasets 各縣市鄉鎮未來 3 天逐 3 小時預報的資料集編號
+// 同縣市未來 1 週逐 12 小時預報的編號為此編號加 2
+var cwaCityDatasets = map[string]int{
+	"宜蘭縣": 1,
+	"桃園市": 5,
+	"新竹縣": 9,
+	"苗栗縣": 13,
+	"彰化縣": 17,
+	"南投縣": 21,
+	"雲林縣": 25,
+	"嘉義縣": 29,
+	"屏東縣": 33,
+	"臺東縣": 37,
+	"花蓮縣": 41,
+	"澎湖縣": 45,
+	"基隆市": 49,
+	"新竹市": 53,
+	"嘉義市": 57,
+	"臺北市": 61,
+	"高雄市": 65,
+	"新北市": 69,
+	"臺中市": 73,
+	"臺南市": 77,
+	"連江縣": 81,
+	"金門縣": 85,
+}
+
+var (
+	// cwaCityDistrictPattern 市的行政區以「區」結尾（例如：北投區、平鎮區）
+	cwaCityDistrictPattern = regexp.MustCompile(`^(.{1,3}?區)`)
+	// cwaCountyDistrictPattern 縣的行政區以「鄉」、「鎮」、「市」結尾（例如：礁溪鄉、太麻里鄉）
+	cwaCountyDistrictPattern = regexp.MustCompile(`^(.{1,3}?[鄉鎮市])`)
+)
+
+// CWAProvider 交通部中央氣象署開放資料平臺的天氣資料來源
+// 指定縣市時使用鄉鎮預報，否則使用全臺各縣市預報，並取距離查詢座標最近的地點
+type CWAProvider struct {
+	baseURL string
+	apiKey  string
+	client  *http.Client
+}
+
+// cwaResponse CWA 預報資料集回應結構
+type cwaResponse struct {
+	Success string `json:"success"`
+	Message string `json:"message"`
+	Records struct {
+		Locations []struct {
+			LocationsName string        `json:"locationsName"`
+			Location      []cwaLocation `json:"location"`
+		} `json:"locations"`
+	} `json:"records"`
+}
+
+// cwaLocation CWA 預報地點
+type cwaLocation struct {
+	LocationName   string              `json:"locationName"`
+	Lat            string              `json:"lat"`
+	Lon            string              `json:"lon"`
+	WeatherElement []cwaWeatherElement `json:"weatherElement"`
+}
+
+// cwaWeatherElement CWA 天氣因子（例如：T 溫度、Wx 天氣現象、PoP6h 6 小時降雨機率）
+type cwaWeatherElement struct {
+	ElementName string `json:"elementName"`
+	Time        []struct {
+		StartTime    string `json:"startTime"`
+		EndTime      string `json:"endTime"`
+		DataTime     string `json:"dataTime"`
+		ElementValue []struct {
+			Value    string `json:"value"`
+			Measures string `json:"measures"`
+		} `json:"elementValue"`
+	} `json:"time"`
+}
+
+// cwaPoint 天氣因子在某一時段的值
+type cwaPoint struct {
+	start, end time.Time
+	values     []string
+}
+
+// cwaSeries 天氣因子依時間排序的值
+type cwaSeries []cwaPoint
+
+// NewCWAProvider 建立 CWA 天氣資料來源
+func NewCWAProvider(cfg config.WeatherConfig) (*CWAProvider, error) {
+	if cfg.APIKey == "" {
+		return nil, fmt.Errorf("CWA 需要設定 weather.apiKey（氣象資料開放平臺授權碼）")
+	}
+
+	baseURL := cfg.BaseURL
+	if baseURL == "" {
+		baseURL = defaultCWAURL
+	}
+
+	timeout := cfg.Timeout
+	if timeout <= 0 {
+		timeout = defaultRequestTimeout
+	}
+
+	return &CWAProvider{
+		baseURL: strings.TrimRight(baseURL, "/"),
+		apiKey:  cfg.APIKey,
+		client:  &http.Client{Timeout: timeout},
+	}, nil
+}
+
+// Name 返回資料來源名稱
+func (p *CWAProvider) Name() string {
+	return "cwa"
+}
+
+// Current 取得指定位置的即時天氣
+// CWA 鄉鎮預報以 3 小時為單位，即時天氣取包含現在時間的預報時段
+func (p *CWAProvider) Current(ctx context.Context, loc Location) (*models.WeatherInfo, error) {
+	location, err := p.fetch(ctx, loc, false)
+	if err != nil {
+		return nil, err
+	}
+
+	periods := cwaPeriods(location, 3*time.Hour)
+	if len(periods) == 0 {
+		return nil, fmt.Errorf("CWA 回應沒有 %s 的預報資料", location.LocationName)
+	}
+
+	now := time.Now()
+	for i := range periods {
+		if periods[i].Contains(now) {
+			return &periods[i].WeatherInfo, nil
+		}
+	}
+	// 資料集尚未更新時，現在時間可能早於第一個時段
+	return &periods[0].WeatherInfo, nil
+}
+
+// Forecast 取得指定位置的預報：逐時預報來自 3 天逐 3 小時預報，逐日預報由 1 週逐 12 小時預報彙整而成
+func (p *CWAProvider) Forecast(ctx context.Context, loc Location, days int) (*models.WeatherForecast, error) {
+	location, err := p.fetch(ctx, loc, false)
+	if err != nil {
+		return nil, err
+	}
+	weekly, err := p.fetch(ctx, loc, true)
+	if err != nil {
+		return nil, err
+	}
+
+	daily := AggregateDaily(cwaPeriods(weekly, 12*time.Hour), cwaTimeZone)
+	if limit := ClampForecastDays(days); len(daily) > limit {
+		daily = daily[:limit]
+	}
+
+	return &models.WeatherForecast{
+		Hourly: cwaPeriods(location, 3*time.Hour),
+		Daily:  daily,
+	}, nil
+}
+
+// fetch 取得最符合查詢位置的預報地點
+func (p *CWAProvider) fetch(ctx context.Context, loc Location, weekly bool) (*cwaLocation, error) {
+	city := normalizeCWAName(loc.City)
+	district := normalizeCWAName(loc.District)
+
+	query := url.Values{}
+	dataset := cwaCountyDataset
+	if weekly {
+		dataset = cwaCountyWeeklyDataset
+	}
+	if id, ok := cwaCityDatasets[city]; ok {
+		if weekly {
+			id += 2
+		}
+		dataset = fmt.Sprintf("F-D0047-%03d", id)
+		if district != "" {
+			query.Set("locationName", district)
+		}
+	} else {
+		district = ""
+		if city != "" {
+			query.Set("locationName", city)
+		}
+	}
+
+	var resp cwaResponse
+	if err := p.get(ctx, dataset, query, &resp); err != nil {
+		return nil, err
+	}
+
+	var candidates []cwaLocation
+	for _, group := range resp.Records.Locations {
+		candidates = append(candidates, group.Location...)
+	}
+	if len(candidates) == 0 {
+		return nil, fmt.Errorf("CWA 資料集 %s 沒有符合的預報地點", dataset)
+	}
+
+	if district != "" {
+		for i := range candidates {
+			if candidates[i].LocationName == district {
+				return &candidates[i], nil
+			}
+		}
+	}
+	return nearestCWALocation(candidates, loc.Latitude, loc.Longitude), nil
+}
+
+// get 呼叫 CWA 資料集 API 並解析 JSON 回應
+func (p *CWAProvider) get(ctx context.Context, dataset string, query url.Values, out *cwaResponse) error {
+	query.Set("Authorization", p.apiKey)
+	query.Set("format", "JSON")
+
+	endpoint := p.baseURL + "/api/v1/rest/datastore/" + dataset + "?" + query.Encode()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return fmt.Errorf("建立 CWA 請求失敗: %w", err)
+	}
+
+	res, err := p.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("呼叫 CWA 失敗: %w", err)
+	}
+	defer res.Body.Close()
+
+	body, err := io.ReadAll(res.Body)
+	if err != nil {
+		return fmt.Errorf("讀取 CWA 回應失敗: %w", err)
+	}
+
+	if res.StatusCode != http.StatusOK {
+		_ = json.Unmarshal(body, out)
+		return fmt.Errorf("CWA 回應錯誤 (HTTP %d): %s", res.StatusCode, out.Message)
+	}
+
+	if err := json.Unmarshal(body, out); err != nil {
+		return fmt.Errorf("解析 CWA 回應失敗: %w", err)
+	}
+	if out.Success != "true" {
+		return fmt.Errorf("CWA 回應錯誤: %s", out.Message)
+	}
+
+	return nil
+}
+
+// cwaPeriods 以天氣現象（Wx）的時段為準，組合各天氣因子成預報時段
+// step 為資料集的時間間隔，用於推算只有資料時間（dataTime）的天氣因子的結束時間
+func cwaPeriods(location *cwaLocation, step time.Duration) []models.ForecastPeriod {
+	series := make(map[string]cwaSeries, len(location.WeatherElement))
+	for _, element := range location.WeatherElement {
+		series[element.ElementName] = parseCWASeries(element, step)
+	}
+
+	wx := series["Wx"]
+	periods := make([]models.ForecastPeriod, 0, len(wx))
+	for _, slot := range wx {
+		period := models.ForecastPeriod{
+			StartTime: slot.start,
+			EndTime:   slot.end,
+		}
+		if len(slot.values) > 0 {
+			period.Description = slot.values[0]
+		}
+		if len(slot.values) > 1 {
+			code, _ := strconv.Atoi(slot.values[1])
+			period.Condition = cwaConditionOf(code)
+		}
+
+		period.TemperatureMin = series["MinT"].floatAt(slot.start)
+		period.TemperatureMax = series["MaxT"].floatAt(slot.start)
+		period.Temperature = series["T"].floatAt(slot.start)
+		if period.Temperature == 0 && (period.TemperatureMin != 0 || period.TemperatureMax != 0) {
+			period.Temperature = math.Round((period.TemperatureMin+period.TemperatureMax)/2*10) / 10
+		}
+		period.Humidity = int(series["RH"].floatAt(slot.start))
+		period.WindSpeed = series["WS"].floatAt(slot.start)
+		period.PrecipitationChance = int(max(series["PoP6h"].floatAt(slot.start), series["PoP12h"].floatAt(slot.start)))
+
+		periods = append(periods, period)
+	}
+
+	return periods
+}
+
+// parseCWASeries 解析天氣因子的時間序列，時間格式錯誤的項目會被略過
+func parseCWASeries(element cwaWeatherElement, step time.Duration) cwaSeries {
+	series := make(cwaSeries, 0, len(element.Time))
+	for _, t := range element.Time {
+		startText := t.StartTime
+		if startText == "" {
+			startText = t.DataTime
+		}
+		start, err := time.ParseInLocation(cwaTimeLayout, startText, cwaTimeZone)
+		if err != nil {
+			continue
+		}
+
+		point := cwaPoint{start: start}
+		if end, err := time.ParseInLocation(cwaTimeLayout, t.EndTime, cwaTimeZone); err == nil {
+			point.end = end
+		}
+		for _, v := range t.ElementValue {
+			point.values = append(point.values, strings.TrimSpace(v.Value))
+		}
+		series = append(series, point)
+	}
+
+	// 只有資料時間的天氣因子，有效至下一筆資料時間為止
+	for i := range series {
+		if !series[i].end.IsZero() {
+			continue
+		}
+		if i+1 < len(series) {
+			series[i].end = series[i+1].start
+		} else {
+			series[i].end = series[i].start.Add(step)
+		}
+	}
+
+	return series
+}
+
+// floatAt 取得包含指定時間的數值，沒有資料或無法解析時回傳 0
+func (s cwaSeries) floatAt(t time.Time) float64 {
+	for _, point := range s {
+		if !t.Before(point.start) && t.Before(point.end) && len(point.values) > 0 {
+			v, _ := strconv.ParseFloat(point.values[0], 64)
+			return v
+		}
+	}
+	return 0
+}
+
+// nearestCWALocation 取距離指定座標最近的預報地點，沒有座標的地點排在最後
+func nearestCWALocation(candidates []cwaLocation, lat, lon float64) *cwaLocation {
+	best, bestDistance := 0, math.Inf(1)
+	for i, c := range candidates {
+		cLat, errLat := strconv.ParseFloat(c.Lat, 64)
+		cLon, errLon := strconv.ParseFloat(c.Lon, 64)
+		if errLat != nil || errLon != nil {
+			continue
+		}
+		if d := utils.CalculateDistance(lat, lon, cLat, cLon); d < bestDistance {
+			best, bestDistance = i, d
+		}
+	}
+	return &candidates[best]
+}
+
+// cwaConditionOf 將 CWA 天氣現象代碼轉換為天氣狀態
+// 參考: https://opendata.cwa.gov.tw 天氣現象代碼表
+func cwaConditionOf(code int) string {
+	switch {
+	case code >= 1 && code <= 3: // 晴、晴時多雲、多雲時晴
+		return ConditionSunny
+	case code == 23 || code == 42: // 雨或雪、下雪
+		return ConditionSnowy
+	case (code >= 8 && code <= 22) || (code >= 29 && code <= 41): // 陣雨、雷雨
+		return ConditionRainy
+	default: // 多雲、陰、霧
+		return ConditionCloudy
+	}
+}
+
+// normalizeCWAName 將「台」統一為 CWA 使用的「臺」
+func normalizeCWAName(name string) string {
+	return strings.ReplaceAll(strings.TrimSpace(name), "台", "臺")
+}
+
+// DistrictFromAddress 從地址解析鄉鎮市區，例如「台北市北投區竹子湖路」解析為「北投區」
+// 地址不以 city 開頭或無法解析時回傳空字串
+func DistrictFromAddress(city, address string) string {
+	city = normalizeCWAName(city)
+	address = normalizeCWAName(address)
+	if city == "" || !strings.HasPrefix(address, city) {
+		return ""
+	}
+
+	pattern := cwaCountyDistrictPattern
+	if strings.HasSuffix(city, "市") {
+		pattern = cwaCityDistrictPattern
+	}
+	if m := pattern.FindStringSubmatch(strings.TrimPrefix(address, city)); m != nil {
+		return m[1]
+	}
+	return ""
+}
+
+// DestinationLocation 將景點轉換為天氣查詢位置，鄉鎮市區由地址解析
+func DestinationLocation(dest *models.Destination) Location {
+	return Location{
+		Latitude:  dest.Latitude,
+		Longitude: dest.Longitude,
+		City:      dest.City,
+		District:  DistrictFromAddress(dest.City, dest.Address),
+	}
+}
@@ -0,0 +1,198 @@
+package weather
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/andy2kuo/TourHelper/internal/config"
+	"github.com/andy2kuo/TourHelper/internal/models"
+)
+
+// newCWATestServer 以 testdata/cwa 中錄製的回應模擬 CWA 開放資料 API，並記錄收到的查詢
+func newCWATestServer(t *testing.T) (*httptest.Server, func() []string) {
+	t.Helper()
+
+	var mu sync.Mutex
+	var requests []string
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("Authorization") != "test-key" {
+			w.WriteHeader(http.StatusUnauthorized)
+			w.Write([]byte(`{"message":"Unauthorized"}`))
+			return
+		}
+
+		dataset := strings.TrimPrefix(r.URL.Path, "/api/v1/rest/datastore/")
+		body, err := os.ReadFile(filepath.Join("testdata", "cwa", dataset+".json"))
+		if err != nil {
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"success":"false","message":"Resource not found"}`))
+			return
+		}
+
+		mu.Lock()
+		requests = append(requests, dataset+"?"+r.URL.Query().Get("locationName"))
+		mu.Unlock()
+		w.Write(body)
+	}))
+	t.Cleanup(srv.Close)
+
+	return srv, func() []string {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]string(nil), requests...)
+	}
+}
+
+func TestCWAProviderCurrent(t *testing.T) {
+	srv, requests := newCWATestServer(t)
+	provider, err := NewCWAProvider(config.WeatherConfig{APIKey: "test-key", BaseURL: srv.URL})
+	if err != nil {
+		t.Fatalf("NewCWAProvider() 錯誤: %v", err)
+	}
+
+	tests := []struct {
+		name        string
+		loc         Location
+		request     string
+		description string
+		condition   string
+		temperature float64
+	}{
+		{
+			name:        "縣市與鄉鎮查詢鄉鎮預報",
+			loc:         Location{Latitude: 25.17, Longitude: 121.54, City: "台北市", District: "北投區"},
+			request:     "F-D0047-061?北投區",
+			description: "晴時多雲",
+			condition:   ConditionSunny,
+			temperature: 27,
+		},
+		{
+			name:        "只有縣市時取最近的鄉鎮",
+			loc:         Location{Latitude: 25.033, Longitude: 121.565, City: "臺北市"},
+			request:     "F-D0047-061?",
+			description: "晴",
+			condition:   ConditionSunny,
+			temperature: 29,
+		},
+		{
+			name:        "沒有縣市時取最近的縣市",
+			loc:         Location{Latitude: 23.98, Longitude: 121.61},
+			request:     "F-D0047-089?",
+			description: "晴時多雲",
+			condition:   ConditionSunny,
+			temperature: 27,
+		},
+	}
+
+	for i, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			info, err := provider.Current(context.Background(), tt.loc)
+			if err != nil {
+				t.Fatalf("Current() 錯誤: %v", err)
+			}
+			if info.Description != tt.description || info.Condition != tt.condition || info.Temperature != tt.temperature {
+				t.Errorf("Current() = %+v, 期望 %s/%s/%v", info, tt.description, tt.condition, tt.temperature)
+			}
+			if got := requests()[i]; got != tt.request {
+				t.Errorf("查詢 = %s, 期望 %s", got, tt.request)
+			}
+		})
+	}
+
+	badKey, _ := NewCWAProvider(config.WeatherConfig{APIKey: "wrong", BaseURL: srv.URL})
+	if _, err := badKey.Current(context.Background(), Location{City: "臺北市"}); err == nil {
+		t.Error("Current() 使用錯誤授權碼時應回傳錯誤")
+	}
+}
+
+func TestCWAProviderForecast(t *testing.T) {
+	srv, requests := newCWATestServer(t)
+	provider, _ := NewCWAProvider(config.WeatherConfig{APIKey: "test-key", BaseURL: srv.URL})
+
+	forecast, err := provider.Forecast(context.Background(), Location{City: "臺北市", District: "北投區"}, 7)
+	if err != nil {
+		t.Fatalf("Forecast() 錯誤: %v", err)
+	}
+	if got := strings.Join(requests(), ","); got != "F-D0047-061?北投區,F-D0047-063?北投區" {
+		t.Errorf("查詢 = %s", got)
+	}
+
+	if len(forecast.Hourly) != 4 {
+		t.Fatalf("逐時預報數 = %d, 期望 4", len(forecast.Hourly))
+	}
+	if h := forecast.Hourly[2]; h.Condition != ConditionRainy || h.Temperature != 31 || h.Humidity != 60 ||
+		h.WindSpeed != 3 || h.PrecipitationChance != 70 || h.StartTime.Hour() != 12 || h.EndTime.Hour() != 15 {
+		t.Errorf("逐時預報 = %+v", h)
+	}
+
+	if len(forecast.Daily) != 3 {
+		t.Fatalf("逐日預報數 = %d, 期望 3", len(forecast.Daily))
+	}
+	if d := forecast.Daily[0]; d.Condition != ConditionRainy || d.TemperatureMin != 25 || d.TemperatureMax != 33 ||
+		d.PrecipitationChance != 60 || d.Description != "多雲午後短暫雷陣雨" {
+		t.Errorf("第一天預報 = %+v", d)
+	}
+	if d := forecast.Daily[2]; d.Condition != ConditionRainy || d.PrecipitationChance != 0 {
+		t.Errorf("第三天預報 = %+v", d)
+	}
+}
+
+func TestDistrictFromAddress(t *testing.T) {
+	tests := []struct {
+		name     string
+		city     string
+		address  string
+		expected string
+	}{
+		{name: "直轄市", city: "台北市", address: "台北市北投區竹子湖路", expected: "北投區"},
+		{name: "台臺混用", city: "臺北市", address: "台北市信義區信義路五段7號", expected: "信義區"},
+		{name: "區名含鎮", city: "桃園市", address: "桃園市平鎮區中豐路", expected: "平鎮區"},
+		{name: "縣轄鄉", city: "臺東縣", address: "臺東縣太麻里鄉大王村", expected: "太麻里鄉"},
+		{name: "縣轄市", city: "彰化縣", address: "彰化縣員林市中山路", expected: "員林市"},
+		{name: "地址不含縣市", city: "新北市", address: "淡水區中正路", expected: ""},
+		{name: "沒有縣市", city: "", address: "新北市淡水區中正路", expected: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := DistrictFromAddress(tt.city, tt.address); got != tt.expected {
+				t.Errorf("DistrictFromAddress() = %q, 期望 %q", got, tt.expected)
+			}
+		})
+	}
+
+	loc := DestinationLocation(&models.Destination{City: "新北市", Address: "新北市瑞芳區基山街"})
+	if loc.District != "瑞芳區" {
+		t.Errorf("DestinationLocation().District = %q, 期望 瑞芳區", loc.District)
+	}
+}
+
+func TestCWAConditionOf(t *testing.T) {
+	tests := []struct {
+		name     string
+		code     int
+		expected string
+	}{
+		{name: "晴", code: 1, expected: ConditionSunny},
+		{name: "陰", code: 7, expected: ConditionCloudy},
+		{name: "午後雷陣雨", code: 15, expected: ConditionRainy},
+		{name: "雨或雪", code: 23, expected: ConditionSnowy},
+		{name: "霧", code: 25, expected: ConditionCloudy},
+		{name: "陣雨", code: 36, expected: ConditionRainy},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := cwaConditionOf(tt.code); got != tt.expected {
+				t.Errorf("cwaConditionOf(%d) = %v, 期望 %v", tt.code, got, tt.expected)
+			}
+		})
+	}
+}
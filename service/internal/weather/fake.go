@@ -0,0 +1,120 @@
+package weather
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"time"
+
+	"github.com/andy2kuo/TourHelper/internal/models"
+)
+
+// fakeConditions 假資料輪替使用的天氣狀態
+var fakeConditions = []string{ConditionSunny, ConditionCloudy, ConditionRainy, ConditionSunny, ConditionCloudy}
+
+// fakeDescriptions 各天氣狀態的描述
+var fakeDescriptions = map[string]string{
+	ConditionSunny:  "晴天",
+	ConditionCloudy: "多雲",
+	ConditionRainy:  "陣雨",
+	ConditionSnowy:  "下雪",
+}
+
+// FakeProvider 不需網路的假天氣資料來源，供本機開發與測試使用
+// 相同座標（取到小數點後兩位）與時段永遠回傳相同的天氣
+type FakeProvider struct {
+	// Fixed 若有設定，所有查詢都回傳此天氣
+	Fixed *models.WeatherInfo
+}
+
+// NewFakeProvider 建立假天氣資料來源
+func NewFakeProvider() *FakeProvider {
+	return &FakeProvider{}
+}
+
+// Name 返回資料來源名稱
+func (p *FakeProvider) Name() string {
+	return "fake"
+}
+
+// Current 取得指定位置的即時天氣
+func (p *FakeProvider) Current(ctx context.Context, loc Location) (*models.WeatherInfo, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	info := p.weatherAt(loc, "current")
+	return &info, nil
+}
+
+// Forecast 取得指定位置的預報：從目前整點起 48 小時的逐時預報，以及 days 天的逐日預報
+// 相同座標與時段永遠回傳相同的天氣
+func (p *FakeProvider) Forecast(ctx context.Context, loc Location, days int) (*models.WeatherForecast, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	start := now.Truncate(time.Hour)
+	hourly := make([]models.ForecastPeriod, 0, fakeHourlyHours)
+	for i := 0; i < fakeHourlyHours; i++ {
+		slot := start.Add(time.Duration(i) * time.Hour)
+		hourly = append(hourly, p.periodAt(loc, slot, slot.Add(time.Hour)))
+	}
+
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	daily := make([]models.ForecastPeriod, 0, ClampForecastDays(days))
+	for i := 0; i < ClampForecastDays(days); i++ {
+		day := today.AddDate(0, 0, i)
+		daily = append(daily, p.periodAt(loc, day, day.AddDate(0, 0, 1)))
+	}
+
+	return &models.WeatherForecast{Hourly: hourly, Daily: daily}, nil
+}
+
+// fakeHourlyHours 假資料提供的逐時預報小時數
+const fakeHourlyHours = 48
+
+// periodAt 產生指定時段的預報
+func (p *FakeProvider) periodAt(loc Location, start, end time.Time) models.ForecastPeriod {
+	info := p.weatherAt(loc, start.Format(time.RFC3339))
+	seed := fakeSeed(loc, start.Format(time.RFC3339))
+
+	chance := int(seed % 30)
+	if info.Condition == ConditionRainy {
+		chance += 60
+	}
+
+	return models.ForecastPeriod{
+		StartTime:           start,
+		EndTime:             end,
+		WeatherInfo:         info,
+		TemperatureMin:      info.Temperature - 3,
+		TemperatureMax:      info.Temperature + 3,
+		PrecipitationChance: chance,
+	}
+}
+
+// weatherAt 產生指定座標與時段的天氣
+func (p *FakeProvider) weatherAt(loc Location, slot string) models.WeatherInfo {
+	if p.Fixed != nil {
+		return *p.Fixed
+	}
+
+	seed := fakeSeed(loc, slot)
+	condition := fakeConditions[seed%uint32(len(fakeConditions))]
+	return models.WeatherInfo{
+		Temperature: 15 + float64(seed%150)/10, // 15.0 ~ 29.9 度
+		Condition:   condition,
+		Humidity:    50 + int(seed%40),
+		WindSpeed:   float64(seed%100) / 10,
+		Description: fakeDescriptions[condition],
+	}
+}
+
+// fakeSeed 由座標與時段產生固定的雜湊值
+func fakeSeed(loc Location, slot string) uint32 {
+	h := fnv.New32a()
+	fmt.Fprintf(h, "%.2f,%.2f,%s", loc.Latitude, loc.Longitude, slot)
+	return h.Sum32()
+}
@@ -0,0 +1,31 @@
+package weather
+
+import (
+	"context"
+	"testing"
+
+	"github.com/andy2kuo/TourHelper/internal/models"
+)
+
+func TestFakeProviderDeterministic(t *testing.T) {
+	provider := NewFakeProvider()
+	loc := Location{Latitude: 25.0340, Longitude: 121.5645}
+
+	first, err := provider.Current(context.Background(), loc)
+	if err != nil {
+		t.Fatalf("Current() 錯誤: %v", err)
+	}
+	second, _ := provider.Current(context.Background(), loc)
+	if *first != *second {
+		t.Errorf("相同座標回傳不同天氣: %+v, %+v", first, second)
+	}
+	if first.Condition == "" || first.Description == "" {
+		t.Errorf("Current() = %+v, 缺少天氣狀態", first)
+	}
+
+	provider.Fixed = &models.WeatherInfo{Condition: ConditionSnowy, Temperature: -2}
+	fixed, _ := provider.Current(context.Background(), loc)
+	if fixed.Condition != ConditionSnowy || fixed.Temperature != -2 {
+		t.Errorf("設定 Fixed 後 Current() = %+v", fixed)
+	}
+}
@@ -0,0 +1,128 @@
+package weather
+
+import (
+	"errors"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/andy2kuo/TourHelper/internal/models"
+)
+
+const (
+	// MaxForecastDays 最多提供的逐日預報天數
+	MaxForecastDays = 7
+	// MinForecastDays 最少提供的逐日預報天數
+	MinForecastDays = 3
+)
+
+// ErrForecastOutOfRange 指定時間超出預報範圍
+var ErrForecastOutOfRange = errors.New("指定時間超出天氣預報範圍")
+
+// conditionSeverity 天氣狀態的嚴重程度，彙整逐日預報遇到同票數時取較嚴重者
+var conditionSeverity = map[string]int{
+	ConditionSunny:  0,
+	ConditionCloudy: 1,
+	ConditionRainy:  2,
+	ConditionSnowy:  3,
+}
+
+// ClampForecastDays 將預報天數限制在 [MinForecastDays, MaxForecastDays]
+func ClampForecastDays(days int) int {
+	return max(MinForecastDays, min(MaxForecastDays, days))
+}
+
+// FindPeriod 找出包含指定時間的預報：優先使用逐時預報，其次使用逐日預報
+func FindPeriod(forecast *models.WeatherForecast, t time.Time) (*models.ForecastPeriod, error) {
+	if forecast != nil {
+		for _, periods := range [][]models.ForecastPeriod{forecast.Hourly, forecast.Daily} {
+			for i := range periods {
+				if periods[i].Contains(t) {
+					return &periods[i], nil
+				}
+			}
+		}
+	}
+	return nil, ErrForecastOutOfRange
+}
+
+// AggregateDaily 將逐時預報依 loc 時區的日期彙整為逐日預報
+// 天氣狀態取出現最多次者，降雨機率與風速取最大值，濕度取平均
+func AggregateDaily(hourly []models.ForecastPeriod, loc *time.Location) []models.ForecastPeriod {
+	type dayBucket struct {
+		period     models.ForecastPeriod
+		conditions map[string]int
+		humidity   int
+		count      int
+		noonDiff   time.Duration // 描述取最接近中午的時段
+	}
+
+	buckets := make(map[time.Time]*dayBucket)
+	for _, h := range hourly {
+		local := h.StartTime.In(loc)
+		day := time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, loc)
+
+		b, ok := buckets[day]
+		if !ok {
+			b = &dayBucket{
+				period: models.ForecastPeriod{
+					StartTime:      day,
+					EndTime:        day.AddDate(0, 0, 1),
+					TemperatureMin: math.Inf(1),
+					TemperatureMax: math.Inf(-1),
+				},
+				conditions: make(map[string]int),
+				noonDiff:   math.MaxInt64,
+			}
+			buckets[day] = b
+		}
+
+		tmin, tmax := h.TemperatureMin, h.TemperatureMax
+		if tmin == 0 && tmax == 0 {
+			tmin, tmax = h.Temperature, h.Temperature
+		}
+		b.period.TemperatureMin = math.Min(b.period.TemperatureMin, tmin)
+		b.period.TemperatureMax = math.Max(b.period.TemperatureMax, tmax)
+		b.period.PrecipitationChance = max(b.period.PrecipitationChance, h.PrecipitationChance)
+		b.period.WindSpeed = math.Max(b.period.WindSpeed, h.WindSpeed)
+		b.conditions[h.Condition]++
+		b.humidity += h.Humidity
+		b.count++
+
+		if diff := absDuration(local.Sub(day.Add(12 * time.Hour))); diff < b.noonDiff {
+			b.noonDiff = diff
+			b.period.Description = h.Description
+		}
+	}
+
+	daily := make([]models.ForecastPeriod, 0, len(buckets))
+	for _, b := range buckets {
+		p := b.period
+		p.Condition = dominantCondition(b.conditions)
+		p.Humidity = b.humidity / b.count
+		p.Temperature = math.Round((p.TemperatureMin+p.TemperatureMax)/2*10) / 10
+		daily = append(daily, p)
+	}
+
+	sort.Slice(daily, func(i, j int) bool { return daily[i].StartTime.Before(daily[j].StartTime) })
+	return daily
+}
+
+// dominantCondition 取出現最多次的天氣狀態，同票數時取較嚴重者
+func dominantCondition(counts map[string]int) string {
+	best, bestCount := "", -1
+	for condition, count := range counts {
+		if count > bestCount || (count == bestCount && conditionSeverity[condition] > conditionSeverity[best]) {
+			best, bestCount = condition, count
+		}
+	}
+	return best
+}
+
+// absDuration 取時間長度的絕對值
+func absDuration(d time.Duration) time.Duration {
+	if d < 0 {
+		return -d
+	}
+	return d
+}
@@ -0,0 +1,58 @@
+package weather
+
+import (
+	"testing"
+	"time"
+
+	"github.com/andy2kuo/TourHelper/internal/models"
+)
+
+func TestAggregateDaily(t *testing.T) {
+	taipei := time.FixedZone("Asia/Taipei", 8*3600)
+	day1 := time.Date(2025, 6, 7, 0, 0, 0, 0, taipei)
+
+	slot := func(start time.Time, temp float64, condition, desc string, pop int) models.ForecastPeriod {
+		return models.ForecastPeriod{
+			StartTime:           start,
+			EndTime:             start.Add(3 * time.Hour),
+			WeatherInfo:         models.WeatherInfo{Temperature: temp, Condition: condition, Humidity: 70, Description: desc},
+			PrecipitationChance: pop,
+		}
+	}
+
+	hourly := []models.ForecastPeriod{
+		slot(day1.Add(6*time.Hour), 24, ConditionSunny, "早晨晴", 0),
+		slot(day1.Add(12*time.Hour), 31, ConditionRainy, "午後雷陣雨", 70),
+		slot(day1.Add(18*time.Hour), 27, ConditionRainy, "傍晚陣雨", 40),
+		// 以 UTC 表示的隔天時段，仍應依台北時間歸到 6/8
+		slot(day1.Add(33*time.Hour).UTC(), 26, ConditionCloudy, "多雲", 10),
+	}
+
+	daily := AggregateDaily(hourly, taipei)
+	if len(daily) != 2 {
+		t.Fatalf("AggregateDaily() 天數 = %d, 期望 2", len(daily))
+	}
+
+	first := daily[0]
+	if !first.StartTime.Equal(day1) || !first.EndTime.Equal(day1.AddDate(0, 0, 1)) {
+		t.Errorf("第一天時段 = %v ~ %v", first.StartTime, first.EndTime)
+	}
+	if first.Condition != ConditionRainy || first.TemperatureMin != 24 || first.TemperatureMax != 31 ||
+		first.PrecipitationChance != 70 || first.Description != "午後雷陣雨" {
+		t.Errorf("第一天彙整結果 = %+v", first)
+	}
+	if daily[1].Condition != ConditionCloudy {
+		t.Errorf("第二天天氣 = %v, 期望 %v", daily[1].Condition, ConditionCloudy)
+	}
+
+	forecast := &models.WeatherForecast{Hourly: hourly, Daily: daily}
+	if p, err := FindPeriod(forecast, day1.Add(13*time.Hour)); err != nil || p.Description != "午後雷陣雨" {
+		t.Errorf("FindPeriod() 應優先使用逐時預報, 得到 %+v, %v", p, err)
+	}
+	if p, err := FindPeriod(forecast, day1.Add(2*time.Hour)); err != nil || !p.StartTime.Equal(day1) {
+		t.Errorf("FindPeriod() 無逐時預報時應使用逐日預報, 得到 %+v, %v", p, err)
+	}
+	if _, err := FindPeriod(forecast, day1.AddDate(0, 0, 5)); err != ErrForecastOutOfRange {
+		t.Errorf("FindPeriod() 超出範圍錯誤 = %v", err)
+	}
+}
@@ -0,0 +1,39 @@
+package weather
+
+import (
+	"fmt"
+	"math"
+)
+
+// SnapToGrid 將座標對齊到 gridSize 度的格子中心，同一格子內的座標會得到相同結果
+// gridSize <= 0 時不做對齊；結果取到小數點後 6 位以避免浮點誤差影響快取鍵值
+func SnapToGrid(lat, lon, gridSize float64) (float64, float64) {
+	if gridSize <= 0 {
+		return roundCoordinate(lat), roundCoordinate(lon)
+	}
+
+	snap := func(v float64) float64 {
+		return roundCoordinate((math.Floor(v/gridSize) + 0.5) * gridSize)
+	}
+	return snap(lat), snap(lon)
+}
+
+// roundCoordinate 座標取到小數點後 6 位（約 0.1 公尺）
+func roundCoordinate(v float64) float64 {
+	return math.Round(v*1e6) / 1e6
+}
+
+// GridKey 對齊格子後座標的文字鍵值，避免以浮點數比對
+func GridKey(lat, lon float64) string {
+	return fmt.Sprintf("%.6f,%.6f", lat, lon)
+}
+
+// LocationKey 查詢位置（座標需先對齊格子）的快取鍵值，作為 Redis 快取與 weather_data 的唯一鍵
+// 帶有縣市或鄉鎮市區時一併納入，同一格子的縣市層級與鄉鎮層級查詢結果不會互相覆寫
+func LocationKey(loc Location) string {
+	key := GridKey(loc.Latitude, loc.Longitude)
+	if loc.City == "" && loc.District == "" {
+		return key
+	}
+	return key + ":" + loc.City + ":" + loc.District
+}
@@ -0,0 +1,56 @@
+package weather
+
+import "testing"
+
+func TestSnapToGrid(t *testing.T) {
+	tests := []struct {
+		name        string
+		lat         float64
+		lon         float64
+		gridSize    float64
+		expectedLat float64
+		expectedLon float64
+	}{
+		{
+			name:        "台北101",
+			lat:         25.0340,
+			lon:         121.5645,
+			gridSize:    0.05,
+			expectedLat: 25.025,
+			expectedLon: 121.575,
+		},
+		{
+			name:        "同一格子內的鄰近座標",
+			lat:         25.0001,
+			lon:         121.5999,
+			gridSize:    0.05,
+			expectedLat: 25.025,
+			expectedLon: 121.575,
+		},
+		{
+			name:        "負座標",
+			lat:         -33.8688,
+			lon:         -151.2093,
+			gridSize:    0.1,
+			expectedLat: -33.85,
+			expectedLon: -151.25,
+		},
+		{
+			name:        "不對齊",
+			lat:         25.03401234,
+			lon:         121.56451234,
+			gridSize:    0,
+			expectedLat: 25.034012,
+			expectedLon: 121.564512,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			lat, lon := SnapToGrid(tt.lat, tt.lon, tt.gridSize)
+			if lat != tt.expectedLat || lon != tt.expectedLon {
+				t.Errorf("SnapToGrid() = (%v, %v), 期望 (%v, %v)", lat, lon, tt.expectedLat, tt.expectedLon)
+			}
+		})
+	}
+}
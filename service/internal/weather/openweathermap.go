@@ -0,0 +1,220 @@
+package weather
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/andy2kuo/TourHelper/internal/config"
+	"github.com/andy2kuo/TourHelper/internal/models"
+)
+
+const (
+	defaultOpenWeatherMapURL = "https://api.openweathermap.org"
+	defaultRequestTimeout    = 5 * time.Second
+)
+
+// OpenWeatherMapProvider OpenWeatherMap 相容 API 的天氣資料來源
+type OpenWeatherMapProvider struct {
+	baseURL  string
+	apiKey   string
+	language string
+	client   *http.Client
+}
+
+// openWeatherMapCurrent OpenWeatherMap /data/2.5/weather 回應結構
+type openWeatherMapCurrent struct {
+	Weather []openWeatherMapCondition `json:"weather"`
+	Main    struct {
+		Temp     float64 `json:"temp"`
+		Humidity int     `json:"humidity"`
+	} `json:"main"`
+	Wind struct {
+		Speed float64 `json:"speed"`
+	} `json:"wind"`
+}
+
+// openWeatherMapCondition OpenWeatherMap 天氣狀態
+type openWeatherMapCondition struct {
+	ID          int    `json:"id"`
+	Main        string `json:"main"`
+	Description string `json:"description"`
+}
+
+// openWeatherMapForecast OpenWeatherMap /data/2.5/forecast 回應結構（5 天、每 3 小時一筆）
+type openWeatherMapForecast struct {
+	List []struct {
+		Dt      int64                     `json:"dt"`
+		Weather []openWeatherMapCondition `json:"weather"`
+		Main    struct {
+			Temp     float64 `json:"temp"`
+			TempMin  float64 `json:"temp_min"`
+			TempMax  float64 `json:"temp_max"`
+			Humidity int     `json:"humidity"`
+		} `json:"main"`
+		Wind struct {
+			Speed float64 `json:"speed"`
+		} `json:"wind"`
+		Pop float64 `json:"pop"` // 降雨機率 (0-1)
+	} `json:"list"`
+	City struct {
+		Timezone int `json:"timezone"` // 與 UTC 的時差（秒）
+	} `json:"city"`
+}
+
+// openWeatherMapForecastStep OpenWeatherMap 預報的時間間隔
+const openWeatherMapForecastStep = 3 * time.Hour
+
+// openWeatherMapError OpenWeatherMap 錯誤回應
+type openWeatherMapError struct {
+	Message string `json:"message"`
+}
+
+// NewOpenWeatherMapProvider 建立 OpenWeatherMap 天氣資料來源
+func NewOpenWeatherMapProvider(cfg config.WeatherConfig) (*OpenWeatherMapProvider, error) {
+	if cfg.APIKey == "" {
+		return nil, fmt.Errorf("OpenWeatherMap 需要設定 weather.apiKey")
+	}
+
+	baseURL := cfg.BaseURL
+	if baseURL == "" {
+		baseURL = defaultOpenWeatherMapURL
+	}
+
+	timeout := cfg.Timeout
+	if timeout <= 0 {
+		timeout = defaultRequestTimeout
+	}
+
+	return &OpenWeatherMapProvider{
+		baseURL:  strings.TrimRight(baseURL, "/"),
+		apiKey:   cfg.APIKey,
+		language: cfg.Language,
+		client:   &http.Client{Timeout: timeout},
+	}, nil
+}
+
+// Name 返回資料來源名稱
+func (p *OpenWeatherMapProvider) Name() string {
+	return "openweathermap"
+}
+
+// Current 取得指定位置的即時天氣
+func (p *OpenWeatherMapProvider) Current(ctx context.Context, loc Location) (*models.WeatherInfo, error) {
+	var resp openWeatherMapCurrent
+	if err := p.get(ctx, "/data/2.5/weather", loc, &resp); err != nil {
+		return nil, err
+	}
+
+	info := &models.WeatherInfo{
+		Temperature: resp.Main.Temp,
+		Humidity:    resp.Main.Humidity,
+		WindSpeed:   resp.Wind.Speed,
+	}
+	if len(resp.Weather) > 0 {
+		info.Condition = openWeatherMapConditionOf(resp.Weather[0].ID)
+		info.Description = resp.Weather[0].Description
+	}
+
+	return info, nil
+}
+
+// Forecast 取得指定位置的逐時與逐日預報
+// OpenWeatherMap 免費方案僅提供 5 天、每 3 小時一筆的預報，逐日預報由逐時預報彙整而成
+func (p *OpenWeatherMapProvider) Forecast(ctx context.Context, loc Location, days int) (*models.WeatherForecast, error) {
+	var resp openWeatherMapForecast
+	if err := p.get(ctx, "/data/2.5/forecast", loc, &resp); err != nil {
+		return nil, err
+	}
+
+	hourly := make([]models.ForecastPeriod, 0, len(resp.List))
+	for _, item := range resp.List {
+		start := time.Unix(item.Dt, 0)
+		period := models.ForecastPeriod{
+			StartTime: start,
+			EndTime:   start.Add(openWeatherMapForecastStep),
+			WeatherInfo: models.WeatherInfo{
+				Temperature: item.Main.Temp,
+				Humidity:    item.Main.Humidity,
+				WindSpeed:   item.Wind.Speed,
+			},
+			TemperatureMin:      item.Main.TempMin,
+			TemperatureMax:      item.Main.TempMax,
+			PrecipitationChance: int(item.Pop*100 + 0.5),
+		}
+		if len(item.Weather) > 0 {
+			period.Condition = openWeatherMapConditionOf(item.Weather[0].ID)
+			period.Description = item.Weather[0].Description
+		}
+		hourly = append(hourly, period)
+	}
+
+	tz := time.FixedZone("", resp.City.Timezone)
+	daily := AggregateDaily(hourly, tz)
+	if limit := ClampForecastDays(days); len(daily) > limit {
+		daily = daily[:limit]
+	}
+
+	return &models.WeatherForecast{Hourly: hourly, Daily: daily}, nil
+}
+
+// get 呼叫 OpenWeatherMap API 並解析 JSON 回應
+func (p *OpenWeatherMapProvider) get(ctx context.Context, path string, loc Location, out any) error {
+	query := url.Values{}
+	query.Set("lat", strconv.FormatFloat(loc.Latitude, 'f', -1, 64))
+	query.Set("lon", strconv.FormatFloat(loc.Longitude, 'f', -1, 64))
+	query.Set("appid", p.apiKey)
+	query.Set("units", "metric")
+	if p.language != "" {
+		query.Set("lang", p.language)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, p.baseURL+path+"?"+query.Encode(), nil)
+	if err != nil {
+		return fmt.Errorf("建立 OpenWeatherMap 請求失敗: %w", err)
+	}
+
+	res, err := p.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("呼叫 OpenWeatherMap 失敗: %w", err)
+	}
+	defer res.Body.Close()
+
+	body, err := io.ReadAll(res.Body)
+	if err != nil {
+		return fmt.Errorf("讀取 OpenWeatherMap 回應失敗: %w", err)
+	}
+
+	if res.StatusCode != http.StatusOK {
+		var apiErr openWeatherMapError
+		_ = json.Unmarshal(body, &apiErr)
+		return fmt.Errorf("OpenWeatherMap 回應錯誤 (HTTP %d): %s", res.StatusCode, apiErr.Message)
+	}
+
+	if err := json.Unmarshal(body, out); err != nil {
+		return fmt.Errorf("解析 OpenWeatherMap 回應失敗: %w", err)
+	}
+
+	return nil
+}
+
+// openWeatherMapConditionOf 將 OpenWeatherMap 天氣代碼轉換為天氣狀態
+// 參考: https://openweathermap.org/weather-conditions
+func openWeatherMapConditionOf(id int) string {
+	switch {
+	case id >= 200 && id < 600: // 雷雨、毛毛雨、雨
+		return ConditionRainy
+	case id >= 600 && id < 700: // 雪
+		return ConditionSnowy
+	case id == 800 || id == 801: // 晴朗、少雲
+		return ConditionSunny
+	default: // 霧霾等大氣現象、多雲
+		return ConditionCloudy
+	}
+}
@@ -0,0 +1,115 @@
+package weather
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/andy2kuo/TourHelper/internal/config"
+)
+
+func TestOpenWeatherMapProviderCurrent(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/data/2.5/weather" {
+			http.NotFound(w, r)
+			return
+		}
+		if r.URL.Query().Get("appid") != "test-key" {
+			w.WriteHeader(http.StatusUnauthorized)
+			w.Write([]byte(`{"cod":401,"message":"Invalid API key"}`))
+			return
+		}
+		if r.URL.Query().Get("units") != "metric" || r.URL.Query().Get("lat") != "25.034" {
+			t.Errorf("查詢參數錯誤: %s", r.URL.RawQuery)
+		}
+		w.Write([]byte(`{
+			"weather": [{"id": 501, "main": "Rain", "description": "中雨"}],
+			"main": {"temp": 22.4, "humidity": 88},
+			"wind": {"speed": 3.6}
+		}`))
+	}))
+	defer srv.Close()
+
+	provider, err := NewOpenWeatherMapProvider(config.WeatherConfig{APIKey: "test-key", BaseURL: srv.URL})
+	if err != nil {
+		t.Fatalf("NewOpenWeatherMapProvider() 錯誤: %v", err)
+	}
+
+	info, err := provider.Current(context.Background(), Location{Latitude: 25.034, Longitude: 121.5645})
+	if err != nil {
+		t.Fatalf("Current() 錯誤: %v", err)
+	}
+	if info.Condition != ConditionRainy || info.Temperature != 22.4 || info.Humidity != 88 ||
+		info.WindSpeed != 3.6 || info.Description != "中雨" {
+		t.Errorf("Current() = %+v, 與預期不符", info)
+	}
+
+	badKey, _ := NewOpenWeatherMapProvider(config.WeatherConfig{APIKey: "wrong", BaseURL: srv.URL})
+	if _, err := badKey.Current(context.Background(), Location{Latitude: 25.034, Longitude: 121.5645}); err == nil {
+		t.Error("Current() 使用錯誤金鑰時應回傳錯誤")
+	}
+}
+
+func TestOpenWeatherMapConditionOf(t *testing.T) {
+	tests := []struct {
+		name     string
+		id       int
+		expected string
+	}{
+		{name: "雷雨", id: 211, expected: ConditionRainy},
+		{name: "毛毛雨", id: 300, expected: ConditionRainy},
+		{name: "下雪", id: 601, expected: ConditionSnowy},
+		{name: "霧", id: 741, expected: ConditionCloudy},
+		{name: "晴朗", id: 800, expected: ConditionSunny},
+		{name: "陰天", id: 804, expected: ConditionCloudy},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if result := openWeatherMapConditionOf(tt.id); result != tt.expected {
+				t.Errorf("openWeatherMapConditionOf(%d) = %v, 期望 %v", tt.id, result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestOpenWeatherMapProviderForecast(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/data/2.5/forecast" {
+			http.NotFound(w, r)
+			return
+		}
+		// 2025-06-07 09:00、12:00 與 2025-06-08 00:00（台北時間）
+		w.Write([]byte(`{
+			"list": [
+				{"dt": 1749258000, "weather": [{"id": 800, "description": "晴"}], "main": {"temp": 28, "temp_min": 27, "temp_max": 29, "humidity": 60}, "wind": {"speed": 2}, "pop": 0},
+				{"dt": 1749268800, "weather": [{"id": 500, "description": "小雨"}], "main": {"temp": 30, "temp_min": 29, "temp_max": 32, "humidity": 75}, "wind": {"speed": 4}, "pop": 0.64},
+				{"dt": 1749312000, "weather": [{"id": 803, "description": "多雲"}], "main": {"temp": 25, "temp_min": 24, "temp_max": 25, "humidity": 80}, "wind": {"speed": 1}, "pop": 0.1}
+			],
+			"city": {"timezone": 28800}
+		}`))
+	}))
+	defer srv.Close()
+
+	provider, _ := NewOpenWeatherMapProvider(config.WeatherConfig{APIKey: "test-key", BaseURL: srv.URL})
+	forecast, err := provider.Forecast(context.Background(), Location{Latitude: 25.034, Longitude: 121.5645}, 7)
+	if err != nil {
+		t.Fatalf("Forecast() 錯誤: %v", err)
+	}
+
+	if len(forecast.Hourly) != 3 {
+		t.Fatalf("逐時預報數 = %d, 期望 3", len(forecast.Hourly))
+	}
+	if h := forecast.Hourly[1]; h.Condition != ConditionRainy || h.PrecipitationChance != 64 ||
+		h.EndTime.Sub(h.StartTime) != openWeatherMapForecastStep {
+		t.Errorf("逐時預報 = %+v", h)
+	}
+
+	if len(forecast.Daily) != 2 {
+		t.Fatalf("逐日預報數 = %d, 期望 2", len(forecast.Daily))
+	}
+	if d := forecast.Daily[0]; d.TemperatureMin != 27 || d.TemperatureMax != 32 || d.PrecipitationChance != 64 {
+		t.Errorf("逐日預報 = %+v", d)
+	}
+}
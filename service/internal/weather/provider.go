@@ -0,0 +1,54 @@
+package weather
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/andy2kuo/TourHelper/internal/config"
+	"github.com/andy2kuo/TourHelper/internal/models"
+)
+
+// 天氣狀態（與 models.WeatherData.Condition 一致）
+const (
+	ConditionSunny  = "sunny"
+	ConditionCloudy = "cloudy"
+	ConditionRainy  = "rainy"
+	ConditionSnowy  = "snowy"
+)
+
+// Location 天氣查詢位置
+// City/District 為選填，供以行政區劃查詢的資料來源使用
+type Location struct {
+	Latitude  float64
+	Longitude float64
+	City      string // 縣市，例如：台北市
+	District  string // 鄉鎮市區，例如：北投區
+}
+
+// Provider 天氣資料來源介面
+type Provider interface {
+	// Name 返回資料來源名稱
+	Name() string
+
+	// Current 取得指定位置的即時天氣
+	Current(ctx context.Context, loc Location) (*models.WeatherInfo, error)
+
+	// Forecast 取得指定位置的逐時與逐日預報，days 為希望取得的逐日預報天數
+	// 資料來源可提供的天數不足時，回傳其可提供的最大範圍
+	Forecast(ctx context.Context, loc Location, days int) (*models.WeatherForecast, error)
+}
+
+// NewProvider 根據設定建立天氣資料來源
+func NewProvider(cfg config.WeatherConfig) (Provider, error) {
+	switch strings.ToLower(cfg.Provider) {
+	case "openweathermap":
+		return NewOpenWeatherMapProvider(cfg)
+	case "cwa":
+		return NewCWAProvider(cfg)
+	case "fake":
+		return NewFakeProvider(), nil
+	default:
+		return nil, fmt.Errorf("不支援的天氣資料來源: %s", cfg.Provider)
+	}
+}
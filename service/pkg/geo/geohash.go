@@ -0,0 +1,54 @@
+package geo
+
+import "math"
+
+// base32 Geohash 使用的 Base32 字元表
+const base32 = "0123456789bcdefghjkmnpqrstuvwxyz"
+
+// EncodeGeohash 將座標編碼為指定長度的 Geohash 字串
+func EncodeGeohash(lat, lon float64, precision int) string {
+	minLat, maxLat := -90.0, 90.0
+	minLon, maxLon := -180.0, 180.0
+
+	hash := make([]byte, 0, precision)
+	bit, ch := 0, 0
+	even := true // Geohash 由經度位元開始交錯
+
+	for len(hash) < precision {
+		if even {
+			mid := (minLon + maxLon) / 2
+			if lon >= mid {
+				ch |= 1 << (4 - bit)
+				minLon = mid
+			} else {
+				maxLon = mid
+			}
+		} else {
+			mid := (minLat + maxLat) / 2
+			if lat >= mid {
+				ch |= 1 << (4 - bit)
+				minLat = mid
+			} else {
+				maxLat = mid
+			}
+		}
+		even = !even
+
+		if bit < 4 {
+			bit++
+		} else {
+			hash = append(hash, base32[ch])
+			bit, ch = 0, 0
+		}
+	}
+
+	return string(hash)
+}
+
+// cellSize 回傳指定 Geohash 長度的格子大小（緯度高度, 經度寬度，單位：度）
+func cellSize(precision int) (float64, float64) {
+	bits := precision * 5
+	lonBits := (bits + 1) / 2
+	latBits := bits / 2
+	return 180 / math.Pow(2, float64(latBits)), 360 / math.Pow(2, float64(lonBits))
+}
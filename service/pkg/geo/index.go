@@ -0,0 +1,286 @@
+package geo
+
+import (
+	"math"
+	"sort"
+	"sync"
+
+	"github.com/andy2kuo/TourHelper/pkg/utils"
+)
+
+// DefaultPrecision 預設 Geohash 長度（約 4.9km x 4.9km 的格子）
+const DefaultPrecision = 5
+
+// kmPerDegreeLat 每一緯度約等於的公里數
+const kmPerDegreeLat = 111.32
+
+// Point 索引中的座標點
+type Point struct {
+	ID        uint
+	Latitude  float64
+	Longitude float64
+}
+
+// Hit 半徑查詢結果
+type Hit struct {
+	ID       uint
+	Distance float64 // 與查詢中心的距離（公里）
+}
+
+// BoundingBox 經緯度矩形範圍，MinLon 大於 MaxLon 表示範圍跨越 180 度經線
+type BoundingBox struct {
+	MinLat float64
+	MinLon float64
+	MaxLat float64
+	MaxLon float64
+}
+
+// CrossesAntimeridian 範圍是否跨越 180 度經線
+func (b BoundingBox) CrossesAntimeridian() bool {
+	return b.MinLon > b.MaxLon
+}
+
+// Contains 檢查座標是否位於範圍內
+func (b BoundingBox) Contains(lat, lon float64) bool {
+	if lat < b.MinLat || lat > b.MaxLat {
+		return false
+	}
+	if b.CrossesAntimeridian() {
+		return lon >= b.MinLon || lon <= b.MaxLon
+	}
+	return lon >= b.MinLon && lon <= b.MaxLon
+}
+
+// Split 將跨越 180 度經線的範圍拆成東西兩段，未跨越時回傳範圍本身
+func (b BoundingBox) Split() []BoundingBox {
+	if !b.CrossesAntimeridian() {
+		return []BoundingBox{b}
+	}
+	return []BoundingBox{
+		{MinLat: b.MinLat, MinLon: b.MinLon, MaxLat: b.MaxLat, MaxLon: 180},
+		{MinLat: b.MinLat, MinLon: -180, MaxLat: b.MaxLat, MaxLon: b.MaxLon},
+	}
+}
+
+// BoundingBoxAround 計算以指定座標為中心、半徑 radiusKm 的外接矩形
+// 範圍超過 180 度經線時繞到另一側（MinLon 大於 MaxLon）；涵蓋極點時包含所有經度
+func BoundingBoxAround(lat, lon, radiusKm float64) BoundingBox {
+	latDelta := radiusKm / kmPerDegreeLat
+	box := BoundingBox{
+		MinLat: math.Max(-90, lat-latDelta),
+		MinLon: -180,
+		MaxLat: math.Min(90, lat+latDelta),
+		MaxLon: 180,
+	}
+	if box.MinLat <= -90 || box.MaxLat >= 90 {
+		return box
+	}
+
+	cos := math.Cos(lat * math.Pi / 180)
+	if cos <= 1e-9 {
+		return box
+	}
+	lonDelta := radiusKm / (kmPerDegreeLat * cos)
+	if lonDelta >= 180 {
+		return box
+	}
+
+	box.MinLon, box.MaxLon = lon-lonDelta, lon+lonDelta
+	if box.MinLon < -180 {
+		box.MinLon += 360
+	}
+	if box.MaxLon > 180 {
+		box.MaxLon -= 360
+	}
+	return box
+}
+
+// Index 以 Geohash 分桶的記憶體空間索引，可安全地被多個 goroutine 同時使用
+type Index struct {
+	precision int
+	cellLat   float64 // 格子緯度高度（度）
+	cellLon   float64 // 格子經度寬度（度）
+
+	mu      sync.RWMutex
+	points  map[uint]Point
+	cells   map[uint]string              // 每個點所在的 Geohash
+	buckets map[string]map[uint]struct{} // Geohash -> 點 ID 集合
+}
+
+// NewIndex 建立空間索引，precision 為 Geohash 長度（1-12），無效值使用 DefaultPrecision
+func NewIndex(precision int) *Index {
+	if precision < 1 || precision > 12 {
+		precision = DefaultPrecision
+	}
+
+	cellLat, cellLon := cellSize(precision)
+	return &Index{
+		precision: precision,
+		cellLat:   cellLat,
+		cellLon:   cellLon,
+		points:    make(map[uint]Point),
+		cells:     make(map[uint]string),
+		buckets:   make(map[string]map[uint]struct{}),
+	}
+}
+
+// Len 回傳索引中的點數量
+func (idx *Index) Len() int {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+
+	return len(idx.points)
+}
+
+// Upsert 新增或更新座標點
+func (idx *Index) Upsert(p Point) {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	idx.upsertLocked(p)
+}
+
+// Remove 移除座標點，不存在時不做任何事
+func (idx *Index) Remove(id uint) {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	idx.removeLocked(id)
+}
+
+// Reset 清空索引並以指定的點重建
+func (idx *Index) Reset(points []Point) {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	idx.points = make(map[uint]Point, len(points))
+	idx.cells = make(map[uint]string, len(points))
+	idx.buckets = make(map[string]map[uint]struct{})
+	for _, p := range points {
+		idx.upsertLocked(p)
+	}
+}
+
+// Radius 查詢距離中心 radiusKm 公里內的點，結果依距離由近至遠排序
+func (idx *Index) Radius(lat, lon, radiusKm float64) []Hit {
+	if radiusKm < 0 {
+		return nil
+	}
+
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+
+	var hits []Hit
+	idx.scanLocked(BoundingBoxAround(lat, lon, radiusKm), func(p Point) {
+		if d := utils.CalculateDistance(lat, lon, p.Latitude, p.Longitude); d <= radiusKm {
+			hits = append(hits, Hit{ID: p.ID, Distance: d})
+		}
+	})
+
+	sort.Slice(hits, func(i, j int) bool {
+		if hits[i].Distance != hits[j].Distance {
+			return hits[i].Distance < hits[j].Distance
+		}
+		return hits[i].ID < hits[j].ID
+	})
+	return hits
+}
+
+// Within 查詢矩形範圍內的點 ID，結果依 ID 排序
+func (idx *Index) Within(box BoundingBox) []uint {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+
+	var ids []uint
+	idx.scanLocked(box, func(p Point) {
+		ids = append(ids, p.ID)
+	})
+
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	return ids
+}
+
+// upsertLocked 新增或更新座標點（呼叫端需持有寫鎖）
+func (idx *Index) upsertLocked(p Point) {
+	cell := EncodeGeohash(p.Latitude, p.Longitude, idx.precision)
+
+	if old, ok := idx.cells[p.ID]; ok && old != cell {
+		idx.removeFromBucket(old, p.ID)
+	}
+
+	bucket, ok := idx.buckets[cell]
+	if !ok {
+		bucket = make(map[uint]struct{})
+		idx.buckets[cell] = bucket
+	}
+	bucket[p.ID] = struct{}{}
+
+	idx.points[p.ID] = p
+	idx.cells[p.ID] = cell
+}
+
+// removeLocked 移除座標點（呼叫端需持有寫鎖）
+func (idx *Index) removeLocked(id uint) {
+	cell, ok := idx.cells[id]
+	if !ok {
+		return
+	}
+
+	idx.removeFromBucket(cell, id)
+	delete(idx.points, id)
+	delete(idx.cells, id)
+}
+
+// removeFromBucket 從格子中移除點，格子為空時一併刪除
+func (idx *Index) removeFromBucket(cell string, id uint) {
+	bucket := idx.buckets[cell]
+	delete(bucket, id)
+	if len(bucket) == 0 {
+		delete(idx.buckets, cell)
+	}
+}
+
+// scanLocked 走訪範圍內的所有點（呼叫端需持有讀鎖），跨越 180 度經線的範圍拆成兩段分別走訪
+func (idx *Index) scanLocked(box BoundingBox, fn func(Point)) {
+	for _, part := range box.Split() {
+		idx.scanRangeLocked(part, fn)
+	}
+}
+
+// scanRangeLocked 走訪未跨越 180 度經線的範圍內的所有點（呼叫端需持有讀鎖）
+// 範圍涵蓋的格子數比現有格子還多時，直接走訪所有格子較有效率
+func (idx *Index) scanRangeLocked(box BoundingBox, fn func(Point)) {
+	visit := func(bucket map[uint]struct{}) {
+		for id := range bucket {
+			if p := idx.points[id]; box.Contains(p.Latitude, p.Longitude) {
+				fn(p)
+			}
+		}
+	}
+
+	minLatIdx := int(math.Floor((box.MinLat + 90) / idx.cellLat))
+	maxLatIdx := int(math.Floor((box.MaxLat + 90) / idx.cellLat))
+	minLonIdx := int(math.Floor((box.MinLon + 180) / idx.cellLon))
+	maxLonIdx := int(math.Floor((box.MaxLon + 180) / idx.cellLon))
+	if maxLatIdx < minLatIdx || maxLonIdx < minLonIdx {
+		return
+	}
+
+	cellCount := (maxLatIdx - minLatIdx + 1) * (maxLonIdx - minLonIdx + 1)
+	if cellCount > len(idx.buckets) {
+		for _, bucket := range idx.buckets {
+			visit(bucket)
+		}
+		return
+	}
+
+	for i := minLatIdx; i <= maxLatIdx; i++ {
+		centerLat := -90 + (float64(i)+0.5)*idx.cellLat
+		for j := minLonIdx; j <= maxLonIdx; j++ {
+			centerLon := -180 + (float64(j)+0.5)*idx.cellLon
+			if bucket, ok := idx.buckets[EncodeGeohash(centerLat, centerLon, idx.precision)]; ok {
+				visit(bucket)
+			}
+		}
+	}
+}
@@ -0,0 +1,178 @@
+package geo
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/andy2kuo/TourHelper/pkg/utils"
+)
+
+func TestEncodeGeohash(t *testing.T) {
+	tests := []struct {
+		name      string
+		lat       float64
+		lon       float64
+		precision int
+		expected  string
+	}{
+		{
+			name:      "維基百科範例",
+			lat:       57.64911,
+			lon:       10.40744,
+			precision: 11,
+			expected:  "u4pruydqqvj",
+		},
+		{
+			name:      "原點",
+			lat:       0,
+			lon:       0,
+			precision: 5,
+			expected:  "s0000",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := EncodeGeohash(tt.lat, tt.lon, tt.precision)
+			if result != tt.expected {
+				t.Errorf("EncodeGeohash() = %v, 期望 %v", result, tt.expected)
+			}
+		})
+	}
+}
+
+// randomTaiwanPoints 產生台灣範圍內的隨機座標點
+func randomTaiwanPoints(n int, seed int64) []Point {
+	r := rand.New(rand.NewSource(seed))
+	points := make([]Point, n)
+	for i := range points {
+		points[i] = Point{
+			ID:        uint(i + 1),
+			Latitude:  21.9 + r.Float64()*3.4,
+			Longitude: 120.0 + r.Float64()*2.0,
+		}
+	}
+	return points
+}
+
+func TestIndexRadius(t *testing.T) {
+	points := randomTaiwanPoints(5000, 1)
+	idx := NewIndex(DefaultPrecision)
+	idx.Reset(points)
+
+	tests := []struct {
+		name   string
+		lat    float64
+		lon    float64
+		radius float64
+	}{
+		{name: "台北 10 公里", lat: 25.0340, lon: 121.5645, radius: 10},
+		{name: "台中 50 公里", lat: 24.1477, lon: 120.6736, radius: 50},
+		{name: "涵蓋全台", lat: 23.7, lon: 121.0, radius: 500},
+		{name: "零半徑", lat: 23.7, lon: 121.0, radius: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// 以暴力搜尋作為正確答案
+			expected := make(map[uint]bool)
+			for _, p := range points {
+				if utils.CalculateDistance(tt.lat, tt.lon, p.Latitude, p.Longitude) <= tt.radius {
+					expected[p.ID] = true
+				}
+			}
+
+			hits := idx.Radius(tt.lat, tt.lon, tt.radius)
+			if len(hits) != len(expected) {
+				t.Fatalf("Radius() 找到 %d 個點, 期望 %d 個", len(hits), len(expected))
+			}
+			for i, hit := range hits {
+				if !expected[hit.ID] {
+					t.Errorf("Radius() 回傳了範圍外的點 %d", hit.ID)
+				}
+				if i > 0 && hits[i-1].Distance > hit.Distance {
+					t.Errorf("Radius() 結果未依距離排序")
+				}
+			}
+		})
+	}
+}
+
+func TestIndexWithin(t *testing.T) {
+	points := randomTaiwanPoints(2000, 2)
+	idx := NewIndex(DefaultPrecision)
+	idx.Reset(points)
+
+	box := BoundingBox{MinLat: 24.9, MinLon: 121.3, MaxLat: 25.3, MaxLon: 121.7}
+	expected := 0
+	for _, p := range points {
+		if box.Contains(p.Latitude, p.Longitude) {
+			expected++
+		}
+	}
+
+	if ids := idx.Within(box); len(ids) != expected {
+		t.Errorf("Within() 找到 %d 個點, 期望 %d 個", len(ids), expected)
+	}
+}
+
+func TestIndexRadiusAcrossAntimeridian(t *testing.T) {
+	// 斐濟附近的點分布在 180 度經線兩側
+	idx := NewIndex(DefaultPrecision)
+	idx.Reset([]Point{
+		{ID: 1, Latitude: -17.0, Longitude: 179.9},
+		{ID: 2, Latitude: -17.0, Longitude: -179.9},
+		{ID: 3, Latitude: -17.0, Longitude: 178.0},
+	})
+
+	box := BoundingBoxAround(-17.0, 179.95, 20)
+	if !box.CrossesAntimeridian() {
+		t.Fatalf("BoundingBoxAround() = %+v, 期望跨越 180 度經線", box)
+	}
+
+	hits := idx.Radius(-17.0, 179.95, 20)
+	if len(hits) != 2 || hits[0].ID+hits[1].ID != 3 {
+		t.Errorf("Radius() = %+v, 期望找到經線兩側的點 1 與 2", hits)
+	}
+	if ids := idx.Within(box); len(ids) != 2 {
+		t.Errorf("Within() = %v, 期望找到經線兩側的點 1 與 2", ids)
+	}
+}
+
+func TestIndexUpsertAndRemove(t *testing.T) {
+	idx := NewIndex(DefaultPrecision)
+	idx.Upsert(Point{ID: 1, Latitude: 25.0340, Longitude: 121.5645}) // 台北101
+	idx.Upsert(Point{ID: 2, Latitude: 25.1896, Longitude: 121.5453}) // 陽明山
+
+	if hits := idx.Radius(25.0340, 121.5645, 1); len(hits) != 1 || hits[0].ID != 1 {
+		t.Fatalf("Radius() = %v, 期望只有點 1", hits)
+	}
+
+	// 將點 1 移到高雄
+	idx.Upsert(Point{ID: 1, Latitude: 22.6273, Longitude: 120.3014})
+	if hits := idx.Radius(25.0340, 121.5645, 1); len(hits) != 0 {
+		t.Errorf("更新座標後 Radius() = %v, 期望沒有結果", hits)
+	}
+	if hits := idx.Radius(22.6273, 120.3014, 1); len(hits) != 1 || hits[0].ID != 1 {
+		t.Errorf("更新座標後 Radius() = %v, 期望找到點 1", hits)
+	}
+
+	idx.Remove(1)
+	idx.Remove(99) // 不存在的點
+	if idx.Len() != 1 {
+		t.Errorf("Len() = %d, 期望 1", idx.Len())
+	}
+	if hits := idx.Radius(22.6273, 120.3014, 1); len(hits) != 0 {
+		t.Errorf("移除後 Radius() = %v, 期望沒有結果", hits)
+	}
+}
+
+func BenchmarkIndexRadius(b *testing.B) {
+	idx := NewIndex(DefaultPrecision)
+	idx.Reset(randomTaiwanPoints(50000, 3))
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		idx.Radius(25.0340, 121.5645, 20)
+	}
+}